@@ -0,0 +1,333 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/config"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/convert"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	driver "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-pd-csi-driver"
+)
+
+func duration(d time.Duration) metav1.Duration {
+	return metav1.Duration{Duration: d}
+}
+
+// flagOverrides sets the configuration field of each flag. Flags that are
+// set on the command line override the configuration file.
+var flagOverrides = map[string]func(c *config.DriverConfiguration) error{
+	"backoff-initial-duration-ms": func(c *config.DriverConfiguration) error {
+		c.Controller.ErrorBackoff.InitialDuration = duration(time.Duration(*errorBackoffInitialDurationMs) * time.Millisecond)
+		return nil
+	},
+	"backoff-max-duration-ms": func(c *config.DriverConfiguration) error {
+		c.Controller.ErrorBackoff.MaxDuration = duration(time.Duration(*errorBackoffMaxDurationMs) * time.Millisecond)
+		return nil
+	},
+	"extra-labels": func(c *config.DriverConfiguration) error {
+		labels, err := convert.ConvertLabelsStringToMap(*extraVolumeLabelsStr)
+		c.Controller.ExtraLabels = labels
+		return err
+	},
+	"attach-disk-backoff-duration": func(c *config.DriverConfiguration) error {
+		c.Controller.AttachDiskBackoff.Duration = duration(*attachDiskBackoffDuration)
+		return nil
+	},
+	"attach-disk-backoff-factor": func(c *config.DriverConfiguration) error {
+		c.Controller.AttachDiskBackoff.Factor = *attachDiskBackoffFactor
+		return nil
+	},
+	"attach-disk-backoff-jitter": func(c *config.DriverConfiguration) error {
+		c.Controller.AttachDiskBackoff.Jitter = *attachDiskBackoffJitter
+		return nil
+	},
+	"attach-disk-backoff-steps": func(c *config.DriverConfiguration) error {
+		c.Controller.AttachDiskBackoff.Steps = *attachDiskBackoffSteps
+		return nil
+	},
+	"attach-disk-backoff-cap": func(c *config.DriverConfiguration) error {
+		c.Controller.AttachDiskBackoff.Cap = duration(*attachDiskBackoffCap)
+		return nil
+	},
+	"wait-op-backoff-duration": func(c *config.DriverConfiguration) error {
+		c.Controller.WaitForOpBackoff.Duration = duration(*waitForOpBackoffDuration)
+		return nil
+	},
+	"wait-op-backoff-factor": func(c *config.DriverConfiguration) error {
+		c.Controller.WaitForOpBackoff.Factor = *waitForOpBackoffFactor
+		return nil
+	},
+	"wait-op-backoff-jitter": func(c *config.DriverConfiguration) error {
+		c.Controller.WaitForOpBackoff.Jitter = *waitForOpBackoffJitter
+		return nil
+	},
+	"wait-op-backoff-steps": func(c *config.DriverConfiguration) error {
+		c.Controller.WaitForOpBackoff.Steps = *waitForOpBackoffSteps
+		return nil
+	},
+	"wait-op-backoff-cap": func(c *config.DriverConfiguration) error {
+		c.Controller.WaitForOpBackoff.Cap = duration(*waitForOpBackoffCap)
+		return nil
+	},
+	"fallback-requisite-zones": func(c *config.DriverConfiguration) error {
+		c.Controller.FallbackRequisiteZones = parseCSVFlag(*fallbackRequisiteZonesFlag)
+		return nil
+	},
+	"allowed-projects": func(c *config.DriverConfiguration) error {
+		c.Controller.AllowedProjects = parseCSVFlag(*allowedProjectsFlag)
+		return nil
+	},
+	"cluster-name": func(c *config.DriverConfiguration) error {
+		c.Controller.ClusterName = *clusterName
+		return nil
+	},
+	"enable-storage-pools": func(c *config.DriverConfiguration) error {
+		c.Controller.EnableStoragePools = *enableStoragePoolsFlag
+		return nil
+	},
+	"allow-hdha-provisioning": func(c *config.DriverConfiguration) error {
+		c.Controller.AllowHdHAProvisioning = *enableHdHAFlag
+		return nil
+	},
+	"disk-topology": func(c *config.DriverConfiguration) error {
+		c.Controller.EnableDiskTopology = *diskTopology
+		return nil
+	},
+	"enable-disk-size-validation": func(c *config.DriverConfiguration) error {
+		c.Controller.EnableDiskSizeValidation = *enableDiskSizeValidation
+		return nil
+	},
+	"multi-zone-volume-handle-enable": func(c *config.DriverConfiguration) error {
+		c.Controller.MultiZoneVolumeHandle.Enable = *multiZoneVolumeHandleEnableFlag
+		return nil
+	},
+	"multi-zone-volume-handle-disk-types": func(c *config.DriverConfiguration) error {
+		c.Controller.MultiZoneVolumeHandle.DiskTypes = parseCSVFlag(*multiZoneVolumeHandleDiskTypesFlag)
+		return nil
+	},
+	"use-instance-api-to-list-volumes-published-nodes": func(c *config.DriverConfiguration) error {
+		c.Controller.ListVolumes.UseInstancesAPIForPublishedNodes = *useInstanceAPIForListVolumesPublishedNodesFlag
+		return nil
+	},
+	"instances-list-filters": func(c *config.DriverConfiguration) error {
+		c.Controller.ListVolumes.InstancesListFilters = parseCSVFlag(*instancesListFiltersFlag)
+		return nil
+	},
+	"use-instance-api-to-poll-attachment-disk-types": func(c *config.DriverConfiguration) error {
+		c.Controller.WaitForAttach.UseInstancesAPIForDiskTypes = parseCSVFlag(*useInstanceAPIOnWaitForAttachDiskTypesFlag)
+		return nil
+	},
+	"supports-dynamic-iops-provisioning": func(c *config.DriverConfiguration) error {
+		c.Controller.ProvisionableDisks.SupportsDynamicIopsProvisioning = parseCSVFlag(*diskSupportsIopsChangeFlag)
+		return nil
+	},
+	"supports-dynamic-throughput-provisioning": func(c *config.DriverConfiguration) error {
+		c.Controller.ProvisionableDisks.SupportsDynamicThroughputProvisioning = parseCSVFlag(*diskSupportsThroughputChangeFlag)
+		return nil
+	},
+	"enable-regional-failover": func(c *config.DriverConfiguration) error {
+		c.Controller.RegionalFailover.Enable = *enableRegionalFailover
+		return nil
+	},
+	"regional-failover-grace-period": func(c *config.DriverConfiguration) error {
+		c.Controller.RegionalFailover.GracePeriod = duration(*regionalFailoverGracePeriod)
+		return nil
+	},
+	"regional-failover-sync-period": func(c *config.DriverConfiguration) error {
+		c.Controller.RegionalFailover.SyncPeriod = duration(*regionalFailoverSyncPeriod)
+		return nil
+	},
+	"enable-final-snapshot-collector": func(c *config.DriverConfiguration) error {
+		c.Controller.FinalSnapshotCollector.Enable = *enableFinalSnapshotCollector
+		return nil
+	},
+	"final-snapshot-collector-period": func(c *config.DriverConfiguration) error {
+		c.Controller.FinalSnapshotCollector.SyncPeriod = duration(*finalSnapshotCollectorPeriod)
+		return nil
+	},
+	"deletion-protection-label": func(c *config.DriverConfiguration) error {
+		c.Controller.DeletionProtection.Label = *deletionProtectionLabel
+		return nil
+	},
+	"deletion-protection-resource-tag": func(c *config.DriverConfiguration) error {
+		c.Controller.DeletionProtection.ResourceTag = *deletionProtectionResourceTag
+		return nil
+	},
+	"deletion-protection-check-ownership": func(c *config.DriverConfiguration) error {
+		c.Controller.DeletionProtection.CheckOwnership = *deletionProtectionCheckOwnership
+		return nil
+	},
+	"deletion-protection-refuse-non-kubernetes-users": func(c *config.DriverConfiguration) error {
+		c.Controller.DeletionProtection.RefuseNonKubernetesUsers = *deletionProtectionRefuseNonKubernetesUsers
+		return nil
+	},
+	"enable-device-in-use-check-on-node-unstage": func(c *config.DriverConfiguration) error {
+		c.Node.DeviceInUseCheck.Enable = *enableDeviceInUseCheck
+		return nil
+	},
+	"device-in-use-timeout": func(c *config.DriverConfiguration) error {
+		c.Node.DeviceInUseCheck.Timeout = duration(*deviceInUseTimeout)
+		return nil
+	},
+	"max-concurrent-format": func(c *config.DriverConfiguration) error {
+		c.Node.MaxConcurrentFormat = *maxConcurrentFormat
+		return nil
+	},
+	"concurrent-format-timeout": func(c *config.DriverConfiguration) error {
+		c.Node.ConcurrentFormatTimeout = duration(*concurrentFormatTimeout)
+		return nil
+	},
+	"max-concurrent-format-and-mount": func(c *config.DriverConfiguration) error {
+		c.Node.MaxConcurrentFormatAndMount = *maxConcurrentFormatAndMount
+		return nil
+	},
+	"format-and-mount-timeout": func(c *config.DriverConfiguration) error {
+		c.Node.FormatAndMountTimeout = duration(*formatAndMountTimeout)
+		return nil
+	},
+	"disk-cache-sync-period": func(c *config.DriverConfiguration) error {
+		c.Node.DiskCacheSyncPeriod = duration(*diskCacheSyncPeriod)
+		return nil
+	},
+	"enable-project-quota": func(c *config.DriverConfiguration) error {
+		c.Node.EnableProjectQuota = *enableProjectQuota
+		return nil
+	},
+	"disk-type-topology": func(c *config.DriverConfiguration) error {
+		c.Node.EnableDiskTypeTopology = *diskTypeTopology
+		return nil
+	},
+	"device-discovery": func(c *config.DriverConfiguration) error {
+		c.Node.DeviceDiscovery = *deviceDiscovery
+		return nil
+	},
+	"startup-reconcile": func(c *config.DriverConfiguration) error {
+		c.Node.StartupReconcile.Enable = *startupReconcile
+		return nil
+	},
+	"startup-reconcile-dry-run": func(c *config.DriverConfiguration) error {
+		c.Node.StartupReconcile.DryRun = *startupReconcileDryRun
+		return nil
+	},
+	"kubelet-root-dir": func(c *config.DriverConfiguration) error {
+		c.Node.KubeletRootDir = *kubeletRootDir
+		return nil
+	},
+	"enable-data-cache": func(c *config.DriverConfiguration) error {
+		c.DataCache.Enable = *enableDataCacheFlag
+		return nil
+	},
+	"data-cache-stats-period": func(c *config.DriverConfiguration) error {
+		c.DataCache.StatsPeriod = duration(*dataCacheStatsPeriod)
+		return nil
+	},
+	"enable-health-checks": func(c *config.DriverConfiguration) error {
+		c.HealthChecks.Enable = *enableHealthChecks
+		return nil
+	},
+	"health-check-ttl": func(c *config.DriverConfiguration) error {
+		c.HealthChecks.TTL = duration(*healthCheckTTL)
+		return nil
+	},
+	"health-check-timeout": func(c *config.DriverConfiguration) error {
+		c.HealthChecks.Timeout = duration(*healthCheckTimeout)
+		return nil
+	},
+	"health-check-failure-threshold": func(c *config.DriverConfiguration) error {
+		c.HealthChecks.FailureThreshold = *healthCheckFailureThreshold
+		return nil
+	},
+	"health-status-path": func(c *config.DriverConfiguration) error {
+		c.HealthChecks.StatusPath = *healthStatusPath
+		return nil
+	},
+}
+
+// applyFlagOverrides sets the fields of the flags set on the command line.
+func applyFlagOverrides(fs *flag.FlagSet, c *config.DriverConfiguration) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if override, ok := flagOverrides[f.Name]; ok && err == nil {
+			err = override(c)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// loadDriverConfig returns the configuration file at path, or the defaults
+// if path is empty, with the flags set on the command line applied.
+func loadDriverConfig(fs *flag.FlagSet, path string) (*config.DriverConfiguration, error) {
+	c := config.Default()
+	if path != "" {
+		var err error
+		if c, err = config.Load(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyFlagOverrides(fs, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func provisionableDisksConfig(c *config.DriverConfiguration) driver.ProvisionableDisksConfig {
+	return driver.ProvisionableDisksConfig{
+		SupportsIopsChange:       c.Controller.ProvisionableDisks.SupportsDynamicIopsProvisioning,
+		SupportsThroughputChange: c.Controller.ProvisionableDisks.SupportsDynamicThroughputProvisioning,
+	}
+}
+
+// applyReloadableConfig applies the fields of c that can change while the
+// driver runs. controllerServer is nil if the controller service is not run.
+func applyReloadableConfig(c *config.DriverConfiguration, controllerServer *driver.GCEControllerServer) {
+	gce.SetAttachDiskBackoff(c.Controller.AttachDiskBackoff.WaitBackoff())
+	gce.SetWaitForOpBackoff(c.Controller.WaitForOpBackoff.WaitBackoff())
+	if controllerServer != nil {
+		controllerServer.SetProvisionableDisksConfig(provisionableDisksConfig(c))
+	}
+}
+
+// watchDriverConfig reloads the configuration file at path when it changes,
+// and applies the fields that can change while the driver runs.
+func watchDriverConfig(ctx context.Context, fs *flag.FlagSet, path string, current *config.DriverConfiguration, controllerServer *driver.GCEControllerServer) {
+	err := config.Watch(ctx, path, func() {
+		updated, err := loadDriverConfig(fs, path)
+		if err != nil {
+			klog.Errorf("Failed to reload configuration, keeping the current one: %v", err)
+			return
+		}
+		next, err := config.Reload(current, updated)
+		if err != nil {
+			klog.Warningf("Configuration %s changed: %v", path, err)
+		}
+		current = next
+		applyReloadableConfig(current, controllerServer)
+		klog.Infof("Reloaded configuration %s", path)
+	})
+	if err != nil {
+		klog.Errorf("Configuration %s will not be reloaded: %v", path, err)
+	}
+}
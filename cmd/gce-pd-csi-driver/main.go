@@ -23,32 +23,48 @@ import (
 	"math/rand"
 	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	"k8s.io/klog/v2"
 	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/convert"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/encryption"
 	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
 	metadataservice "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/metadata"
 	driver "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-pd-csi-driver"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/health"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/linkcache"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
 	mountmanager "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/mount-manager"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/quota"
 )
 
 var (
-	cloudConfigFilePath  = flag.String("cloud-config", "", "Path to GCE cloud provider config")
-	endpoint             = flag.String("endpoint", "unix:/tmp/csi.sock", "CSI endpoint")
-	runControllerService = flag.Bool("run-controller-service", true, "If set to false then the CSI driver does not activate its controller service (default: true)")
-	runNodeService       = flag.Bool("run-node-service", true, "If set to false then the CSI driver does not activate its node service (default: true)")
-	httpEndpoint         = flag.String("http-endpoint", "", "The TCP network address where the prometheus metrics endpoint will listen (example: `:8080`). The default is empty string, which means metrics endpoint is disabled.")
-	metricsPath          = flag.String("metrics-path", "/metrics", "The HTTP path where prometheus metrics will be exposed. Default is `/metrics`.")
-	grpcLogCharCap       = flag.Int("grpc-log-char-cap", 10000, "The maximum amount of characters logged for every grpc responses")
-	enableOtelTracing    = flag.Bool("enable-otel-tracing", false, "If set, enable opentelemetry tracing for the driver. The tracing is disabled by default. Configure the exporter endpoint with OTEL_EXPORTER_OTLP_ENDPOINT and other env variables, see https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/#general-sdk-configuration.")
+	cloudConfigFilePath         = flag.String("cloud-config", "", "Path to GCE cloud provider config")
+	configFilePath              = flag.String("config", "", "Path to a DriverConfiguration file (apiVersion pd.csi.storage.gke.io/v1alpha1) with the controller and node settings. Flags set on the command line override the file. Changes of controller.attachDiskBackoff, controller.waitForOpBackoff and controller.provisionableDisks are applied without a restart.")
+	endpoint                    = flag.String("endpoint", "unix:/tmp/csi.sock", "CSI endpoint")
+	runControllerService        = flag.Bool("run-controller-service", true, "If set to false then the CSI driver does not activate its controller service (default: true)")
+	runNodeService              = flag.Bool("run-node-service", true, "If set to false then the CSI driver does not activate its node service (default: true)")
+	httpEndpoint                = flag.String("http-endpoint", "", "The TCP network address where the prometheus metrics endpoint will listen (example: `:8080`). The default is empty string, which means metrics endpoint is disabled.")
+	metricsPath                 = flag.String("metrics-path", "/metrics", "The HTTP path where prometheus metrics will be exposed. Default is `/metrics`.")
+	grpcLogCharCap              = flag.Int("grpc-log-char-cap", 10000, "The maximum amount of characters logged for every grpc responses")
+	enableHealthChecks          = flag.Bool("enable-health-checks", false, "If set to true, Probe reports the driver not ready when a health check fails --health-check-failure-threshold times in a row: GCE API access for the controller, and the metadata server, filesystem and udev tools and Data Cache volume group for the node. The results are also served as JSON on --health-status-path of the metrics server. This flag is disabled by default.")
+	healthCheckTTL              = flag.Duration("health-check-ttl", 30*time.Second, "How long the result of a health check is reused by Probe before the check runs again.")
+	healthCheckTimeout          = flag.Duration("health-check-timeout", 10*time.Second, "Timeout of a single health check.")
+	healthCheckFailureThreshold = flag.Int("health-check-failure-threshold", 3, "Number of consecutive failures of a health check after which Probe reports the driver not ready.")
+	healthStatusPath            = flag.String("health-status-path", "/health", "The HTTP path of the metrics endpoint where the health check results are served.")
+	shutdownDrainTimeout        = flag.Duration("shutdown-drain-timeout", 25*time.Second, "On SIGTERM or SIGINT, how long the driver stops accepting new RPCs and waits for in-flight volume operations to finish before it exits. Keep it below the terminationGracePeriodSeconds of the driver pods.")
+	enableOtelTracing           = flag.Bool("enable-otel-tracing", false, "If set, enable opentelemetry tracing for the driver. The tracing is disabled by default. Configure the exporter endpoint with OTEL_EXPORTER_OTLP_ENDPOINT and other env variables, see https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/#general-sdk-configuration.")
 
 	errorBackoffInitialDurationMs = flag.Int("backoff-initial-duration-ms", 200, "The amount of ms for the initial duration of the backoff condition for controller publish/unpublish CSI operations. Default is 200.")
 	errorBackoffMaxDurationMs     = flag.Int("backoff-max-duration-ms", 300000, "The amount of ms for the max duration of the backoff condition for controller publish/unpublish CSI operations. Default is 300000 (5m).")
@@ -75,6 +91,7 @@ var (
 	maxConcurrentFormatAndMount = flag.Int("max-concurrent-format-and-mount", 1, "If set then format and mount operations are serialized on each node. This is stronger than max-concurrent-format as it includes fsck and other mount operations")
 	formatAndMountTimeout       = flag.Duration("format-and-mount-timeout", 1*time.Minute, "The maximum duration of a format and mount operation before another such operation will be started. Used only if --serialize-format-and-mount")
 	fallbackRequisiteZonesFlag  = flag.String("fallback-requisite-zones", "", "Comma separated list of requisite zones that will be used if there are not sufficient zones present in requisite topologies when provisioning a disk")
+	allowedProjectsFlag         = flag.String("allowed-projects", "", "Comma separated list of projects that the project StorageClass parameter may provision disks in. Disks of these projects are also listed by ListVolumes")
 	enableStoragePoolsFlag      = flag.Bool("enable-storage-pools", false, "If set to true, the CSI Driver will allow volumes to be provisioned in Storage Pools")
 	enableHdHAFlag              = flag.Bool("allow-hdha-provisioning", false, "If set to true, will allow the driver to provision Hyperdisk-balanced High Availability disks")
 	enableDataCacheFlag         = flag.Bool("enable-data-cache", false, "If set to true, the CSI Driver will allow volumes to be provisioned with Data Cache configuration")
@@ -95,14 +112,38 @@ var (
 	diskSupportsIopsChangeFlag       = flag.String("supports-dynamic-iops-provisioning", "", "Comma separated list of disk types that support dynamic IOPS provisioning")
 	diskSupportsThroughputChangeFlag = flag.String("supports-dynamic-throughput-provisioning", "", "Comma separated list of disk types that support dynamic throughput provisioning")
 
+	enableRegionalFailover      = flag.Bool("enable-regional-failover", false, "If set to true, the controller force attaches regional disks in their other replica zone when all the nodes of the zone they are attached in have been NotReady for --regional-failover-grace-period, without waiting for Kubernetes to detach them. Events are recorded on the NotReady nodes. This flag is disabled by default.")
+	regionalFailoverGracePeriod = flag.Duration("regional-failover-grace-period", 2*time.Minute, "How long all the nodes of a zone must be NotReady for regional failover to consider the zone failed.")
+	regionalFailoverSyncPeriod  = flag.Duration("regional-failover-sync-period", 30*time.Second, "Period of the regional failover checks of the nodes and the disks attached to them.")
+
+	enableFinalSnapshotCollector = flag.Bool("enable-final-snapshot-collector", false, "If set to true, the controller deletes the final snapshots taken before deleting volumes with the snapshot-before-delete parameter once their snapshot-before-delete-retention-days have passed. The snapshots of the default project, of --allowed-projects and of the projects final snapshots were taken in with Secret credentials since the controller started are deleted. This flag is disabled by default.")
+	finalSnapshotCollectorPeriod = flag.Duration("final-snapshot-collector-period", time.Hour, "Period of the checks of the final snapshots for expired ones.")
+
+	clusterName                                = flag.String("cluster-name", "", "Name of the cluster of the driver. It is recorded in the description of the disks the driver creates, and checked by --deletion-protection-check-ownership")
+	deletionProtectionLabel                    = flag.String("deletion-protection-label", "", "If set, DeleteVolume refuses to delete the disks which have this GCE label set to true")
+	deletionProtectionResourceTag              = flag.String("deletion-protection-resource-tag", "", "If set, DeleteVolume refuses to delete the disks which have this resource tag, given as '<parent_id>/<tag_key>/<tag_value>'")
+	deletionProtectionCheckOwnership           = flag.Bool("deletion-protection-check-ownership", false, "If set to true, DeleteVolume refuses to delete the disks whose description names another cluster than --cluster-name, or another PV than the one of the volume")
+	deletionProtectionRefuseNonKubernetesUsers = flag.Bool("deletion-protection-refuse-non-kubernetes-users", false, "If set to true, DeleteVolume refuses to delete the disks attached to VMs which are not nodes of the cluster")
+
 	extraTagsStr = flag.String("extra-tags", "", "Extra tags to attach to each Compute Disk, Image, Snapshot created. It is a comma separated list of parent id, key and value like '<parent_id1>/<tag_key1>/<tag_value1>,...,<parent_idN>/<tag_keyN>/<tag_valueN>'. parent_id is the Organization or the Project ID or Project name where the tag key and the tag value resources exist. A maximum of 50 tags bindings is allowed for a resource. See https://cloud.google.com/resource-manager/docs/tags/tags-overview, https://cloud.google.com/resource-manager/docs/tags/tags-creating-and-managing for details")
 
 	diskTopology = flag.Bool("disk-topology", false, "If set to true, the driver will add a disk-type.gke.io/[disk-type] topology label when the StorageClass has the use-allowed-disk-topology parameter set to true. That topology label is included in the Topologies returned in CreateVolumeResponse. This flag is disabled by default.")
 
-	diskCacheSyncPeriod = flag.Duration("disk-cache-sync-period", 10*time.Minute, "Period for the disk cache to check the /dev/disk/by-id/ directory and evaluate the symlinks")
+	dataCacheStatsPeriod = flag.Duration("data-cache-stats-period", time.Minute, "Period for the node to collect the statistics of Data Cache volumes, which are exported as metrics when --http-endpoint is set.")
+	diskCacheSyncPeriod  = flag.Duration("disk-cache-sync-period", 10*time.Minute, "Period for the disk cache to check the /dev/disk/by-id/ directory and evaluate the symlinks. The cache is also updated on block device uevents, so this is only a fallback.")
 
 	enableDiskSizeValidation = flag.Bool("enable-disk-size-validation", false, "If set to true, the driver will validate that the requested disk size is matches the physical disk size. This flag is disabled by default.")
 
+	enableProjectQuota = flag.Bool("enable-project-quota", false, "If set to true, volumes whose volume context sets quota-subpath are published as xfs or ext4 project quota limited subdirectories, and their stats are reported per quota project. This flag is disabled by default.")
+
+	startupReconcile       = flag.Bool("startup-reconcile", false, "If set to true, the node plugin cleans up on startup after volumes that were detached while it was not running: it unmounts their staging paths, removes dangling /dev/disk/by-id symlinks and removes their Data Cache logical volumes. Every action is logged and counted in the node_startup_reconcile_actions metric. Run it first with --startup-reconcile-dry-run to review the actions. Linux only. This flag is disabled by default.")
+	startupReconcileDryRun = flag.Bool("startup-reconcile-dry-run", false, "If set to true together with --startup-reconcile, the node plugin only logs the clean up actions it would take on startup.")
+	kubeletRootDir         = flag.String("kubelet-root-dir", "/var/lib/kubelet", "Root directory of kubelet on the node, under which the staging paths of volumes are found by --startup-reconcile.")
+
+	diskTypeTopology = flag.Bool("disk-type-topology", false, "If set to true, the node plugin publishes a disk-type.pd.csi.storage.gke.io/[disk-type] topology segment for every disk type, \"true\" when the machine series of the node can attach it and \"false\" otherwise, and attach-limit.pd.csi.storage.gke.io/pd and attach-limit.pd.csi.storage.gke.io/hyperdisk segments with its attach limits. The controller only provisions disks in zones with nodes that can attach them. This flag is disabled by default.")
+
+	deviceDiscovery = flag.String("device-discovery", deviceutils.DeviceDiscoveryUdev, "How the node finds attached disks. \"udev\" (default) relies on the /dev/disk/by-id symlinks created by udev and repairs them with udevadm. \"sysfs\" reads SCSI VPD page 0x80 and NVMe identify namespace serials directly and creates missing by-id symlinks itself, for node images where udev is not available to the driver.")
+
 	version string
 )
 
@@ -166,6 +207,7 @@ func handle() {
 		switch {
 		case *runControllerService:
 			mm.RegisterPDCSIMetric()
+			mm.RegisterDeleteVolumeRefusalMetric()
 			if metrics.IsGKEComponentVersionAvailable() {
 				mm.EmitGKEComponentVersion()
 			}
@@ -174,16 +216,20 @@ func handle() {
 				klog.Errorf("Failed to emit process start time: %v", err.Error())
 			}
 			mm.RegisterMountMetric()
+			mm.RegisterDevicePathChangeMetric()
+			mm.RegisterDataCacheMetric()
+			mm.RegisterStartupReconcileMetric()
 		}
 		metricsManager = &mm
 	}
 
-	if len(*extraVolumeLabelsStr) > 0 && !*runControllerService {
-		klog.Fatalf("Extra volume labels provided but not running controller")
-	}
-	extraVolumeLabels, err := convert.ConvertLabelsStringToMap(*extraVolumeLabelsStr)
+	cfg, err := loadDriverConfig(flag.CommandLine, *configFilePath)
 	if err != nil {
-		klog.Fatalf("Bad extra volume labels: %v", err.Error())
+		klog.Fatalf("Failed to load configuration: %v", err.Error())
+	}
+
+	if len(cfg.Controller.ExtraLabels) > 0 && !*runControllerService {
+		klog.Fatalf("Extra volume labels provided but not running controller")
 	}
 
 	if len(*extraTagsStr) > 0 && !*runControllerService {
@@ -202,38 +248,24 @@ func handle() {
 
 	// Initialize identity server
 	identityServer := driver.NewIdentityServer(gceDriver)
+	var healthChecker *health.Checker
+	if cfg.HealthChecks.Enable {
+		healthChecker = health.NewChecker(cfg.HealthChecks.TTL.Duration, cfg.HealthChecks.Timeout.Duration, cfg.HealthChecks.FailureThreshold)
+		identityServer.HealthChecker = healthChecker
+	}
 
-	// Initialize requisite zones
-	fallbackRequisiteZones := parseCSVFlag(*fallbackRequisiteZonesFlag)
-
-	// Initialize multi-zone disk types
-	multiZoneVolumeHandleDiskTypes := parseCSVFlag(*multiZoneVolumeHandleDiskTypesFlag)
 	multiZoneVolumeHandleConfig := driver.MultiZoneVolumeHandleConfig{
-		Enable:    *multiZoneVolumeHandleEnableFlag,
-		DiskTypes: multiZoneVolumeHandleDiskTypes,
+		Enable:    cfg.Controller.MultiZoneVolumeHandle.Enable,
+		DiskTypes: cfg.Controller.MultiZoneVolumeHandle.DiskTypes,
 	}
-
-	// Initialize waitForAttach config
-	useInstanceAPIOnWaitForAttachDiskTypes := parseCSVFlag(*useInstanceAPIOnWaitForAttachDiskTypesFlag)
 	waitForAttachConfig := gce.WaitForAttachConfig{
-		UseInstancesAPIForDiskTypes: useInstanceAPIOnWaitForAttachDiskTypes,
+		UseInstancesAPIForDiskTypes: cfg.Controller.WaitForAttach.UseInstancesAPIForDiskTypes,
 	}
-
-	// Initialize listVolumes config
-	instancesListFilters := parseCSVFlag(*instancesListFiltersFlag)
 	listInstancesConfig := gce.ListInstancesConfig{
-		Filters: instancesListFilters,
+		Filters: cfg.Controller.ListVolumes.InstancesListFilters,
 	}
 	listVolumesConfig := driver.ListVolumesConfig{
-		UseInstancesAPIForPublishedNodes: *useInstanceAPIForListVolumesPublishedNodesFlag,
-	}
-
-	// Initialize provisionableDisks config
-	supportsIopsChange := parseCSVFlag(*diskSupportsIopsChangeFlag)
-	supportsThroughputChange := parseCSVFlag(*diskSupportsThroughputChangeFlag)
-	provisionableDisksConfig := driver.ProvisionableDisksConfig{
-		SupportsIopsChange:       supportsIopsChange,
-		SupportsThroughputChange: supportsThroughputChange,
+		UseInstancesAPIForPublishedNodes: cfg.Controller.ListVolumes.UseInstancesAPIForPublishedNodes,
 	}
 
 	// Initialize requirements for the controller service
@@ -244,21 +276,63 @@ func handle() {
 			klog.Fatalf("Failed to get cloud provider: %v", err.Error())
 		}
 
+		cloudProvider.AdditionalProjects = cfg.Controller.AllowedProjects
+
 		if *enableMultitenancyFlag {
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 			go cloudProvider.TenantInformer.Run(ctx.Done())
 		}
 
-		initialBackoffDuration := time.Duration(*errorBackoffInitialDurationMs) * time.Millisecond
-		maxBackoffDuration := time.Duration(*errorBackoffMaxDurationMs) * time.Millisecond
 		// TODO(2042): Move more of the constructor args into this struct
 		args := &driver.GCEControllerServerArgs{
-			EnableDiskTopology:       *diskTopology,
-			EnableDiskSizeValidation: *enableDiskSizeValidation,
+			EnableDiskTopology:       cfg.Controller.EnableDiskTopology,
+			EnableDiskSizeValidation: cfg.Controller.EnableDiskSizeValidation,
+			AllowedProjects:          cfg.Controller.AllowedProjects,
+			ClusterName:              cfg.Controller.ClusterName,
+		}
+		if protectionCfg := cfg.Controller.DeletionProtection; protectionCfg.Label != "" || protectionCfg.ResourceTag != "" || protectionCfg.CheckOwnership || protectionCfg.RefuseNonKubernetesUsers {
+			args.DeletionProtection = &driver.DeletionProtection{
+				Label:          protectionCfg.Label,
+				ResourceTag:    protectionCfg.ResourceTag,
+				CheckOwnership: protectionCfg.CheckOwnership,
+			}
+			if protectionCfg.CheckOwnership {
+				pvIndexer, err := k8sclient.NewPersistentVolumeIndexer(ctx, 0)
+				if err != nil {
+					klog.Fatalf("Failed to set up PV indexer for deletion protection: %v", err.Error())
+				}
+				args.DeletionProtection.PVIndexer = pvIndexer
+			}
+			if protectionCfg.RefuseNonKubernetesUsers {
+				nodeLister, err := k8sclient.NewNodeLister(ctx, 0)
+				if err != nil {
+					klog.Fatalf("Failed to set up node lister for deletion protection: %v", err.Error())
+				}
+				args.DeletionProtection.NodeLister = nodeLister
+			}
+		}
+		if failoverCfg := cfg.Controller.RegionalFailover; failoverCfg.Enable {
+			nodeLister, err := k8sclient.NewNodeLister(ctx, failoverCfg.SyncPeriod.Duration)
+			if err != nil {
+				klog.Fatalf("Failed to set up node lister for regional failover: %v", err.Error())
+			}
+			recorder, err := k8sclient.NewNodesEventRecorder(driverName)
+			if err != nil {
+				klog.Fatalf("Failed to set up event recorder for regional failover: %v", err.Error())
+			}
+			args.RegionalFailover = driver.NewRegionalFailover(cloudProvider, nodeLister, recorder, failoverCfg.GracePeriod.Duration)
+			go args.RegionalFailover.Run(ctx, failoverCfg.SyncPeriod.Duration)
+		}
+		if collectorCfg := cfg.Controller.FinalSnapshotCollector; collectorCfg.Enable {
+			args.FinalSnapshotCollector = driver.NewFinalSnapshotCollector(cloudProvider, cfg.Controller.AllowedProjects)
+			go args.FinalSnapshotCollector.Run(ctx, collectorCfg.SyncPeriod.Duration)
 		}
 
-		controllerServer = driver.NewControllerServer(gceDriver, cloudProvider, initialBackoffDuration, maxBackoffDuration, fallbackRequisiteZones, *enableStoragePoolsFlag, *enableDataCacheFlag, multiZoneVolumeHandleConfig, listVolumesConfig, provisionableDisksConfig, *enableHdHAFlag, args)
+		controllerServer = driver.NewControllerServer(gceDriver, cloudProvider, cfg.Controller.ErrorBackoff.InitialDuration.Duration, cfg.Controller.ErrorBackoff.MaxDuration.Duration, cfg.Controller.FallbackRequisiteZones, cfg.Controller.EnableStoragePools, cfg.DataCache.Enable, multiZoneVolumeHandleConfig, listVolumesConfig, provisionableDisksConfig(cfg), cfg.Controller.AllowHdHAProvisioning, args)
+		if healthChecker != nil {
+			driver.RegisterControllerHealthChecks(healthChecker, cloudProvider)
+		}
 	} else if *cloudConfigFilePath != "" {
 		klog.Warningf("controller service is disabled but cloud config given - it has no effect")
 	}
@@ -266,76 +340,124 @@ func handle() {
 	// Initialize requirements for the node service
 	var nodeServer *driver.GCENodeServer
 	if *runNodeService {
-		mounter, err := mountmanager.NewSafeMounter(*maxConcurrentFormat, *concurrentFormatTimeout)
+		mounter, err := mountmanager.NewSafeMounter(cfg.Node.MaxConcurrentFormat, cfg.Node.ConcurrentFormatTimeout.Duration)
 		if err != nil {
 			klog.Fatalf("Failed to get safe mounter: %v", err.Error())
 		}
 
-		deviceUtils := deviceutils.NewDeviceUtils()
+		deviceUtils, err := deviceutils.NewDeviceUtilsForDiscovery(cfg.Node.DeviceDiscovery)
+		if err != nil {
+			klog.Fatalf("Failed to set up device discovery: %v", err.Error())
+		}
 		statter := mountmanager.NewStatter(mounter)
 		meta, err := metadataservice.NewMetadataService()
 		if err != nil {
 			klog.Fatalf("Failed to set up metadata service: %v", err.Error())
 		}
-		isDataCacheEnabledNodePool, err := driver.IsDataCacheEnabledNodePool(ctx, *nodeName, *enableDataCacheFlag)
+		isDataCacheEnabledNodePool, err := driver.IsDataCacheEnabledNodePool(ctx, *nodeName, cfg.DataCache.Enable)
 		if err != nil {
 			klog.Fatalf("Failed to get node info from API server: %v", err.Error())
 		}
 
-		deviceCache, err := linkcache.NewDeviceCacheForNode(ctx, *diskCacheSyncPeriod, *nodeName, driverName, deviceUtils)
+		deviceCache, err := linkcache.NewDeviceCacheForNode(ctx, cfg.Node.DiskCacheSyncPeriod.Duration, *nodeName, driverName, deviceUtils, metricsManager)
 		if err != nil {
 			klog.Warningf("Failed to create device cache: %v", err.Error())
 		} else {
 			go deviceCache.Run(ctx)
 		}
 
+		dataCacheLvm := lvm.NewLVM(common.NewCommandRunner())
+
 		// TODO(2042): Move more of the constructor args into this struct
 		nsArgs := &driver.NodeServerArgs{
-			EnableDeviceInUseCheck:   *enableDeviceInUseCheck,
-			DeviceInUseTimeout:       *deviceInUseTimeout,
-			EnableDataCache:          *enableDataCacheFlag,
+			EnableDeviceInUseCheck:   cfg.Node.DeviceInUseCheck.Enable,
+			DeviceInUseTimeout:       cfg.Node.DeviceInUseCheck.Timeout.Duration,
+			EnableDataCache:          cfg.DataCache.Enable,
 			DataCacheEnabledNodePool: isDataCacheEnabledNodePool,
 			SysfsPath:                "/sys",
 			MetricsManager:           metricsManager,
 			DeviceCache:              deviceCache,
+			EnableProjectQuota:       cfg.Node.EnableProjectQuota,
+			ProjectQuota:             quota.NewProjectQuota(mounter.Exec),
+			KeyUnwrapper:             encryption.NewCloudKMSUnwrapper(),
+			LVM:                      dataCacheLvm,
+			EnableDiskTypeTopology:   cfg.Node.EnableDiskTypeTopology,
+			AttachLimits:             cfg.Node.AttachLimits,
+		}
+		if runtime.GOOS != "windows" {
+			// Node encryption relies on dm-crypt, so Windows nodes leave Luks
+			// unset rather than shelling out to a missing cryptsetup.
+			nsArgs.Luks = encryption.NewCryptsetupLuks(mounter.Exec)
+		}
+		if cfg.DataCache.Enable && isDataCacheEnabledNodePool {
+			eventRecorder, err := k8sclient.NewNodeEventRecorder(*nodeName, driverName)
+			if err != nil {
+				klog.Warningf("Failed to create event recorder, Data Cache changes will not be reported as events: %v", err.Error())
+			} else {
+				nsArgs.EventRecorder = eventRecorder
+			}
 		}
 		nodeServer = driver.NewNodeServer(gceDriver, mounter, deviceUtils, meta, statter, nsArgs)
+		if healthChecker != nil {
+			nodeServer.RegisterHealthChecks(healthChecker, cfg.Node.DeviceDiscovery == deviceutils.DeviceDiscoveryUdev)
+		}
 
-		if *maxConcurrentFormatAndMount > 0 {
-			nodeServer = nodeServer.WithSerializedFormatAndMount(*formatAndMountTimeout, *maxConcurrentFormatAndMount)
+		if cfg.Node.MaxConcurrentFormatAndMount > 0 {
+			nodeServer = nodeServer.WithSerializedFormatAndMount(cfg.Node.FormatAndMountTimeout.Duration, cfg.Node.MaxConcurrentFormatAndMount)
 		}
-		if *enableDataCacheFlag {
+		if cfg.DataCache.Enable {
 			if nodeName == nil || *nodeName == "" {
 				klog.Errorf("Data Cache enabled, but --node-name not passed")
 			}
 			if nsArgs.DataCacheEnabledNodePool {
-				if err := setupDataCache(ctx, *nodeName, nodeServer.MetadataService.GetName()); err != nil {
+				if err := setupDataCache(ctx, dataCacheLvm, *nodeName, nodeServer.MetadataService.GetName()); err != nil {
 					klog.Errorf("Data Cache setup failed: %v", err)
 				}
-				go driver.StartWatcher(ctx, *nodeName)
+				go driver.StartWatcher(ctx, dataCacheLvm, *nodeName)
+				if metricsManager != nil {
+					go driver.StartCacheStatsCollector(ctx, dataCacheLvm, nodeServer.MetadataService.GetName(), cfg.DataCache.StatsPeriod.Duration, metricsManager)
+				}
 			}
 		}
 
+		// Reconcile before serving, so that NodeStageVolume calls for the
+		// volumes that are still attached find a clean node.
+		if cfg.Node.StartupReconcile.Enable && runtime.GOOS == "linux" {
+			actions, err := nodeServer.ReconcileNode(driver.ReconcileOptions{
+				StagingDir: filepath.Join(cfg.Node.KubeletRootDir, "plugins", "kubernetes.io", "csi", driverName),
+				ByIdDir:    "/dev/disk/by-id",
+				DryRun:     cfg.Node.StartupReconcile.DryRun,
+			})
+			if err != nil {
+				klog.Errorf("Startup reconciliation failed: %v", err)
+			}
+			klog.Infof("Startup reconciliation found %d stale items (dry run: %v)", len(actions), cfg.Node.StartupReconcile.DryRun)
+		}
+
+	}
+
+	if healthChecker != nil && metricsManager != nil {
+		metricsManager.Handle(cfg.HealthChecks.StatusPath, healthChecker)
 	}
 
-	err = gceDriver.SetupGCEDriver(driverName, version, extraVolumeLabels, extraTags, identityServer, controllerServer, nodeServer)
+	err = gceDriver.SetupGCEDriver(driverName, version, cfg.Controller.ExtraLabels, extraTags, identityServer, controllerServer, nodeServer)
 	if err != nil {
 		klog.Fatalf("Failed to initialize GCE CSI Driver: %v", err.Error())
 	}
 
-	gce.AttachDiskBackoff.Duration = *attachDiskBackoffDuration
-	gce.AttachDiskBackoff.Factor = *attachDiskBackoffFactor
-	gce.AttachDiskBackoff.Jitter = *attachDiskBackoffJitter
-	gce.AttachDiskBackoff.Steps = *attachDiskBackoffSteps
-	gce.AttachDiskBackoff.Cap = *attachDiskBackoffCap
-
-	gce.WaitForOpBackoff.Duration = *waitForOpBackoffDuration
-	gce.WaitForOpBackoff.Factor = *waitForOpBackoffFactor
-	gce.WaitForOpBackoff.Jitter = *waitForOpBackoffJitter
-	gce.WaitForOpBackoff.Steps = *waitForOpBackoffSteps
-	gce.WaitForOpBackoff.Cap = *waitForOpBackoffCap
+	applyReloadableConfig(cfg, controllerServer)
+	if *configFilePath != "" {
+		go watchDriverConfig(ctx, flag.CommandLine, *configFilePath, cfg, controllerServer)
+	}
 
-	gceDriver.Run(*endpoint, *grpcLogCharCap, *enableOtelTracing, metricsManager)
+	// The driver stops serving on SIGTERM, e.g. during a rollout of the
+	// driver pods. ctx is canceled by the deferred cancel once in-flight
+	// operations are drained, which stops the watchers and caches started
+	// above, and the otel exporter is flushed last.
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stopSignals()
+	gceDriver.Run(signalCtx, *endpoint, *grpcLogCharCap, *enableOtelTracing, metricsManager, *shutdownDrainTimeout)
+	klog.Infof("Driver stopped")
 }
 
 func notEmpty(v string) bool {
@@ -397,13 +519,13 @@ func urlFlag(target **url.URL, name string, usage string) {
 	})
 }
 
-func fetchLssdsForRaiding(lssdCount int) ([]string, error) {
+func fetchLssdsForRaiding(l lvm.LVM, lssdCount int) ([]string, error) {
 	allLssds, err := driver.FetchAllLssds()
 	if err != nil {
 		return nil, fmt.Errorf("Error listing all LSSDs %v", err)
 	}
 
-	raidedLssds, err := driver.FetchRaidedLssds()
+	raidedLssds, err := driver.FetchRaidedLssds(l)
 	if err != nil {
 		return nil, fmt.Errorf("Error listing RAIDed LSSDs %v", err)
 	}
@@ -429,8 +551,8 @@ func fetchLssdsForRaiding(lssdCount int) ([]string, error) {
 	return availableLssds[:lssdCount], nil
 }
 
-func setupDataCache(ctx context.Context, nodeName string, nodeId string) error {
-	isAlreadyRaided, err := driver.IsRaided()
+func setupDataCache(ctx context.Context, l lvm.LVM, nodeName string, nodeId string) error {
+	isAlreadyRaided, err := driver.IsRaided(l)
 	if err != nil {
 		klog.V(4).Infof("Errored while scanning for available LocalSSDs err:%v; continuing Raiding", err)
 	} else if isAlreadyRaided {
@@ -450,17 +572,17 @@ func setupDataCache(ctx context.Context, nodeName string, nodeId string) error {
 			return nil
 		}
 	}
-	lssdNames, err := fetchLssdsForRaiding(lssdCount)
+	lssdNames, err := fetchLssdsForRaiding(l, lssdCount)
 	if err != nil {
 		klog.Fatalf("Failed to get sufficient SSDs for Data Cache's caching setup: %v", err)
 	}
 	klog.V(4).Infof("Raiding local ssds to setup Data Cache: %v", lssdNames)
-	if err := driver.RaidLocalSsds(lssdNames); err != nil {
+	if err := driver.RaidLocalSsds(l, lssdNames); err != nil {
 		return fmt.Errorf("Failed to Raid local SSDs, unable to setup Data Cache, got error %v", err)
 	}
 
 	// Initializing data cache node (VG checks w/ raided lssd)
-	if err := driver.InitializeDataCacheNode(nodeId); err != nil {
+	if err := driver.InitializeDataCacheNode(l, nodeId); err != nil {
 		return err
 	}
 
@@ -0,0 +1,88 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// gce-pd-csi-failover fails a PersistentVolume with asynchronous replication
+// over to its secondary disk. It reads the PersistentVolume as YAML, stops
+// the replication, and writes the PersistentVolume of the secondary disk to
+// create in the recovery cluster.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	driver "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-pd-csi-driver"
+)
+
+var (
+	cloudConfigFilePath = flag.String("cloud-config", "", "Path to GCE cloud provider config")
+	pvFile              = flag.String("pv", "-", "Path to the PersistentVolume YAML to fail over, - for stdin")
+	pvName              = flag.String("name", "", "Name of the PersistentVolume of the secondary disk, defaults to the name of the PersistentVolume")
+
+	version string
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	if err := run(context.Background()); err != nil {
+		klog.Fatalf("Failover failed: %v", err)
+	}
+}
+
+func run(ctx context.Context) error {
+	var data []byte
+	var err error
+	if *pvFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*pvFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read persistent volume: %w", err)
+	}
+	pv := &v1.PersistentVolume{}
+	if err := yaml.Unmarshal(data, pv); err != nil {
+		return fmt.Errorf("failed to decode persistent volume: %w", err)
+	}
+	name := *pvName
+	if name == "" {
+		name = pv.Name
+	}
+
+	cloudProvider, err := gce.CreateCloudProvider(ctx, version, *cloudConfigFilePath, nil, gce.EnvironmentProduction, gce.WaitForAttachConfig{}, gce.ListInstancesConfig{}, false)
+	if err != nil {
+		return fmt.Errorf("failed to get cloud provider: %w", err)
+	}
+	failover, err := driver.FailoverPersistentVolume(ctx, cloudProvider, pv, name)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(failover)
+	if err != nil {
+		return fmt.Errorf("failed to encode persistent volume: %w", err)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
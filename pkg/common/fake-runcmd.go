@@ -0,0 +1,76 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeCommand is a command expected by a FakeCommandRunner, and its result.
+type FakeCommand struct {
+	// Command is the command line with its arguments separated by spaces,
+	// followed by " | " and the piped command, if any.
+	Command string
+	Output  string
+	Err     error
+}
+
+// FakeCommandRunner is a CommandRunner that expects commands in a fixed
+// order and fails any command that isn't next.
+type FakeCommandRunner struct {
+	mutex    sync.Mutex
+	expected []FakeCommand
+	// Ran holds the command lines that were run, in order.
+	Ran []string
+}
+
+var _ CommandRunner = &FakeCommandRunner{}
+
+func NewFakeCommandRunner(expected ...FakeCommand) *FakeCommandRunner {
+	return &FakeCommandRunner{expected: expected}
+}
+
+func (r *FakeCommandRunner) RunCommand(pipeCmd string, pipeCmdArg []string, cmd1 string, execCmdArgs ...string) ([]byte, error) {
+	command := strings.Join(append([]string{cmd1}, execCmdArgs...), " ")
+	if pipeCmd != "" {
+		command += " | " + strings.Join(append([]string{pipeCmd}, pipeCmdArg...), " ")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Ran = append(r.Ran, command)
+	if len(r.expected) == 0 {
+		return nil, fmt.Errorf("unexpected command %q", command)
+	}
+	next := r.expected[0]
+	if next.Command != command {
+		return nil, fmt.Errorf("unexpected command %q, expected %q", command, next.Command)
+	}
+	r.expected = r.expected[1:]
+	return []byte(next.Output), next.Err
+}
+
+// Pending returns the expected commands that haven't run yet.
+func (r *FakeCommandRunner) Pending() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	pending := []string{}
+	for _, c := range r.expected {
+		pending = append(pending, c.Command)
+	}
+	return pending
+}
@@ -13,6 +13,23 @@ const (
 	errNoChildProcesses = "wait: no child processes"
 )
 
+// CommandRunner runs commands the way RunCommand does, so that callers can be
+// tested without running them.
+type CommandRunner interface {
+	RunCommand(pipeCmd string, pipeCmdArg []string, cmd1 string, execCmdArgs ...string) ([]byte, error)
+}
+
+type execCommandRunner struct{}
+
+// NewCommandRunner returns a CommandRunner that runs commands with RunCommand.
+func NewCommandRunner() CommandRunner {
+	return execCommandRunner{}
+}
+
+func (execCommandRunner) RunCommand(pipeCmd string, pipeCmdArg []string, cmd1 string, execCmdArgs ...string) ([]byte, error) {
+	return RunCommand(pipeCmd, pipeCmdArg, cmd1, execCmdArgs...)
+}
+
 // RunCommand wraps a k8s exec to deal with the no child process error. Same as exec.CombinedOutput.
 // On error, the output is included so callers don't need to echo it again.
 
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -212,6 +213,12 @@ func GetDeviceName(volKey *meta.Key) (string, error) {
 	}
 }
 
+// DiskNameFromDeviceName returns the name of the disk attached with
+// deviceName, the reverse of GetDeviceName.
+func DiskNameFromDeviceName(deviceName string) string {
+	return strings.TrimSuffix(deviceName, regionalDeviceNameSuffix)
+}
+
 func CreateNodeID(project, zone, name string) string {
 	return fmt.Sprintf(nodeIDFmt, project, zone, name)
 }
@@ -454,31 +461,75 @@ func ShortString(s string) string {
 	return string(short)
 }
 
-// GetHyperdiskAttachLimit returns the hyperdisk attach limit based on machine type prefix and vCPUs
-func GetHyperdiskAttachLimit(machineTypePrefix string, vCPUs int64) int64 {
-	var limitMap []constants.MachineHyperdiskLimit
-
-	switch machineTypePrefix {
-	case "c4":
-		limitMap = constants.C4MachineHyperdiskAttachLimitMap
-	case "c4d":
-		limitMap = constants.C4DMachineHyperdiskAttachLimitMap
-	case "n4":
-		limitMap = constants.N4MachineHyperdiskAttachLimitMap
-	case "c4a":
-		limitMap = constants.C4AMachineHyperdiskAttachLimitMap
-	case "a4x":
-		limitMap = constants.A4XMachineHyperdiskAttachLimitMap
-	default:
-		// Fallback to the most conservative Gen4 map for unknown types
-		return MapNumber(vCPUs, constants.C4DMachineHyperdiskAttachLimitMap)
+// GetDiskFamilyAttachLimits returns the PD and Hyperdisk attach limits of a
+// machine type, from overrides or constants.MachineAttachLimits. Both are
+// looked up by machine type, then by bare metal machine series, then by
+// machine series.
+func GetDiskFamilyAttachLimits(machineType string, overrides map[string]constants.DiskFamilyAttachLimits) (pd int64, hyperdisk int64, err error) {
+	limits := constants.DefaultAttachLimits
+	series := MachineSeries(machineType)
+	keys := []string{machineType}
+	if strings.HasSuffix(machineType, "-metal") {
+		keys = append(keys, series+"-metal")
+	}
+	keys = append(keys, series)
+lookup:
+	for _, key := range keys {
+		for _, table := range []map[string]constants.DiskFamilyAttachLimits{overrides, constants.MachineAttachLimits} {
+			if l, ok := table[key]; ok {
+				limits = l
+				break lookup
+			}
+		}
 	}
 
-	return MapNumber(vCPUs, limitMap)
+	if pd, err = attachLimit(machineType, limits.PD); err != nil {
+		return 0, 0, err
+	}
+	if hyperdisk, err = attachLimit(machineType, limits.Hyperdisk); err != nil {
+		return 0, 0, err
+	}
+	return pd, hyperdisk, nil
 }
 
-// mapNumber maps the vCPUs to the appropriate hyperdisk limit
-func MapNumber(vCPUs int64, limitMap []constants.MachineHyperdiskLimit) int64 {
+// attachLimit returns the limit of limits matching the vCPUs, or GPUs, of the
+// machine type.
+func attachLimit(machineType string, limits []constants.AttachLimit) (int64, error) {
+	if len(limits) == 0 {
+		return 0, nil
+	}
+	if len(limits) == 1 && limits[0].Max == 0 {
+		return limits[0].Value, nil
+	}
+	size, err := machineTypeSize(machineType)
+	if err != nil {
+		return 0, err
+	}
+	return MapNumber(size, limits), nil
+}
+
+// machineTypeSize returns the vCPUs of a machine type, e.g. 8 for
+// "n4-standard-8" or "n4-custom-8-16384", or the GPUs of GPU counted machine
+// types, e.g. 2 for "a4x-highgpu-2g".
+func machineTypeSize(machineType string) (int64, error) {
+	machineTypeSlice := strings.Split(machineType, "-")
+	i := 2
+	if machineTypeSlice[0] == "custom" {
+		i = 1
+	}
+	if len(machineTypeSlice) <= i {
+		return 0, fmt.Errorf("unconventional machine type: %v", machineType)
+	}
+	sizeString := strings.TrimSuffix(machineTypeSlice[i], "g")
+	size, err := strconv.ParseInt(sizeString, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid vCPU or GPU count %s for machine type: %v", machineTypeSlice[i], machineType)
+	}
+	return size, nil
+}
+
+// MapNumber maps the vCPUs to the appropriate attach limit
+func MapNumber(vCPUs int64, limitMap []constants.AttachLimit) int64 {
 	for _, limit := range limitMap {
 		if vCPUs <= limit.Max {
 			return limit.Value
@@ -495,6 +546,36 @@ func DiskTypeLabelKey(diskType string) string {
 	return fmt.Sprintf("%s/%s", constants.DiskTypeKeyPrefix, diskType)
 }
 
+// NodeDiskTypeKey returns the topology key of the nodes telling whether they
+// can attach a disk type.
+func NodeDiskTypeKey(diskType string) string {
+	return fmt.Sprintf("%s/%s", constants.NodeDiskTypeKeyPrefix, diskType)
+}
+
+// AttachLimitKey returns the topology key of the attach limit of a disk
+// family.
+func AttachLimitKey(diskFamily string) string {
+	return fmt.Sprintf("%s/%s", constants.AttachLimitKeyPrefix, diskFamily)
+}
+
+// MachineSeries returns the machine series of a machine type, e.g. "n2" for
+// "n2-standard-4". Custom machine types without a series prefix are N1.
+func MachineSeries(machineType string) string {
+	series, _, _ := strings.Cut(machineType, "-")
+	if series == "custom" {
+		return "n1"
+	}
+	return series
+}
+
+// AttachableDiskTypes returns the disk types instances of the machine type
+// can attach, and false if its machine series is not in the compatibility
+// table.
+func AttachableDiskTypes(machineType string) ([]string, bool) {
+	diskTypes, ok := constants.MachineSeriesDiskTypes[MachineSeries(machineType)]
+	return diskTypes, ok
+}
+
 // IsUpdateIopsThroughputValuesAllowed checks if a disk type is hyperdisk,
 // which implies that IOPS and throughput values can be updated.
 func IsUpdateIopsThroughputValuesAllowed(disk *computev1.Disk) bool {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"slices"
 	"syscall"
 	"testing"
 
@@ -32,6 +33,7 @@ import (
 	"google.golang.org/api/googleapi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
 )
 
@@ -1080,3 +1082,126 @@ func TestGetMinIopsThroughput(t *testing.T) {
 		})
 	}
 }
+
+func TestAttachableDiskTypes(t *testing.T) {
+	testcases := []struct {
+		machineType  string
+		expectSeries string
+		expectKnown  bool
+		expectPDStd  bool
+		expectHdBal  bool
+		expectPDSSD  bool
+	}{
+		{machineType: "n1-standard-4", expectSeries: "n1", expectKnown: true, expectPDStd: true, expectPDSSD: true},
+		{machineType: "custom-4-8192", expectSeries: "n1", expectKnown: true, expectPDStd: true, expectPDSSD: true},
+		{machineType: "e2-micro", expectSeries: "e2", expectKnown: true, expectPDStd: true, expectPDSSD: true},
+		{machineType: "c3-standard-8", expectSeries: "c3", expectKnown: true, expectHdBal: true, expectPDSSD: true},
+		{machineType: "c4-standard-8", expectSeries: "c4", expectKnown: true, expectHdBal: true},
+		{machineType: "n4-custom-4-8192", expectSeries: "n4", expectKnown: true, expectHdBal: true},
+		{machineType: "zz9-standard-4", expectSeries: "zz9"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.machineType, func(t *testing.T) {
+			if got := MachineSeries(tc.machineType); got != tc.expectSeries {
+				t.Errorf("MachineSeries(%s): got %s, want %s", tc.machineType, got, tc.expectSeries)
+			}
+			diskTypes, known := AttachableDiskTypes(tc.machineType)
+			if known != tc.expectKnown {
+				t.Fatalf("AttachableDiskTypes(%s) known: got %v, want %v", tc.machineType, known, tc.expectKnown)
+			}
+			for diskType, want := range map[string]bool{
+				"pd-standard":        tc.expectPDStd,
+				"pd-ssd":             tc.expectPDSSD,
+				"hyperdisk-balanced": tc.expectHdBal,
+			} {
+				if got := slices.Contains(diskTypes, diskType); got != want {
+					t.Errorf("AttachableDiskTypes(%s) contains %s: got %v, want %v", tc.machineType, diskType, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDiskFamilyAttachLimits(t *testing.T) {
+	testcases := []struct {
+		machineType     string
+		overrides       map[string]constants.DiskFamilyAttachLimits
+		expectPD        int64
+		expectHyperdisk int64
+		expectErr       bool
+	}{
+		{machineType: "n1-standard-4", expectPD: 127},
+		{machineType: "custom-2-4096", expectPD: 127},
+		{machineType: "e2-micro", expectPD: 15},
+		{machineType: "e2-standard-2", expectPD: 127},
+		{machineType: "n2-standard-8", expectPD: 127, expectHyperdisk: 127},
+		{machineType: "c3-standard-4", expectPD: 127, expectHyperdisk: 127},
+		{machineType: "c3-standard-192-metal", expectHyperdisk: 15},
+		{machineType: "c4-standard-2", expectHyperdisk: 7},
+		{machineType: "c4-standard-48", expectHyperdisk: 63},
+		{machineType: "c4-standard-192", expectHyperdisk: 127},
+		{machineType: "n4-custom-8-12345-ext", expectHyperdisk: 15},
+		{machineType: "c4a-standard-32-lssd", expectHyperdisk: 31},
+		{machineType: "x4-megamem-960-metal", expectHyperdisk: 39},
+		{machineType: "a4x-highgpu-1g", expectHyperdisk: 63},
+		{machineType: "a4x-highgpu-metal", expectHyperdisk: 31},
+		{machineType: "zz9-standard-4", expectPD: 127, expectHyperdisk: 127},
+		{machineType: "n4-micro", expectErr: true},
+		{machineType: "n4-highcpu-4xyz", expectErr: true},
+		{
+			machineType: "n5-standard-16",
+			overrides: map[string]constants.DiskFamilyAttachLimits{
+				"n5": {Hyperdisk: []constants.AttachLimit{{Max: 8, Value: 15}, {Max: 96, Value: 31}}},
+			},
+			expectHyperdisk: 31,
+		},
+		{
+			machineType: "c4-standard-2",
+			overrides: map[string]constants.DiskFamilyAttachLimits{
+				"c4": {Hyperdisk: []constants.AttachLimit{{Value: 11}}},
+			},
+			expectHyperdisk: 11,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.machineType, func(t *testing.T) {
+			pd, hyperdisk, err := GetDiskFamilyAttachLimits(tc.machineType, tc.overrides)
+			if gotErr := err != nil; gotErr != tc.expectErr {
+				t.Fatalf("GetDiskFamilyAttachLimits(%s) error: got %v, want error %v", tc.machineType, err, tc.expectErr)
+			}
+			if pd != tc.expectPD || hyperdisk != tc.expectHyperdisk {
+				t.Errorf("GetDiskFamilyAttachLimits(%s): got %d, %d, want %d, %d", tc.machineType, pd, hyperdisk, tc.expectPD, tc.expectHyperdisk)
+			}
+		})
+	}
+}
+
+// TestMachineSeriesTables checks that every machine series of the disk
+// compatibility table has attach limits for exactly the disk families it can
+// attach.
+func TestMachineSeriesTables(t *testing.T) {
+	for series, diskTypes := range constants.MachineSeriesDiskTypes {
+		t.Run(series, func(t *testing.T) {
+			if _, ok := constants.MachineAttachLimits[series]; !ok {
+				t.Fatalf("machine series %s is missing from the attach limits", series)
+			}
+			pd, hyperdisk, err := GetDiskFamilyAttachLimits(series+"-standard-4", nil)
+			if err != nil {
+				t.Fatalf("GetDiskFamilyAttachLimits(%s-standard-4) returned error: %v", series, err)
+			}
+			attachesPD := slices.ContainsFunc(diskTypes, func(diskType string) bool { return !IsHyperdisk(diskType) })
+			attachesHyperdisk := slices.ContainsFunc(diskTypes, IsHyperdisk)
+			if (pd > 0) != attachesPD {
+				t.Errorf("machine series %s: PD attach limit %d, attaches PD: %v", series, pd, attachesPD)
+			}
+			if (hyperdisk > 0) != attachesHyperdisk {
+				t.Errorf("machine series %s: Hyperdisk attach limit %d, attaches Hyperdisk: %v", series, hyperdisk, attachesHyperdisk)
+			}
+		})
+	}
+	for key := range constants.MachineAttachLimits {
+		if _, ok := constants.MachineSeriesDiskTypes[MachineSeries(key)]; !ok {
+			t.Errorf("attach limits key %s is not a machine type or series of the disk compatibility table", key)
+		}
+	}
+}
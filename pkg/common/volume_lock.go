@@ -17,6 +17,7 @@ limitations under the License.
 package common
 
 import (
+	"context"
 	"sync"
 
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -27,6 +28,9 @@ import (
 type VolumeLocks struct {
 	locks sets.String
 	mux   sync.Mutex
+	// idle is closed when the last lock is released. It is nil while no lock
+	// is held.
+	idle chan struct{}
 }
 
 func NewVolumeLocks() *VolumeLocks {
@@ -43,6 +47,9 @@ func (vl *VolumeLocks) TryAcquire(volumeID string) bool {
 	if vl.locks.Has(volumeID) {
 		return false
 	}
+	if vl.locks.Len() == 0 {
+		vl.idle = make(chan struct{})
+	}
 	vl.locks.Insert(volumeID)
 	return true
 }
@@ -51,4 +58,30 @@ func (vl *VolumeLocks) Release(volumeID string) {
 	vl.mux.Lock()
 	defer vl.mux.Unlock()
 	vl.locks.Delete(volumeID)
+	if vl.locks.Len() == 0 && vl.idle != nil {
+		close(vl.idle)
+		vl.idle = nil
+	}
+}
+
+// WaitIdle blocks until no operation holds a lock or ctx is done. It returns
+// the volume IDs that are still locked, which is empty unless ctx is done.
+func (vl *VolumeLocks) WaitIdle(ctx context.Context) []string {
+	for {
+		vl.mux.Lock()
+		idle := vl.idle
+		if idle == nil {
+			vl.mux.Unlock()
+			return nil
+		}
+		vl.mux.Unlock()
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			vl.mux.Lock()
+			defer vl.mux.Unlock()
+			return vl.locks.List()
+		}
+	}
 }
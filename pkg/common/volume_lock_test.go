@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVolumeLocksWaitIdle(t *testing.T) {
+	vl := NewVolumeLocks()
+	if locked := vl.WaitIdle(context.Background()); len(locked) != 0 {
+		t.Errorf("Expected no locked volumes, got %v", locked)
+	}
+
+	vl.TryAcquire("vol-1")
+	vl.TryAcquire("vol-2")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if diff := cmp.Diff([]string{"vol-1", "vol-2"}, vl.WaitIdle(ctx)); diff != "" {
+		t.Errorf("Unexpected locked volumes after timeout (-want +got):\n%s", diff)
+	}
+
+	done := make(chan []string)
+	go func() {
+		done <- vl.WaitIdle(context.Background())
+	}()
+	vl.Release("vol-1")
+	vl.Release("vol-2")
+	select {
+	case locked := <-done:
+		if len(locked) != 0 {
+			t.Errorf("Expected no locked volumes, got %v", locked)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("WaitIdle did not return after all locks were released")
+	}
+
+	// Locks can be acquired again once idle.
+	if !vl.TryAcquire("vol-1") {
+		t.Errorf("Expected to acquire vol-1")
+	}
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config defines the versioned configuration file of the driver,
+// which holds the settings of the controller and node services that were
+// only available as flags before.
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+)
+
+const (
+	// APIVersion is the only supported apiVersion of the configuration file.
+	APIVersion = "pd.csi.storage.gke.io/v1alpha1"
+	// Kind is the kind of the configuration file.
+	Kind = "DriverConfiguration"
+)
+
+// DriverConfiguration is the configuration file of the driver. Fields left
+// out of the file keep the defaults of Default, which match the defaults of
+// the corresponding flags.
+type DriverConfiguration struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	Controller   ControllerConfiguration   `json:"controller"`
+	Node         NodeConfiguration         `json:"node"`
+	DataCache    DataCacheConfiguration    `json:"dataCache"`
+	HealthChecks HealthChecksConfiguration `json:"healthChecks"`
+}
+
+// ControllerConfiguration configures the controller service.
+type ControllerConfiguration struct {
+	// ErrorBackoff is the backoff of ControllerPublish and
+	// ControllerUnpublish calls for a node and disk pair after an error.
+	ErrorBackoff ErrorBackoff `json:"errorBackoff"`
+	// AttachDiskBackoff is used to wait for a disk to be attached. Reloaded
+	// on changes.
+	AttachDiskBackoff Backoff `json:"attachDiskBackoff"`
+	// WaitForOpBackoff is used to wait for GCE operations. Reloaded on
+	// changes.
+	WaitForOpBackoff Backoff `json:"waitForOpBackoff"`
+
+	// ExtraLabels are added to every disk the driver creates.
+	ExtraLabels map[string]string `json:"extraLabels,omitempty"`
+	// FallbackRequisiteZones are used when the requisite topology of a
+	// request doesn't have enough zones for the disk.
+	FallbackRequisiteZones []string `json:"fallbackRequisiteZones,omitempty"`
+	// AllowedProjects are the projects the project parameter of
+	// StorageClasses may provision disks in.
+	AllowedProjects []string `json:"allowedProjects,omitempty"`
+	// ClusterName is recorded in the description of the disks the driver
+	// creates, and checked by DeletionProtection.
+	ClusterName string `json:"clusterName,omitempty"`
+
+	EnableStoragePools    bool `json:"enableStoragePools"`
+	AllowHdHAProvisioning bool `json:"allowHdHAProvisioning"`
+	EnableDiskTopology    bool `json:"enableDiskTopology"`
+	// EnableDiskSizeValidation checks the size of existing disks against
+	// the requested capacity.
+	EnableDiskSizeValidation bool `json:"enableDiskSizeValidation"`
+
+	MultiZoneVolumeHandle MultiZoneVolumeHandle `json:"multiZoneVolumeHandle"`
+	ListVolumes           ListVolumes           `json:"listVolumes"`
+	WaitForAttach         WaitForAttach         `json:"waitForAttach"`
+	// ProvisionableDisks lists the disk types with dynamic IOPS and
+	// throughput provisioning. Reloaded on changes.
+	ProvisionableDisks ProvisionableDisks `json:"provisionableDisks"`
+	RegionalFailover   RegionalFailover   `json:"regionalFailover"`
+	// FinalSnapshotCollector deletes the final snapshots of deleted volumes
+	// once their retention has expired.
+	FinalSnapshotCollector FinalSnapshotCollector `json:"finalSnapshotCollector"`
+	// DeletionProtection refuses DeleteVolume of protected disks.
+	DeletionProtection DeletionProtection `json:"deletionProtection"`
+}
+
+// ErrorBackoff is an exponential backoff between InitialDuration and
+// MaxDuration.
+type ErrorBackoff struct {
+	InitialDuration metav1.Duration `json:"initialDuration"`
+	MaxDuration     metav1.Duration `json:"maxDuration"`
+}
+
+// Backoff is the configuration of a wait.Backoff.
+type Backoff struct {
+	Duration metav1.Duration `json:"duration"`
+	Factor   float64         `json:"factor"`
+	Jitter   float64         `json:"jitter"`
+	Steps    int             `json:"steps"`
+	Cap      metav1.Duration `json:"cap"`
+}
+
+// WaitBackoff returns b as a wait.Backoff.
+func (b Backoff) WaitBackoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: b.Duration.Duration,
+		Factor:   b.Factor,
+		Jitter:   b.Jitter,
+		Steps:    b.Steps,
+		Cap:      b.Cap.Duration,
+	}
+}
+
+// MultiZoneVolumeHandle configures the multi-zone volumeHandle feature.
+type MultiZoneVolumeHandle struct {
+	Enable    bool     `json:"enable"`
+	DiskTypes []string `json:"diskTypes,omitempty"`
+}
+
+// ListVolumes configures ListVolumes.
+type ListVolumes struct {
+	// UseInstancesAPIForPublishedNodes uses instances.list rather than
+	// disks.list to find the nodes volumes are published to.
+	UseInstancesAPIForPublishedNodes bool `json:"useInstancesAPIForPublishedNodes"`
+	// InstancesListFilters are the filters of the instances.list calls.
+	InstancesListFilters []string `json:"instancesListFilters,omitempty"`
+}
+
+// WaitForAttach configures how ControllerPublish polls for attachments.
+type WaitForAttach struct {
+	// UseInstancesAPIForDiskTypes are the disk types whose attachment is
+	// polled with instances.get.
+	UseInstancesAPIForDiskTypes []string `json:"useInstancesAPIForDiskTypes,omitempty"`
+}
+
+// ProvisionableDisks lists disk types by the performance settings they
+// support.
+type ProvisionableDisks struct {
+	SupportsDynamicIopsProvisioning       []string `json:"supportsDynamicIopsProvisioning,omitempty"`
+	SupportsDynamicThroughputProvisioning []string `json:"supportsDynamicThroughputProvisioning,omitempty"`
+}
+
+// RegionalFailover configures the force attach of regional disks in their
+// other replica zone when all the nodes of a zone are NotReady.
+type RegionalFailover struct {
+	Enable bool `json:"enable"`
+	// GracePeriod is how long all the nodes of a zone must be NotReady for
+	// the zone to be considered failed.
+	GracePeriod metav1.Duration `json:"gracePeriod"`
+	// SyncPeriod is the period of the checks of the nodes and the disks
+	// attached to them.
+	SyncPeriod metav1.Duration `json:"syncPeriod"`
+}
+
+// FinalSnapshotCollector configures the deletion of the expired final
+// snapshots taken before deleting volumes.
+type FinalSnapshotCollector struct {
+	Enable bool `json:"enable"`
+	// SyncPeriod is the period of the checks of the final snapshots.
+	SyncPeriod metav1.Duration `json:"syncPeriod"`
+}
+
+// DeletionProtection configures the checks of the disks before DeleteVolume
+// deletes them.
+type DeletionProtection struct {
+	// Label is the GCE label of the protected disks, whose value is "true".
+	Label string `json:"label,omitempty"`
+	// ResourceTag is the namespaced value, <parent>/<key>/<value>, of the
+	// resource tag of the protected disks.
+	ResourceTag string `json:"resourceTag,omitempty"`
+	// CheckOwnership refuses the disks created for another cluster or PV
+	// than the one of the volume.
+	CheckOwnership bool `json:"checkOwnership"`
+	// RefuseNonKubernetesUsers refuses the disks attached to VMs which are
+	// not nodes of the cluster.
+	RefuseNonKubernetesUsers bool `json:"refuseNonKubernetesUsers"`
+}
+
+// NodeConfiguration configures the node service.
+type NodeConfiguration struct {
+	DeviceInUseCheck DeviceInUseCheck `json:"deviceInUseCheck"`
+
+	// MaxConcurrentFormat limits the concurrent mkfs calls.
+	MaxConcurrentFormat     int             `json:"maxConcurrentFormat"`
+	ConcurrentFormatTimeout metav1.Duration `json:"concurrentFormatTimeout"`
+	// MaxConcurrentFormatAndMount limits the concurrent format and mount
+	// operations, including fsck. 0 disables the limit.
+	MaxConcurrentFormatAndMount int             `json:"maxConcurrentFormatAndMount"`
+	FormatAndMountTimeout       metav1.Duration `json:"formatAndMountTimeout"`
+
+	// DiskCacheSyncPeriod is the period of the full resync of the device
+	// cache.
+	DiskCacheSyncPeriod metav1.Duration `json:"diskCacheSyncPeriod"`
+	EnableProjectQuota  bool            `json:"enableProjectQuota"`
+	// DeviceDiscovery is "udev" or "sysfs".
+	DeviceDiscovery  string           `json:"deviceDiscovery"`
+	StartupReconcile StartupReconcile `json:"startupReconcile"`
+	KubeletRootDir   string           `json:"kubeletRootDir"`
+	// EnableDiskTypeTopology publishes the disk types the machine series of
+	// the node can attach, and its attach limits, in its topology.
+	EnableDiskTypeTopology bool `json:"enableDiskTypeTopology"`
+	// AttachLimits override the PD and Hyperdisk attach limits of machine
+	// types or series. Only available in the file.
+	AttachLimits map[string]constants.DiskFamilyAttachLimits `json:"attachLimits,omitempty"`
+}
+
+// DeviceInUseCheck configures the check that blocks NodeUnstageVolume while
+// the device is in use.
+type DeviceInUseCheck struct {
+	Enable  bool            `json:"enable"`
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// StartupReconcile configures the clean up of stale node state on startup.
+type StartupReconcile struct {
+	Enable bool `json:"enable"`
+	DryRun bool `json:"dryRun"`
+}
+
+// DataCacheConfiguration configures Data Cache.
+type DataCacheConfiguration struct {
+	Enable bool `json:"enable"`
+	// StatsPeriod is the period of the node to collect Data Cache
+	// statistics.
+	StatsPeriod metav1.Duration `json:"statsPeriod"`
+}
+
+// HealthChecksConfiguration configures the health checks reported by Probe.
+type HealthChecksConfiguration struct {
+	Enable  bool            `json:"enable"`
+	TTL     metav1.Duration `json:"ttl"`
+	Timeout metav1.Duration `json:"timeout"`
+	// FailureThreshold is the number of consecutive failures of a check
+	// after which Probe reports the driver not ready.
+	FailureThreshold int `json:"failureThreshold"`
+	// StatusPath is the HTTP path of the check results on the metrics
+	// server.
+	StatusPath string `json:"statusPath"`
+}
+
+// Default returns the default configuration.
+func Default() *DriverConfiguration {
+	return &DriverConfiguration{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Controller: ControllerConfiguration{
+			ErrorBackoff: ErrorBackoff{
+				InitialDuration: metav1.Duration{Duration: 200 * time.Millisecond},
+				MaxDuration:     metav1.Duration{Duration: 5 * time.Minute},
+			},
+			AttachDiskBackoff: Backoff{
+				Duration: metav1.Duration{Duration: 5 * time.Second},
+				Steps:    24,
+			},
+			WaitForOpBackoff: Backoff{
+				Duration: metav1.Duration{Duration: 2 * time.Minute},
+				Steps:    3,
+			},
+			RegionalFailover: RegionalFailover{
+				GracePeriod: metav1.Duration{Duration: 2 * time.Minute},
+				SyncPeriod:  metav1.Duration{Duration: 30 * time.Second},
+			},
+			FinalSnapshotCollector: FinalSnapshotCollector{
+				SyncPeriod: metav1.Duration{Duration: time.Hour},
+			},
+		},
+		Node: NodeConfiguration{
+			DeviceInUseCheck: DeviceInUseCheck{
+				Enable:  true,
+				Timeout: metav1.Duration{Duration: 30 * time.Second},
+			},
+			MaxConcurrentFormat:         1,
+			ConcurrentFormatTimeout:     metav1.Duration{Duration: time.Minute},
+			MaxConcurrentFormatAndMount: 1,
+			FormatAndMountTimeout:       metav1.Duration{Duration: time.Minute},
+			DiskCacheSyncPeriod:         metav1.Duration{Duration: 10 * time.Minute},
+			DeviceDiscovery:             "udev",
+			KubeletRootDir:              "/var/lib/kubelet",
+		},
+		DataCache: DataCacheConfiguration{
+			StatsPeriod: metav1.Duration{Duration: time.Minute},
+		},
+		HealthChecks: HealthChecksConfiguration{
+			TTL:              metav1.Duration{Duration: 30 * time.Second},
+			Timeout:          metav1.Duration{Duration: 10 * time.Second},
+			FailureThreshold: 3,
+			StatusPath:       "/health",
+		},
+	}
+}
+
+// Parse decodes a configuration file over the defaults and validates it.
+// Unknown fields are an error, so that typos don't go unnoticed.
+func Parse(data []byte) (*DriverConfiguration, error) {
+	c := Default()
+	c.APIVersion, c.Kind = "", ""
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		return nil, fmt.Errorf("unsupported configuration %s %q, expected %s %q", c.Kind, c.APIVersion, Kind, APIVersion)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load reads and parses the configuration file at path.
+func Load(path string) (*DriverConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+	return c, nil
+}
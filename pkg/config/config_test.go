@@ -0,0 +1,249 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   string
+		modify func(c *DriverConfiguration)
+		expErr bool
+	}{
+		{
+			name: "defaults",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+`,
+		},
+		{
+			name: "fields override defaults",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+controller:
+  attachDiskBackoff:
+    duration: 2s
+    factor: 1.5
+  provisionableDisks:
+    supportsDynamicIopsProvisioning: [hyperdisk-balanced, hyperdisk-extreme]
+  extraLabels:
+    team: storage
+node:
+  deviceInUseCheck:
+    enable: false
+  deviceDiscovery: sysfs
+dataCache:
+  enable: true
+`,
+			modify: func(c *DriverConfiguration) {
+				c.Controller.AttachDiskBackoff.Duration = metav1.Duration{Duration: 2 * time.Second}
+				c.Controller.AttachDiskBackoff.Factor = 1.5
+				c.Controller.ProvisionableDisks.SupportsDynamicIopsProvisioning = []string{"hyperdisk-balanced", "hyperdisk-extreme"}
+				c.Controller.ExtraLabels = map[string]string{"team": "storage"}
+				c.Node.DeviceInUseCheck.Enable = false
+				c.Node.DeviceDiscovery = "sysfs"
+				c.DataCache.Enable = true
+			},
+		},
+		{
+			name: "attach limits",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+node:
+  attachLimits:
+    n5:
+      pd:
+      - value: 127
+      hyperdisk:
+      - max: 8
+        value: 15
+      - max: 96
+        value: 31
+`,
+			modify: func(c *DriverConfiguration) {
+				c.Node.AttachLimits = map[string]constants.DiskFamilyAttachLimits{
+					"n5": {
+						PD:        []constants.AttachLimit{{Value: 127}},
+						Hyperdisk: []constants.AttachLimit{{Max: 8, Value: 15}, {Max: 96, Value: 31}},
+					},
+				}
+			},
+		},
+		{
+			name: "invalid attach limits",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+node:
+  attachLimits:
+    n5:
+      hyperdisk:
+      - max: 96
+        value: 31
+      - max: 8
+        value: 200
+`,
+			expErr: true,
+		},
+		{
+			name: "invalid deletion protection resource tag",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+controller:
+  deletionProtection:
+    resourceTag: my-project/protected
+`,
+			expErr: true,
+		},
+		{
+			name:   "missing apiVersion",
+			data:   "kind: DriverConfiguration\n",
+			expErr: true,
+		},
+		{
+			name: "unsupported apiVersion",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1
+kind: DriverConfiguration
+`,
+			expErr: true,
+		},
+		{
+			name: "unknown field",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+controller:
+  enableStoragePool: true
+`,
+			expErr: true,
+		},
+		{
+			name: "invalid values",
+			data: `
+apiVersion: pd.csi.storage.gke.io/v1alpha1
+kind: DriverConfiguration
+controller:
+  errorBackoff:
+    initialDuration: 10m
+  waitForOpBackoff:
+    steps: 0
+node:
+  deviceDiscovery: devfs
+`,
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Parse([]byte(tc.data))
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if tc.expErr {
+				return
+			}
+			want := Default()
+			if tc.modify != nil {
+				tc.modify(want)
+			}
+			if diff := cmp.Diff(want, c); diff != "" {
+				t.Errorf("Unexpected configuration (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	current := Default()
+
+	updated := Default()
+	updated.Controller.WaitForOpBackoff.Steps = 10
+	updated.Controller.ProvisionableDisks.SupportsDynamicThroughputProvisioning = []string{"hyperdisk-throughput"}
+	next, err := Reload(current, updated)
+	if err != nil {
+		t.Errorf("Reload of reloadable fields returned error: %v", err)
+	}
+	if diff := cmp.Diff(updated, next); diff != "" {
+		t.Errorf("Unexpected reloaded configuration (-want +got):\n%s", diff)
+	}
+
+	updated.Node.DeviceDiscovery = "sysfs"
+	updated.DataCache.Enable = true
+	next, err = Reload(current, updated)
+	if err == nil {
+		t.Errorf("Expected error for fields that need a restart")
+	}
+	if next.Node.DeviceDiscovery != current.Node.DeviceDiscovery || next.DataCache.Enable {
+		t.Errorf("Fields that need a restart were reloaded: %+v", next)
+	}
+	if next.Controller.WaitForOpBackoff.Steps != 10 {
+		t.Errorf("Expected waitForOpBackoff steps 10, got %d", next.Controller.WaitForOpBackoff.Steps)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to write configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, path, func() { changed <- struct{}{} })
+	}()
+	// Give the watcher time to start.
+	time.Sleep(100 * time.Millisecond)
+
+	// A ConfigMap update replaces the file.
+	tmp := filepath.Join(dir, "config.yaml.tmp")
+	if err := os.WriteFile(tmp, []byte("b"), 0644); err != nil {
+		t.Fatalf("Failed to write configuration: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to replace configuration: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Change of configuration was not reported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned error: %v", err)
+	}
+}
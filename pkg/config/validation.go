@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+)
+
+// Validate returns all the invalid fields of c.
+func (c *DriverConfiguration) Validate() error {
+	var errs []error
+	nonNegative := func(field string, d metav1.Duration) {
+		if d.Duration < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %v", field, d.Duration))
+		}
+	}
+	positive := func(field string, d metav1.Duration) {
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive, got %v", field, d.Duration))
+		}
+	}
+	backoff := func(field string, b Backoff) {
+		positive(field+".duration", b.Duration)
+		nonNegative(field+".cap", b.Cap)
+		if b.Factor < 0 {
+			errs = append(errs, fmt.Errorf("%s.factor: must not be negative, got %v", field, b.Factor))
+		}
+		if b.Jitter < 0 {
+			errs = append(errs, fmt.Errorf("%s.jitter: must not be negative, got %v", field, b.Jitter))
+		}
+		if b.Steps <= 0 {
+			errs = append(errs, fmt.Errorf("%s.steps: must be positive, got %d", field, b.Steps))
+		}
+	}
+
+	ctrl := c.Controller
+	nonNegative("controller.errorBackoff.initialDuration", ctrl.ErrorBackoff.InitialDuration)
+	positive("controller.errorBackoff.maxDuration", ctrl.ErrorBackoff.MaxDuration)
+	if ctrl.ErrorBackoff.InitialDuration.Duration > ctrl.ErrorBackoff.MaxDuration.Duration {
+		errs = append(errs, fmt.Errorf("controller.errorBackoff.initialDuration: must not exceed maxDuration %v", ctrl.ErrorBackoff.MaxDuration.Duration))
+	}
+	backoff("controller.attachDiskBackoff", ctrl.AttachDiskBackoff)
+	backoff("controller.waitForOpBackoff", ctrl.WaitForOpBackoff)
+	positive("controller.regionalFailover.gracePeriod", ctrl.RegionalFailover.GracePeriod)
+	positive("controller.regionalFailover.syncPeriod", ctrl.RegionalFailover.SyncPeriod)
+	positive("controller.finalSnapshotCollector.syncPeriod", ctrl.FinalSnapshotCollector.SyncPeriod)
+	if tag := ctrl.DeletionProtection.ResourceTag; tag != "" {
+		if parts := strings.Split(tag, "/"); len(parts) != 3 || slices.Contains(parts, "") {
+			errs = append(errs, fmt.Errorf("controller.deletionProtection.resourceTag: must be <parent>/<key>/<value>, got %q", tag))
+		}
+	}
+
+	node := c.Node
+	nonNegative("node.deviceInUseCheck.timeout", node.DeviceInUseCheck.Timeout)
+	if node.MaxConcurrentFormat <= 0 {
+		errs = append(errs, fmt.Errorf("node.maxConcurrentFormat: must be positive, got %d", node.MaxConcurrentFormat))
+	}
+	positive("node.concurrentFormatTimeout", node.ConcurrentFormatTimeout)
+	if node.MaxConcurrentFormatAndMount < 0 {
+		errs = append(errs, fmt.Errorf("node.maxConcurrentFormatAndMount: must not be negative, got %d", node.MaxConcurrentFormatAndMount))
+	}
+	positive("node.formatAndMountTimeout", node.FormatAndMountTimeout)
+	positive("node.diskCacheSyncPeriod", node.DiskCacheSyncPeriod)
+	if node.DeviceDiscovery != "udev" && node.DeviceDiscovery != "sysfs" {
+		errs = append(errs, fmt.Errorf("node.deviceDiscovery: must be \"udev\" or \"sysfs\", got %q", node.DeviceDiscovery))
+	}
+	if !filepath.IsAbs(node.KubeletRootDir) {
+		errs = append(errs, fmt.Errorf("node.kubeletRootDir: must be an absolute path, got %q", node.KubeletRootDir))
+	}
+	attachLimits := func(field string, limits []constants.AttachLimit) {
+		for i, l := range limits {
+			if l.Value < 0 || l.Value > 127 {
+				errs = append(errs, fmt.Errorf("%s[%d].value: must be between 0 and 127, got %d", field, i, l.Value))
+			}
+			if i > 0 && l.Max <= limits[i-1].Max {
+				errs = append(errs, fmt.Errorf("%s[%d].max: must be greater than the previous max %d, got %d", field, i, limits[i-1].Max, l.Max))
+			}
+		}
+	}
+	for key, limits := range node.AttachLimits {
+		attachLimits(fmt.Sprintf("node.attachLimits[%s].pd", key), limits.PD)
+		attachLimits(fmt.Sprintf("node.attachLimits[%s].hyperdisk", key), limits.Hyperdisk)
+	}
+
+	positive("dataCache.statsPeriod", c.DataCache.StatsPeriod)
+	positive("healthChecks.ttl", c.HealthChecks.TTL)
+	positive("healthChecks.timeout", c.HealthChecks.Timeout)
+	if c.HealthChecks.FailureThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("healthChecks.failureThreshold: must be positive, got %d", c.HealthChecks.FailureThreshold))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	fsnotify "github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+)
+
+// reloadDelay batches the events of a single update of the file, e.g. the
+// symlink swaps of a ConfigMap volume.
+const reloadDelay = time.Second
+
+// withReloadableFields returns a copy of c with the fields that can change
+// without a restart taken from from.
+func (c *DriverConfiguration) withReloadableFields(from *DriverConfiguration) *DriverConfiguration {
+	out := *c
+	out.Controller.AttachDiskBackoff = from.Controller.AttachDiskBackoff
+	out.Controller.WaitForOpBackoff = from.Controller.WaitForOpBackoff
+	out.Controller.ProvisionableDisks = from.Controller.ProvisionableDisks
+	return &out
+}
+
+// Reload returns the configuration to apply on an update of the file from
+// current to updated: current with the reloadable fields of updated. It
+// also returns an error if fields that need a restart changed, which are
+// left as they are.
+func Reload(current, updated *DriverConfiguration) (*DriverConfiguration, error) {
+	next := current.withReloadableFields(updated)
+	if !reflect.DeepEqual(updated.withReloadableFields(current), current) {
+		return next, fmt.Errorf("fields other than controller.attachDiskBackoff, controller.waitForOpBackoff and controller.provisionableDisks changed, restart the driver to apply them")
+	}
+	return next, nil
+}
+
+// Watch calls onChange after the file at path changes, until ctx is done.
+// The directory of the file is watched rather than the file itself, so that
+// files replaced by a rename, like ConfigMap volumes, are followed.
+func Watch(ctx context.Context, path string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+	klog.V(2).Infof("Watching configuration %s for changes", path)
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			klog.Errorf("Error watching configuration %s: %v", path, err)
+		case event := <-watcher.Events:
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
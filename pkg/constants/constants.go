@@ -24,9 +24,23 @@ const (
 	// of the Disk Topology feature.
 	DiskTypeKeyPrefix = "disk-type.gke.io"
 
+	// NodeDiskTypeKeyPrefix is the prefix of the topology keys of the disk
+	// types nodes can attach. Unlike the DiskTypeKeyPrefix labels, which GKE
+	// manages on its nodes, these are owned by the driver: kubelet labels the
+	// nodes with them.
+	NodeDiskTypeKeyPrefix = "disk-type.pd.csi.storage.gke.io"
+
 	// VolumeAttributes for Partition
 	VolumeAttributePartition = "partition"
 
+	// VolumeAttributes for publishing a project quota limited subdirectory of
+	// a filesystem volume. quota-subpath is relative to the volume root,
+	// quota-limit is a resource quantity (e.g. "10Gi") and quota-project-id
+	// optionally pins the project ID instead of deriving it from the subpath.
+	VolumeAttributeQuotaSubPath   = "quota-subpath"
+	VolumeAttributeQuotaLimit     = "quota-limit"
+	VolumeAttributeQuotaProjectID = "quota-project-id"
+
 	UnspecifiedValue = "UNSPECIFIED"
 
 	// VolumeOperationAlreadyExistsFmt is the error message format for when a volume operation already exists
@@ -40,6 +54,24 @@ const (
 	// Label that is set on a disk when it is used by a 'multi-zone' VolumeHandle
 	MultiZoneLabel = "goog-gke-multi-zone"
 
+	// Labels set on the disks whose volumes take a final snapshot before
+	// they are deleted. The storage location and retention labels are
+	// optional.
+	FinalSnapshotLabel                = "snapshot-before-delete"
+	FinalSnapshotStorageLocationLabel = "snapshot-before-delete-storage-location"
+	// FinalSnapshotRetentionDaysLabel is also set on the final snapshots,
+	// which are deleted that many days after their creation.
+	FinalSnapshotRetentionDaysLabel = "snapshot-before-delete-retention-days"
+
+	// DiskTypeMigrationLabel is set to the target disk type on the disks
+	// being migrated to another disk type, and on their migration snapshots.
+	DiskTypeMigrationLabel = "disk-type-migration"
+
+	// AsyncReplicationDeleteLabel is set on the disks whose asynchronous
+	// replication is stopped by DeleteVolume, which then deletes their
+	// stopped secondary disks.
+	AsyncReplicationDeleteLabel = "async-replication-delete"
+
 	// GCE Access Modes that are valid for hyperdisks only.
 	GCEReadOnlyManyAccessMode  = "READ_ONLY_MANY"
 	GCEReadWriteManyAccessMode = "READ_WRITE_MANY"
@@ -53,6 +85,15 @@ const (
 	ContextDataCacheMode = "data-cache-mode"
 	ContextDiskSizeGB    = "disk-size"
 
+	// Keys in the volume context for node-side encryption
+	ContextNodeEncryption       = "node-encryption"
+	ContextNodeEncryptionKMSKey = "node-encryption-kms-key"
+
+	// Keys in the volume context for the secondary disk of asynchronous
+	// replication
+	ContextAsyncReplicationSecondaryVolume = "async-replication-secondary-volume"
+	ContextAsyncReplicationSecondaryZones  = "async-replication-secondary-zones"
+
 	// Keys in the publish context
 	ContexLocalSsdCacheSize = "local-ssd-cache-size"
 	// Node name for E2E tests
@@ -69,48 +110,190 @@ const (
 	AttachLimitOverrideLabel   = "gke-volume-attach-limit-override"
 )
 
-// doc https://cloud.google.com/compute/docs/general-purpose-machines
-// MachineHyperdiskLimit represents the mapping between max vCPUs and hyperdisk (balanced) attach limit
-type MachineHyperdiskLimit struct {
-	Max   int64
-	Value int64
+// AttachLimit is the attach limit of the machine types of a series with up to
+// Max vCPUs, or GPUs for A4X. A Max of 0 matches all the machine types of the
+// series.
+type AttachLimit struct {
+	Max   int64 `json:"max,omitempty"`
+	Value int64 `json:"value"`
 }
 
-// C4 Machine Types - Hyperdisk Balanced Limits
-var C4MachineHyperdiskAttachLimitMap = []MachineHyperdiskLimit{
-	{Max: 2, Value: 7},
-	{Max: 4, Value: 15},
-	{Max: 24, Value: 31},
-	{Max: 48, Value: 63},
-	{Max: 96, Value: 127},
+// DiskFamilyAttachLimits are the attach limits of a machine series for PD and
+// Hyperdisk disks, by increasing Max. A family without limits can't be
+// attached by the series.
+type DiskFamilyAttachLimits struct {
+	PD        []AttachLimit `json:"pd,omitempty"`
+	Hyperdisk []AttachLimit `json:"hyperdisk,omitempty"`
 }
 
-// C4D Machine Types - Hyperdisk Balanced Limits
-var C4DMachineHyperdiskAttachLimitMap = []MachineHyperdiskLimit{
-	{Max: 2, Value: 3},
-	{Max: 4, Value: 7},
-	{Max: 8, Value: 15},
-	{Max: 96, Value: 31},
-	{Max: 192, Value: 63},
-	{Max: 384, Value: 127},
-}
+// Disk families of the attach limits, used in the topology keys of the node.
+const (
+	DiskFamilyPD        = "pd"
+	DiskFamilyHyperdisk = "hyperdisk"
 
-// N4 Machine Types - Hyperdisk Balanced Limits
-var N4MachineHyperdiskAttachLimitMap = []MachineHyperdiskLimit{
-	{Max: 8, Value: 15},
-	{Max: 80, Value: 31},
+	// AttachLimitKeyPrefix is the prefix of the topology keys with the attach
+	// limit of each disk family.
+	AttachLimitKeyPrefix = "attach-limit.pd.csi.storage.gke.io"
+)
+
+// These limits are all the documented attach limit minus one because the node
+// boot disk is considered an attachable disk so effective attach limit is one
+// less.
+var (
+	attachLimitSmall = []AttachLimit{{Value: 15}}
+	attachLimitBig   = []AttachLimit{{Value: 127}}
+)
+
+// DefaultAttachLimits are the attach limits of machine series missing from
+// MachineAttachLimits.
+var DefaultAttachLimits = DiskFamilyAttachLimits{PD: attachLimitBig, Hyperdisk: attachLimitBig}
+
+// MachineAttachLimits maps a machine type, a bare metal machine series with a
+// "-metal" suffix, or a machine series to its attach limits, looked up in
+// that order. Please refer to docs
+// https://cloud.google.com/compute/docs/disks/#pdnumberlimits and
+// https://cloud.google.com/compute/docs/general-purpose-machines
+var MachineAttachLimits = map[string]DiskFamilyAttachLimits{
+	// General purpose
+	"e2-micro":  {PD: attachLimitSmall},
+	"e2-small":  {PD: attachLimitSmall},
+	"e2-medium": {PD: attachLimitSmall},
+	"e2":        {PD: attachLimitBig},
+	"f1":        {PD: attachLimitSmall},
+	"g1":        {PD: attachLimitSmall},
+	"n1":        {PD: attachLimitBig},
+	"n2":        {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"n2d":       {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"t2a":       {PD: attachLimitBig},
+	"t2d":       {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"n4": {Hyperdisk: []AttachLimit{
+		{Max: 8, Value: 15},
+		{Max: 80, Value: 31},
+	}},
+	"n4a": {Hyperdisk: attachLimitBig},
+	"n4d": {Hyperdisk: attachLimitBig},
+	// Compute optimized
+	"c2":       {PD: attachLimitBig},
+	"c2d":      {PD: attachLimitBig},
+	"c3":       {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"c3-metal": {Hyperdisk: attachLimitSmall},
+	"c3d":      {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"c4": {Hyperdisk: []AttachLimit{
+		{Max: 2, Value: 7},
+		{Max: 4, Value: 15},
+		{Max: 24, Value: 31},
+		{Max: 48, Value: 63},
+		{Max: 96, Value: 127},
+	}},
+	"c4a": {Hyperdisk: []AttachLimit{
+		{Max: 2, Value: 7},
+		{Max: 8, Value: 15},
+		{Max: 48, Value: 31},
+		{Max: 72, Value: 63},
+	}},
+	"c4d": {Hyperdisk: []AttachLimit{
+		{Max: 2, Value: 3},
+		{Max: 4, Value: 7},
+		{Max: 8, Value: 15},
+		{Max: 96, Value: 31},
+		{Max: 192, Value: 63},
+		{Max: 384, Value: 127},
+	}},
+	"h3":  {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"h4d": {Hyperdisk: attachLimitBig},
+	// Memory optimized
+	"m1": {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"m2": {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"m3": {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"m4": {Hyperdisk: attachLimitBig},
+	// doc https://cloud.google.com/compute/docs/memory-optimized-machines#x4_disks
+	"x4": {Hyperdisk: []AttachLimit{{Value: 39}}},
+	// Storage optimized
+	"z3": {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	// Accelerator optimized
+	"a2": {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"a3": {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	// doc https://cloud.google.com/compute/docs/accelerator-optimized-machines#a4-disks
+	"a4": {Hyperdisk: attachLimitBig},
+	// The Max of A4X is the GPU count.
+	"a4x": {Hyperdisk: []AttachLimit{
+		{Max: 1, Value: 63},
+		{Max: 2, Value: 127},
+	}},
+	"a4x-metal": {Hyperdisk: []AttachLimit{{Value: 31}}},
+	"g2":        {PD: attachLimitBig, Hyperdisk: attachLimitBig},
+	"g4":        {Hyperdisk: attachLimitBig},
 }
 
-// C4A Machine Types - Hyperdisk Balanced Limits
-var C4AMachineHyperdiskAttachLimitMap = []MachineHyperdiskLimit{
-	{Max: 2, Value: 7},
-	{Max: 8, Value: 15},
-	{Max: 48, Value: 31},
-	{Max: 72, Value: 63},
+// Disk types of the machine series disk compatibility table.
+const (
+	DiskTypePDStandard   = "pd-standard"
+	DiskTypePDBalanced   = "pd-balanced"
+	DiskTypePDSSD        = "pd-ssd"
+	DiskTypePDExtreme    = "pd-extreme"
+	DiskTypeHdBalanced   = "hyperdisk-balanced"
+	DiskTypeHdBalancedHA = "hyperdisk-balanced-high-availability"
+	DiskTypeHdExtreme    = "hyperdisk-extreme"
+	DiskTypeHdThroughput = "hyperdisk-throughput"
+	DiskTypeHdML         = "hyperdisk-ml"
+)
+
+// CompatibilityDiskTypes are the disk types published in the node topology
+// when the machine series disk compatibility is enabled.
+var CompatibilityDiskTypes = []string{
+	DiskTypePDStandard,
+	DiskTypePDBalanced,
+	DiskTypePDSSD,
+	DiskTypePDExtreme,
+	DiskTypeHdBalanced,
+	DiskTypeHdBalancedHA,
+	DiskTypeHdExtreme,
+	DiskTypeHdThroughput,
+	DiskTypeHdML,
 }
 
-// A4X Machine Types - Hyperdisk Balanced Limits. The max here is actually the GPU count (not CPU, like the others).
-var A4XMachineHyperdiskAttachLimitMap = []MachineHyperdiskLimit{
-	{Max: 1, Value: 63},
-	{Max: 2, Value: 127},
+// MachineSeriesDiskTypes maps a machine series to the disk types its
+// instances can attach. Please refer to doc
+// https://cloud.google.com/compute/docs/disks#machine-series-support
+// Some disk types are only supported by the larger shapes of a series, the
+// table lists them when any shape of the series supports them. Machine series
+// missing from the table are assumed to support all disk types.
+var MachineSeriesDiskTypes = map[string][]string{
+	// General purpose
+	"e2":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"f1":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"g1":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"n1":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"n2":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD, DiskTypePDExtreme, DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdThroughput},
+	"n2d": {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdBalanced, DiskTypeHdThroughput},
+	"t2a": {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"t2d": {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdThroughput},
+	"n4":  {DiskTypeHdBalanced, DiskTypeHdBalancedHA},
+	"n4a": {DiskTypeHdBalanced, DiskTypeHdBalancedHA},
+	"n4d": {DiskTypeHdBalanced, DiskTypeHdBalancedHA},
+	// Compute optimized
+	"c2":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"c2d": {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD},
+	"c3":  {DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdBalanced, DiskTypeHdBalancedHA, DiskTypeHdExtreme, DiskTypeHdThroughput, DiskTypeHdML},
+	"c3d": {DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdBalanced, DiskTypeHdBalancedHA, DiskTypeHdExtreme, DiskTypeHdThroughput, DiskTypeHdML},
+	"c4":  {DiskTypeHdBalanced, DiskTypeHdBalancedHA, DiskTypeHdExtreme, DiskTypeHdThroughput, DiskTypeHdML},
+	"c4a": {DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdThroughput, DiskTypeHdML},
+	"c4d": {DiskTypeHdBalanced, DiskTypeHdBalancedHA, DiskTypeHdExtreme, DiskTypeHdThroughput, DiskTypeHdML},
+	"h3":  {DiskTypePDBalanced, DiskTypeHdBalanced, DiskTypeHdThroughput},
+	"h4d": {DiskTypeHdBalanced, DiskTypeHdBalancedHA},
+	// Memory optimized
+	"m1": {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD, DiskTypePDExtreme, DiskTypeHdBalanced, DiskTypeHdExtreme},
+	"m2": {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD, DiskTypePDExtreme, DiskTypeHdBalanced, DiskTypeHdExtreme},
+	"m3": {DiskTypePDBalanced, DiskTypePDSSD, DiskTypePDExtreme, DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdThroughput},
+	"m4": {DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdThroughput},
+	"x4": {DiskTypeHdBalanced, DiskTypeHdExtreme},
+	// Storage optimized
+	"z3": {DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdThroughput},
+	// Accelerator optimized
+	"a2":  {DiskTypePDStandard, DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdML},
+	"a3":  {DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdThroughput, DiskTypeHdML},
+	"a4":  {DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdML},
+	"a4x": {DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdML},
+	"g2":  {DiskTypePDBalanced, DiskTypePDSSD, DiskTypeHdThroughput, DiskTypeHdML},
+	"g4":  {DiskTypeHdBalanced, DiskTypeHdExtreme, DiskTypeHdML},
 }
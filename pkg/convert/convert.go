@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -114,6 +115,27 @@ func ConvertLabelsStringToMap(labels string) (map[string]string, error) {
 	return labelsMap, nil
 }
 
+// SanitizeLabelValue converts value to a valid GCE label value: it is
+// lowercased, the characters other than lowercase letters, digits, _ and - are
+// replaced by -, and it is truncated to 63 characters.
+// example: "Team.A/Prod" gets converted into "team-a-prod"
+func SanitizeLabelValue(value string) string {
+	const maxLabelValueLength = 63
+	var b strings.Builder
+	n := 0
+	for _, r := range strings.ToLower(value) {
+		if n == maxLabelValueLength {
+			break
+		}
+		if !unicode.IsLower(r) && (r < '0' || r > '9') && r != '_' && r != '-' {
+			r = '-'
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String()
+}
+
 // ConvertTagsStringToMap converts the tags from string to Tag slice
 // example: "parent_id1/tag_key1/tag_value1,parent_id2/tag_key2/tag_value2" gets
 // converted into {"parent_id1/tag_key1":"tag_value1", "parent_id2/tag_key2":"tag_value2"}
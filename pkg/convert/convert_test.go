@@ -2,6 +2,7 @@ package convert
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -187,6 +188,55 @@ func TestConvertLabelsStringToMap(t *testing.T) {
 
 }
 
+func TestSanitizeLabelValue(t *testing.T) {
+	tests := []struct {
+		desc     string
+		value    string
+		expected string
+	}{
+		{
+			desc:     "valid value",
+			value:    "team-a_1",
+			expected: "team-a_1",
+		},
+		{
+			desc:     "uppercase",
+			value:    "TeamA",
+			expected: "teama",
+		},
+		{
+			desc:     "invalid characters",
+			value:    "team.a/prod:1",
+			expected: "team-a-prod-1",
+		},
+		{
+			desc:     "international characters",
+			value:    "équipe",
+			expected: "équipe",
+		},
+		{
+			desc:     "too long",
+			value:    strings.Repeat("a", 70),
+			expected: strings.Repeat("a", 63),
+		},
+		{
+			desc:     "empty",
+			value:    "",
+			expected: "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := SanitizeLabelValue(tc.value); got != tc.expected {
+				t.Errorf("SanitizeLabelValue(%q) = %q; expect %q", tc.value, got, tc.expected)
+			}
+			if _, err := ConvertLabelsStringToMap("key=" + SanitizeLabelValue(tc.value)); err != nil {
+				t.Errorf("Got invalid label value for %q: %v", tc.value, err)
+			}
+		})
+	}
+}
+
 func TestConvertTagsStringToMap(t *testing.T) {
 	t.Run("parsing tags string into slice", func(t *testing.T) {
 		testCases := []struct {
@@ -86,6 +86,10 @@ type DeviceUtils interface {
 	// exists on the machine, or an empty string if none exists
 	VerifyDevicePath(devicePaths []string, deviceName string) (string, error)
 
+	// DevicePathMatches returns whether devicePath resolves to the disk
+	// with the given device name, without repairing devicePath.
+	DevicePathMatches(devicePath string, deviceName string) (bool, error)
+
 	// Resize returns whether or not a device needs resizing.
 	Resize(resizer resizefs.Resizefs, devicePath string, deviceMountPath string) (bool, error)
 
@@ -108,9 +112,19 @@ func NewDeviceUtils() *deviceUtils {
 
 // Returns list of all /dev/disk/by-id/* paths for given PD.
 func (m *deviceUtils) GetDiskByIdPaths(deviceName string, partition string) []string {
+	return getDiskByIdPaths(diskByIdPath, deviceName, partition)
+}
+
+// IsDiskByIdName reports whether name is the name of a /dev/disk/by-id symlink
+// of a PD, as returned by GetDiskByIdPaths.
+func IsDiskByIdName(name string) bool {
+	return strings.HasPrefix(name, diskGooglePrefix) || strings.HasPrefix(name, diskScsiGooglePrefix)
+}
+
+func getDiskByIdPaths(byIdPath, deviceName, partition string) []string {
 	devicePaths := []string{
-		path.Join(diskByIdPath, diskGooglePrefix+deviceName),
-		path.Join(diskByIdPath, diskScsiGooglePrefix+deviceName),
+		path.Join(byIdPath, diskGooglePrefix+deviceName),
+		path.Join(byIdPath, diskScsiGooglePrefix+deviceName),
 	}
 
 	if partition != "" {
@@ -209,6 +223,18 @@ func ensureUdevToolsExist() error {
 	return nil
 }
 
+// CheckUdevTools returns an error if the tools VerifyDevicePath relies on to
+// read disk serials and repair by-id symlinks are missing.
+func CheckUdevTools() error {
+	if err := ensureUdevToolsExist(); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("udevadm"); err != nil {
+		return fmt.Errorf("could not find udevadm: %w", err)
+	}
+	return nil
+}
+
 // VerifyDevicePath returns the first devicePath that maps to a real disk in the
 // candidate devicePaths or an empty string if none is found.
 // If the device is not found, it will attempt to fix any issues
@@ -296,6 +322,18 @@ func (m *deviceUtils) VerifyDevicePath(devicePaths []string, deviceName string)
 	return devicePath, nil
 }
 
+func (m *deviceUtils) DevicePathMatches(devicePath string, deviceName string) (bool, error) {
+	devFsPath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return false, fmt.Errorf("filepath.EvalSymlinks(%q) failed: %w", devicePath, err)
+	}
+	devFsSerial, err := getDevFsSerial(devFsPath)
+	if err != nil {
+		return false, fmt.Errorf("couldn't get serial number for disk %s at device path %s: %w", deviceName, devFsPath, err)
+	}
+	return len(devFsSerial) != 0 && devFsSerial == deviceName, nil
+}
+
 func (m *deviceUtils) Resize(resizer resizefs.Resizefs, devicePath string, deviceMountPath string) (bool, error) {
 	return resizer.Resize(devicePath, deviceMountPath)
 }
@@ -42,6 +42,10 @@ func (m *fakeDeviceUtils) VerifyDevicePath(devicePaths []string, diskName string
 	return "/dev/disk/fake-path", nil
 }
 
+func (m *fakeDeviceUtils) DevicePathMatches(devicePath string, diskName string) (bool, error) {
+	return true, nil
+}
+
 func (_ *fakeDeviceUtils) DisableDevice(devicePath string) error {
 	// No-op for testing.
 	return nil
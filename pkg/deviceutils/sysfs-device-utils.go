@@ -0,0 +1,343 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deviceutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DeviceDiscoveryUdev finds devices through the by-id symlinks maintained
+	// by udev, using the tools in /lib/udev_containerized to repair them.
+	DeviceDiscoveryUdev = "udev"
+	// DeviceDiscoverySysfs finds devices by reading their serials from sysfs
+	// and NVMe ioctls, and maintains the by-id symlinks itself.
+	DeviceDiscoverySysfs = "sysfs"
+
+	// Size of the NVMe identify namespace data structure, and offset of its
+	// vendor specific area where GCE stores the device name as JSON.
+	nvmeIdentifyDataSize    = 4096
+	nvmeVendorSpecificStart = 384
+
+	// Header length of the SCSI Unit Serial Number VPD page (0x80).
+	vpdPage80HeaderLength = 4
+	vpdPage80Code         = 0x80
+
+	defaultSysfsPollInterval = 500 * time.Millisecond
+	defaultSysfsPollTimeout  = 10 * time.Second
+)
+
+var (
+	// Whole disks under /sys/block. Partitions are children of those.
+	sysfsScsiDiskRegex = regexp.MustCompile(`^sd[a-z]+$`)
+	sysfsNvmeDiskRegex = regexp.MustCompile(`^nvme[0-9]+n[0-9]+$`)
+)
+
+// nvmeIdentifyNamespaceFunc returns the identify namespace data of namespace
+// nsid of the NVMe device at devFsPath.
+type nvmeIdentifyNamespaceFunc func(devFsPath string, nsid uint32) ([]byte, error)
+
+// sysfsDeviceUtils verifies device paths without udev: it matches the GCE
+// device name against the SCSI VPD page 0x80 serial or the NVMe identify
+// namespace vendor data, and creates the by-id symlink if it is missing or
+// points at the wrong device.
+type sysfsDeviceUtils struct {
+	*deviceUtils
+
+	sysfsPath             string
+	devPath               string
+	nvmeIdentifyNamespace nvmeIdentifyNamespaceFunc
+	pollInterval          time.Duration
+	pollTimeout           time.Duration
+}
+
+var _ DeviceUtils = &sysfsDeviceUtils{}
+
+func NewSysfsDeviceUtils() *sysfsDeviceUtils {
+	return newSysfsDeviceUtils("/sys", "/dev", nvmeIdentifyNamespace)
+}
+
+func newSysfsDeviceUtils(sysfsPath, devPath string, identify nvmeIdentifyNamespaceFunc) *sysfsDeviceUtils {
+	return &sysfsDeviceUtils{
+		deviceUtils:           NewDeviceUtils(),
+		sysfsPath:             sysfsPath,
+		devPath:               devPath,
+		nvmeIdentifyNamespace: identify,
+		pollInterval:          defaultSysfsPollInterval,
+		pollTimeout:           defaultSysfsPollTimeout,
+	}
+}
+
+// NewDeviceUtilsForDiscovery returns the DeviceUtils for the given discovery
+// backend, one of DeviceDiscoveryUdev or DeviceDiscoverySysfs.
+func NewDeviceUtilsForDiscovery(discovery string) (DeviceUtils, error) {
+	switch discovery {
+	case DeviceDiscoveryUdev:
+		return NewDeviceUtils(), nil
+	case DeviceDiscoverySysfs:
+		return NewSysfsDeviceUtils(), nil
+	default:
+		return nil, fmt.Errorf("unknown device discovery %q, must be %q or %q", discovery, DeviceDiscoveryUdev, DeviceDiscoverySysfs)
+	}
+}
+
+func (m *sysfsDeviceUtils) GetDiskByIdPaths(deviceName string, partition string) []string {
+	return getDiskByIdPaths(filepath.Join(m.devPath, "disk", "by-id"), deviceName, partition)
+}
+
+// VerifyDevicePath returns the first of devicePaths that resolves to the disk
+// with the given device name. If none does, the first of devicePaths is
+// (re)created as a symlink to the matching disk found in sysfs.
+func (m *sysfsDeviceUtils) VerifyDevicePath(devicePaths []string, deviceName string) (string, error) {
+	if len(devicePaths) == 0 {
+		return "", fmt.Errorf("no device paths given for disk %s", deviceName)
+	}
+	partition := partitionFromDevicePath(devicePaths[0])
+
+	var devicePath string
+	err := wait.Poll(m.pollInterval, m.pollTimeout, func() (bool, error) {
+		for _, candidate := range devicePaths {
+			devFsPath, err := filepath.EvalSymlinks(candidate)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return false, fmt.Errorf("filepath.EvalSymlinks(%q) failed: %w", candidate, err)
+			}
+			serial, err := m.getDiskSerial(m.diskForDevFsName(filepath.Base(devFsPath)))
+			if err != nil {
+				klog.Warningf("For disk %s couldn't get serial of %s (aka %s): %v", deviceName, candidate, devFsPath, err)
+				continue
+			}
+			if serial == deviceName {
+				devicePath = candidate
+				return true, nil
+			}
+			klog.Warningf("For disk %s device path %s (aka %s) has mismatched serial %q", deviceName, candidate, devFsPath, serial)
+		}
+
+		// None of the candidates point at the disk, find it in sysfs and link it.
+		devFsName, err := m.findDevFsName(deviceName, partition)
+		if err != nil {
+			return false, err
+		}
+		if devFsName == "" {
+			// The disk may still be attaching.
+			klog.V(4).Infof("For disk %s no matching device found in %s yet", deviceName, m.sysfsPath)
+			return false, nil
+		}
+		if err := m.linkDevice(devicePaths[0], devFsName); err != nil {
+			return false, err
+		}
+		devicePath = devicePaths[0]
+		return true, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find disk %s in sysfs after retrying for %v: %w", deviceName, m.pollTimeout, err)
+	}
+	return devicePath, nil
+}
+
+func (m *sysfsDeviceUtils) DevicePathMatches(devicePath string, deviceName string) (bool, error) {
+	devFsPath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return false, fmt.Errorf("filepath.EvalSymlinks(%q) failed: %w", devicePath, err)
+	}
+	serial, err := m.getDiskSerial(m.diskForDevFsName(filepath.Base(devFsPath)))
+	if err != nil {
+		return false, fmt.Errorf("couldn't get serial of %s (aka %s): %w", devicePath, devFsPath, err)
+	}
+	return serial == deviceName, nil
+}
+
+// diskForDevFsName returns the whole disk devFsName belongs to. Partitions
+// are subdirectories of their disk in /sys/block.
+func (m *sysfsDeviceUtils) diskForDevFsName(devFsName string) string {
+	if _, err := os.Stat(filepath.Join(m.sysfsPath, "block", devFsName)); err == nil {
+		return devFsName
+	}
+	matches, _ := filepath.Glob(filepath.Join(m.sysfsPath, "block", "*", devFsName))
+	if len(matches) == 1 {
+		return filepath.Base(filepath.Dir(matches[0]))
+	}
+	return devFsName
+}
+
+// findDevFsName returns the name in /dev of the disk with the given device
+// name, or of its partition if partition is set. It returns the empty string
+// if there is no such disk.
+func (m *sysfsDeviceUtils) findDevFsName(deviceName, partition string) (string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.sysfsPath, "block"))
+	if err != nil {
+		return "", fmt.Errorf("failed to list block devices: %w", err)
+	}
+	for _, entry := range entries {
+		disk := entry.Name()
+		if !sysfsScsiDiskRegex.MatchString(disk) && !sysfsNvmeDiskRegex.MatchString(disk) {
+			continue
+		}
+		serial, err := m.getDiskSerial(disk)
+		if err != nil {
+			// Not all disks report a serial, eg local SSDs on older images.
+			klog.V(4).Infof("Couldn't get serial of %s: %v", disk, err)
+			continue
+		}
+		if serial != deviceName {
+			continue
+		}
+		if partition == "" {
+			return disk, nil
+		}
+		return m.findPartition(disk, partition)
+	}
+	return "", nil
+}
+
+func (m *sysfsDeviceUtils) findPartition(disk, partition string) (string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.sysfsPath, "block", disk))
+	if err != nil {
+		return "", fmt.Errorf("failed to list partitions of %s: %w", disk, err)
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), disk) {
+			continue
+		}
+		number, err := os.ReadFile(filepath.Join(m.sysfsPath, "block", disk, entry.Name(), "partition"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(number)) == partition {
+			return entry.Name(), nil
+		}
+	}
+	// The partition table may not have been read yet.
+	return "", nil
+}
+
+// getDiskSerial returns the serial of the whole disk with the given name in
+// /sys/block.
+func (m *sysfsDeviceUtils) getDiskSerial(disk string) (string, error) {
+	switch {
+	case sysfsScsiDiskRegex.MatchString(disk):
+		page, err := os.ReadFile(filepath.Join(m.sysfsPath, "block", disk, "device", "vpd_pg80"))
+		if err != nil {
+			return "", err
+		}
+		return parseVpdPage80(page)
+	case sysfsNvmeDiskRegex.MatchString(disk):
+		nsidStr, err := os.ReadFile(filepath.Join(m.sysfsPath, "block", disk, "nsid"))
+		if err != nil {
+			return "", err
+		}
+		nsid, err := strconv.ParseUint(strings.TrimSpace(string(nsidStr)), 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid nsid %q for %s: %w", nsidStr, disk, err)
+		}
+		data, err := m.nvmeIdentifyNamespace(filepath.Join(m.devPath, disk), uint32(nsid))
+		if err != nil {
+			return "", err
+		}
+		return parseNvmeIdentifyNamespace(data)
+	default:
+		return "", fmt.Errorf("%s is not a SCSI or NVMe disk", disk)
+	}
+}
+
+// linkDevice points the by-id symlink devicePath at /dev/<devFsName>, the
+// same way the udev rules do.
+func (m *sysfsDeviceUtils) linkDevice(devicePath, devFsName string) error {
+	if err := os.MkdirAll(filepath.Dir(devicePath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(devicePath), err)
+	}
+	target, err := filepath.Rel(filepath.Dir(devicePath), filepath.Join(m.devPath, devFsName))
+	if err != nil {
+		return err
+	}
+	// Replace any existing link atomically so that concurrent readers always
+	// see a valid link.
+	tmpPath := devicePath + ".tmp"
+	os.Remove(tmpPath)
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return fmt.Errorf("failed to create symlink %s -> %s: %w", tmpPath, target, err)
+	}
+	if err := os.Rename(tmpPath, devicePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename %s to %s: %w", tmpPath, devicePath, err)
+	}
+	klog.Infof("Linked %s to %s", devicePath, target)
+	return nil
+}
+
+// partitionFromDevicePath returns the partition number of a by-id path, or
+// the empty string if it is a whole disk.
+func partitionFromDevicePath(devicePath string) string {
+	i := strings.LastIndex(devicePath, diskPartitionSuffix)
+	if i < 0 {
+		return ""
+	}
+	partition := devicePath[i+len(diskPartitionSuffix):]
+	if _, err := strconv.Atoi(partition); err != nil {
+		return ""
+	}
+	return partition
+}
+
+// parseVpdPage80 extracts the serial from the raw contents of a SCSI Unit
+// Serial Number VPD page. GCE reports the device name as serial.
+func parseVpdPage80(page []byte) (string, error) {
+	if len(page) < vpdPage80HeaderLength || page[1] != vpdPage80Code {
+		return "", fmt.Errorf("invalid VPD page 0x80: %q", page)
+	}
+	length := int(binary.BigEndian.Uint16(page[2:4]))
+	if len(page) < vpdPage80HeaderLength+length {
+		return "", fmt.Errorf("truncated VPD page 0x80: want %d bytes, got %d", vpdPage80HeaderLength+length, len(page))
+	}
+	serial := strings.TrimSpace(string(bytes.TrimRight(page[vpdPage80HeaderLength:vpdPage80HeaderLength+length], "\x00")))
+	if serial == "" {
+		return "", fmt.Errorf("empty serial in VPD page 0x80")
+	}
+	return serial, nil
+}
+
+// parseNvmeIdentifyNamespace extracts the device name GCE stores as JSON in
+// the vendor specific area of the identify namespace data.
+func parseNvmeIdentifyNamespace(data []byte) (string, error) {
+	if len(data) < nvmeIdentifyDataSize {
+		return "", fmt.Errorf("identify namespace data is %d bytes, expected %d", len(data), nvmeIdentifyDataSize)
+	}
+	vendor := bytes.TrimRight(data[nvmeVendorSpecificStart:nvmeIdentifyDataSize], "\x00")
+	var ext struct {
+		DeviceName string `json:"device_name"`
+	}
+	if err := json.Unmarshal(vendor, &ext); err != nil {
+		return "", fmt.Errorf("NVMe vendor extension cannot be parsed: %w", err)
+	}
+	if ext.DeviceName == "" {
+		return "", fmt.Errorf("NVMe vendor extension has no device name")
+	}
+	return ext.DeviceName, nil
+}
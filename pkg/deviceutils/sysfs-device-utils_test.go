@@ -0,0 +1,392 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deviceutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func vpdPage80(serial string) []byte {
+	page := []byte{0x00, vpdPage80Code, 0x00, byte(len(serial))}
+	return append(page, serial...)
+}
+
+func nvmeIdentifyData(deviceName string) []byte {
+	data := make([]byte, nvmeIdentifyDataSize)
+	copy(data[nvmeVendorSpecificStart:], fmt.Sprintf(`{"device_name":"%s","disk_type":"PERSISTENT"}`, deviceName))
+	return data
+}
+
+// fakeSysfs lays out a fake /sys and /dev under a temporary directory.
+type fakeSysfs struct {
+	t         *testing.T
+	sysfsPath string
+	devPath   string
+	// nvme maps /dev paths to the device name in their identify data.
+	nvme map[string]string
+}
+
+func newFakeSysfs(t *testing.T) *fakeSysfs {
+	root := t.TempDir()
+	f := &fakeSysfs{
+		t:         t,
+		sysfsPath: filepath.Join(root, "sys"),
+		devPath:   filepath.Join(root, "dev"),
+		nvme:      map[string]string{},
+	}
+	f.mkdir(filepath.Join(f.sysfsPath, "block"))
+	f.mkdir(filepath.Join(f.devPath, "disk", "by-id"))
+	return f
+}
+
+func (f *fakeSysfs) mkdir(dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		f.t.Fatalf("Failed to create %s: %v", dir, err)
+	}
+}
+
+func (f *fakeSysfs) writeFile(path string, data []byte) {
+	f.mkdir(filepath.Dir(path))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		f.t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func (f *fakeSysfs) addScsiDisk(disk, serial string, partitions ...string) {
+	f.writeFile(filepath.Join(f.sysfsPath, "block", disk, "device", "vpd_pg80"), vpdPage80(serial))
+	f.writeFile(filepath.Join(f.devPath, disk), nil)
+	for _, partition := range partitions {
+		f.writeFile(filepath.Join(f.sysfsPath, "block", disk, disk+partition, "partition"), []byte(partition+"\n"))
+		f.writeFile(filepath.Join(f.devPath, disk+partition), nil)
+	}
+}
+
+func (f *fakeSysfs) addNvmeDisk(disk, nsid, deviceName string) {
+	f.writeFile(filepath.Join(f.sysfsPath, "block", disk, "nsid"), []byte(nsid+"\n"))
+	f.writeFile(filepath.Join(f.devPath, disk), nil)
+	f.nvme[filepath.Join(f.devPath, disk)] = deviceName
+}
+
+func (f *fakeSysfs) link(name, target string) {
+	if err := os.Symlink(filepath.Join("..", "..", target), filepath.Join(f.devPath, "disk", "by-id", name)); err != nil {
+		f.t.Fatalf("Failed to link %s: %v", name, err)
+	}
+}
+
+func (f *fakeSysfs) identify(devFsPath string, nsid uint32) ([]byte, error) {
+	deviceName, ok := f.nvme[devFsPath]
+	if !ok {
+		return nil, fmt.Errorf("%s is not an NVMe device", devFsPath)
+	}
+	return nvmeIdentifyData(deviceName), nil
+}
+
+func (f *fakeSysfs) deviceUtils() *sysfsDeviceUtils {
+	du := newSysfsDeviceUtils(f.sysfsPath, f.devPath, f.identify)
+	du.pollInterval = time.Millisecond
+	du.pollTimeout = 50 * time.Millisecond
+	return du
+}
+
+func TestSysfsVerifyDevicePath(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setup      func(f *fakeSysfs)
+		deviceName string
+		partition  string
+		expPath    string
+		expTarget  string
+		expErr     bool
+	}{
+		{
+			name: "existing scsi link",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1")
+				f.link("google-pvc-1", "sdb")
+			},
+			deviceName: "pvc-1",
+			expPath:    "disk/by-id/google-pvc-1",
+			expTarget:  "sdb",
+		},
+		{
+			name: "existing udev scsi link",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1")
+				f.link("scsi-0Google_PersistentDisk_pvc-1", "sdb")
+			},
+			deviceName: "pvc-1",
+			expPath:    "disk/by-id/scsi-0Google_PersistentDisk_pvc-1",
+			expTarget:  "sdb",
+		},
+		{
+			name: "missing scsi link is created",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sda", "persistent-disk-0")
+				f.addScsiDisk("sdb", "pvc-1")
+			},
+			deviceName: "pvc-1",
+			expPath:    "disk/by-id/google-pvc-1",
+			expTarget:  "sdb",
+		},
+		{
+			name: "stale scsi link is replaced",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1")
+				f.addScsiDisk("sdc", "pvc-2")
+				f.link("google-pvc-1", "sdc")
+			},
+			deviceName: "pvc-1",
+			expPath:    "disk/by-id/google-pvc-1",
+			expTarget:  "sdb",
+		},
+		{
+			name: "missing partition link is created",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1", "1", "2")
+			},
+			deviceName: "pvc-1",
+			partition:  "2",
+			expPath:    "disk/by-id/google-pvc-1-part2",
+			expTarget:  "sdb2",
+		},
+		{
+			name: "existing partition link",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1", "1")
+				f.link("google-pvc-1-part1", "sdb1")
+			},
+			deviceName: "pvc-1",
+			partition:  "1",
+			expPath:    "disk/by-id/google-pvc-1-part1",
+			expTarget:  "sdb1",
+		},
+		{
+			name: "missing nvme link is created",
+			setup: func(f *fakeSysfs) {
+				f.addNvmeDisk("nvme0n1", "1", "persistent-disk-0")
+				f.addNvmeDisk("nvme0n2", "2", "pvc-1")
+			},
+			deviceName: "pvc-1",
+			expPath:    "disk/by-id/google-pvc-1",
+			expTarget:  "nvme0n2",
+		},
+		{
+			name: "existing nvme link",
+			setup: func(f *fakeSysfs) {
+				f.addNvmeDisk("nvme0n2", "2", "pvc-1")
+				f.link("google-pvc-1", "nvme0n2")
+			},
+			deviceName: "pvc-1",
+			expPath:    "disk/by-id/google-pvc-1",
+			expTarget:  "nvme0n2",
+		},
+		{
+			name: "disk not attached",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sda", "persistent-disk-0")
+			},
+			deviceName: "pvc-1",
+			expErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeSysfs(t)
+			tc.setup(f)
+			du := f.deviceUtils()
+
+			got, err := du.VerifyDevicePath(du.GetDiskByIdPaths(tc.deviceName, tc.partition), tc.deviceName)
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("Expected error, got device path %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if want := filepath.Join(f.devPath, tc.expPath); got != want {
+				t.Errorf("Expected device path %s, got %s", want, got)
+			}
+			target, err := filepath.EvalSymlinks(got)
+			if err != nil {
+				t.Fatalf("Failed to resolve %s: %v", got, err)
+			}
+			if want := filepath.Join(f.devPath, tc.expTarget); target != want {
+				t.Errorf("Expected %s to point at %s, got %s", got, want, target)
+			}
+		})
+	}
+}
+
+func TestSysfsDevicePathMatches(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setup      func(f *fakeSysfs)
+		deviceName string
+		expMatches bool
+		expErr     bool
+	}{
+		{
+			name: "matching scsi disk",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1")
+				f.link("google-pvc-1", "sdb")
+			},
+			deviceName: "pvc-1",
+			expMatches: true,
+		},
+		{
+			name: "reused scsi disk",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-2")
+				f.link("google-pvc-1", "sdb")
+			},
+			deviceName: "pvc-1",
+		},
+		{
+			name: "matching nvme disk",
+			setup: func(f *fakeSysfs) {
+				f.addNvmeDisk("nvme0n2", "2", "pvc-1")
+				f.link("google-pvc-1", "nvme0n2")
+			},
+			deviceName: "pvc-1",
+			expMatches: true,
+		},
+		{
+			name: "missing link",
+			setup: func(f *fakeSysfs) {
+				f.addScsiDisk("sdb", "pvc-1")
+			},
+			deviceName: "pvc-1",
+			expErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeSysfs(t)
+			tc.setup(f)
+			du := f.deviceUtils()
+
+			matches, err := du.DevicePathMatches(filepath.Join(f.devPath, "disk/by-id/google-pvc-1"), tc.deviceName)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if matches != tc.expMatches {
+				t.Errorf("Expected matches %v, got %v", tc.expMatches, matches)
+			}
+		})
+	}
+}
+
+func TestParseVpdPage80(t *testing.T) {
+	testCases := []struct {
+		name   string
+		page   []byte
+		serial string
+		expErr bool
+	}{
+		{
+			name:   "valid page",
+			page:   vpdPage80("pvc-8ee0cf44-6acd-456e-9f3b-95ccd65065b9"),
+			serial: "pvc-8ee0cf44-6acd-456e-9f3b-95ccd65065b9",
+		},
+		{
+			name:   "padded serial",
+			page:   vpdPage80("persistent-disk-0   "),
+			serial: "persistent-disk-0",
+		},
+		{
+			name:   "wrong page code",
+			page:   []byte{0x00, 0x83, 0x00, 0x01, 'a'},
+			expErr: true,
+		},
+		{
+			name:   "truncated page",
+			page:   []byte{0x00, vpdPage80Code, 0x00, 0x10, 'a'},
+			expErr: true,
+		},
+		{
+			name:   "empty serial",
+			page:   vpdPage80(""),
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serial, err := parseVpdPage80(tc.page)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if serial != tc.serial {
+				t.Errorf("Expected serial %q, got %q", tc.serial, serial)
+			}
+		})
+	}
+}
+
+func TestParseNvmeIdentifyNamespace(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   []byte
+		serial string
+		expErr bool
+	}{
+		{
+			name:   "valid vendor extension",
+			data:   nvmeIdentifyData("pvc-1"),
+			serial: "pvc-1",
+		},
+		{
+			name:   "no vendor extension",
+			data:   make([]byte, nvmeIdentifyDataSize),
+			expErr: true,
+		},
+		{
+			name:   "short data",
+			data:   make([]byte, nvmeVendorSpecificStart),
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serial, err := parseNvmeIdentifyNamespace(tc.data)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if serial != tc.serial {
+				t.Errorf("Expected serial %q, got %q", tc.serial, serial)
+			}
+		})
+	}
+}
+
+func TestPartitionFromDevicePath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/dev/disk/by-id/google-pvc-1":              "",
+		"/dev/disk/by-id/google-pvc-1-part3":        "3",
+		"/dev/disk/by-id/google-my-partition-disk":  "",
+		"/dev/disk/by-id/google-my-partfoo-disk":    "",
+		"/dev/disk/by-id/google-my-part-disk-part1": "1",
+	} {
+		if got := partitionFromDevicePath(path); got != want {
+			t.Errorf("partitionFromDevicePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// FakeLuks keeps LUKS headers and open mappings in memory.
+type FakeLuks struct {
+	// Formatted maps device paths to the passphrase of their LUKS header.
+	Formatted map[string][]byte
+	// Opened maps mapper names to the device path they expose.
+	Opened map[string]string
+	// Resized records the mapper names passed to Resize.
+	Resized []string
+}
+
+var _ Luks = &FakeLuks{}
+
+func NewFakeLuks() *FakeLuks {
+	return &FakeLuks{
+		Formatted: map[string][]byte{},
+		Opened:    map[string]string{},
+	}
+}
+
+func (f *FakeLuks) IsLuks(devicePath string) (bool, error) {
+	_, ok := f.Formatted[devicePath]
+	return ok, nil
+}
+
+func (f *FakeLuks) Format(devicePath string, passphrase []byte) error {
+	f.Formatted[devicePath] = passphrase
+	return nil
+}
+
+func (f *FakeLuks) Open(devicePath, mapperName string, passphrase []byte) error {
+	key, ok := f.Formatted[devicePath]
+	if !ok {
+		return fmt.Errorf("%s is not a LUKS device", devicePath)
+	}
+	if !bytes.Equal(key, passphrase) {
+		return fmt.Errorf("no key available with this passphrase for %s", devicePath)
+	}
+	f.Opened[mapperName] = devicePath
+	return nil
+}
+
+func (f *FakeLuks) Close(mapperName string) error {
+	delete(f.Opened, mapperName)
+	return nil
+}
+
+func (f *FakeLuks) Resize(mapperName string) error {
+	if _, ok := f.Opened[mapperName]; !ok {
+		return fmt.Errorf("mapping %s is not active", mapperName)
+	}
+	f.Resized = append(f.Resized, mapperName)
+	return nil
+}
+
+func (f *FakeLuks) IsOpen(mapperName string) (bool, error) {
+	_, ok := f.Opened[mapperName]
+	return ok, nil
+}
+
+// FakeKeyUnwrapper "decrypts" ciphertexts by looking them up in Keys, which
+// is indexed by KMS key name and then by ciphertext.
+type FakeKeyUnwrapper struct {
+	Keys map[string]map[string][]byte
+}
+
+var _ KeyUnwrapper = &FakeKeyUnwrapper{}
+
+func NewFakeKeyUnwrapper() *FakeKeyUnwrapper {
+	return &FakeKeyUnwrapper{Keys: map[string]map[string][]byte{}}
+}
+
+func (f *FakeKeyUnwrapper) Unwrap(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error) {
+	plaintext, ok := f.Keys[keyName][string(ciphertext)]
+	if !ok {
+		return nil, fmt.Errorf("failed to decrypt with KMS key %s", keyName)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	cloudkms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// KeyUnwrapper decrypts a wrapped LUKS passphrase.
+type KeyUnwrapper interface {
+	Unwrap(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error)
+}
+
+var _ KeyUnwrapper = &cloudKMSUnwrapper{}
+
+type cloudKMSUnwrapper struct {
+	mutex  sync.Mutex
+	client *cloudkms.KeyManagementClient
+}
+
+// NewCloudKMSUnwrapper returns a KeyUnwrapper backed by Cloud KMS. The client
+// is created on first use with the node's default credentials so that nodes
+// that never stage encrypted volumes don't need access to Cloud KMS.
+func NewCloudKMSUnwrapper() *cloudKMSUnwrapper {
+	return &cloudKMSUnwrapper{}
+}
+
+func (u *cloudKMSUnwrapper) Unwrap(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error) {
+	client, err := u.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:       keyName,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with KMS key %s: %w", keyName, err)
+	}
+	return resp.GetPlaintext(), nil
+}
+
+func (u *cloudKMSUnwrapper) getClient(ctx context.Context) (*cloudkms.KeyManagementClient, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.client == nil {
+		client, err := cloudkms.NewKeyManagementClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create KMS client: %w", err)
+		}
+		u.client = client
+	}
+	return u.client, nil
+}
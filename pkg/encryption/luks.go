@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/exec"
+)
+
+const (
+	// LuksFormatType is the TYPE blkid reports for a LUKS device.
+	LuksFormatType = "crypto_LUKS"
+
+	mapperDir    = "/dev/mapper"
+	mapperPrefix = "luks-"
+	cryptsetup   = "cryptsetup"
+
+	// cryptsetupWrongDevice is the exit status of cryptsetup for devices
+	// that don't exist, which is what `cryptsetup status` reports for
+	// inactive mappings.
+	cryptsetupWrongDevice = 4
+)
+
+// Luks manages dm-crypt mappings of LUKS formatted devices.
+type Luks interface {
+	// IsLuks returns true if devicePath holds a LUKS header.
+	IsLuks(devicePath string) (bool, error)
+	// Format writes a new LUKS2 header to devicePath protected by passphrase.
+	Format(devicePath string, passphrase []byte) error
+	// Open unlocks devicePath and exposes the plaintext device at
+	// MapperPath(mapperName).
+	Open(devicePath, mapperName string, passphrase []byte) error
+	// Close removes the mapping.
+	Close(mapperName string) error
+	// Resize grows the mapping to the size of the underlying device.
+	Resize(mapperName string) error
+	// IsOpen returns true if the mapping is active.
+	IsOpen(mapperName string) (bool, error)
+}
+
+// MapperName returns the device mapper name used for the PD with the given
+// device name.
+func MapperName(deviceName string) string {
+	return mapperPrefix + deviceName
+}
+
+// MapperPath returns the path of the plaintext device of an open mapping.
+func MapperPath(mapperName string) string {
+	return filepath.Join(mapperDir, mapperName)
+}
+
+var _ Luks = &cryptsetupLuks{}
+
+type cryptsetupLuks struct {
+	exec exec.Interface
+}
+
+// NewCryptsetupLuks returns a Luks that shells out to cryptsetup through exec.
+func NewCryptsetupLuks(exec exec.Interface) *cryptsetupLuks {
+	return &cryptsetupLuks{exec: exec}
+}
+
+func (c *cryptsetupLuks) IsLuks(devicePath string) (bool, error) {
+	_, err := c.run(nil, "isLuks", devicePath)
+	if err == nil {
+		return true, nil
+	}
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == 1 {
+		// cryptsetup exits with 1 if the device is not a LUKS device.
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *cryptsetupLuks) Format(devicePath string, passphrase []byte) error {
+	klog.V(4).Infof("Formatting %s as LUKS2", devicePath)
+	_, err := c.run(passphrase, "luksFormat", "--type", "luks2", "--batch-mode", "--key-file", "-", devicePath)
+	return err
+}
+
+func (c *cryptsetupLuks) Open(devicePath, mapperName string, passphrase []byte) error {
+	klog.V(4).Infof("Opening LUKS device %s as %s", devicePath, mapperName)
+	// --disable-keyring keeps the volume key in the dm-crypt table rather than
+	// the kernel keyring, so that resizing the mapping does not require the
+	// passphrase again.
+	_, err := c.run(passphrase, "luksOpen", "--disable-keyring", "--key-file", "-", devicePath, mapperName)
+	return err
+}
+
+func (c *cryptsetupLuks) Close(mapperName string) error {
+	klog.V(4).Infof("Closing LUKS mapping %s", mapperName)
+	_, err := c.run(nil, "luksClose", mapperName)
+	return err
+}
+
+func (c *cryptsetupLuks) Resize(mapperName string) error {
+	klog.V(4).Infof("Resizing LUKS mapping %s", mapperName)
+	_, err := c.run(nil, "resize", mapperName)
+	return err
+}
+
+func (c *cryptsetupLuks) IsOpen(mapperName string) (bool, error) {
+	_, err := c.run(nil, "status", mapperName)
+	if err == nil {
+		return true, nil
+	}
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == cryptsetupWrongDevice {
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *cryptsetupLuks) run(stdin []byte, args ...string) ([]byte, error) {
+	cmd := c.exec.Command(cryptsetup, args...)
+	if stdin != nil {
+		cmd.SetStdin(bytes.NewReader(stdin))
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("%s %s failed: %w; output: %s", cryptsetup, strings.Join(args, " "), err, string(output))
+	}
+	return output, nil
+}
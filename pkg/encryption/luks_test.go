@@ -0,0 +1,144 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+type fakeRun struct {
+	args     string
+	expStdin string
+	err      error
+
+	cmd *testingexec.FakeCmd
+}
+
+func newFakeExec(t *testing.T, runs []*fakeRun) *testingexec.FakeExec {
+	fakeExec := &testingexec.FakeExec{ExactOrder: true}
+	for _, run := range runs {
+		run := run
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+			if cmd != cryptsetup {
+				t.Errorf("expected %s to be run, got %s", cryptsetup, cmd)
+			}
+			if got := strings.Join(args, " "); got != run.args {
+				t.Errorf("expected args %q, got %q", run.args, got)
+			}
+			run.cmd = &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return nil, nil, run.err },
+				},
+			}
+			return testingexec.InitFakeCmd(run.cmd, cmd, args...)
+		})
+	}
+	return fakeExec
+}
+
+func TestCryptsetupLuks(t *testing.T) {
+	exitErr := &testingexec.FakeExitError{Status: 1}
+	testCases := []struct {
+		name    string
+		runs    []*fakeRun
+		call    func(l Luks) (bool, error)
+		expBool bool
+		expErr  bool
+	}{
+		{
+			name: "format",
+			runs: []*fakeRun{{args: "luksFormat --type luks2 --batch-mode --key-file - /dev/sdb", expStdin: "secret"}},
+			call: func(l Luks) (bool, error) { return false, l.Format("/dev/sdb", []byte("secret")) },
+		},
+		{
+			name: "open",
+			runs: []*fakeRun{{args: "luksOpen --disable-keyring --key-file - /dev/sdb luks-disk", expStdin: "secret"}},
+			call: func(l Luks) (bool, error) { return false, l.Open("/dev/sdb", "luks-disk", []byte("secret")) },
+		},
+		{
+			name:   "open with wrong passphrase",
+			runs:   []*fakeRun{{args: "luksOpen --disable-keyring --key-file - /dev/sdb luks-disk", expStdin: "wrong", err: &testingexec.FakeExitError{Status: 2}}},
+			call:   func(l Luks) (bool, error) { return false, l.Open("/dev/sdb", "luks-disk", []byte("wrong")) },
+			expErr: true,
+		},
+		{
+			name: "close",
+			runs: []*fakeRun{{args: "luksClose luks-disk"}},
+			call: func(l Luks) (bool, error) { return false, l.Close("luks-disk") },
+		},
+		{
+			name: "resize",
+			runs: []*fakeRun{{args: "resize luks-disk"}},
+			call: func(l Luks) (bool, error) { return false, l.Resize("luks-disk") },
+		},
+		{
+			name:    "is luks",
+			runs:    []*fakeRun{{args: "isLuks /dev/sdb"}},
+			call:    func(l Luks) (bool, error) { return l.IsLuks("/dev/sdb") },
+			expBool: true,
+		},
+		{
+			name: "is not luks",
+			runs: []*fakeRun{{args: "isLuks /dev/sdb", err: exitErr}},
+			call: func(l Luks) (bool, error) { return l.IsLuks("/dev/sdb") },
+		},
+		{
+			name:    "is open",
+			runs:    []*fakeRun{{args: "status luks-disk"}},
+			call:    func(l Luks) (bool, error) { return l.IsOpen("luks-disk") },
+			expBool: true,
+		},
+		{
+			name: "is not open",
+			runs: []*fakeRun{{args: "status luks-disk", err: &testingexec.FakeExitError{Status: 4}}},
+			call: func(l Luks) (bool, error) { return l.IsOpen("luks-disk") },
+		},
+		{
+			name:   "status fails",
+			runs:   []*fakeRun{{args: "status luks-disk", err: &testingexec.FakeExitError{Status: 2}}},
+			call:   func(l Luks) (bool, error) { return l.IsOpen("luks-disk") },
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeExec := newFakeExec(t, tc.runs)
+			got, err := tc.call(NewCryptsetupLuks(fakeExec))
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("expected error: %v, got: %v", tc.expErr, err)
+			}
+			if got != tc.expBool {
+				t.Errorf("expected %v, got %v", tc.expBool, got)
+			}
+			if fakeExec.CommandCalls != len(tc.runs) {
+				t.Fatalf("expected %d commands to be run, got %d", len(tc.runs), fakeExec.CommandCalls)
+			}
+			for _, run := range tc.runs {
+				var stdin []byte
+				if run.cmd.Stdin != nil {
+					stdin, _ = io.ReadAll(run.cmd.Stdin)
+				}
+				if string(stdin) != run.expStdin {
+					t.Errorf("expected %q on stdin of %q, got %q", run.expStdin, run.args, string(stdin))
+				}
+			}
+		})
+	}
+}
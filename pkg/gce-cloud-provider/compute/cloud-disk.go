@@ -15,6 +15,7 @@ limitations under the License.
 package gcecloudprovider
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
@@ -122,6 +123,28 @@ func (d *CloudDisk) GetPDType() string {
 	return strings.TrimSpace(respType[len(respType)-1])
 }
 
+func (d *CloudDisk) GetID() uint64 {
+	switch {
+	case d.disk != nil:
+		return d.disk.Id
+	case d.betaDisk != nil:
+		return d.betaDisk.Id
+	default:
+		return 0
+	}
+}
+
+func (d *CloudDisk) GetDescription() string {
+	switch {
+	case d.disk != nil:
+		return d.disk.Description
+	case d.betaDisk != nil:
+		return d.betaDisk.Description
+	default:
+		return ""
+	}
+}
+
 func (d *CloudDisk) GetSelfLink() string {
 	switch {
 	case d.disk != nil:
@@ -268,6 +291,17 @@ func (d *CloudDisk) GetLabels() map[string]string {
 	}
 }
 
+func (d *CloudDisk) GetLabelFingerprint() string {
+	switch {
+	case d.disk != nil:
+		return d.disk.LabelFingerprint
+	case d.betaDisk != nil:
+		return d.betaDisk.LabelFingerprint
+	default:
+		return ""
+	}
+}
+
 func (d *CloudDisk) GetAccessMode() string {
 	switch {
 	case d.disk != nil:
@@ -300,3 +334,62 @@ func (d *CloudDisk) GetProvisionedThroughput() int64 {
 		return 0
 	}
 }
+
+func (d *CloudDisk) GetReplicaZones() []string {
+	switch {
+	case d.disk != nil:
+		return d.disk.ReplicaZones
+	case d.betaDisk != nil:
+		return d.betaDisk.ReplicaZones
+	default:
+		return nil
+	}
+}
+
+// GetAsyncPrimaryDisk returns the primary disk of a secondary disk of
+// asynchronous replication.
+func (d *CloudDisk) GetAsyncPrimaryDisk() string {
+	switch {
+	case d.disk != nil && d.disk.AsyncPrimaryDisk != nil:
+		return d.disk.AsyncPrimaryDisk.Disk
+	case d.betaDisk != nil && d.betaDisk.AsyncPrimaryDisk != nil:
+		return d.betaDisk.AsyncPrimaryDisk.Disk
+	default:
+		return ""
+	}
+}
+
+// GetAsyncSecondaryDisks returns the secondary disks of a primary disk of
+// asynchronous replication.
+func (d *CloudDisk) GetAsyncSecondaryDisks() []string {
+	secondaryDisks := []string{}
+	switch {
+	case d.disk != nil:
+		for _, s := range d.disk.AsyncSecondaryDisks {
+			if s.AsyncReplicationDisk != nil {
+				secondaryDisks = append(secondaryDisks, s.AsyncReplicationDisk.Disk)
+			}
+		}
+	case d.betaDisk != nil:
+		for _, s := range d.betaDisk.AsyncSecondaryDisks {
+			if s.AsyncReplicationDisk != nil {
+				secondaryDisks = append(secondaryDisks, s.AsyncReplicationDisk.Disk)
+			}
+		}
+	}
+	sort.Strings(secondaryDisks)
+	return secondaryDisks
+}
+
+// GetAsyncReplicationState returns the state of the replication to a
+// secondary disk, e.g. CREATED before the replication is started.
+func (d *CloudDisk) GetAsyncReplicationState() string {
+	switch {
+	case d.disk != nil && d.disk.ResourceStatus != nil && d.disk.ResourceStatus.AsyncPrimaryDisk != nil:
+		return d.disk.ResourceStatus.AsyncPrimaryDisk.State
+	case d.betaDisk != nil && d.betaDisk.ResourceStatus != nil && d.betaDisk.ResourceStatus.AsyncPrimaryDisk != nil:
+		return d.betaDisk.ResourceStatus.AsyncPrimaryDisk.State
+	default:
+		return ""
+	}
+}
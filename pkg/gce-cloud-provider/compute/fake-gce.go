@@ -16,9 +16,12 @@ package gcecloudprovider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
@@ -103,6 +106,26 @@ func (cloud *FakeCloudProvider) GetDefaultZone() string {
 	return cloud.zone
 }
 
+// WithCredentials returns a copy of the fake sharing its resources, with the
+// project of the credentials as default project.
+func (cloud *FakeCloudProvider) WithCredentials(ctx context.Context, credentialsJSON []byte) (GCECompute, error) {
+	var creds struct {
+		Type      string `json:"type"`
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal(credentialsJSON, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if creds.Type != "service_account" {
+		return nil, fmt.Errorf("unknown credentials type %q", creds.Type)
+	}
+	cp := *cloud
+	if creds.ProjectID != "" {
+		cp.project = creds.ProjectID
+	}
+	return &cp, nil
+}
+
 func (cloud *FakeCloudProvider) RepairUnderspecifiedVolumeKey(ctx context.Context, project string, volumeKey *meta.Key) (string, *meta.Key, error) {
 	if project == constants.UnspecifiedValue {
 		project = cloud.project
@@ -168,26 +191,18 @@ func (cloud *FakeCloudProvider) ListInstances(ctx context.Context, fields []goog
 	return instances, "", nil
 }
 
-func (cloud *FakeCloudProvider) ListSnapshots(ctx context.Context, filter string) ([]*computev1.Snapshot, string, error) {
-	var sourceDisk string
-	snapshots := []*computev1.Snapshot{}
-	if len(filter) > 0 {
-		filterSplits := strings.Fields(filter)
-		if len(filterSplits) != 3 || filterSplits[0] != "sourceDisk" {
-			return nil, "", invalidError()
+// ListSnapshots pages through the snapshots of project by name. It only
+// supports "sourceDisk eq <regex>" and "labels.<key> eq <regex>" filters.
+func (cloud *FakeCloudProvider) ListSnapshots(ctx context.Context, project, filter string, maxResults int64, pageToken string) ([]*computev1.Snapshot, string, error) {
+	snapshots := make(map[string]*computev1.Snapshot)
+	for name, s := range cloud.snapshots {
+		if s.SelfLink == cloud.getGlobalSnapshotURI(project, name) {
+			snapshots[name] = s
 		}
-		sourceDisk = filterSplits[2]
 	}
-	for _, snapshot := range cloud.snapshots {
-		if len(sourceDisk) > 0 {
-			if snapshot.SourceDisk == sourceDisk {
-				continue
-			}
-		}
-		snapshots = append(snapshots, snapshot)
-	}
-
-	return snapshots, "", nil
+	return fakeListPage(snapshots, func(s *computev1.Snapshot, field string) (string, bool) {
+		return fakeFilterField(field, s.SourceDisk, s.Labels)
+	}, filter, maxResults, pageToken)
 }
 
 // Disk Methods
@@ -210,10 +225,17 @@ func (cloud *FakeCloudProvider) InsertDisk(ctx context.Context, project string,
 		}
 	}
 
+	description, err := encodeTags(params.Tags)
+	if err != nil {
+		return err
+	}
+	if description == "" {
+		description = "Disk created by GCE-PD CSI Driver"
+	}
 	computeDisk := &computebeta.Disk{
 		Name:                      volKey.Name,
 		SizeGb:                    common.BytesToGbRoundUp(capBytes),
-		Description:               "Disk created by GCE-PD CSI Driver",
+		Description:               description,
 		Type:                      cloud.GetDiskTypeURI(project, volKey, params.DiskType),
 		SourceDiskId:              volumeContentSourceVolumeID,
 		Status:                    cloud.mockDiskStatus,
@@ -245,6 +267,16 @@ func (cloud *FakeCloudProvider) InsertDisk(ctx context.Context, project string,
 			KmsKeyName: params.DiskEncryptionKMSKey,
 		}
 	}
+	if len(params.ResourceTags) > 0 {
+		// Kept by the fake to be returned by GetDiskResourceTags.
+		computeDisk.Params = &computebeta.DiskParams{ResourceManagerTags: params.ResourceTags}
+	}
+	if params.AsyncPrimaryDisk != "" {
+		computeDisk.AsyncPrimaryDisk = &computebeta.DiskAsyncReplication{Disk: params.AsyncPrimaryDisk}
+		computeDisk.ResourceStatus = &computebeta.DiskResourceStatus{
+			AsyncPrimaryDisk: &computebeta.DiskResourceStatusAsyncReplicationStatus{State: "CREATED"},
+		}
+	}
 	switch volKey.Type() {
 	case meta.Zonal:
 		computeDisk.Zone = volKey.Zone
@@ -289,6 +321,28 @@ func (cloud *FakeCloudProvider) UpdateDisk(ctx context.Context, project string,
 	return nil
 }
 
+// GetDiskResourceTags returns the resource tags the disk was created with or
+// that were bound to it by SetDiskResourceTags.
+func (cloud *FakeCloudProvider) GetDiskResourceTags(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk) ([]string, error) {
+	disk, ok := cloud.disks[volKey.String()]
+	if !ok {
+		return nil, notFoundError()
+	}
+	var tags []string
+	if disk.disk != nil && disk.disk.Params != nil {
+		for key, value := range disk.disk.Params.ResourceManagerTags {
+			tags = append(tags, key+"/"+value)
+		}
+	}
+	if disk.betaDisk != nil && disk.betaDisk.Params != nil {
+		for key, value := range disk.betaDisk.Params.ResourceManagerTags {
+			tags = append(tags, key+"/"+value)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
 func (cloud *FakeCloudProvider) DeleteDisk(ctx context.Context, project string, volKey *meta.Key) error {
 	delete(cloud.disks, volKey.String())
 	return nil
@@ -346,6 +400,114 @@ func (cloud *FakeCloudProvider) SetDiskAccessMode(ctx context.Context, project s
 	return nil
 }
 
+func (cloud *FakeCloudProvider) SetDiskLabels(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk, labels map[string]string) error {
+	disk, ok := cloud.disks[volKey.String()]
+	if !ok {
+		return notFoundError()
+	}
+
+	if disk.disk != nil {
+		disk.disk.Labels = labels
+	}
+	if disk.betaDisk != nil {
+		disk.betaDisk.Labels = labels
+	}
+
+	return nil
+}
+
+func (cloud *FakeCloudProvider) SetDiskResourceTags(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk, resourceTags map[string]string) error {
+	disk, ok := cloud.disks[volKey.String()]
+	if !ok {
+		return notFoundError()
+	}
+	if len(resourceTags) == 0 {
+		return nil
+	}
+
+	var tags map[string]string
+	switch {
+	case disk.disk != nil:
+		if disk.disk.Params == nil {
+			disk.disk.Params = &computev1.DiskParams{}
+		}
+		if disk.disk.Params.ResourceManagerTags == nil {
+			disk.disk.Params.ResourceManagerTags = map[string]string{}
+		}
+		tags = disk.disk.Params.ResourceManagerTags
+	case disk.betaDisk != nil:
+		if disk.betaDisk.Params == nil {
+			disk.betaDisk.Params = &computebeta.DiskParams{}
+		}
+		if disk.betaDisk.Params.ResourceManagerTags == nil {
+			disk.betaDisk.Params.ResourceManagerTags = map[string]string{}
+		}
+		tags = disk.betaDisk.Params.ResourceManagerTags
+	}
+	for tagParentIDKey, tagValue := range resourceTags {
+		tags[tagParentIDKey] = tagValue
+	}
+
+	return nil
+}
+
+func (cloud *FakeCloudProvider) StartAsyncReplication(ctx context.Context, project string, volKey, secondaryVolKey *meta.Key) error {
+	primary, ok := cloud.disks[volKey.String()]
+	if !ok {
+		return notFoundError()
+	}
+	secondary, ok := cloud.disks[secondaryVolKey.String()]
+	if !ok {
+		return notFoundError()
+	}
+	primaryVolumeID, err := common.KeyToVolumeID(volKey, project)
+	if err != nil {
+		return err
+	}
+	if secondary.GetAsyncPrimaryDisk() != primaryVolumeID || secondary.betaDisk == nil || primary.betaDisk == nil {
+		return invalidError()
+	}
+	secondaryDisk := cloud.GetDiskSourceURI(project, secondaryVolKey)
+	if primary.betaDisk.AsyncSecondaryDisks == nil {
+		primary.betaDisk.AsyncSecondaryDisks = map[string]computebeta.DiskAsyncReplicationList{}
+	}
+	primary.betaDisk.AsyncSecondaryDisks[secondaryDisk] = computebeta.DiskAsyncReplicationList{
+		AsyncReplicationDisk: &computebeta.DiskAsyncReplication{Disk: secondaryDisk},
+	}
+	secondary.betaDisk.ResourceStatus.AsyncPrimaryDisk.State = "ACTIVE"
+	return nil
+}
+
+func (cloud *FakeCloudProvider) StopAsyncReplication(ctx context.Context, project string, volKey *meta.Key) error {
+	disk, ok := cloud.disks[volKey.String()]
+	if !ok {
+		return notFoundError()
+	}
+	if disk.betaDisk == nil {
+		return invalidError()
+	}
+	stop := func(secondary *CloudDisk) {
+		if secondary.betaDisk != nil && secondary.betaDisk.ResourceStatus != nil {
+			secondary.betaDisk.ResourceStatus.AsyncPrimaryDisk.State = "STOPPED"
+		}
+	}
+	if disk.GetAsyncPrimaryDisk() != "" {
+		// Stopped on the secondary disk, which the primary disk still
+		// lists.
+		stop(disk)
+		return nil
+	}
+	for _, secondaryDisk := range disk.GetAsyncSecondaryDisks() {
+		for _, d := range cloud.disks {
+			if d.GetSelfLink() == secondaryDisk {
+				stop(d)
+			}
+		}
+	}
+	disk.betaDisk.AsyncSecondaryDisks = nil
+	return nil
+}
+
 func (cloud *FakeCloudProvider) GetDiskTypeURI(project string, volKey *meta.Key, diskType string) string {
 	switch volKey.Type() {
 	case meta.Zonal:
@@ -397,7 +559,10 @@ func (cloud *FakeCloudProvider) GetSnapshot(ctx context.Context, project, snapsh
 	if !ok {
 		return nil, notFoundError()
 	}
-	snapshot.Status = "READY"
+	// The upload finishes once the snapshot is read back.
+	if snapshot.Status == "UPLOADING" {
+		snapshot.Status = "READY"
+	}
 	return snapshot, nil
 }
 
@@ -406,6 +571,10 @@ func (cloud *FakeCloudProvider) CreateSnapshot(ctx context.Context, project stri
 		return snapshot, nil
 	}
 
+	description, err := encodeTags(snapshotParams.Tags)
+	if err != nil {
+		return nil, err
+	}
 	snapshotToCreate := &computev1.Snapshot{
 		Name:              snapshotName,
 		DiskSizeGb:        int64(DiskSizeGb),
@@ -413,6 +582,7 @@ func (cloud *FakeCloudProvider) CreateSnapshot(ctx context.Context, project stri
 		Status:            "UPLOADING",
 		SelfLink:          cloud.getGlobalSnapshotURI(project, snapshotName),
 		StorageLocations:  snapshotParams.StorageLocations,
+		Description:       description,
 		Labels:            snapshotParams.Labels,
 	}
 	switch volKey.Type() {
@@ -448,26 +618,84 @@ func (cloud *FakeCloudProvider) DeleteSnapshot(ctx context.Context, project, sna
 	return nil
 }
 
-func (cloud *FakeCloudProvider) ListImages(ctx context.Context, filter string) ([]*computev1.Image, string, error) {
-	var sourceDisk string
-	images := []*computev1.Image{}
+// ListImages pages through the images of project by name. It only supports
+// "sourceDisk eq <regex>" and "labels.<key> eq <regex>" filters.
+func (cloud *FakeCloudProvider) ListImages(ctx context.Context, project, filter string, maxResults int64, pageToken string) ([]*computev1.Image, string, error) {
+	images := make(map[string]*computev1.Image)
+	for name, i := range cloud.images {
+		if i.SelfLink == cloud.getGlobalImageURI(project, name) {
+			images[name] = i
+		}
+	}
+	return fakeListPage(images, func(i *computev1.Image, field string) (string, bool) {
+		return fakeFilterField(field, i.SourceDisk, i.Labels)
+	}, filter, maxResults, pageToken)
+}
+
+// fakeListPage returns a page of the items matching filter, sorted by name.
+// Page tokens are the index of the first item of the page. field returns the
+// value of a filtered field of an item, and false if it is not set.
+func fakeListPage[T any](items map[string]T, field func(T, string) (string, bool), filter string, maxResults int64, pageToken string) ([]T, string, error) {
+	var filterField string
+	var filterRegex *regexp.Regexp
 	if len(filter) > 0 {
 		filterSplits := strings.Fields(filter)
-		if len(filterSplits) != 3 || filterSplits[0] != "sourceDisk" {
+		if len(filterSplits) != 3 || filterSplits[1] != "eq" {
+			return nil, "", invalidError()
+		}
+		if filterSplits[0] != "sourceDisk" && !strings.HasPrefix(filterSplits[0], "labels.") {
+			return nil, "", invalidError()
+		}
+		re, err := regexp.Compile("^(?:" + filterSplits[2] + ")$")
+		if err != nil {
 			return nil, "", invalidError()
 		}
-		sourceDisk = filterSplits[2]
+		filterField = filterSplits[0]
+		filterRegex = re
 	}
-	for _, image := range cloud.images {
-		if len(sourceDisk) > 0 {
-			if image.SourceDisk == sourceDisk {
-				continue
-			}
+	start := 0
+	if pageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(pageToken); err != nil {
+			return nil, "", invalidError()
 		}
-		images = append(images, image)
+	}
+	if maxResults <= 0 || maxResults > 500 {
+		maxResults = 500
 	}
 
-	return images, "", nil
+	names := make([]string, 0, len(items))
+	for name, item := range items {
+		if filterRegex == nil {
+			names = append(names, name)
+		} else if v, ok := field(item, filterField); ok && filterRegex.MatchString(v) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if start > len(names) {
+		return nil, "", invalidError()
+	}
+	end := min(start+int(maxResults), len(names))
+	page := make([]T, 0, end-start)
+	for _, name := range names[start:end] {
+		page = append(page, items[name])
+	}
+	nextPageToken := ""
+	if end < len(names) {
+		nextPageToken = strconv.Itoa(end)
+	}
+	return page, nextPageToken, nil
+}
+
+// fakeFilterField returns the value of the field of a snapshot or image with
+// sourceDisk and labels, which is sourceDisk or labels.<key>.
+func fakeFilterField(field, sourceDisk string, labels map[string]string) (string, bool) {
+	if field == "sourceDisk" {
+		return sourceDisk, true
+	}
+	v, ok := labels[strings.TrimPrefix(field, "labels.")]
+	return v, ok
 }
 
 func (cloud *FakeCloudProvider) GetImage(ctx context.Context, project, imageName string) (*computev1.Image, error) {
@@ -20,8 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	rscmgr "cloud.google.com/go/resourcemanager/apiv3"
@@ -68,23 +70,55 @@ const (
 
 var GCEAPIVersions = []GCEAPIVersion{GCEAPIVersionBeta, GCEAPIVersionV1}
 
-// AttachDiskBackoff is backoff used to wait for AttachDisk to complete.
-// Default values are similar to Poll every 5 seconds with 2 minute timeout.
-var AttachDiskBackoff = wait.Backoff{
-	Duration: 5 * time.Second,
-	Factor:   0.0,
-	Jitter:   0.0,
-	Steps:    24,
-	Cap:      0}
-
-// WaitForOpBackoff is backoff used to wait for Global, Regional or Zonal operation to complete.
-// Default values are similar to Poll every 2 minutes with 6 minute timeout.
-var WaitForOpBackoff = wait.Backoff{
-	Duration: 2 * time.Minute,
-	Factor:   0.0,
-	Jitter:   0.0,
-	Steps:    3,
-	Cap:      0}
+var (
+	backoffMutex sync.RWMutex
+
+	// attachDiskBackoff is backoff used to wait for AttachDisk to complete.
+	// Default values are similar to Poll every 5 seconds with 2 minute timeout.
+	attachDiskBackoff = wait.Backoff{
+		Duration: 5 * time.Second,
+		Factor:   0.0,
+		Jitter:   0.0,
+		Steps:    24,
+		Cap:      0}
+
+	// waitForOpBackoff is backoff used to wait for Global, Regional or Zonal operation to complete.
+	// Default values are similar to Poll every 2 minutes with 6 minute timeout.
+	waitForOpBackoff = wait.Backoff{
+		Duration: 2 * time.Minute,
+		Factor:   0.0,
+		Jitter:   0.0,
+		Steps:    3,
+		Cap:      0}
+)
+
+// SetAttachDiskBackoff sets the backoff used to wait for AttachDisk to
+// complete. Waits that already started keep their backoff.
+func SetAttachDiskBackoff(b wait.Backoff) {
+	backoffMutex.Lock()
+	defer backoffMutex.Unlock()
+	attachDiskBackoff = b
+}
+
+// SetWaitForOpBackoff sets the backoff used to wait for operations to
+// complete. Waits that already started keep their backoff.
+func SetWaitForOpBackoff(b wait.Backoff) {
+	backoffMutex.Lock()
+	defer backoffMutex.Unlock()
+	waitForOpBackoff = b
+}
+
+func getAttachDiskBackoff() wait.Backoff {
+	backoffMutex.RLock()
+	defer backoffMutex.RUnlock()
+	return attachDiskBackoff
+}
+
+func getWaitForOpBackoff() wait.Backoff {
+	backoffMutex.RLock()
+	defer backoffMutex.RUnlock()
+	return waitForOpBackoff
+}
 
 // Custom error type to propagate error messages up to clients.
 type UnsupportedDiskError struct {
@@ -99,6 +133,9 @@ type GCECompute interface {
 	// Metadata information
 	GetDefaultProject() string
 	GetDefaultZone() string
+	// WithCredentials returns a GCECompute making its calls with the given
+	// service account key or workload identity federation configuration.
+	WithCredentials(ctx context.Context, credentialsJSON []byte) (GCECompute, error)
 	// Disk Methods
 	GetDisk(ctx context.Context, project string, volumeKey *meta.Key) (*CloudDisk, error)
 	RepairUnderspecifiedVolumeKey(ctx context.Context, project string, volumeKey *meta.Key) (string, *meta.Key, error)
@@ -108,11 +145,21 @@ type GCECompute interface {
 	AttachDisk(ctx context.Context, project string, volKey *meta.Key, readWrite, diskType, instanceZone, instanceName string, forceAttach bool) error
 	DetachDisk(ctx context.Context, project, deviceName, instanceZone, instanceName string) error
 	SetDiskAccessMode(ctx context.Context, project string, volKey *meta.Key, accessMode string) error
+	// SetDiskLabels replaces the labels of the disk with labels.
+	SetDiskLabels(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk, labels map[string]string) error
+	// SetDiskResourceTags binds the resource tags, keyed by <parent>/<key>,
+	// to the disk, replacing the values bound to it for the same keys.
+	SetDiskResourceTags(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk, resourceTags map[string]string) error
+	StartAsyncReplication(ctx context.Context, project string, volKey, secondaryVolKey *meta.Key) error
+	StopAsyncReplication(ctx context.Context, project string, volKey *meta.Key) error
 	ListCompatibleDiskTypeZones(ctx context.Context, project string, zones []string, diskType string) ([]string, error)
 	GetDiskSourceURI(project string, volKey *meta.Key) string
 	GetDiskTypeURI(project string, volKey *meta.Key, diskType string) string
 	WaitForAttach(ctx context.Context, project string, volKey *meta.Key, diskType, instanceZone, instanceName string) error
 	ResizeDisk(ctx context.Context, project string, volKey *meta.Key, requestBytes int64) (int64, error)
+	// GetDiskResourceTags returns the namespaced values, <parent>/<key>/<value>,
+	// of the resource tags in effect on the disk.
+	GetDiskResourceTags(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk) ([]string, error)
 	ListDisks(ctx context.Context, fields []googleapi.Field) ([]*computev1.Disk, string, error)
 	ListDisksWithFilter(ctx context.Context, fields []googleapi.Field, filter string) ([]*computev1.Disk, string, error)
 	ListInstances(ctx context.Context, fields []googleapi.Field) ([]*computev1.Instance, string, error)
@@ -122,11 +169,11 @@ type GCECompute interface {
 	GetInstanceOrError(ctx context.Context, project, instanceZone, instanceName string) (*computev1.Instance, error)
 	// Zone Methods
 	ListZones(ctx context.Context, region string) ([]string, error)
-	ListSnapshots(ctx context.Context, filter string) ([]*computev1.Snapshot, string, error)
+	ListSnapshots(ctx context.Context, project, filter string, maxResults int64, pageToken string) ([]*computev1.Snapshot, string, error)
 	GetSnapshot(ctx context.Context, project, snapshotName string) (*computev1.Snapshot, error)
 	CreateSnapshot(ctx context.Context, project string, volKey *meta.Key, snapshotName string, snapshotParams parameters.SnapshotParameters) (*computev1.Snapshot, error)
 	DeleteSnapshot(ctx context.Context, project, snapshotName string) error
-	ListImages(ctx context.Context, filter string) ([]*computev1.Image, string, error)
+	ListImages(ctx context.Context, project, filter string, maxResults int64, pageToken string) ([]*computev1.Image, string, error)
 	GetImage(ctx context.Context, project, imageName string) (*computev1.Image, error)
 	CreateImage(ctx context.Context, project string, volKey *meta.Key, imageName string, snapshotParams parameters.SnapshotParameters) (*computev1.Image, error)
 	DeleteImage(ctx context.Context, project, imageName string) error
@@ -142,7 +189,7 @@ func (cloud *CloudProvider) GetDefaultZone() string {
 	return cloud.zone
 }
 
-// ListDisks lists disks based on maxEntries and pageToken only in the project
+// ListDisks lists disks based on maxEntries and pageToken only in the projects
 // and region that the driver is running in.
 func (cloud *CloudProvider) ListDisks(ctx context.Context, fields []googleapi.Field) ([]*computev1.Disk, string, error) {
 	filter := ""
@@ -170,6 +217,17 @@ func (cloud *CloudProvider) listDisksInternal(ctx context.Context, fields []goog
 		return nil, "", err
 	}
 	disks = append(disks, rDisks...)
+	for _, p := range cloud.AdditionalProjects {
+		if p == cloud.project {
+			continue
+		}
+		klog.Infof("Getting regional disks for additional project: %s", p)
+		rDisks, err := listRegionalDisksForProject(cloud.service, p, region, fields, filter)
+		if err != nil {
+			return nil, "", err
+		}
+		disks = append(disks, rDisks...)
+	}
 	// listing out regional disks in the region for each tenant project
 	for p, s := range cloud.tenantServiceMap {
 		klog.Infof("Getting regional disks for tenant project: %s", p)
@@ -186,6 +244,17 @@ func (cloud *CloudProvider) listDisksInternal(ctx context.Context, fields []goog
 		return nil, "", err
 	}
 	disks = append(disks, zDisks...)
+	for _, p := range cloud.AdditionalProjects {
+		if p == cloud.project {
+			continue
+		}
+		klog.Infof("Getting zonal disks for additional project: %s", p)
+		zDisks, err := listZonalDisksForProject(cloud.service, p, zones, fields, filter)
+		if err != nil {
+			return nil, "", err
+		}
+		disks = append(disks, zDisks...)
+	}
 	// listing out zonal disks in all zones of the region for each tenant project
 	for p, s := range cloud.tenantServiceMap {
 		klog.Infof("Getting zonal disks for tenant project: %s", p)
@@ -363,20 +432,19 @@ func (cloud *CloudProvider) ListZones(ctx context.Context, region string) ([]str
 
 }
 
-func (cloud *CloudProvider) ListSnapshots(ctx context.Context, filter string) ([]*computev1.Snapshot, string, error) {
-	klog.V(5).Infof("Listing snapshots with filter: %s", filter)
-	items := []*computev1.Snapshot{}
-	lCall := cloud.service.Snapshots.List(cloud.project).Filter(filter)
-	nextPageToken := "pageToken"
-	for nextPageToken != "" {
-		snapshotList, err := lCall.Do()
-		if err != nil {
-			return nil, "", err
-		}
-		items = append(items, snapshotList.Items...)
-		nextPageToken = snapshotList.NextPageToken
+// ListSnapshots returns a page of up to maxResults snapshots of project
+// matching filter, and the token of the next page.
+func (cloud *CloudProvider) ListSnapshots(ctx context.Context, project, filter string, maxResults int64, pageToken string) ([]*computev1.Snapshot, string, error) {
+	klog.V(5).Infof("Listing snapshots of project %s with filter %q and page token %q", project, filter, pageToken)
+	lCall := cloud.service.Snapshots.List(project).Context(ctx).Filter(filter).MaxResults(maxResults)
+	if pageToken != "" {
+		lCall = lCall.PageToken(pageToken)
 	}
-	return items, "", nil
+	snapshotList, err := lCall.Do()
+	if err != nil {
+		return nil, "", err
+	}
+	return snapshotList.Items, snapshotList.NextPageToken, nil
 }
 
 func (cloud *CloudProvider) GetDisk(ctx context.Context, project string, key *meta.Key) (*CloudDisk, error) {
@@ -595,6 +663,12 @@ func (cloud *CloudProvider) constructDiskToCreate(
 		diskToCreate.SourceDisk = volumeContentSourceVolumeID
 	}
 
+	if params.AsyncPrimaryDisk != "" {
+		diskToCreate.AsyncPrimaryDisk = &computebeta.DiskAsyncReplication{
+			Disk: params.AsyncPrimaryDisk,
+		}
+	}
+
 	if params.DiskEncryptionKMSKey != "" {
 		diskToCreate.DiskEncryptionKey = &computebeta.CustomerEncryptionKey{
 			KmsKeyName: params.DiskEncryptionKMSKey,
@@ -987,6 +1061,108 @@ func (cloud *CloudProvider) SetDiskAccessMode(ctx context.Context, project strin
 	return nil
 }
 
+func (cloud *CloudProvider) SetDiskLabels(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk, labels map[string]string) error {
+	switch volKey.Type() {
+	case meta.Zonal:
+		req := &computev1.ZoneSetLabelsRequest{
+			Labels:           labels,
+			LabelFingerprint: disk.GetLabelFingerprint(),
+		}
+		op, err := cloud.service.Disks.SetLabels(project, volKey.Zone, volKey.Name, req).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to set labels for zonal volume %v: %w", volKey, err)
+		}
+		klog.V(5).Infof("SetDiskLabels operation %s for disk %s", op.Name, volKey.Name)
+
+		err = cloud.waitForZonalOp(ctx, project, op.Name, volKey.Zone)
+		if err != nil {
+			return fmt.Errorf("failed waiting for op for zonal disk set labels for %v: %w", volKey, err)
+		}
+	case meta.Regional:
+		req := &computev1.RegionSetLabelsRequest{
+			Labels:           labels,
+			LabelFingerprint: disk.GetLabelFingerprint(),
+		}
+		op, err := cloud.service.RegionDisks.SetLabels(project, volKey.Region, volKey.Name, req).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to set labels for regional volume %v: %w", volKey, err)
+		}
+		klog.V(5).Infof("SetDiskLabels operation %s for disk %s", op.Name, volKey.Name)
+
+		err = cloud.waitForRegionalOp(ctx, project, op.Name, volKey.Region)
+		if err != nil {
+			return fmt.Errorf("failed waiting for op for regional disk set labels for %v: %w", volKey, err)
+		}
+	default:
+		return fmt.Errorf("volume key %v not zonal nor regional", volKey.Name)
+	}
+
+	return nil
+}
+
+// StartAsyncReplication starts the asynchronous replication of the disk
+// volKey to the secondary disk secondaryVolKey, which was created with the
+// disk as its primary disk.
+func (cloud *CloudProvider) StartAsyncReplication(ctx context.Context, project string, volKey, secondaryVolKey *meta.Key) error {
+	klog.V(5).Infof("Starting asynchronous replication of disk %v to %v", volKey, secondaryVolKey)
+	secondaryDisk := cloud.GetDiskSourceURI(project, secondaryVolKey)
+	switch volKey.Type() {
+	case meta.Zonal:
+		req := &computev1.DisksStartAsyncReplicationRequest{AsyncSecondaryDisk: secondaryDisk}
+		op, err := cloud.service.Disks.StartAsyncReplication(project, volKey.Zone, volKey.Name, req).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to start asynchronous replication of zonal volume %v: %w", volKey, err)
+		}
+		klog.V(5).Infof("StartAsyncReplication operation %s for disk %s", op.Name, volKey.Name)
+		if err := cloud.waitForZonalOp(ctx, project, op.Name, volKey.Zone); err != nil {
+			return fmt.Errorf("failed waiting for op to start asynchronous replication of zonal volume %v: %w", volKey, err)
+		}
+	case meta.Regional:
+		req := &computev1.RegionDisksStartAsyncReplicationRequest{AsyncSecondaryDisk: secondaryDisk}
+		op, err := cloud.service.RegionDisks.StartAsyncReplication(project, volKey.Region, volKey.Name, req).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to start asynchronous replication of regional volume %v: %w", volKey, err)
+		}
+		klog.V(5).Infof("StartAsyncReplication operation %s for disk %s", op.Name, volKey.Name)
+		if err := cloud.waitForRegionalOp(ctx, project, op.Name, volKey.Region); err != nil {
+			return fmt.Errorf("failed waiting for op to start asynchronous replication of regional volume %v: %w", volKey, err)
+		}
+	default:
+		return fmt.Errorf("volume key %v not zonal nor regional", volKey.Name)
+	}
+	return nil
+}
+
+// StopAsyncReplication stops the asynchronous replication of the disk volKey,
+// which may be either the primary or the secondary disk. Stopping it on the
+// secondary disk works when the region of the primary disk is unavailable.
+func (cloud *CloudProvider) StopAsyncReplication(ctx context.Context, project string, volKey *meta.Key) error {
+	klog.V(5).Infof("Stopping asynchronous replication of disk %v", volKey)
+	switch volKey.Type() {
+	case meta.Zonal:
+		op, err := cloud.service.Disks.StopAsyncReplication(project, volKey.Zone, volKey.Name).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to stop asynchronous replication of zonal volume %v: %w", volKey, err)
+		}
+		klog.V(5).Infof("StopAsyncReplication operation %s for disk %s", op.Name, volKey.Name)
+		if err := cloud.waitForZonalOp(ctx, project, op.Name, volKey.Zone); err != nil {
+			return fmt.Errorf("failed waiting for op to stop asynchronous replication of zonal volume %v: %w", volKey, err)
+		}
+	case meta.Regional:
+		op, err := cloud.service.RegionDisks.StopAsyncReplication(project, volKey.Region, volKey.Name).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to stop asynchronous replication of regional volume %v: %w", volKey, err)
+		}
+		klog.V(5).Infof("StopAsyncReplication operation %s for disk %s", op.Name, volKey.Name)
+		if err := cloud.waitForRegionalOp(ctx, project, op.Name, volKey.Region); err != nil {
+			return fmt.Errorf("failed waiting for op to stop asynchronous replication of regional volume %v: %w", volKey, err)
+		}
+	default:
+		return fmt.Errorf("volume key %v not zonal nor regional", volKey.Name)
+	}
+	return nil
+}
+
 func (cloud *CloudProvider) ListCompatibleDiskTypeZones(ctx context.Context, project string, zones []string, diskType string) ([]string, error) {
 	diskTypeFilter := fmt.Sprintf("name=%s", diskType)
 	filters := []string{diskTypeFilter}
@@ -1066,7 +1242,7 @@ func (cloud *CloudProvider) getRegionalDiskTypeURI(project string, region, diskT
 }
 
 func (cloud *CloudProvider) waitForZonalOp(ctx context.Context, project, opName string, zone string) error {
-	return wait.ExponentialBackoff(WaitForOpBackoff, func() (bool, error) {
+	return wait.ExponentialBackoff(getWaitForOpBackoff(), func() (bool, error) {
 		waitOp, err := cloud.service.ZoneOperations.Wait(project, zone, opName).Context(ctx).Do()
 		// In case of service unavailable do not propogate the error so ExponentialBackoff will retry
 		if err != nil && waitOp.HttpErrorStatusCode == 503 {
@@ -1083,7 +1259,7 @@ func (cloud *CloudProvider) waitForZonalOp(ctx context.Context, project, opName
 }
 
 func (cloud *CloudProvider) waitForRegionalOp(ctx context.Context, project, opName string, region string) error {
-	return wait.ExponentialBackoff(WaitForOpBackoff, func() (bool, error) {
+	return wait.ExponentialBackoff(getWaitForOpBackoff(), func() (bool, error) {
 		waitOp, err := cloud.service.RegionOperations.Wait(project, region, opName).Context(ctx).Do()
 		// In case of service unavailable do not propogate the error so ExponentialBackoff will retry
 		if err != nil && waitOp.HttpErrorStatusCode == 503 {
@@ -1100,7 +1276,7 @@ func (cloud *CloudProvider) waitForRegionalOp(ctx context.Context, project, opNa
 }
 
 func (cloud *CloudProvider) waitForGlobalOp(ctx context.Context, project, opName string) error {
-	return wait.ExponentialBackoff(WaitForOpBackoff, func() (bool, error) {
+	return wait.ExponentialBackoff(getWaitForOpBackoff(), func() (bool, error) {
 		waitOp, err := cloud.service.GlobalOperations.Wait(project, opName).Context(ctx).Do()
 		// In case of service unavailable do not propogate the error so ExponentialBackoff will retry
 		if err != nil && waitOp.HttpErrorStatusCode == 503 {
@@ -1119,7 +1295,7 @@ func (cloud *CloudProvider) waitForGlobalOp(ctx context.Context, project, opName
 func (cloud *CloudProvider) waitForAttachOnInstance(ctx context.Context, project string, volKey *meta.Key, instanceZone, instanceName string) error {
 	klog.V(5).Infof("Waiting for attach of disk %v to instance %v to complete...", volKey.Name, instanceName)
 	start := time.Now()
-	return wait.ExponentialBackoff(AttachDiskBackoff, func() (bool, error) {
+	return wait.ExponentialBackoff(getAttachDiskBackoff(), func() (bool, error) {
 		klog.V(6).Infof("Polling instances.get for attach of disk %v to instance %v to complete for %v", volKey.Name, instanceName, time.Since(start))
 		instance, err := cloud.GetInstanceOrError(ctx, project, instanceZone, instanceName)
 		if err != nil {
@@ -1147,7 +1323,7 @@ func (cloud *CloudProvider) waitForAttachOnInstance(ctx context.Context, project
 func (cloud *CloudProvider) waitForAttachOnDisk(ctx context.Context, project string, volKey *meta.Key, instanceZone, instanceName string) error {
 	klog.V(5).Infof("Waiting for attach of disk %v to instance %v to complete...", volKey.Name, instanceName)
 	start := time.Now()
-	return wait.ExponentialBackoff(AttachDiskBackoff, func() (bool, error) {
+	return wait.ExponentialBackoff(getAttachDiskBackoff(), func() (bool, error) {
 		klog.V(6).Infof("Polling disks.get for attach of disk %v to instance %v to complete for %v", volKey.Name, instanceName, time.Since(start))
 		disk, err := cloud.GetDisk(ctx, project, volKey)
 		if err != nil {
@@ -1383,25 +1559,24 @@ func (cloud *CloudProvider) GetImage(ctx context.Context, project, imageName str
 	return image, nil
 }
 
-func (cloud *CloudProvider) ListImages(ctx context.Context, filter string) ([]*computev1.Image, string, error) {
-	klog.V(5).Infof("Listing images with filter: %s", filter)
-	var items []*computev1.Image
-	lCall := cloud.service.Images.List(cloud.project).Context(ctx).Filter(filter)
-	nextPageToken := "pageToken"
-	for nextPageToken != "" {
-		imageList, err := lCall.Do()
-		if err != nil {
-			return nil, "", err
-		}
-		items = append(items, imageList.Items...)
-		nextPageToken = imageList.NextPageToken
+// ListImages returns a page of up to maxResults images of project matching
+// filter, and the token of the next page.
+func (cloud *CloudProvider) ListImages(ctx context.Context, project, filter string, maxResults int64, pageToken string) ([]*computev1.Image, string, error) {
+	klog.V(5).Infof("Listing images of project %s with filter %q and page token %q", project, filter, pageToken)
+	lCall := cloud.service.Images.List(project).Context(ctx).Filter(filter).MaxResults(maxResults)
+	if pageToken != "" {
+		lCall = lCall.PageToken(pageToken)
 	}
-	return items, "", nil
+	imageList, err := lCall.Do()
+	if err != nil {
+		return nil, "", err
+	}
+	return imageList.Items, imageList.NextPageToken, nil
 }
 
 func (cloud *CloudProvider) DeleteImage(ctx context.Context, project, imageName string) error {
 	klog.V(5).Infof("Deleting image %v", imageName)
-	op, err := cloud.service.Images.Delete(cloud.project, imageName).Context(ctx).Do()
+	op, err := cloud.service.Images.Delete(project, imageName).Context(ctx).Do()
 	if err != nil {
 		if IsGCEError(err, "notFound") {
 			return nil
@@ -1744,6 +1919,76 @@ func (cloud *CloudProvider) attachTagsToResource(
 	return nil
 }
 
+func (cloud *CloudProvider) GetDiskResourceTags(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk) ([]string, error) {
+	location, scope := volKey.Zone, "zones"
+	if volKey.Type() == meta.Regional {
+		location, scope = volKey.Region, "regions"
+	}
+	tagBindingsClient, err := createTagBindingsClient(ctx, cloud.tokenSource, location, resourceManagerHostSubPath)
+	if err != nil || tagBindingsClient == nil {
+		return nil, fmt.Errorf("failed to create tag binding client for listing the tags of disk %v: %w", volKey, err)
+	}
+	defer tagBindingsClient.Close()
+
+	parent := fmt.Sprintf(zonalOrRegionalComputeParentPathFmt, project, scope, location, disksType, disk.GetID())
+	effectiveTags := tagBindingsClient.ListEffectiveTags(ctx, &rscmgrpb.ListEffectiveTagsRequest{Parent: parent})
+	var tags []string
+	for {
+		tag, err := effectiveTags.Next()
+		if errors.Is(err, iterator.Done) {
+			return tags, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list effective tags on %s compute resource: %w", parent, err)
+		}
+		tags = append(tags, tag.GetNamespacedTagValue())
+	}
+}
+
+func (cloud *CloudProvider) SetDiskResourceTags(ctx context.Context, project string, volKey *meta.Key, disk *CloudDisk, resourceTags map[string]string) error {
+	if len(resourceTags) == 0 {
+		return nil
+	}
+	location, scope, isZonal := volKey.Zone, "zones", true
+	if volKey.Type() == meta.Regional {
+		location, scope, isZonal = volKey.Region, "regions", false
+	}
+	tagBindingsClient, err := createTagBindingsClient(ctx, cloud.tokenSource, location, resourceManagerHostSubPath)
+	if err != nil || tagBindingsClient == nil {
+		return fmt.Errorf("failed to create tag binding client for updating the tags of disk %v: %w", volKey, err)
+	}
+	defer tagBindingsClient.Close()
+
+	// A tag key has a single value on a resource, so the bindings of the
+	// other values of the keys are deleted before binding the new ones.
+	parent := fmt.Sprintf(zonalOrRegionalComputeParentPathFmt, project, scope, location, disksType, disk.GetID())
+	effectiveTags := tagBindingsClient.ListEffectiveTags(ctx, &rscmgrpb.ListEffectiveTagsRequest{Parent: parent})
+	for {
+		tag, err := effectiveTags.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list effective tags on %s compute resource: %w", parent, err)
+		}
+		tagValue, ok := resourceTags[tag.GetNamespacedTagKey()]
+		if !ok || tag.GetInherited() || tag.GetNamespacedTagValue() == tag.GetNamespacedTagKey()+"/"+tagValue {
+			continue
+		}
+		klog.V(4).Infof("Replacing tag %s of disk %v with value %s", tag.GetNamespacedTagValue(), volKey, tagValue)
+		op, err := tagBindingsClient.DeleteTagBinding(ctx, &rscmgrpb.DeleteTagBindingRequest{
+			Name: fmt.Sprintf("tagBindings/%s/%s", url.QueryEscape(parent), tag.GetTagValue()),
+		}, getRetryCallOptions()...)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s tag binding of disk %v: %w", tag.GetNamespacedTagValue(), volKey, err)
+		}
+		if err := op.Wait(ctx); err != nil {
+			return fmt.Errorf("failed to delete %s tag binding of disk %v: %w", tag.GetNamespacedTagValue(), volKey, err)
+		}
+	}
+	return cloud.attachTagsToResource(ctx, resourceTags, project, disk.GetID(), disksType, location, isZonal, resourceManagerHostSubPath)
+}
+
 // kmsKeyEqual returns true if fetchedKMSKey and storageClassKMSKey refer to the same key.
 // fetchedKMSKey - key returned by the server
 //
@@ -1780,3 +2025,13 @@ func encodeTags(tags map[string]string) (string, error) {
 	}
 	return string(enc), nil
 }
+
+// DecodeTags returns the tags encoded by encodeTags in the description of a PD
+// or Snapshot, or nil if the description holds no tags.
+func DecodeTags(description string) map[string]string {
+	var tags map[string]string
+	if err := json.Unmarshal([]byte(description), &tags); err != nil {
+		return nil
+	}
+	return tags
+}
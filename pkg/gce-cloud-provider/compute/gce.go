@@ -16,6 +16,9 @@ package gcecloudprovider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -56,6 +59,12 @@ const (
 
 	regionURITemplate = "projects/%s/regions/%s"
 
+	// maxCredentialsProviders is the number of cloud providers of CSI secret
+	// credentials that are cached.
+	maxCredentialsProviders = 64
+
+	googleUniverseDomain = "googleapis.com"
+
 	replicaZoneURITemplateSingleZone             = "projects/%s/zones/%s" // {gce.projectID}/zones/{disk.Zone}
 	EnvironmentStaging               Environment = "staging"
 	EnvironmentProduction            Environment = "production"
@@ -88,11 +97,18 @@ var (
 type ResourceType string
 
 var (
+	// disksType is the resource type of compute disks.
+	disksType ResourceType = "disks"
 	// snapshotsType is the resource type of compute snapshots.
 	snapshotsType ResourceType = "snapshots"
 	// imagesType is the resource type of compute images.
 	imagesType         ResourceType = "images"
 	tenantServiceMutex sync.Mutex
+	credentialsMutex   sync.Mutex
+
+	// allowedServiceAccountTokenURIs are the token URIs of service account
+	// keys accepted from CSI secrets.
+	allowedServiceAccountTokenURIs = []string{TokenURL, "https://oauth2.googleapis.com/token"}
 )
 
 // CloudProvider only supports GCE v1/beta Disk APIs. See
@@ -116,6 +132,20 @@ type CloudProvider struct {
 	TenantInformer tenancy.TenantsInformer
 	// tenantServiceMap maintains Compute Services for the default project as well as any tenant projects for any tenant-aware GCE operations
 	tenantServiceMap map[string]*compute.Service
+	// AdditionalProjects are projects other than the default project that
+	// disks are provisioned in, whose disks are listed with the disks of the
+	// default project.
+	AdditionalProjects []string
+
+	// credentialsProviders maintains clones of the cloud provider using the
+	// credentials of CSI secrets, keyed by the SHA-256 of the credentials.
+	credentialsProviders map[string]*CloudProvider
+	// credentialsProviderKeys are the keys of credentialsProviders, oldest
+	// first.
+	credentialsProviderKeys []string
+	vendorVersion           string
+	computeEndpoint         *url.URL
+	computeEnvironment      Environment
 
 	enableHdHA bool
 }
@@ -191,8 +221,12 @@ func CreateCloudProvider(ctx context.Context, vendorVersion string, configPath s
 		listInstancesConfig: listInstancesConfig,
 		// GCP has a rate limit of 600 requests per minute, restricting
 		// here to 8 requests per second.
-		tagsRateLimiter:  common.NewLimiter(gcpTagsRequestRateLimit, gcpTagsRequestTokenBucketSize, true),
-		tenantServiceMap: make(map[string]*compute.Service),
+		tagsRateLimiter:      common.NewLimiter(gcpTagsRequestRateLimit, gcpTagsRequestTokenBucketSize, true),
+		tenantServiceMap:     make(map[string]*compute.Service),
+		credentialsProviders: make(map[string]*CloudProvider),
+		vendorVersion:        vendorVersion,
+		computeEndpoint:      computeEndpoint,
+		computeEnvironment:   computeEnvironment,
 	}
 
 	if multiTenancyEnabled {
@@ -245,6 +279,122 @@ func CreateCloudProvider(ctx context.Context, vendorVersion string, configPath s
 	return cp, nil
 }
 
+// WithCredentials returns a cloud provider that makes its GCE calls with
+// credentialsJSON, a service account key. The default project of the returned cloud provider is the
+// project of the credentials if set. Cloud providers are cached per
+// credentials, so that their compute services are reused across calls.
+func (cloud *CloudProvider) WithCredentials(ctx context.Context, credentialsJSON []byte) (GCECompute, error) {
+	sum := sha256.Sum256(credentialsJSON)
+	key := hex.EncodeToString(sum[:])
+
+	credentialsMutex.Lock()
+	cp, ok := cloud.credentialsProviders[key]
+	credentialsMutex.Unlock()
+	if ok {
+		return cp, nil
+	}
+
+	// The credentials come from secrets of the users of the driver: reject
+	// those which would make the controller read local files, run commands or
+	// send tokens to other servers than Google's.
+	if err := validateCredentialsJSON(credentialsJSON); err != nil {
+		return nil, err
+	}
+	// The token source and compute services outlive ctx, which is only the
+	// one of the request for callers: tokens are refreshed with the context
+	// given here.
+	svcCtx := context.Background()
+	creds, err := google.CredentialsFromJSON(svcCtx, credentialsJSON, compute.CloudPlatformScope, compute.ComputeScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	// Fail fast on credentials that cannot get a token, instead of retrying
+	// in newOauthClient. The token is fetched without holding
+	// credentialsMutex, so that slow token servers don't block the other
+	// credentials.
+	if _, err := creds.TokenSource.Token(); err != nil {
+		return nil, fmt.Errorf("failed to get token from credentials: %w", err)
+	}
+	var computeEndpoint *url.URL
+	if cloud.computeEndpoint != nil {
+		u := *cloud.computeEndpoint
+		computeEndpoint = &u
+	}
+	svc, err := createCloudService(svcCtx, cloud.vendorVersion, creds.TokenSource, computeEndpoint, cloud.computeEnvironment)
+	if err != nil {
+		return nil, err
+	}
+	betasvc, err := createBetaCloudService(svcCtx, cloud.vendorVersion, creds.TokenSource, computeEndpoint, cloud.computeEnvironment)
+	if err != nil {
+		return nil, err
+	}
+
+	newCP := *cloud
+	newCP.service = svc
+	newCP.betaService = betasvc
+	newCP.tokenSource = creds.TokenSource
+	newCP.TenantInformer = nil
+	newCP.tenantServiceMap = make(map[string]*compute.Service)
+	newCP.credentialsProviders = nil
+	newCP.credentialsProviderKeys = nil
+	if creds.ProjectID != "" {
+		newCP.project = creds.ProjectID
+	}
+	klog.V(4).Infof("Created compute services for credentials of project %q", newCP.project)
+	return cloud.cacheCredentialsProvider(key, &newCP), nil
+}
+
+// cacheCredentialsProvider caches cp under key, and returns the cached cloud
+// provider, which is the one of a concurrent call if it cached one first.
+func (cloud *CloudProvider) cacheCredentialsProvider(key string, cp *CloudProvider) *CloudProvider {
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+	if cached, ok := cloud.credentialsProviders[key]; ok {
+		return cached
+	}
+	// Evict the oldest cloud providers, whose credentials may have been
+	// rotated out of their secrets.
+	for len(cloud.credentialsProviderKeys) >= maxCredentialsProviders {
+		delete(cloud.credentialsProviders, cloud.credentialsProviderKeys[0])
+		cloud.credentialsProviderKeys = cloud.credentialsProviderKeys[1:]
+	}
+	cloud.credentialsProviders[key] = cp
+	cloud.credentialsProviderKeys = append(cloud.credentialsProviderKeys, key)
+	return cp
+}
+
+// credentialsConfig holds the fields of credentials JSON checked by
+// validateCredentialsJSON.
+type credentialsConfig struct {
+	Type           string `json:"type"`
+	TokenURI       string `json:"token_uri"`
+	UniverseDomain string `json:"universe_domain"`
+}
+
+// validateCredentialsJSON checks that credentialsJSON, from a CSI secret, is
+// a service account key getting its tokens from Google. Workload identity
+// federation configurations are refused, as their credential sources are
+// files, URLs or executables of the controller, see
+// https://cloud.google.com/docs/authentication/external/externally-sourced-credentials.
+func validateCredentialsJSON(credentialsJSON []byte) error {
+	var c credentialsConfig
+	if err := json.Unmarshal(credentialsJSON, &c); err != nil {
+		return fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if c.UniverseDomain != "" && c.UniverseDomain != googleUniverseDomain {
+		return fmt.Errorf("credentials with universe domain %q are not supported", c.UniverseDomain)
+	}
+	switch c.Type {
+	case "service_account":
+		if c.TokenURI != "" && !slices.Contains(allowedServiceAccountTokenURIs, c.TokenURI) {
+			return fmt.Errorf("service account key with token URI %q is not supported", c.TokenURI)
+		}
+	default:
+		return fmt.Errorf("credentials of type %q are not supported, only service_account is", c.Type)
+	}
+	return nil
+}
+
 func generateTokenSource(ctx context.Context, configFile *ConfigFile) (oauth2.TokenSource, error) {
 	if configFile != nil && configFile.Global.TokenURL != "" && configFile.Global.TokenURL != "nil" {
 		// configFile.Global.TokenURL is defined
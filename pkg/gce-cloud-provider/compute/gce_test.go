@@ -19,6 +19,8 @@ package gcecloudprovider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -222,3 +224,121 @@ func convertStringToURL(urlString string) *url.URL {
 	}
 	return parsedURL
 }
+
+func TestWithCredentials(t *testing.T) {
+	cached := &CloudProvider{project: "cached-project"}
+	cachedJSON := []byte(`{"type": "service_account", "project_id": "cached-project"}`)
+	sum := sha256.Sum256(cachedJSON)
+	cloud := &CloudProvider{
+		project:              "test-project",
+		credentialsProviders: map[string]*CloudProvider{hex.EncodeToString(sum[:]): cached},
+	}
+
+	testCases := []struct {
+		name            string
+		credentialsJSON string
+		expectError     bool
+	}{
+		{
+			name:            "cached credentials",
+			credentialsJSON: string(cachedJSON),
+		},
+		{
+			name:            "invalid JSON",
+			credentialsJSON: "not json",
+			expectError:     true,
+		},
+		{
+			name:            "service account key with invalid private key",
+			credentialsJSON: `{"type": "service_account", "project_id": "other-project", "client_email": "sa@other-project.iam.gserviceaccount.com", "private_key": "invalid"}`,
+			expectError:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cloud.WithCredentials(context.Background(), []byte(tc.credentialsJSON))
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got cloud provider for project %s", got.GetDefaultProject())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithCredentials returned error: %v", err)
+			}
+			if got != cached {
+				t.Errorf("Expected the cached cloud provider, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialsJSON(t *testing.T) {
+	testCases := []struct {
+		name            string
+		credentialsJSON string
+		expectError     bool
+	}{
+		{
+			name:            "service account key",
+			credentialsJSON: `{"type": "service_account", "project_id": "p", "token_uri": "https://oauth2.googleapis.com/token"}`,
+		},
+		{
+			name:            "service account key with other token URI",
+			credentialsJSON: `{"type": "service_account", "token_uri": "https://attacker.example.com/token"}`,
+			expectError:     true,
+		},
+		{
+			name:            "service account key of other universe domain",
+			credentialsJSON: `{"type": "service_account", "universe_domain": "example.com"}`,
+			expectError:     true,
+		},
+		{
+			name:            "external account",
+			credentialsJSON: `{"type": "external_account", "token_url": "https://sts.googleapis.com/v1/token", "credential_source": {"file": "/var/run/secrets/kubernetes.io/serviceaccount/token"}}`,
+			expectError:     true,
+		},
+		{
+			name:            "authorized user",
+			credentialsJSON: `{"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "token"}`,
+			expectError:     true,
+		},
+		{
+			name:            "impersonated service account",
+			credentialsJSON: `{"type": "impersonated_service_account", "service_account_impersonation_url": "https://iamcredentials.googleapis.com/"}`,
+			expectError:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCredentialsJSON([]byte(tc.credentialsJSON))
+			if gotErr := err != nil; gotErr != tc.expectError {
+				t.Errorf("validateCredentialsJSON() = %v; expectError: %v", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestCacheCredentialsProvider(t *testing.T) {
+	cloud := &CloudProvider{credentialsProviders: make(map[string]*CloudProvider)}
+	for i := 0; i < maxCredentialsProviders+1; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		cp := &CloudProvider{project: key}
+		if got := cloud.cacheCredentialsProvider(key, cp); got != cp {
+			t.Fatalf("Expected cloud provider %s to be cached, got %s", key, got.project)
+		}
+	}
+	if len(cloud.credentialsProviders) != maxCredentialsProviders {
+		t.Errorf("Expected %d cached cloud providers, got %d", maxCredentialsProviders, len(cloud.credentialsProviders))
+	}
+	if _, ok := cloud.credentialsProviders["key-0"]; ok {
+		t.Errorf("Expected the oldest cloud provider to be evicted")
+	}
+
+	// A concurrent call caching the same credentials gets the first cloud
+	// provider.
+	first := cloud.credentialsProviders["key-1"]
+	if got := cloud.cacheCredentialsProvider("key-1", &CloudProvider{}); got != first {
+		t.Errorf("Expected the cached cloud provider, got %+v", got)
+	}
+}
@@ -25,6 +25,8 @@ var (
 	FakeZone        = "country-region-zone"
 	FakeProject     = "test-project"
 	FakeName        = "test-name"
+
+	FakeAttachedDiskDeviceNames = []string{}
 )
 
 func NewFakeService() MetadataService {
@@ -47,6 +49,10 @@ func (manager *fakeServiceManager) GetMachineType() string {
 	return FakeMachineType
 }
 
+func (manager *fakeServiceManager) GetAttachedDiskDeviceNames() ([]string, error) {
+	return FakeAttachedDiskDeviceNames, nil
+}
+
 func SetMachineType(s string) {
 	FakeMachineType = s
 }
@@ -58,3 +64,7 @@ func SetZone(s string) {
 func SetName(s string) {
 	FakeName = s
 }
+
+func SetAttachedDiskDeviceNames(deviceNames []string) {
+	FakeAttachedDiskDeviceNames = deviceNames
+}
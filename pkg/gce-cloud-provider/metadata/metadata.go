@@ -17,6 +17,7 @@ limitations under the License.
 package metadata
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -30,6 +31,9 @@ type MetadataService interface {
 	GetProject() string
 	GetName() string
 	GetMachineType() string
+	// GetAttachedDiskDeviceNames returns the device names of the disks
+	// currently attached to the instance, which is queried on every call.
+	GetAttachedDiskDeviceNames() ([]string, error)
 }
 
 type metadataServiceManager struct {
@@ -86,3 +90,21 @@ func (manager *metadataServiceManager) GetName() string {
 func (manager *metadataServiceManager) GetMachineType() string {
 	return manager.machineType
 }
+
+func (manager *metadataServiceManager) GetAttachedDiskDeviceNames() ([]string, error) {
+	resp, err := metadata.Get("instance/disks/?recursive=true")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attached disks: %w", err)
+	}
+	var disks []struct {
+		DeviceName string `json:"deviceName"`
+	}
+	if err := json.Unmarshal([]byte(resp), &disks); err != nil {
+		return nil, fmt.Errorf("failed to parse attached disks %q: %w", resp, err)
+	}
+	deviceNames := []string{}
+	for _, disk := range disks {
+		deviceNames = append(deviceNames, disk.DeviceName)
+	}
+	return deviceNames, nil
+}
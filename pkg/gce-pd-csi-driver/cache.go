@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
 )
 
 const (
@@ -28,34 +30,61 @@ const (
 	KiB                float64 = 1024
 )
 
+// Outcomes of reconfigureCache, reported in events and metrics.
+const (
+	cacheReconfigureUnchanged = "unchanged"
+	cacheReconfigured         = "reconfigured"
+	cacheReconfigureRefused   = "refused"
+	cacheReconfigureFailed    = "failed"
+)
+
 var (
 	maxChunkSize float64 = 1 * GiB   // Max allowed chunk size as per LVM documentation
 	minChunkSize float64 = 160 * KiB // This is randomly selected, we need a multiple of 32KiB, the default size would be too small for caching https://man7.org/linux/man-pages/man8/lvcreate.8.html (--chunksize)
 )
 
-func fetchRAIDedLocalSsdPath() (string, error) {
-	args := []string{
-		"--detail",
-		"--scan",
+// fetchRAIDedLocalSsd returns the RAID array of the local SSDs reserved for
+// Data Cache, or nil if they aren't RAIDed.
+func fetchRAIDedLocalSsd(l lvm.LVM) (*lvm.RaidArray, error) {
+	arrays, err := l.ListRaidArrays()
+	if err != nil {
+		return nil, err
 	}
-	info, err := common.RunCommand("grep", []string{raidedLocalSsdName}, "mdadm", args...)
-	if err != nil || len(info) == 0 {
-		return "", fmt.Errorf("Error getting RAIDed device path for Data Cache %v, output:%v", err, string(info))
+	for _, array := range arrays {
+		if array.Name == raidedLocalSsdName {
+			return &array, nil
+		}
 	}
-	infoString := strings.TrimSpace(string(info))
-	infoSlice := strings.Fields(infoString)
+	return nil, nil
+}
 
-	// We want to get the second element in the array (sample: ARRAY /dev/md126 metadata=1.2 name=csi-driver-data-cache UUID=*),
-	//  which is the path to the RAIDed device
-	return infoSlice[1], nil
+func fetchRAIDedLocalSsdPath(l lvm.LVM) (string, error) {
+	array, err := fetchRAIDedLocalSsd(l)
+	if err != nil {
+		return "", fmt.Errorf("Error getting RAIDed device path for Data Cache %v", err)
+	}
+	if array == nil {
+		return "", fmt.Errorf("Error getting RAIDed device path for Data Cache, RAID array %s not found", raidedLocalSsdName)
+	}
+	return array.Path, nil
 }
 
-func setupCaching(devicePath string, req *csi.NodeStageVolumeRequest, nodeId string) (string, error) {
+// cacheReconfiguration is the outcome of changing the cache of a volume that
+// was already cached to the size and mode in its publish context.
+type cacheReconfiguration struct {
+	outcome string
+	message string
+}
+
+// setupCaching adds the PD to the volume group of the node and attaches a
+// cache of the size and mode of the publish context to it. staged is set when
+// NodeStageVolume is retried for a volume already mounted at its staging path.
+func setupCaching(l lvm.LVM, devicePath string, req *csi.NodeStageVolumeRequest, nodeId string, staged bool) (string, *cacheReconfiguration, error) {
 
 	// The device path may have changed after rebooting, so we need to fetch the path again
-	raidedLocalSsdPath, err := fetchRAIDedLocalSsdPath()
+	raidedLocalSsdPath, err := fetchRAIDedLocalSsdPath(l)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	volumeId := req.GetVolumeId()
@@ -63,117 +92,104 @@ func setupCaching(devicePath string, req *csi.NodeStageVolumeRequest, nodeId str
 	mainDevicePath := "/dev/" + volumeGroupName + "/" + getLvName(mainLvSuffix, volumeId)
 	mainLvName := getLvName(mainLvSuffix, volumeId)
 	klog.V(4).Infof("Volume group available on node %v ", volumeGroupName)
-	vgExists := checkVgExists(volumeGroupName)
+	vgExists := checkVgExists(l, volumeGroupName)
 	if vgExists {
 		// Clean up Volume Group before adding the PD
-		reduceVolumeGroup(volumeGroupName, true)
+		reduceVolumeGroup(l, volumeGroupName, true)
 	} else {
-		err := createVg(volumeGroupName, raidedLocalSsdPath)
+		err := createVg(l, volumeGroupName, raidedLocalSsdPath)
 		if err != nil {
-			return mainDevicePath, err
+			return mainDevicePath, nil, err
 		}
 	}
 
 	// Check if the Physical Volume(PV) is part of some other volume group
-	args := []string{
-		"--select",
-		"pv_name=" + devicePath,
-		"-o",
-		"vg_name",
-	}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "pvs", args...)
+	vgNameForPv := ""
+	pvs, err := l.ListPhysicalVolumes()
 	if err != nil {
-		klog.Errorf("errored while checking physical volume details %v: %s", err, info)
-		// On error info contains the error message which we cannot use for further steps
-		info = nil
+		// Assume the PV isn't in a volume group yet, vgextend fails otherwise.
+		klog.Errorf("errored while checking physical volume details %v", err)
+	}
+	for _, pv := range pvs {
+		if pv.Name == devicePath {
+			vgNameForPv = pv.VGName
+		}
 	}
-
-	infoString := strings.TrimSpace(strings.ReplaceAll(string(info), "\n", " "))
-	infoString = strings.ReplaceAll(infoString, ".", "")
-	infoString = strings.ReplaceAll(infoString, "\"", "")
-	infoSlice := strings.Split(strings.TrimSpace(infoString), " ")
-	vgNameForPv := strings.TrimSpace(infoSlice[(len(infoSlice) - 1)])
 	klog.V(4).Infof("Physical volume is part of Volume group: %v", vgNameForPv)
 	if vgNameForPv == volumeGroupName {
 		klog.V(4).Infof("Physical Volume(PV) already exists in the Volume Group %v", volumeGroupName)
-	} else if vgNameForPv != "VG" && vgNameForPv != "" {
-
-		info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgchange", []string{"-an", vgNameForPv}...)
-		if err != nil {
-			klog.Errorf("Errored while deactivating VG %v: err: %v: %s", vgNameForPv, err, info)
+	} else if vgNameForPv != "" {
+		if err := l.SetVolumeGroupActive(vgNameForPv, false); err != nil {
+			klog.Errorf("Errored while deactivating VG %v: err: %v", vgNameForPv, err)
 		}
 		// CLean up volume group to remove any dangling PV refrences
-		reduceVolumeGroup(vgNameForPv, false)
-		_, isCached := isCachingSetup(mainLvName)
+		reduceVolumeGroup(l, vgNameForPv, false)
+		isCached, _ := isCachingSetup(l, vgNameForPv, mainLvName)
 		// We will continue to uncache even if it errors to check caching as it is not a terminal issue.
 
 		if isCached {
-			// Uncache LV
-			args = []string{
-				"--uncache",
-				vgNameForPv + "/" + mainLvName,
-				"--force",
-				"-y", // force remove cache without flushing data
-			}
-			info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvconvert", args...)
-			if err != nil {
-				return "", fmt.Errorf("errored while uncaching main LV. %v: %s", err, info)
+			// Uncache LV, force remove cache without flushing data
+			if err := l.Uncache(vgNameForPv, mainLvName, true /* force */); err != nil {
+				return "", nil, fmt.Errorf("errored while uncaching main LV. %w", err)
 			}
 			// CLean up volume group to remove any dangling PV refrences
-			reduceVolumeGroup(vgNameForPv, false)
+			reduceVolumeGroup(l, vgNameForPv, false)
 		}
-		info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgmerge", []string{volumeGroupName, vgNameForPv}...)
-		if err != nil {
-			return "", fmt.Errorf("Errored while merging the PV Volume group %s into %s %v: %s", vgNameForPv, volumeGroupName, err, info)
+		if err := l.MergeVolumeGroups(volumeGroupName, vgNameForPv); err != nil {
+			return "", nil, fmt.Errorf("Errored while merging the PV Volume group %s into %s %w", vgNameForPv, volumeGroupName, err)
 		}
 
 	} else {
-		info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgextend", []string{volumeGroupName, devicePath}...)
-		if err != nil {
-			return "", fmt.Errorf("Errored while extending Volume group to add PV %v, error: %v: %s", devicePath, err, info)
+		if err := l.ExtendVolumeGroup(volumeGroupName, devicePath); err != nil {
+			return "", nil, fmt.Errorf("Errored while extending Volume group to add PV %v, error: %w", devicePath, err)
 		}
 	}
 
 	// Create LV if not already created
-	args = []string{
-		"--select",
-		"vg_name=" + volumeGroupName,
-		"-o",
-		"lv_name",
-	}
-	lvList, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvs", args...)
-	if err != nil {
-		return mainDevicePath, fmt.Errorf("Errored while checking logical volume for the device %s %w: %s", devicePath, err, info)
-	}
-	if !strings.Contains(string(lvList), mainLvName) {
-		args = []string{
-			"--yes",
-			"-n",
-			mainLvName,
-			"-l",
-			"100%PVS", // Use 100% of the PV
-			volumeGroupName,
-			devicePath,
-		}
-		info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvcreate", args...)
+	lvs, err := l.ListLogicalVolumes(volumeGroupName)
+	if err != nil {
+		return mainDevicePath, nil, fmt.Errorf("Errored while checking logical volume for the device %s %w", devicePath, err)
+	}
+	if findLogicalVolume(lvs, mainLvName) == nil {
+		err = l.CreateLogicalVolume(lvm.CreateLogicalVolumeOptions{
+			Name:    mainLvName,
+			VGName:  volumeGroupName,
+			Extents: "100%PVS", // Use 100% of the PV
+			Tags:    []string{volumeIdTag(volumeId)},
+			PVs:     []string{devicePath},
+		})
 		if err != nil {
-			return mainDevicePath, fmt.Errorf("Errored setting up logical volume for the volume %s %w: %s", devicePath, err, info)
+			return mainDevicePath, nil, fmt.Errorf("Errored setting up logical volume for the volume %s %w", devicePath, err)
 		}
 
 	}
-	err, isCached := isCachingSetup(mainLvName)
+	isCached, err := isCachingSetup(l, volumeGroupName, mainLvName)
 	if err != nil {
 		klog.Errorf("failed to check if caching is setup for LV, continuing to setup caching.")
 	}
 	cacheLvName := getLvName(cacheSuffix, volumeId)
-	if isCached {
-		// Validate that cache is setup for required size
-		klog.V(4).Infof("Assuming valid data cache size and mode, resizing cache is not supported")
-	} else {
-		cacheSize := req.GetPublishContext()[constants.ContextDataCacheSize]
+	cacheSize := req.GetPublishContext()[constants.ContextDataCacheSize]
+	cacheMode := req.GetPublishContext()[constants.ContextDataCacheMode]
+	var reconfiguration *cacheReconfiguration
+	if isCached && staged {
+		// The publish context, and so the cache settings, of a volume only
+		// change when it is attached again, after being unstaged. A retry
+		// of NodeStageVolume keeps the cache of the staged volume as is.
+		klog.V(4).Infof("Volume %s is already staged, keeping its cache", volumeId)
+	} else if isCached {
+		reconfiguration, err = reconfigureCache(l, volumeGroupName, mainLvName, cacheLvName, cacheSize, cacheMode)
+		switch reconfiguration.outcome {
+		case cacheReconfigureFailed:
+			return mainDevicePath, reconfiguration, err
+		case cacheReconfigured:
+			// The old cache is gone, create the new one below.
+			isCached = false
+		}
+	}
+	if !isCached {
 		maxChunkSizeStr := strconv.FormatInt(int64(maxChunkSize/KiB), 10)
 		var chunkSize string
-		cachePvSize, err := fetchPvSizeGiB()
+		cachePvSize, err := fetchPvSizeGiB(l)
 		if err != nil {
 			klog.Errorf("Errored while fetching PV size, got %v, falling back to default chunkSize of %v", err, maxChunkSize)
 			chunkSize = maxChunkSizeStr
@@ -185,62 +201,156 @@ func setupCaching(devicePath string, req *csi.NodeStageVolumeRequest, nodeId str
 			}
 		}
 		// Check if LV exists
-		info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvs", args...)
-		lvExists := strings.Contains(string(info), cacheLvName)
-		if !lvExists {
-			args = []string{
-				"--yes",
-				"-n",
-				cacheLvName,
-				"-L",
-				// ConvertGiStringToInt64 converts the input size to GiB so default to "g" for cache size - LVM g|G is GiB.
-				cacheSize + "g",
-				volumeGroupName,
-				raidedLocalSsdPath,
+		lvs, err = l.ListLogicalVolumes(volumeGroupName)
+		if err != nil {
+			return mainDevicePath, reconfiguration, fmt.Errorf("Errored while checking cache for the device %s %w", devicePath, err)
+		}
+		if findLogicalVolume(lvs, cacheLvName) == nil {
+			// ConvertGiStringToInt64 converts the input size to GiB.
+			cacheSizeGiB, err := strconv.ParseInt(cacheSize, 10, 64)
+			if err != nil {
+				return mainDevicePath, reconfiguration, status.Error(codes.InvalidArgument, fmt.Sprintf("Error setting up cache: invalid data cache size %q", cacheSize))
 			}
-			info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvcreate", args...)
+			err = l.CreateLogicalVolume(lvm.CreateLogicalVolumeOptions{
+				Name:    cacheLvName,
+				VGName:  volumeGroupName,
+				SizeGiB: cacheSizeGiB,
+				PVs:     []string{raidedLocalSsdPath},
+			})
 			if err != nil {
 				if strings.Contains(err.Error(), "insufficient free space") {
-					return mainDevicePath, status.Error(codes.InvalidArgument, fmt.Sprintf("Error setting up cache: %v", err.Error()))
+					return mainDevicePath, reconfiguration, status.Error(codes.InvalidArgument, fmt.Sprintf("Error setting up cache: %v", err.Error()))
 				}
-				return mainDevicePath, fmt.Errorf("Errored while creating cache %w: %s", err, info)
+				return mainDevicePath, reconfiguration, fmt.Errorf("Errored while creating cache %w", err)
 			}
 		}
 
 		// Once caching is setup, link the PD to cache
-		args = []string{
-			"--type",
-			"cache",
-			"--cachevol",
-			cacheLvName,
-			"--zero",
-			"y",
-			"--cachemode",
-			req.GetPublishContext()[constants.ContextDataCacheMode],
-			volumeGroupName + "/" + mainLvName,
-			"--chunksize",
-			chunkSize,
-			"--force",
-			"-y",
-		}
-		info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvconvert", args...)
+		err = l.AttachCache(volumeGroupName, mainLvName, lvm.AttachCacheOptions{
+			CacheLVName: cacheLvName,
+			Mode:        cacheMode,
+			ChunkSize:   chunkSize,
+		})
 		if err != nil {
-			return mainDevicePath, fmt.Errorf("Errored while setting up caching for volume %s %w: %s", devicePath, err, info)
+			return mainDevicePath, reconfiguration, fmt.Errorf("Errored while setting up caching for volume %s %w", devicePath, err)
 		}
 	}
 
 	// activate all the LVs in the Volume group
-	info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgchange", []string{"-ay", volumeGroupName}...)
-	if err != nil {
+	if err := l.SetVolumeGroupActive(volumeGroupName, true); err != nil {
 		// The logical volumes would not be accessible if the group is not activated
-		return mainDevicePath, fmt.Errorf("Failed to activate volume group %v %v:%s", volumeGroupName, err, info)
+		return mainDevicePath, reconfiguration, fmt.Errorf("Failed to activate volume group %v %v", volumeGroupName, err)
 	}
-	return mainDevicePath, nil
+	return mainDevicePath, reconfiguration, nil
 }
 
-func ValidateDataCacheConfig(dataCacheMode string, dataCacheSize string, ctx context.Context) error {
+// reconfigureCache detaches and removes the cache of mainLvName when its size
+// or mode differs from the requested one, so that setupCaching can attach a
+// new one. A writeback cache with dirty blocks is left in place, as detaching
+// it would have to drop data that only lives on the local SSDs.
+//
+// cleanupCache removes the cache on NodeUnstageVolume, so only the caches of
+// volumes that weren't unstaged, e.g. before a node restart, are found here.
+func reconfigureCache(l lvm.LVM, volumeGroupName, mainLvName, cacheLvName, cacheSize, cacheMode string) (*cacheReconfiguration, error) {
+	current, err := getCacheStatus(l, volumeGroupName, mainLvName, cacheLvName)
+	if err != nil {
+		return &cacheReconfiguration{outcome: cacheReconfigureFailed, message: err.Error()}, err
+	}
+	requestedSize, err := strconv.ParseInt(cacheSize, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("invalid data cache size %q: %w", cacheSize, err)
+		return &cacheReconfiguration{outcome: cacheReconfigureFailed, message: err.Error()}, err
+	}
+	if current.sizeGiB == requestedSize && current.mode == cacheMode {
+		return &cacheReconfiguration{outcome: cacheReconfigureUnchanged}, nil
+	}
+	change := fmt.Sprintf("from %dGiB %s to %dGiB %s", current.sizeGiB, current.mode, requestedSize, cacheMode)
+	if current.mode == constants.DataCacheModeWriteBack && current.dirtyBlocks > 0 {
+		message := fmt.Sprintf("not changing cache %s, it has %d dirty blocks that are not written back to the persistent disk yet", change, current.dirtyBlocks)
+		klog.Warningf("Volume %s: %s", mainLvName, message)
+		return &cacheReconfiguration{outcome: cacheReconfigureRefused, message: message}, nil
+	}
+
+	klog.V(2).Infof("Changing cache of %s %s", mainLvName, change)
+	// SplitCache flushes the cache before detaching it, unlike the forced
+	// Uncache in setupCaching.
+	if err := l.SplitCache(volumeGroupName, mainLvName); err != nil {
+		err = fmt.Errorf("errored while detaching cache of %s: %w", mainLvName, err)
+		return &cacheReconfiguration{outcome: cacheReconfigureFailed, message: err.Error()}, err
+	}
+	if err := l.RemoveLogicalVolume(volumeGroupName, cacheLvName); err != nil {
+		err = fmt.Errorf("errored while removing cache %s: %w", cacheLvName, err)
+		return &cacheReconfiguration{outcome: cacheReconfigureFailed, message: err.Error()}, err
+	}
+	klog.V(4).Infof("Removed cache %s", cacheLvName)
+	return &cacheReconfiguration{outcome: cacheReconfigured, message: "changed cache " + change}, nil
+}
+
+type cacheStatus struct {
+	mode        string
+	sizeGiB     int64
+	dirtyBlocks uint64
+}
+
+// getCacheStatus returns the mode and dirty blocks of the cache attached to
+// mainLvName, and the size of cacheLvName. Once attached, the cache LV is
+// hidden and renamed with lvm.CacheVolSuffix.
+func getCacheStatus(l lvm.LVM, volumeGroupName, mainLvName, cacheLvName string) (*cacheStatus, error) {
+	lvs, err := l.ListLogicalVolumes(volumeGroupName)
+	if err != nil {
+		return nil, fmt.Errorf("errored while checking cache of %s: %w", mainLvName, err)
+	}
+	mainLv := findLogicalVolume(lvs, mainLvName)
+	if mainLv == nil || mainLv.Cache == nil {
+		return nil, fmt.Errorf("cache of %s is not active", mainLvName)
+	}
+	cacheLv := findLogicalVolume(lvs, cacheLvName+lvm.CacheVolSuffix)
+	if cacheLv == nil {
+		return nil, fmt.Errorf("cache %s of %s not found", cacheLvName, mainLvName)
+	}
+	return &cacheStatus{
+		mode:        mainLv.Cache.Mode,
+		sizeGiB:     int64(math.Round(float64(cacheLv.SizeBytes) / GiB)),
+		dirtyBlocks: mainLv.Cache.DirtyBlocks,
+	}, nil
+}
+
+// expandCachedVolume grows the main LV of a volume staged with Data Cache to
+// the new size of its PD, and returns the path of the LV. It returns an empty
+// path if the volume was staged without a cache.
+func expandCachedVolume(l lvm.LVM, devicePath string, volumeId string, nodeId string) (string, error) {
+	volumeGroupName := getVolumeGroupName(nodeId)
+	mainLvName := getLvName(mainLvSuffix, volumeId)
+	lvs, err := l.ListLogicalVolumes(volumeGroupName)
+	if err != nil {
+		return "", fmt.Errorf("errored while checking logical volume %s: %w", mainLvName, err)
+	}
+	if findLogicalVolume(lvs, mainLvName) == nil {
+		klog.V(4).Infof("Logical volume %s not found, volume %s is not cached", mainLvName, volumeId)
+		return "", nil
+	}
+
+	// The PD is registered in the volume group with its /dev path.
+	devFsPath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		klog.Errorf("filepath.EvalSymlinks(%q) failed when trying to resize physical volume: %v", devicePath, err)
+		devFsPath = devicePath
+	}
+	if err := l.ResizePhysicalVolume(devFsPath); err != nil {
+		return "", err
+	}
+	// Like the main LV in setupCaching, only extents of the PD are used so
+	// that it never spills onto the local SSDs of the cache.
+	if err := l.ExtendLogicalVolume(volumeGroupName, mainLvName, devFsPath); err != nil {
+		return "", err
+	}
+	klog.V(4).Infof("Extended logical volume %s to physical volume %s", mainLvName, devFsPath)
+	return "/dev/" + volumeGroupName + "/" + mainLvName, nil
+}
+
+func ValidateDataCacheConfig(l lvm.LVM, dataCacheMode string, dataCacheSize string, ctx context.Context) error {
 	if dataCacheMode != "" && dataCacheSize != "" {
-		isAlreadyRaided, err := IsRaided()
+		isAlreadyRaided, err := IsRaided(l)
 		if err != nil {
 			return fmt.Errorf("Local SSDs are not setup for caching; got error: %v", err)
 		}
@@ -269,50 +379,26 @@ func GetDataCacheCountFromNodeLabel(ctx context.Context, nodeName string) (int,
 	return 0, nil
 }
 
-func FetchRaidedLssdCountForDatacache() (int, error) {
-	raidedPath, err := fetchRAIDedLocalSsdPath()
-	if err != nil {
-		return 0, err
-	}
-	args := []string{
-		"--detail",
-		raidedPath,
-	}
-	info, err := common.RunCommand("grep", []string{"Raid Devices"}, "mdadm", args...)
+func FetchRaidedLssdCountForDatacache(l lvm.LVM) (int, error) {
+	array, err := fetchRAIDedLocalSsd(l)
 	if err != nil {
-		return 0, fmt.Errorf("Error getting RAIDed devices for Data Cache")
+		return 0, fmt.Errorf("Error getting RAIDed devices for Data Cache: %v", err)
 	}
-	if len(info) != 0 {
-		raidedDeviceInfo := strings.Split(strings.TrimSpace(string(info)), ":")
-		// raidedDeviceInfo should be in "Raid Devices : X" format
-		raidedDeviceCount, _ := strconv.Atoi(strings.TrimSpace(raidedDeviceInfo[1]))
-		return raidedDeviceCount, nil
+	if array == nil {
+		return 0, nil
 	}
-	return 0, nil
+	return len(array.Devices), nil
 }
 
-func FetchRaidedLssds() ([]string, error) {
-	raidedLssdList := []string{}
-
-	args := []string{
-		"--detail",
-		"--scan",
-		"--export",
-	}
-
-	info, err := common.RunCommand("grep", []string{"/dev"}, "mdadm", args...)
+// FetchRaidedLssds returns the devices of all RAID arrays on the node.
+func FetchRaidedLssds(l lvm.LVM) ([]string, error) {
+	arrays, err := l.ListRaidArrays()
 	if err != nil {
-		return nil, fmt.Errorf("error fetching RAIDed LSSDs: %v; err:%v", info, err)
+		return nil, fmt.Errorf("error fetching RAIDed LSSDs: %v", err)
 	}
-
-	if len(info) != 0 {
-		infoList := strings.Split(strings.TrimSpace(string(info)), "\n")
-		for _, ssd := range infoList {
-			ssdInfo := strings.TrimSpace(ssd)
-			// SSD name comes after "=" on each output line (e.g. MD_DEVICE_dev_nvme3n1_DEV=/dev/nvme3n1)
-			ssdName := strings.Split(ssdInfo, "=")[1]
-			raidedLssdList = append(raidedLssdList, ssdName)
-		}
+	raidedLssdList := []string{}
+	for _, array := range arrays {
+		raidedLssdList = append(raidedLssdList, array.Devices...)
 	}
 
 	klog.V(4).Infof("Raided NVME list %v", raidedLssdList)
@@ -361,62 +447,60 @@ func FetchLSSDsWihtEmptyMountPoint() ([]string, error) {
 	return diskList, nil
 }
 
-func checkVgExists(volumeGroupName string) bool {
-	args := []string{}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgscan", args...)
+func checkVgExists(l lvm.LVM, volumeGroupName string) bool {
+	vgs, err := l.ListVolumeGroups()
 	if err != nil {
-		klog.Errorf("Errored while checking if volume group exists %v: %s", err, info)
+		klog.Errorf("Errored while checking if volume group exists %v", err)
 		return false
 	}
-	// Check if the required volume group already exists
-	klog.Infof("check vg exists output: %v, volumeGroupName: %v", string(info), volumeGroupName)
-	return strings.Contains(string(info), volumeGroupName)
+	for _, vg := range vgs {
+		if vg.Name == volumeGroupName {
+			return true
+		}
+	}
+	return false
 }
 
-func cleanupCache(volumeId string, nodeId string) error {
+func cleanupCache(l lvm.LVM, volumeId string, nodeId string) error {
 
 	volumeGroupName := getVolumeGroupName(nodeId)
-	if !checkVgExists(volumeGroupName) {
+	if !checkVgExists(l, volumeGroupName) {
 		klog.V(4).Infof("Volume group %s not found, no cache clean up needed", volumeGroupName)
 		// If volume group doesn't exist then there's nothing to uncache
 		return nil
 	}
-	reduceVolumeGroup(volumeGroupName, true)
+	reduceVolumeGroup(l, volumeGroupName, true)
 	mainLvName := getLvName(mainLvSuffix, volumeId)
-	if !checkLvExists(mainLvName) {
+	lvs, err := l.ListLogicalVolumes(volumeGroupName)
+	if err != nil {
+		return fmt.Errorf("Failed to check logical volumes for uncaching %s %w", volumeId, err)
+	}
+	mainLv := findLogicalVolume(lvs, mainLvName)
+	if mainLv == nil {
 		klog.V(4).Infof("Logical volume %s not found, assuming caching wasn't setup for the PVC %s or is cleaned up", mainLvName, volumeId)
 		// If logical volume doesn't exist then there's nothing to uncache
 		return nil
 	}
-	args := []string{
-		"-an",
-		"/dev/" + volumeGroupName + "/" + mainLvName,
-	}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvchange", args...)
-	if err != nil {
-		return fmt.Errorf("Failed to deactivate volume for uncaching %s %v: %s", volumeId, err, info)
+	if err := l.SetLogicalVolumeActive(volumeGroupName, mainLvName, false); err != nil {
+		return fmt.Errorf("Failed to deactivate volume for uncaching %s %w", volumeId, err)
 	}
-	args = []string{
-		"--uncache",
-		volumeGroupName + "/" + mainLvName,
-		"-y",
+	if mainLv.SegType != lvm.SegTypeCache {
+		klog.V(4).Infof("Logical volume %s has no cache, no cache clean up needed", mainLvName)
+		return nil
 	}
-	info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvconvert", args...)
-	if err != nil {
-		return fmt.Errorf("Failed to uncache volume %s %w: %s", volumeId, err, info)
+	if err := l.Uncache(volumeGroupName, mainLvName, false /* force */); err != nil {
+		return fmt.Errorf("Failed to uncache volume %s %w", volumeId, err)
 	}
 	return nil
 }
 
-func checkLvExists(lvName string) bool {
-	args := []string{}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "lvscan", args...)
-	if err != nil {
-		klog.Errorf("Errored while checking if logical volume exists for %s %v: %s", lvName, err, info)
-		return false
+func findLogicalVolume(lvs []lvm.LogicalVolume, lvName string) *lvm.LogicalVolume {
+	for i := range lvs {
+		if lvs[i].Name == lvName {
+			return &lvs[i]
+		}
 	}
-	// Check if the required logical volume already exists
-	return strings.Contains(string(info), lvName)
+	return nil
 }
 
 func getVolumeGroupName(nodePath string) string {
@@ -432,62 +516,29 @@ func getLvName(suffix string, volumeId string) string {
 	return fmt.Sprintf("%s-%s", suffix, pvcName)
 }
 
-func createVg(volumeGroupName string, raidedLocalSsds string) error {
-	args := []string{
-		"--zero",
-		"y",
-		volumeGroupName,
-		raidedLocalSsds,
-		"-v",
-	}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgcreate", args...)
-	if err != nil {
-		return fmt.Errorf("Volume group creation failed %w: %s", err, info)
+func createVg(l lvm.LVM, volumeGroupName string, raidedLocalSsds string) error {
+	if err := l.CreateVolumeGroup(volumeGroupName, raidedLocalSsds); err != nil {
+		return fmt.Errorf("Volume group creation failed %w", err)
 	}
 	klog.V(4).Infof("Volume group creation succeeded for %v", volumeGroupName)
-
-	args = []string{}
-	info, err = common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgscan", args...)
-	if err != nil {
-		klog.Errorf("Failed to scan for volume group post creation, continuing: %v: %s", err, info)
-	}
 	return nil
 }
 
-func reduceVolumeGroup(volumeGroupName string, force bool) {
-	if !checkVgExists(volumeGroupName) {
+func reduceVolumeGroup(l lvm.LVM, volumeGroupName string, force bool) {
+	if !checkVgExists(l, volumeGroupName) {
 		return
 	}
-	args := []string{
-		"--removemissing",
-		volumeGroupName,
-	}
-	if force {
-		args = append(args, "--force")
-	}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgreduce", args...)
-	if err != nil {
-		klog.Errorf("Errored while cleaning up volume group %v: %s", err, info)
+	if err := l.RemoveMissingPhysicalVolumes(volumeGroupName, force); err != nil {
+		klog.Errorf("Errored while cleaning up volume group %v", err)
 	}
 }
 
-func RaidLocalSsds(availableLssds []string) error {
-	args := []string{
-		"--create",
-		raidedLocalSsdName,
-		"-l" + raidMode,
-		// Force RAIDing as sometime it might fail for caution if there is just 1 LSSD present as 1 LSSD need not be RAIDed
-		"--force",
-		"-n",
-		strconv.Itoa(len(availableLssds)),
-	}
-	args = append(args, availableLssds...)
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipeCmdArg */, "mdadm", args...)
-	if err != nil {
-		return fmt.Errorf("errored while RAIDing LSSDs info: %v; err:%v", info, err)
+func RaidLocalSsds(l lvm.LVM, availableLssds []string) error {
+	if err := l.CreateRaidArray(raidedLocalSsdName, raidMode, availableLssds); err != nil {
+		return fmt.Errorf("errored while RAIDing LSSDs: %v", err)
 	}
 	// Validate if Raided successfully
-	isAlreadyRaided, err := IsRaided()
+	isAlreadyRaided, err := IsRaided(l)
 	if err != nil {
 		klog.V(4).Infof("Errored while scanning for available raided LocalSSDs err:%v=", err)
 	}
@@ -495,7 +546,7 @@ func RaidLocalSsds(availableLssds []string) error {
 		return fmt.Errorf("failed raiding, raided device not found on scanning")
 	}
 
-	raidedDataCacheCount, err := FetchRaidedLssdCountForDatacache()
+	raidedDataCacheCount, err := FetchRaidedLssdCountForDatacache(l)
 	if err != nil {
 		return err
 	}
@@ -505,37 +556,22 @@ func RaidLocalSsds(availableLssds []string) error {
 	return nil
 }
 
-func IsRaided() (bool, error) {
-	args := []string{
-		"--detail",
-		"--scan",
-	}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipeCmdArg */, "mdadm", args...)
+func IsRaided(l lvm.LVM) (bool, error) {
+	array, err := fetchRAIDedLocalSsd(l)
 	if err != nil {
-		return false, fmt.Errorf("errored while scanning for raided LSSD %v: %s", err, info)
-	}
-	if info != nil && strings.Contains(string(info), raidedLocalSsdName) {
-		return true, nil
+		return false, fmt.Errorf("errored while scanning for raided LSSD %v", err)
 	}
-	return false, nil
+	return array != nil, nil
 }
 
-func isCachingSetup(mainLvName string) (error, bool) {
+func isCachingSetup(l lvm.LVM, volumeGroupName string, mainLvName string) (bool, error) {
 	// Verify caching is setup for PD
-	args := []string{
-		"--select",
-		"lv_name=" + mainLvName,
-		"-o",
-		"pool_lv",
-	}
-	poolName, err := common.RunCommand("" /* pipedCmd */, nil /* pipeCmdArg */, "lvs", args...)
+	lvs, err := l.ListLogicalVolumes(volumeGroupName)
 	if err != nil {
-		return fmt.Errorf("Failed to check if caching is setup %w", err), false
-	}
-	if strings.Contains(string(poolName), "csi-fast") {
-		return nil, true
+		return false, fmt.Errorf("Failed to check if caching is setup %w", err)
 	}
-	return nil, false
+	mainLv := findLogicalVolume(lvs, mainLvName)
+	return mainLv != nil && mainLv.SegType == lvm.SegTypeCache, nil
 }
 
 // cacheSize is always in GiB
@@ -555,26 +591,26 @@ func fetchChunkSizeKiB(cacheSize string) (string, error) {
 	return strconv.FormatInt(int64(chunkSize), 10) + "KiB", nil
 }
 
-func InitializeDataCacheNode(nodeId string) error {
-	raidedLocalSsdPath, err := fetchRAIDedLocalSsdPath()
+func InitializeDataCacheNode(l lvm.LVM, nodeId string) error {
+	raidedLocalSsdPath, err := fetchRAIDedLocalSsdPath(l)
 	if err != nil {
 		return err
 	}
 	volumeGroupName := getVolumeGroupName(nodeId)
 
-	vgExists := checkVgExists(volumeGroupName)
+	vgExists := checkVgExists(l, volumeGroupName)
 	// Check if the required volume group already exists
 	if vgExists {
 		// Clean up Volume Group before adding the PD
-		reduceVolumeGroup(volumeGroupName, true)
+		reduceVolumeGroup(l, volumeGroupName, true)
 
 		// validate that raidedLSSD is part of VG
-		err = validateRaidedLSSDinVG(volumeGroupName, raidedLocalSsdPath)
+		err = validateRaidedLSSDinVG(l, volumeGroupName, raidedLocalSsdPath)
 		if err != nil {
 			return fmt.Errorf("failed validate local ssd in vg %v: %v", volumeGroupName, err)
 		}
 	} else {
-		err := createVg(volumeGroupName, raidedLocalSsdPath)
+		err := createVg(l, volumeGroupName, raidedLocalSsdPath)
 		if err != nil {
 			return err
 		}
@@ -582,11 +618,12 @@ func InitializeDataCacheNode(nodeId string) error {
 	return nil
 }
 
-func StartWatcher(ctx context.Context, nodeName string) {
+func StartWatcher(ctx context.Context, l lvm.LVM, nodeName string) {
 	dirToWatch := "/dev/"
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		klog.V(2).ErrorS(err, "errored while creating watcher")
+		return
 	}
 	klog.V(2).Infof("Watcher started for directory %v", dirToWatch)
 	defer watcher.Close()
@@ -597,15 +634,17 @@ func StartWatcher(ctx context.Context, nodeName string) {
 	}
 	errorCh := make(chan error, 1)
 	// Handle the error received from the watcher goroutine
-	go watchDiskDetaches(ctx, watcher, nodeName, errorCh)
+	go watchDiskDetaches(ctx, l, watcher, nodeName, errorCh)
 
 	select {
 	case err := <-errorCh:
 		klog.Errorf("watcher encountered an error: %v", err)
+	case <-ctx.Done():
+		klog.Infof("Context done, stopping watcher of directory %v", dirToWatch)
 	}
 }
 
-func watchDiskDetaches(ctx context.Context, watcher *fsnotify.Watcher, nodeName string, errorCh chan error) error {
+func watchDiskDetaches(ctx context.Context, l lvm.LVM, watcher *fsnotify.Watcher, nodeName string, errorCh chan error) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -615,89 +654,63 @@ func watchDiskDetaches(ctx context.Context, watcher *fsnotify.Watcher, nodeName
 		case err := <-watcher.Errors:
 			errorCh <- fmt.Errorf("disk update event errored: %v", err)
 		// watch for events
-		case <-watcher.Events:
+		case event := <-watcher.Events:
+			// Only devices being added or removed can change the volume
+			// group, other changes in /dev are ignored.
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) {
+				continue
+			}
 			// In case of an event i.e. creation or deletion of any new PV, we update the VG metadata.
 			// This might include some non-LVM changes, no harm in updating metadata multiple times.
-			args := []string{
-				"--updatemetadata",
-				getVolumeGroupName(nodeName),
-			}
-			_, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgck", args...)
-			if err != nil {
+			volumeGroupName := getVolumeGroupName(nodeName)
+			if err := l.UpdateVolumeGroupMetadata(volumeGroupName); err != nil {
 				klog.Errorf("Error updating volume group's metadata: %v", err)
 			}
-			reduceVolumeGroup(getVolumeGroupName(nodeName), true)
+			reduceVolumeGroup(l, volumeGroupName, true)
 		}
 	}
 }
 
-func validateRaidedLSSDinVG(vgName string, lssdPath string) error {
-	args := []string{
-		"--noheadings",
-		"-o",
-		"pv_name",
-		"--select",
-		"vg_name=" + vgName,
+func validateRaidedLSSDinVG(l lvm.LVM, vgName string, lssdPath string) error {
+	pvs, err := l.ListPhysicalVolumes()
+	if err != nil {
+		return fmt.Errorf("errored while checking physical volume details %v", err)
 	}
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "pvs", args...)
+	// The RAIDed device may be registered with its /dev/md127 equivalent.
+	resolvedPath, err := filepath.EvalSymlinks(lssdPath)
 	if err != nil {
-		return fmt.Errorf("errored while checking physical volume details %v: %s", err, info)
-		// On error info contains the error message which we cannot use for further steps
+		resolvedPath = lssdPath
 	}
-
-	if !strings.Contains(string(info), lssdPath) {
-		return addRaidedLSSDToVg(vgName, lssdPath)
+	for _, pv := range pvs {
+		if pv.VGName == vgName && (pv.Name == lssdPath || pv.Name == resolvedPath) {
+			return nil
+		}
 	}
-	return nil
+	return addRaidedLSSDToVg(l, vgName, lssdPath)
 }
 
-func addRaidedLSSDToVg(vgName, lssdPath string) error {
-	info, err := common.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, "vgextend", []string{vgName, lssdPath}...)
-	if err != nil {
-		return fmt.Errorf("errored while extending VGs %v: %s", err, info)
+func addRaidedLSSDToVg(l lvm.LVM, vgName, lssdPath string) error {
+	if err := l.ExtendVolumeGroup(vgName, lssdPath); err != nil {
+		return fmt.Errorf("errored while extending VGs %v", err)
 	}
 	return nil
 }
 
-func fetchPvSizeGiB() (string, error) {
-	args := []string{
-		"-o", "pv_name,pv_size",
-		"--noheadings",
-		"--units=b",
-	}
-	// RAIDed device is always registered with its /dev/md127 equivalent in VG so cannot check it directly based on the RAIDed LSSD path which could be /dev/md/csi-driver-data-cache
-	info, err := common.RunCommand("grep" /* pipedCmd */, []string{"/dev/md"} /* pipedCmdArg */, "pvs", args...)
+func fetchPvSizeGiB(l lvm.LVM) (string, error) {
+	pvs, err := l.ListPhysicalVolumes()
 	if err != nil {
-		return "", fmt.Errorf("errored while fetching PV size %v: %s", err, info)
+		return "", fmt.Errorf("errored while fetching PV size %v", err)
 	}
-	infoString := strings.TrimSpace(string(info))
-	infoSlice := strings.Fields(infoString)
-	pvSize, err := fetchNumberGiB(infoSlice)
-	if err != nil {
-		return "", fmt.Errorf("Error fetching PV size for cache %v", err)
+	// RAIDed device is always registered with its /dev/md127 equivalent in VG so cannot check it directly based on the RAIDed LSSD path which could be /dev/md/csi-driver-data-cache
+	for _, pv := range pvs {
+		if strings.HasPrefix(pv.Name, "/dev/md") {
+			return pvSizeGiB(pv.SizeBytes), nil
+		}
 	}
-	return pvSize, nil
-
+	return "", fmt.Errorf("Error fetching PV size for cache, no RAIDed physical volume found")
 }
 
-func fetchNumberGiB(infoSlice []string) (string, error) {
-	re, err := regexp.Compile("^[0-9]+B$")
-	if err != nil {
-		return "", fmt.Errorf("Failed to compile regex match %v", err)
-	}
-	var pvSize string
-	for _, i := range infoSlice {
-		if re.MatchString(i) {
-			pvSize, err = strings.TrimSuffix(i, "B"), nil
-			if err != nil {
-				return "", fmt.Errorf("Failed to extract PV size %v", err)
-			}
-			break
-		}
-	}
-	pvSizeInt, err := strconv.ParseFloat(pvSize, 64)
-	if err != nil {
-		return "", fmt.Errorf("Error while fetching PV size for cache %v", err)
-	}
-	return strconv.FormatInt(int64(math.Ceil(pvSizeInt/GiB)), 10) + "GiB", nil
+// pvSizeGiB rounds sizeBytes up to GiB.
+func pvSizeGiB(sizeBytes int64) string {
+	return strconv.FormatInt(int64(math.Ceil(float64(sizeBytes)/GiB)), 10) + "GiB"
 }
@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
+)
+
+// volumeIdTagPrefix prefixes the LVM tag that setupCaching adds to the main
+// LV to record its volume ID, as the LV name only has the disk name.
+const volumeIdTagPrefix = "pd-csi-volume-id="
+
+func volumeIdTag(volumeId string) string {
+	return volumeIdTagPrefix + volumeId
+}
+
+// StartCacheStatsCollector reports the statistics of the cached LVs of the
+// node every period until ctx is done.
+func StartCacheStatsCollector(ctx context.Context, l lvm.LVM, nodeId string, period time.Duration, mm *metrics.MetricsManager) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		stats, err := collectCacheStats(l, nodeId)
+		if err != nil {
+			klog.Errorf("Failed to collect Data Cache statistics: %v", err)
+		} else {
+			mm.RecordDataCacheStats(stats)
+		}
+		select {
+		case <-ctx.Done():
+			klog.Infof("Context done, stopping Data Cache statistics collector")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectCacheStats returns the statistics of the active caches of the node.
+// Volumes cached before their LV was tagged are reported with their disk name.
+func collectCacheStats(l lvm.LVM, nodeId string) ([]metrics.DataCacheStats, error) {
+	lvs, err := l.ListLogicalVolumes(getVolumeGroupName(nodeId))
+	if err != nil {
+		return nil, fmt.Errorf("errored while listing cached logical volumes: %w", err)
+	}
+	stats := []metrics.DataCacheStats{}
+	for _, lv := range lvs {
+		if lv.Cache == nil || !strings.HasPrefix(lv.Name, mainLvSuffix+"-") {
+			continue
+		}
+		s := metrics.DataCacheStats{
+			VolumeID:    strings.TrimPrefix(lv.Name, mainLvSuffix+"-"),
+			Policy:      lv.Cache.Policy,
+			Mode:        lv.Cache.Mode,
+			ReadHits:    lv.Cache.ReadHits,
+			ReadMisses:  lv.Cache.ReadMisses,
+			WriteHits:   lv.Cache.WriteHits,
+			WriteMisses: lv.Cache.WriteMisses,
+			TotalBlocks: lv.Cache.TotalBlocks,
+			UsedBlocks:  lv.Cache.UsedBlocks,
+			DirtyBlocks: lv.Cache.DirtyBlocks,
+		}
+		for _, tag := range lv.Tags {
+			if volumeId, ok := strings.CutPrefix(tag, volumeIdTagPrefix); ok {
+				s.VolumeID = volumeId
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
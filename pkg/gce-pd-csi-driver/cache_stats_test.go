@@ -0,0 +1,96 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
+)
+
+func TestCollectCacheStats(t *testing.T) {
+	l := newDataCacheLVM(t)
+	stats, err := collectCacheStats(l, testCacheNodeID)
+	if err != nil {
+		t.Fatalf("collectCacheStats failed: %v", err)
+	}
+	if len(stats) != 0 {
+		t.Errorf("Expected no stats before caching, got %+v", stats)
+	}
+
+	setupTestCache(t, l, "100", constants.DataCacheModeWriteBack)
+	vgName := getVolumeGroupName(testCacheNodeID)
+	err = l.SetCacheStatus(vgName, "csi-main-test-disk", lvm.CacheStatus{
+		Mode:        constants.DataCacheModeWriteBack,
+		Policy:      "smq",
+		TotalBlocks: 81920,
+		UsedBlocks:  10240,
+		DirtyBlocks: 12,
+		ReadHits:    4000,
+		ReadMisses:  500,
+		WriteHits:   3000,
+		WriteMisses: 20,
+	})
+	if err != nil {
+		t.Fatalf("SetCacheStatus failed: %v", err)
+	}
+	// A cache attached before main LVs were tagged is reported with the disk
+	// name.
+	l.AddDevice("/dev/sdc", int64(10*GiB))
+	if err := l.ExtendVolumeGroup(vgName, "/dev/sdc"); err != nil {
+		t.Fatalf("ExtendVolumeGroup failed: %v", err)
+	}
+	if err := l.CreateLogicalVolume(lvm.CreateLogicalVolumeOptions{Name: "csi-main-old-disk", VGName: vgName, Extents: "100%PVS", PVs: []string{"/dev/sdc"}}); err != nil {
+		t.Fatalf("CreateLogicalVolume failed: %v", err)
+	}
+	if err := l.CreateLogicalVolume(lvm.CreateLogicalVolumeOptions{Name: "csi-fast-old-disk", VGName: vgName, SizeGiB: 10}); err != nil {
+		t.Fatalf("CreateLogicalVolume failed: %v", err)
+	}
+	if err := l.AttachCache(vgName, "csi-main-old-disk", lvm.AttachCacheOptions{CacheLVName: "csi-fast-old-disk", Mode: constants.DataCacheModeWriteThrough, ChunkSize: "160KiB"}); err != nil {
+		t.Fatalf("AttachCache failed: %v", err)
+	}
+
+	stats, err = collectCacheStats(l, testCacheNodeID)
+	if err != nil {
+		t.Fatalf("collectCacheStats failed: %v", err)
+	}
+	want := []metrics.DataCacheStats{
+		{
+			VolumeID: "old-disk",
+			Policy:   "smq",
+			Mode:     constants.DataCacheModeWriteThrough,
+		},
+		{
+			VolumeID:    testCacheVolumeID,
+			Policy:      "smq",
+			Mode:        constants.DataCacheModeWriteBack,
+			TotalBlocks: 81920,
+			UsedBlocks:  10240,
+			DirtyBlocks: 12,
+			ReadHits:    4000,
+			ReadMisses:  500,
+			WriteHits:   3000,
+			WriteMisses: 20,
+		},
+	}
+	if diff := cmp.Diff(want, stats); diff != "" {
+		t.Errorf("Unexpected stats (-want +got):\n%s", diff)
+	}
+}
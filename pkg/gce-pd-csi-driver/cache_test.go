@@ -1,7 +1,24 @@
 package gceGCEDriver
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/mount-utils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	metadataservice "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/metadata"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
+	mountmanager "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/mount-manager"
 )
 
 func TestFetchChunkSizeKiB(t *testing.T) {
@@ -61,62 +78,439 @@ func TestFetchChunkSizeKiB(t *testing.T) {
 
 }
 
-func TestFetchNumberGiB(t *testing.T) {
+func TestPvSizeGiB(t *testing.T) {
+	testCases := []struct {
+		name      string
+		sizeBytes int64
+		expOutput string
+	}{
+		{
+			name:      "5GB",
+			sizeBytes: 5000000000,
+			expOutput: "5GiB",
+		},
+		{
+			name:      "1 LSSD attached",
+			sizeBytes: 375000000000,
+			expOutput: "350GiB",
+		},
+		{
+			name:      "24 LSSD attached",
+			sizeBytes: 9000000000000,
+			expOutput: "8382GiB",
+		},
+		{
+			name:      "less than 1GiB is rounded up",
+			sizeBytes: 900000,
+			expOutput: "1GiB",
+		},
+	}
+	for _, tc := range testCases {
+		if v := pvSizeGiB(tc.sizeBytes); v != tc.expOutput {
+			t.Errorf("%s: got %s want %s", tc.name, v, tc.expOutput)
+		}
+	}
+}
+
+const (
+	testCacheNodeID   = "test-node"
+	testCacheVolumeID = "projects/test-project/zones/us-central1-c/disks/test-disk"
+	testCachePdPath   = "/dev/sdb"
+)
+
+// newDataCacheLVM returns a FakeLVM with two RAIDed 375GiB local SSDs in the
+// volume group of the node, and a 100GiB PD at testCachePdPath.
+func newDataCacheLVM(t *testing.T) *lvm.FakeLVM {
+	t.Helper()
+	l := lvm.NewFakeLVM()
+	l.AddDevice("/dev/nvme0n1", int64(375*GiB))
+	l.AddDevice("/dev/nvme1n1", int64(375*GiB))
+	l.AddDevice(testCachePdPath, int64(100*GiB))
+	if err := RaidLocalSsds(l, []string{"/dev/nvme0n1", "/dev/nvme1n1"}); err != nil {
+		t.Fatalf("RaidLocalSsds failed: %v", err)
+	}
+	if err := InitializeDataCacheNode(l, testCacheNodeID); err != nil {
+		t.Fatalf("InitializeDataCacheNode failed: %v", err)
+	}
+	return l
+}
+
+func cacheStageRequest(cacheSize, cacheMode string) *csi.NodeStageVolumeRequest {
+	return &csi.NodeStageVolumeRequest{
+		VolumeId: testCacheVolumeID,
+		PublishContext: map[string]string{
+			constants.ContextDataCacheSize: cacheSize,
+			constants.ContextDataCacheMode: cacheMode,
+		},
+	}
+}
+
+// setupTestCache stages testCacheVolumeID with a cache and returns the main
+// LV of the volume.
+func setupTestCache(t *testing.T, l *lvm.FakeLVM, cacheSize, cacheMode string) *lvm.LogicalVolume {
+	t.Helper()
+	path, r, err := setupCaching(l, testCachePdPath, cacheStageRequest(cacheSize, cacheMode), testCacheNodeID, false)
+	if err != nil {
+		t.Fatalf("setupCaching failed: %v", err)
+	}
+	if r != nil {
+		t.Errorf("Expected no reconfiguration, got %+v", r)
+	}
+	vgName := getVolumeGroupName(testCacheNodeID)
+	if want := "/dev/" + vgName + "/csi-main-test-disk"; path != want {
+		t.Errorf("Expected path %q, got %q", want, path)
+	}
+	return getTestLv(t, l, "csi-main-test-disk")
+}
+
+func getTestLv(t *testing.T, l *lvm.FakeLVM, lvName string) *lvm.LogicalVolume {
+	t.Helper()
+	lvs, err := l.ListLogicalVolumes(getVolumeGroupName(testCacheNodeID))
+	if err != nil {
+		t.Fatalf("ListLogicalVolumes failed: %v", err)
+	}
+	return findLogicalVolume(lvs, lvName)
+}
+
+func TestSetupCaching(t *testing.T) {
+	l := newDataCacheLVM(t)
+	mainLv := setupTestCache(t, l, "100", constants.DataCacheModeWriteBack)
+	if mainLv == nil || mainLv.Cache == nil {
+		t.Fatalf("Expected cached main LV, got %+v", mainLv)
+	}
+	if mainLv.Cache.Mode != constants.DataCacheModeWriteBack {
+		t.Errorf("Expected cache mode %q, got %q", constants.DataCacheModeWriteBack, mainLv.Cache.Mode)
+	}
+	if diff := cmp.Diff([]string{volumeIdTag(testCacheVolumeID)}, mainLv.Tags); diff != "" {
+		t.Errorf("Unexpected tags (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{testCachePdPath}, mainLv.Devices); diff != "" {
+		t.Errorf("Main LV must only be on the PD (-want +got):\n%s", diff)
+	}
+	cacheLv := getTestLv(t, l, "csi-fast-test-disk"+lvm.CacheVolSuffix)
+	if cacheLv == nil || cacheLv.SizeBytes != int64(100*GiB) {
+		t.Errorf("Expected 100GiB cache LV, got %+v", cacheLv)
+	}
+
+	// Staging again with the same configuration keeps the cache.
+	_, r, err := setupCaching(l, testCachePdPath, cacheStageRequest("100", constants.DataCacheModeWriteBack), testCacheNodeID, false)
+	if err != nil {
+		t.Fatalf("setupCaching failed: %v", err)
+	}
+	if r == nil || r.outcome != cacheReconfigureUnchanged {
+		t.Errorf("Expected unchanged cache, got %+v", r)
+	}
+
+	// The retry of the stage of a staged volume doesn't look at its cache.
+	l.Calls = nil
+	_, r, err = setupCaching(l, testCachePdPath, cacheStageRequest("200", constants.DataCacheModeWriteThrough), testCacheNodeID, true)
+	if err != nil {
+		t.Fatalf("setupCaching failed: %v", err)
+	}
+	if r != nil {
+		t.Errorf("Expected no reconfiguration of a staged volume, got %+v", r)
+	}
+	if slices.Contains(l.Calls, "SplitCache") {
+		t.Errorf("Expected the cache of a staged volume to be kept, got calls %v", l.Calls)
+	}
+	if mainLv := getTestLv(t, l, "csi-main-test-disk"); mainLv.Cache == nil || mainLv.Cache.Mode != constants.DataCacheModeWriteBack {
+		t.Errorf("Expected writeback cache, got %+v", mainLv)
+	}
+
+	// Caches larger than the local SSDs are rejected.
+	l.AddDevice("/dev/sdc", int64(10*GiB))
+	req := cacheStageRequest("1000", constants.DataCacheModeWriteBack)
+	req.VolumeId = "projects/test-project/zones/us-central1-c/disks/other-disk"
+	_, _, err = setupCaching(l, "/dev/sdc", req, testCacheNodeID, false)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a cache larger than the local SSDs, got %v", err)
+	}
+}
+
+func TestExpandCachedVolume(t *testing.T) {
+	vgName := getVolumeGroupName(testCacheNodeID)
+	testCases := []struct {
+		name    string
+		cached  bool
+		errors  map[string]error
+		expPath string
+		expErr  bool
+	}{
+		{
+			name: "volume without cache",
+		},
+		{
+			name:    "cached volume is extended",
+			cached:  true,
+			expPath: "/dev/" + vgName + "/csi-main-test-disk",
+		},
+		{
+			name:   "pvresize fails",
+			cached: true,
+			errors: map[string]error{"ResizePhysicalVolume": errors.New("exit status 5")},
+			expErr: true,
+		},
+		{
+			name:   "lvextend fails",
+			cached: true,
+			errors: map[string]error{"ExtendLogicalVolume": errors.New("Insufficient free space")},
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newDataCacheLVM(t)
+			if tc.cached {
+				setupTestCache(t, l, "100", constants.DataCacheModeWriteThrough)
+			}
+			l.AddDevice(testCachePdPath, int64(200*GiB))
+			for method, err := range tc.errors {
+				l.Errors[method] = err
+			}
+			path, err := expandCachedVolume(l, testCachePdPath, testCacheVolumeID, testCacheNodeID)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if path != tc.expPath {
+				t.Errorf("Expected path %q, got %q", tc.expPath, path)
+			}
+			if tc.expPath == "" {
+				return
+			}
+			if mainLv := getTestLv(t, l, "csi-main-test-disk"); mainLv.SizeBytes != int64(200*GiB) {
+				t.Errorf("Expected main LV of 200GiB, got %d bytes", mainLv.SizeBytes)
+			}
+		})
+	}
+}
+
+func TestReconfigureCache(t *testing.T) {
+	vgName := getVolumeGroupName(testCacheNodeID)
+	mainLv := "csi-main-test-disk"
+	cacheLv := "csi-fast-test-disk"
 	testCases := []struct {
 		name        string
-		stringInput []string
-		expOutput   string // Outputs value in GiB
+		currentMode string
+		dirtyBlocks uint64
+		cacheSize   string
+		cacheMode   string
+		errors      map[string]error
+		expOutcome  string
 		expErr      bool
 	}{
 		{
-			name:        "valid input 1",
-			stringInput: []string{"5000000000B"},
-			expOutput:   "5GiB", //range defined in fetchChunkSizeKiB
+			name:        "same size and mode",
+			currentMode: constants.DataCacheModeWriteThrough,
+			cacheSize:   "100",
+			cacheMode:   constants.DataCacheModeWriteThrough,
+			expOutcome:  cacheReconfigureUnchanged,
 		},
 		{
-			name:        "valid input 2",
-			stringInput: []string{"375000000000B"}, // 1 LSSD attached
-			expOutput:   "350GiB",                  //range defined in fetchChunkSizeKiB
+			name:        "grow cache",
+			currentMode: constants.DataCacheModeWriteThrough,
+			cacheSize:   "200",
+			cacheMode:   constants.DataCacheModeWriteThrough,
+			expOutcome:  cacheReconfigured,
 		},
 		{
-			name:        "valid input 3",
-			stringInput: []string{"9000000000000B"}, // 24 LSSD attached
-			expOutput:   "8382GiB",                  //range defined in fetchChunkSizeKiB
+			name:        "switch clean writeback cache to writethrough",
+			currentMode: constants.DataCacheModeWriteBack,
+			cacheSize:   "100",
+			cacheMode:   constants.DataCacheModeWriteThrough,
+			expOutcome:  cacheReconfigured,
 		},
 		{
-			name:        "valid input 4",
-			stringInput: []string{"Some text before ", "9000000000000B", "Some text after"}, // 24 LSSD attached
-			expOutput:   "8382GiB",                                                          //range defined in fetchChunkSizeKiB
+			name:        "dirty writeback cache is kept",
+			currentMode: constants.DataCacheModeWriteBack,
+			dirtyBlocks: 42,
+			cacheSize:   "100",
+			cacheMode:   constants.DataCacheModeWriteThrough,
+			expOutcome:  cacheReconfigureRefused,
 		},
 		{
-			name:        "invalid input 1",
-			stringInput: []string{"9000000000000"},
+			name:        "detaching cache fails",
+			currentMode: constants.DataCacheModeWriteBack,
+			cacheSize:   "200",
+			cacheMode:   constants.DataCacheModeWriteBack,
+			errors:      map[string]error{"SplitCache": errors.New("exit status 5")},
+			expOutcome:  cacheReconfigureFailed,
 			expErr:      true,
 		},
 		{
-			name:        "invalid input 2",
-			stringInput: []string{"A9000000000000B"},
+			name:        "listing logical volumes fails",
+			currentMode: constants.DataCacheModeWriteBack,
+			cacheSize:   "100",
+			cacheMode:   constants.DataCacheModeWriteBack,
+			errors:      map[string]error{"ListLogicalVolumes": errors.New("exit status 5")},
+			expOutcome:  cacheReconfigureFailed,
 			expErr:      true,
 		},
-		{
-			name:        "valid input 5",
-			stringInput: []string{"900000B"}, // <1GiB gets rounded off to 0GiB
-			expOutput:   "1GiB",
-		},
 	}
-
 	for _, tc := range testCases {
-		v, err := fetchNumberGiB(tc.stringInput)
-		if err != nil {
-			if !tc.expErr {
-				t.Errorf("Errored %s", err)
+		t.Run(tc.name, func(t *testing.T) {
+			l := newDataCacheLVM(t)
+			setupTestCache(t, l, "100", tc.currentMode)
+			if err := l.SetCacheStatus(vgName, mainLv, lvm.CacheStatus{Mode: tc.currentMode, DirtyBlocks: tc.dirtyBlocks}); err != nil {
+				t.Fatalf("SetCacheStatus failed: %v", err)
 			}
-			continue
+			for method, err := range tc.errors {
+				l.Errors[method] = err
+			}
+			r, err := reconfigureCache(l, vgName, mainLv, cacheLv, tc.cacheSize, tc.cacheMode)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if r.outcome != tc.expOutcome {
+				t.Errorf("Expected outcome %q, got %q (%s)", tc.expOutcome, r.outcome, r.message)
+			}
+			if tc.expOutcome != cacheReconfigured {
+				return
+			}
+			// The old cache is removed so that setupCaching creates the new one.
+			l.Errors = map[string]error{}
+			if lv := getTestLv(t, l, cacheLv); lv != nil {
+				t.Errorf("Expected cache LV to be removed, got %+v", lv)
+			}
+			if lv := getTestLv(t, l, mainLv); lv.SegType == lvm.SegTypeCache {
+				t.Errorf("Expected main LV to be uncached, got %+v", lv)
+			}
+		})
+	}
+}
+
+func TestRestageWithDifferentCache(t *testing.T) {
+	l := newDataCacheLVM(t)
+	setupTestCache(t, l, "100", constants.DataCacheModeWriteBack)
+
+	_, r, err := setupCaching(l, testCachePdPath, cacheStageRequest("200", constants.DataCacheModeWriteThrough), testCacheNodeID, false)
+	if err != nil {
+		t.Fatalf("setupCaching failed: %v", err)
+	}
+	if r == nil || r.outcome != cacheReconfigured {
+		t.Fatalf("Expected reconfigured cache, got %+v", r)
+	}
+	mainLv := getTestLv(t, l, "csi-main-test-disk")
+	if mainLv.Cache == nil || mainLv.Cache.Mode != constants.DataCacheModeWriteThrough {
+		t.Errorf("Expected writethrough cache, got %+v", mainLv)
+	}
+	if cacheLv := getTestLv(t, l, "csi-fast-test-disk"+lvm.CacheVolSuffix); cacheLv == nil || cacheLv.SizeBytes != int64(200*GiB) {
+		t.Errorf("Expected 200GiB cache LV, got %+v", cacheLv)
+	}
+}
+
+// devicePathDeviceUtils finds every disk at devicePath.
+type devicePathDeviceUtils struct {
+	deviceutils.DeviceUtils
+	devicePath string
+}
+
+func (d *devicePathDeviceUtils) VerifyDevicePath(devicePaths []string, deviceName string) (string, error) {
+	return d.devicePath, nil
+}
+
+func TestUnstageRestageWithDifferentCache(t *testing.T) {
+	// The PD is a file, as NodeStageVolume resolves its device path.
+	devicePath := filepath.Join(t.TempDir(), "sdb")
+	if err := os.WriteFile(devicePath, nil, 0644); err != nil {
+		t.Fatalf("Failed to create device: %v", err)
+	}
+	nodeId := metadataservice.FakeName
+	l := lvm.NewFakeLVM()
+	l.AddDevice("/dev/nvme0n1", int64(375*GiB))
+	l.AddDevice(devicePath, int64(100*GiB))
+	if err := RaidLocalSsds(l, []string{"/dev/nvme0n1"}); err != nil {
+		t.Fatalf("RaidLocalSsds failed: %v", err)
+	}
+	if err := InitializeDataCacheNode(l, nodeId); err != nil {
+		t.Fatalf("InitializeDataCacheNode failed: %v", err)
+	}
+	recorder := k8sclient.NewFakeEventRecorder()
+	du := &devicePathDeviceUtils{DeviceUtils: deviceutils.NewFakeDeviceUtils(false), devicePath: devicePath}
+	gceDriver := getCustomTestGCEDriver(t, mountmanager.NewCustomFakeSafeMounter(&mount.FakeMounter{MountPoints: []mount.MountPoint{}}, nil), du, metadataservice.NewFakeService(), &NodeServerArgs{
+		EnableDataCache:          true,
+		DataCacheEnabledNodePool: true,
+		LVM:                      l,
+		EventRecorder:            recorder,
+	})
+	stagingPath := filepath.Join(t.TempDir(), "globalmount")
+	stage := func(cacheSize, cacheMode string) {
+		t.Helper()
+		req := cacheStageRequest(cacheSize, cacheMode)
+		req.StagingTargetPath = stagingPath
+		req.VolumeCapability = &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
 		}
-		if v != tc.expOutput {
-			t.Errorf("Got %s want %s", v, tc.expOutput)
+		if _, err := gceDriver.ns.NodeStageVolume(context.Background(), req); err != nil {
+			t.Fatalf("NodeStageVolume failed: %v", err)
 		}
+	}
+	getLv := func(lvName string) *lvm.LogicalVolume {
+		t.Helper()
+		lvs, err := l.ListLogicalVolumes(getVolumeGroupName(nodeId))
+		if err != nil {
+			t.Fatalf("ListLogicalVolumes failed: %v", err)
+		}
+		return findLogicalVolume(lvs, lvName)
+	}
+
+	stage("100", constants.DataCacheModeWriteBack)
+	if _, err := gceDriver.ns.NodeUnstageVolume(context.Background(), &csi.NodeUnstageVolumeRequest{VolumeId: testCacheVolumeID, StagingTargetPath: stagingPath}); err != nil {
+		t.Fatalf("NodeUnstageVolume failed: %v", err)
+	}
+	if cacheLv := getLv("csi-fast-test-disk" + lvm.CacheVolSuffix); cacheLv != nil {
+		t.Errorf("Expected cache LV to be removed on unstage, got %+v", cacheLv)
+	}
 
+	// The volume is staged again with the new publish context of its new
+	// attachment.
+	stage("200", constants.DataCacheModeWriteThrough)
+	mainLv := getLv("csi-main-test-disk")
+	if mainLv == nil || mainLv.Cache == nil || mainLv.Cache.Mode != constants.DataCacheModeWriteThrough {
+		t.Errorf("Expected writethrough cache, got %+v", mainLv)
+	}
+	if cacheLv := getLv("csi-fast-test-disk" + lvm.CacheVolSuffix); cacheLv == nil || cacheLv.SizeBytes != int64(200*GiB) {
+		t.Errorf("Expected 200GiB cache LV, got %+v", cacheLv)
+	}
+	// The cache was recreated rather than reconfigured in place.
+	if events := recorder.Events(); len(events) != 0 {
+		t.Errorf("Expected no reconfiguration events, got %v", events)
 	}
+}
+
+func TestCleanupCache(t *testing.T) {
+	l := newDataCacheLVM(t)
+	setupTestCache(t, l, "100", constants.DataCacheModeWriteThrough)
 
+	if err := cleanupCache(l, testCacheVolumeID, testCacheNodeID); err != nil {
+		t.Fatalf("cleanupCache failed: %v", err)
+	}
+	mainLv := getTestLv(t, l, "csi-main-test-disk")
+	if mainLv.SegType == lvm.SegTypeCache || mainLv.Active {
+		t.Errorf("Expected inactive uncached main LV, got %+v", mainLv)
+	}
+	if cacheLv := getTestLv(t, l, "csi-fast-test-disk"+lvm.CacheVolSuffix); cacheLv != nil {
+		t.Errorf("Expected cache LV to be removed, got %+v", cacheLv)
+	}
+	// Cleaning up again, e.g. on a retried NodeUnstageVolume, is a no-op.
+	if err := cleanupCache(l, testCacheVolumeID, testCacheNodeID); err != nil {
+		t.Errorf("Second cleanupCache failed: %v", err)
+	}
+}
+
+func TestReportCacheReconfiguration(t *testing.T) {
+	recorder := k8sclient.NewFakeEventRecorder()
+	ns := &GCENodeServer{EventRecorder: recorder}
+	ns.reportCacheReconfiguration("test-volume", nil)
+	ns.reportCacheReconfiguration("test-volume", &cacheReconfiguration{outcome: cacheReconfigureUnchanged})
+	ns.reportCacheReconfiguration("test-volume", &cacheReconfiguration{outcome: cacheReconfigured, message: "changed cache"})
+	ns.reportCacheReconfiguration("test-volume", &cacheReconfiguration{outcome: cacheReconfigureRefused, message: "dirty"})
+
+	want := []string{
+		"Normal DataCacheReconfigured Volume test-volume: changed cache",
+		"Warning DataCacheReconfigureRefused Volume test-volume: dirty",
+	}
+	if diff := cmp.Diff(want, recorder.Events()); diff != "" {
+		t.Errorf("Unexpected events (-want +got):\n%s", diff)
+	}
 }
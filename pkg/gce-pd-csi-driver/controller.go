@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
@@ -54,9 +55,6 @@ type GCEControllerServer struct {
 	volumeEntries     []*csi.ListVolumesResponse_Entry
 	volumeEntriesSeen map[string]int
 
-	snapshots      []*csi.ListSnapshotsResponse_Entry
-	snapshotTokens map[string]int
-
 	// A map storing all volumes with ongoing operations so that additional
 	// operations for that same volume (as defined by Volume Key) return an
 	// Aborted error
@@ -119,8 +117,36 @@ type GCEControllerServer struct {
 
 	listVolumesConfig ListVolumesConfig
 
+	// provisionableDisksConfig can be updated while the driver runs, see
+	// SetProvisionableDisksConfig.
+	provisionableDisksMutex  sync.RWMutex
 	provisionableDisksConfig ProvisionableDisksConfig
 
+	// regionalFailover force attaches regional disks out of failed zones,
+	// nil if disabled.
+	regionalFailover *RegionalFailover
+
+	// finalSnapshotCollector deletes the expired final snapshots, nil if
+	// disabled.
+	finalSnapshotCollector *FinalSnapshotCollector
+
+	// allowedProjects are the projects the project parameter of
+	// StorageClasses may provision disks in.
+	allowedProjects []string
+
+	// clusterName is recorded in the description of the disks, and checked
+	// by deletion protection.
+	clusterName string
+
+	// deletionProtection refuses DeleteVolume of protected disks, nil if
+	// disabled.
+	deletionProtection *DeletionProtection
+
+	// diskTypeMigrations holds the volume IDs whose disk was deleted by a
+	// disk type migration which has not created the new disk yet, so that
+	// DeleteVolume deletes their migration snapshot.
+	diskTypeMigrations sync.Map
+
 	// Embed UnimplementedControllerServer to ensure the driver returns Unimplemented for any
 	// new RPC methods that might be introduced in future versions of the spec.
 	csi.UnimplementedControllerServer
@@ -132,6 +158,19 @@ type GCEControllerServer struct {
 type GCEControllerServerArgs struct {
 	EnableDiskTopology       bool
 	EnableDiskSizeValidation bool
+	// RegionalFailover is nil if regional failover is disabled.
+	RegionalFailover *RegionalFailover
+	// FinalSnapshotCollector is nil if the final snapshot collector is
+	// disabled.
+	FinalSnapshotCollector *FinalSnapshotCollector
+	// AllowedProjects are the projects the project parameter of
+	// StorageClasses may provision disks in.
+	AllowedProjects []string
+	// ClusterName is the name of the cluster of the driver, empty if
+	// unknown.
+	ClusterName string
+	// DeletionProtection is nil if deletion protection is disabled.
+	DeletionProtection *DeletionProtection
 }
 
 type MultiZoneVolumeHandleConfig struct {
@@ -308,6 +347,10 @@ func useVolumeCloning(req *csi.CreateVolumeRequest) bool {
 }
 
 func (gceCS *GCEControllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	ctx, err := gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	response, err := gceCS.createVolumeInternal(ctx, req)
 	if err != nil && req != nil {
 		klog.V(4).Infof("CreateVolume succeeded for volume %v", req.Name)
@@ -348,17 +391,20 @@ func (gceCS *GCEControllerServer) createVolumeInternal(ctx context.Context, req
 	// https://github.com/container-storage-interface/spec/blob/master/spec.md#createvolume
 	// mutable_parameters MUST take precedence over the values from parameters.
 	mutableParams := req.GetMutableParameters()
-	// If the disk type does not support dynamic provisioning, throw an error
-	supportsIopsChange := gceCS.diskSupportsIopsChange(params.DiskType)
-	supportsThroughputChange := gceCS.diskSupportsThroughputChange(params.DiskType)
 	if len(mutableParams) > 0 {
-		if !supportsIopsChange && !supportsThroughputChange {
-			return nil, status.Errorf(codes.InvalidArgument, "Disk type %s does not support dynamic provisioning", params.DiskType)
-		}
 		p, err := parameters.ExtractModifyVolumeParameters(mutableParams)
 		if err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "Invalid mutable parameters: %v", err)
 		}
+		if p.DiskType != "" {
+			params.DiskType = p.DiskType
+		}
+		// If the disk type does not support dynamic provisioning, throw an error
+		supportsIopsChange := gceCS.diskSupportsIopsChange(params.DiskType)
+		supportsThroughputChange := gceCS.diskSupportsThroughputChange(params.DiskType)
+		if (p.IOPS != nil || p.Throughput != nil) && !supportsIopsChange && !supportsThroughputChange {
+			return nil, status.Errorf(codes.InvalidArgument, "Disk type %s does not support dynamic provisioning", params.DiskType)
+		}
 		if p.IOPS != nil {
 			if !supportsIopsChange {
 				return nil, status.Errorf(codes.InvalidArgument, "Cannot specify IOPS for disk type %s", params.DiskType)
@@ -371,13 +417,36 @@ func (gceCS *GCEControllerServer) createVolumeInternal(ctx context.Context, req
 			}
 			params.ProvisionedThroughputOnCreate = *p.Throughput
 		}
+		for labelKey, labelValue := range p.Labels {
+			params.Labels[labelKey] = labelValue
+		}
+		for tagParentIDKey, tagValue := range p.ResourceTags {
+			params.ResourceTags[tagParentIDKey] = tagValue
+		}
+		if p.AccessMode != "" {
+			// The access mode of new disks follows their volume capabilities,
+			// which must agree with the one of the VolumeAttributesClass.
+			if !slices.Contains(disksWithModifiableAccessMode, params.DiskType) {
+				return nil, status.Errorf(codes.InvalidArgument, "Cannot specify access mode for disk type %s", params.DiskType)
+			}
+			readonly, _ := getReadOnlyFromCapabilities(volumeCapabilities)
+			if readonly != (p.AccessMode == constants.GCEReadOnlyManyAccessMode) {
+				return nil, status.Errorf(codes.InvalidArgument, "Access mode %s conflicts with the volume capabilities", p.AccessMode)
+			}
+		}
 	}
 
 	// Validate multiwriter
 	if _, err := getMultiWriterFromCapabilities(volumeCapabilities); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "VolumeCapabilities is invalid: %v", err.Error())
 	}
-	err = validateStoragePools(req, params, gceCS.CloudProvider.GetDefaultProject())
+	project := diskProject(gceCS.cloudProvider(ctx), params)
+	if params.Project != "" {
+		if err := validateResourceTagsProject(project, params.ResourceTags); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "CreateVolume failed to validate resource tags: %v", err)
+		}
+	}
+	err = validateStoragePools(req, params, project)
 	if err != nil {
 		// Reassign error so that all errors are reported as InvalidArgument to RecordOperationErrorMetrics.
 		err = status.Errorf(codes.InvalidArgument, "CreateVolume failed to validate storage pools: %v", err)
@@ -425,8 +494,8 @@ func (gceCS *GCEControllerServer) createVolumeInternal(ctx context.Context, req
 }
 
 func (gceCS *GCEControllerServer) getSupportedZonesForPDType(ctx context.Context, zones []string, diskType string) ([]string, error) {
-	project := gceCS.CloudProvider.GetDefaultProject()
-	zones, err := gceCS.CloudProvider.ListCompatibleDiskTypeZones(ctx, project, zones, diskType)
+	project := gceCS.cloudProvider(ctx).GetDefaultProject()
+	zones, err := gceCS.cloudProvider(ctx).ListCompatibleDiskTypeZones(ctx, project, zones, diskType)
 	if err != nil {
 		return nil, err
 	}
@@ -438,6 +507,10 @@ func (gceCS *GCEControllerServer) getMultiZoneProvisioningZones(ctx context.Cont
 	if top == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "no topology specified")
 	}
+	top, err := compatibleTopology(top, params.DiskType)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to pick zones for disk: %v", err.Error())
+	}
 	prefZones, err := getZonesFromTopology(top.GetPreferred())
 	if err != nil {
 		return nil, fmt.Errorf("could not get zones from preferred topology: %w", err)
@@ -487,7 +560,7 @@ func (gceCS *GCEControllerServer) createMultiZoneDisk(ctx context.Context, req *
 	}
 
 	multiZoneVolKey := meta.ZonalKey(req.GetName(), constants.MultiZoneValue)
-	volumeID, err := common.KeyToVolumeID(multiZoneVolKey, gceCS.CloudProvider.GetDefaultProject())
+	volumeID, err := common.KeyToVolumeID(multiZoneVolKey, diskProject(gceCS.cloudProvider(ctx), params))
 	if err != nil {
 		return nil, err
 	}
@@ -525,16 +598,17 @@ func (gceCS *GCEControllerServer) createMultiZoneDisk(ctx context.Context, req *
 	}
 
 	// Use the first response as a template
-	volumeId := fmt.Sprintf("projects/%s/zones/%s/disks/%s", gceCS.CloudProvider.GetDefaultProject(), constants.MultiZoneValue, req.GetName())
+	volumeId := fmt.Sprintf("projects/%s/zones/%s/disks/%s", diskProject(gceCS.cloudProvider(ctx), params), constants.MultiZoneValue, req.GetName())
 	klog.V(4).Infof("CreateVolume succeeded for multi-zone disks in zones %s: %v", zones, multiZoneVolKey)
 
-	return gceCS.generateCreateVolumeResponseWithVolumeId(createdDisks[0], zones, params, dataCacheParams, enableDataCache, volumeId), nil
+	diskTypeTopology := hasDiskTypeTopology(req.GetAccessibilityRequirements(), params.DiskType)
+	return gceCS.generateCreateVolumeResponseWithVolumeId(createdDisks[0], zones, params, dataCacheParams, enableDataCache, volumeId, diskTypeTopology), nil
 }
 
 func (gceCS *GCEControllerServer) getZonesWithDiskNameAndType(ctx context.Context, name string, diskType string) ([]string, error) {
 	zoneOnlyFields := []googleapi.Field{"items/zone", "items/type"}
 	nameAndRegionFilter := fmt.Sprintf("name=%s", name)
-	disksWithZone, _, err := gceCS.CloudProvider.ListDisksWithFilter(ctx, zoneOnlyFields, nameAndRegionFilter)
+	disksWithZone, _, err := gceCS.cloudProvider(ctx).ListDisksWithFilter(ctx, zoneOnlyFields, nameAndRegionFilter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing zones for disk name %v: %w", name, err)
 	}
@@ -569,7 +643,7 @@ func (gceCS *GCEControllerServer) updateAccessModeIfNecessary(ctx context.Contex
 		return nil
 	}
 
-	return gceCS.CloudProvider.SetDiskAccessMode(ctx, project, volKey, constants.GCEReadOnlyManyAccessMode)
+	return gceCS.cloudProvider(ctx).SetDiskAccessMode(ctx, project, volKey, constants.GCEReadOnlyManyAccessMode)
 }
 
 func (gceCS *GCEControllerServer) createSingleDeviceDisk(ctx context.Context, req *csi.CreateVolumeRequest, params parameters.DiskParameters, dataCacheParams parameters.DataCacheParameters, enableDataCache bool) (*csi.CreateVolumeResponse, error) {
@@ -586,7 +660,7 @@ func (gceCS *GCEControllerServer) createSingleDeviceDisk(ctx context.Context, re
 	var zones []string
 	var volKey *meta.Key
 	if params.IsRegional() {
-		zones, err = gceCS.pickZones(ctx, req.GetAccessibilityRequirements(), 2, locationTopReq)
+		zones, err = gceCS.pickZones(ctx, req.GetAccessibilityRequirements(), 2, locationTopReq, params.DiskType)
 		if err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "CreateVolume failed to pick zones for disk: %v", err.Error())
 		}
@@ -596,7 +670,7 @@ func (gceCS *GCEControllerServer) createSingleDeviceDisk(ctx context.Context, re
 		}
 		volKey = meta.RegionalKey(req.GetName(), region)
 	} else if params.ReplicationType == replicationTypeNone {
-		zones, err = gceCS.pickZones(ctx, req.GetAccessibilityRequirements(), 1, locationTopReq)
+		zones, err = gceCS.pickZones(ctx, req.GetAccessibilityRequirements(), 1, locationTopReq, params.DiskType)
 		if err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "CreateVolume failed to pick zones for disk: %v", err.Error())
 		}
@@ -607,8 +681,13 @@ func (gceCS *GCEControllerServer) createSingleDeviceDisk(ctx context.Context, re
 	} else {
 		return nil, status.Errorf(codes.InvalidArgument, "CreateVolume replication type '%s' is not supported", params.ReplicationType)
 	}
+	if len(params.AsyncReplicationSecondaryZones) > 0 {
+		if _, err := asyncSecondaryKey(volKey, params); err != nil {
+			return nil, err
+		}
+	}
 
-	volumeID, err := common.KeyToVolumeID(volKey, gceCS.CloudProvider.GetDefaultProject())
+	volumeID, err := common.KeyToVolumeID(volKey, diskProject(gceCS.cloudProvider(ctx), params))
 	if err != nil {
 		return nil, common.LoggedError("Failed to convert volume key to volume ID: ", err)
 	}
@@ -634,7 +713,20 @@ func (gceCS *GCEControllerServer) createSingleDeviceDisk(ctx context.Context, re
 		return nil, common.LoggedError("CreateVolume failed: %v", err)
 	}
 
-	return gceCS.generateCreateVolumeResponseWithVolumeId(disk, zones, params, dataCacheParams, enableDataCache, volumeID), nil
+	diskTypeTopology := hasDiskTypeTopology(req.GetAccessibilityRequirements(), params.DiskType)
+	resp := gceCS.generateCreateVolumeResponseWithVolumeId(disk, zones, params, dataCacheParams, enableDataCache, volumeID, diskTypeTopology)
+	if len(params.AsyncReplicationSecondaryZones) > 0 {
+		secondaryVolumeID, err := gceCS.setupAsyncReplication(ctx, req, params, volKey, accessMode)
+		if err != nil {
+			return nil, common.LoggedError("CreateVolume failed to set up asynchronous replication: ", err)
+		}
+		if resp.Volume.VolumeContext == nil {
+			resp.Volume.VolumeContext = map[string]string{}
+		}
+		resp.Volume.VolumeContext[constants.ContextAsyncReplicationSecondaryVolume] = secondaryVolumeID
+		resp.Volume.VolumeContext[constants.ContextAsyncReplicationSecondaryZones] = strings.Join(params.AsyncReplicationSecondaryZones, ",")
+	}
+	return resp, nil
 }
 
 func getAccessMode(req *csi.CreateVolumeRequest, params parameters.DiskParameters) (string, error) {
@@ -670,7 +762,7 @@ func (gceCS *GCEControllerServer) createSingleDisk(ctx context.Context, req *csi
 	}
 
 	// Validate if disk already exists
-	existingDisk, err := gceCS.CloudProvider.GetDisk(ctx, gceCS.CloudProvider.GetDefaultProject(), volKey)
+	existingDisk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, diskProject(gceCS.cloudProvider(ctx), params), volKey)
 	if err != nil {
 		if !gce.IsGCEError(err, "notFound") {
 			// failed to GetDisk, however the Disk may already be created, the error code should be non-Final
@@ -725,7 +817,7 @@ func (gceCS *GCEControllerServer) createSingleDisk(ctx context.Context, req *csi
 			}
 
 			// Verify that the volume in VolumeContentSource exists.
-			diskFromSourceVolume, err := gceCS.CloudProvider.GetDisk(ctx, project, sourceVolKey)
+			diskFromSourceVolume, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, sourceVolKey)
 			if err != nil {
 				if gce.IsGCEError(err, "notFound") {
 					return nil, status.Errorf(codes.NotFound, "CreateVolume source volume %s does not exist", volumeContentSourceVolumeID)
@@ -783,7 +875,7 @@ func (gceCS *GCEControllerServer) createSingleDisk(ctx context.Context, req *csi
 		if len(zones) != 2 {
 			return nil, status.Errorf(codes.Internal, "CreateVolume failed to get a 2 zones for creating regional disk, instead got: %v", zones)
 		}
-		disk, err = createRegionalDisk(ctx, gceCS.CloudProvider, name, zones, params, capacityRange, capBytes, snapshotID, volumeContentSourceVolumeID, multiWriter, accessMode)
+		disk, err = createRegionalDisk(ctx, gceCS.cloudProvider(ctx), name, zones, params, capacityRange, capBytes, snapshotID, volumeContentSourceVolumeID, multiWriter, accessMode)
 		if err != nil {
 			return nil, common.LoggedError("CreateVolume failed to create regional disk "+name+": ", err)
 		}
@@ -791,7 +883,7 @@ func (gceCS *GCEControllerServer) createSingleDisk(ctx context.Context, req *csi
 		if len(zones) != 1 {
 			return nil, status.Errorf(codes.Internal, "CreateVolume failed to get a single zone for creating zonal disk, instead got: %v", zones)
 		}
-		disk, err = createSingleZoneDisk(ctx, gceCS.CloudProvider, name, zones, params, capacityRange, capBytes, snapshotID, volumeContentSourceVolumeID, multiWriter, accessMode)
+		disk, err = createSingleZoneDisk(ctx, gceCS.cloudProvider(ctx), name, zones, params, capacityRange, capBytes, snapshotID, volumeContentSourceVolumeID, multiWriter, accessMode)
 		if err != nil {
 			return nil, common.LoggedError("CreateVolume failed to create single zonal disk "+name+": ", err)
 		}
@@ -811,7 +903,17 @@ func (gceCS *GCEControllerServer) createSingleDisk(ctx context.Context, req *csi
 	return disk, nil
 }
 
+// SetProvisionableDisksConfig replaces the disk types that support dynamic
+// IOPS and throughput provisioning.
+func (gceCS *GCEControllerServer) SetProvisionableDisksConfig(c ProvisionableDisksConfig) {
+	gceCS.provisionableDisksMutex.Lock()
+	defer gceCS.provisionableDisksMutex.Unlock()
+	gceCS.provisionableDisksConfig = c
+}
+
 func (gceCS *GCEControllerServer) diskSupportsIopsChange(diskType string) bool {
+	gceCS.provisionableDisksMutex.RLock()
+	defer gceCS.provisionableDisksMutex.RUnlock()
 	for _, disk := range gceCS.provisionableDisksConfig.SupportsIopsChange {
 		if disk == diskType {
 			return true
@@ -821,6 +923,8 @@ func (gceCS *GCEControllerServer) diskSupportsIopsChange(diskType string) bool {
 }
 
 func (gceCS *GCEControllerServer) diskSupportsThroughputChange(diskType string) bool {
+	gceCS.provisionableDisksMutex.RLock()
+	defer gceCS.provisionableDisksMutex.RUnlock()
 	for _, disk := range gceCS.provisionableDisksConfig.SupportsThroughputChange {
 		if disk == diskType {
 			return true
@@ -831,6 +935,10 @@ func (gceCS *GCEControllerServer) diskSupportsThroughputChange(diskType string)
 
 func (gceCS *GCEControllerServer) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 
 	volumeID := req.GetVolumeId()
 	klog.V(4).Infof("Modifying Volume ID: %s", volumeID)
@@ -854,10 +962,24 @@ func (gceCS *GCEControllerServer) ControllerModifyVolume(ctx context.Context, re
 	}
 	klog.V(4).Infof("Modify Volume Parameters for %s: %v", volumeID, volumeModifyParams)
 
-	existingDisk, err := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
+	if acquired := gceCS.volumeLocks.TryAcquire(volumeID); !acquired {
+		return nil, status.Errorf(codes.Aborted, constants.VolumeOperationAlreadyExistsFmt, volumeID)
+	}
+	defer gceCS.volumeLocks.Release(volumeID)
+
+	var existingDisk *gce.CloudDisk
+	if volumeModifyParams.DiskType != "" {
+		// The other modifications apply to the migrated disk.
+		existingDisk, err = gceCS.migrateDiskType(ctx, project, volKey, volumeModifyParams)
+	} else {
+		existingDisk, err = gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
+	}
 	metrics.UpdateRequestMetadataFromDisk(ctx, existingDisk)
 
 	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
 		err = fmt.Errorf("Failed to get volume: %w", err)
 		return nil, err
 	}
@@ -867,35 +989,120 @@ func (gceCS *GCEControllerServer) ControllerModifyVolume(ctx context.Context, re
 		return nil, err
 	}
 
-	// Check if the disk supports dynamic IOPS/Throughput provisioning
-	diskType := existingDisk.GetPDType()
-	supportsIopsChange := gceCS.diskSupportsIopsChange(diskType)
-	supportsThroughputChange := gceCS.diskSupportsThroughputChange(diskType)
-	if !supportsIopsChange && !supportsThroughputChange {
-		err = status.Errorf(codes.InvalidArgument, "Failed to modify volume: modifications not supported for disk type %s", diskType)
+	if err := gceCS.validateModifyVolume(ctx, project, existingDisk, volumeModifyParams); err != nil {
 		return nil, err
 	}
-	if !supportsIopsChange && volumeModifyParams.IOPS != nil {
-		err = status.Errorf(codes.InvalidArgument, "Cannot specify IOPS for disk type %s", diskType)
-		return nil, err
+
+	if performanceChanged(existingDisk, volumeModifyParams) {
+		err = gceCS.cloudProvider(ctx).UpdateDisk(ctx, project, volKey, existingDisk, volumeModifyParams)
+		if err != nil {
+			klog.Errorf("Failed to modify volume %s: %v", volumeID, err)
+			err = fmt.Errorf("Failed to modify volume %s: %w", volumeID, err)
+			return nil, err
+		}
 	}
-	if !supportsThroughputChange && volumeModifyParams.Throughput != nil {
-		err = status.Errorf(codes.InvalidArgument, "Cannot specify throughput for disk type %s", diskType)
-		return nil, err
+
+	if labels, changed := mergeDiskLabels(existingDisk.GetLabels(), volumeModifyParams.Labels); changed {
+		err = gceCS.cloudProvider(ctx).SetDiskLabels(ctx, project, volKey, existingDisk, labels)
+		if err != nil {
+			klog.Errorf("Failed to set labels of volume %s: %v", volumeID, err)
+			err = fmt.Errorf("Failed to set labels of volume %s: %w", volumeID, err)
+			return nil, err
+		}
 	}
 
-	err = gceCS.CloudProvider.UpdateDisk(ctx, project, volKey, existingDisk, volumeModifyParams)
-	if err != nil {
-		klog.Errorf("Failed to modify volume %s: %v", volumeID, err)
-		err = fmt.Errorf("Failed to modify volume %s: %w", volumeID, err)
-		return nil, err
+	if len(volumeModifyParams.ResourceTags) > 0 {
+		err = gceCS.cloudProvider(ctx).SetDiskResourceTags(ctx, project, volKey, existingDisk, volumeModifyParams.ResourceTags)
+		if err != nil {
+			klog.Errorf("Failed to set resource tags of volume %s: %v", volumeID, err)
+			err = fmt.Errorf("Failed to set resource tags of volume %s: %w", volumeID, err)
+			return nil, err
+		}
+	}
+
+	if accessMode := volumeModifyParams.AccessMode; accessMode != "" && accessMode != existingDisk.GetAccessMode() {
+		err = gceCS.cloudProvider(ctx).SetDiskAccessMode(ctx, project, volKey, accessMode)
+		if err != nil {
+			klog.Errorf("Failed to set access mode of volume %s: %v", volumeID, err)
+			err = fmt.Errorf("Failed to set access mode of volume %s: %w", volumeID, err)
+			return nil, err
+		}
 	}
 
 	return &csi.ControllerModifyVolumeResponse{}, nil
 }
 
+// performanceChanged returns whether params set a provisioned IOPS or
+// throughput other than the disk's, e.g. one not already set by a disk type
+// migration.
+func performanceChanged(disk *gce.CloudDisk, params parameters.ModifyVolumeParameters) bool {
+	if params.IOPS != nil && *params.IOPS != disk.GetProvisionedIops() {
+		return true
+	}
+	return params.Throughput != nil && *params.Throughput != disk.GetProvisionedThroughput()
+}
+
+// validateModifyVolume checks that the disk supports the modifications of
+// params.
+func (gceCS *GCEControllerServer) validateModifyVolume(ctx context.Context, project string, disk *gce.CloudDisk, params parameters.ModifyVolumeParameters) error {
+	// Check if the disk supports dynamic IOPS/Throughput provisioning
+	diskType := disk.GetPDType()
+	supportsIopsChange := gceCS.diskSupportsIopsChange(diskType)
+	supportsThroughputChange := gceCS.diskSupportsThroughputChange(diskType)
+	if params.IOPS != nil || params.Throughput != nil {
+		if !supportsIopsChange && !supportsThroughputChange {
+			return status.Errorf(codes.InvalidArgument, "Failed to modify volume: modifications not supported for disk type %s", diskType)
+		}
+		if !supportsIopsChange && params.IOPS != nil {
+			return status.Errorf(codes.InvalidArgument, "Cannot specify IOPS for disk type %s", diskType)
+		}
+		if !supportsThroughputChange && params.Throughput != nil {
+			return status.Errorf(codes.InvalidArgument, "Cannot specify throughput for disk type %s", diskType)
+		}
+	}
+
+	if len(params.ResourceTags) > 0 && project != gceCS.cloudProvider(ctx).GetDefaultProject() {
+		if err := validateResourceTagsProject(project, params.ResourceTags); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Failed to validate resource tags: %v", err)
+		}
+	}
+
+	if params.AccessMode != "" && params.AccessMode != disk.GetAccessMode() {
+		if !slices.Contains(disksWithModifiableAccessMode, diskType) {
+			return status.Errorf(codes.InvalidArgument, "Cannot specify access mode for disk type %s", diskType)
+		}
+		// GCE only changes the access mode of detached disks. The disk may
+		// still be detached later, so the modification is retried.
+		if users := disk.GetUsers(); len(users) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "Cannot change the access mode of disk %s to %s while it is attached to %v", disk.GetName(), params.AccessMode, users)
+		}
+	}
+	return nil
+}
+
+// mergeDiskLabels returns the labels of a disk with the labels of a
+// VolumeAttributesClass, and whether they differ from the labels of the disk.
+func mergeDiskLabels(diskLabels, labels map[string]string) (map[string]string, bool) {
+	merged := make(map[string]string, len(diskLabels)+len(labels))
+	for k, v := range diskLabels {
+		merged[k] = v
+	}
+	changed := false
+	for k, v := range labels {
+		if current, ok := merged[k]; !ok || current != v {
+			merged[k] = v
+			changed = true
+		}
+	}
+	return merged, changed
+}
+
 func (gceCS *GCEControllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	// Validate arguments
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
@@ -931,7 +1138,7 @@ func getGCEApiVersion(multiWriter bool) gce.GCEAPIVersion {
 func (gceCS *GCEControllerServer) deleteMultiZoneDisk(ctx context.Context, req *csi.DeleteVolumeRequest, project string, volKey *meta.Key) (*csi.DeleteVolumeResponse, error) {
 	// List disks with same name
 	var err error
-	existingZones := []string{gceCS.CloudProvider.GetDefaultZone()}
+	existingZones := []string{gceCS.cloudProvider(ctx).GetDefaultZone()}
 	zones, err := getDefaultZonesInRegion(ctx, gceCS, existingZones)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list default zones: %w", err)
@@ -943,19 +1150,51 @@ func (gceCS *GCEControllerServer) deleteMultiZoneDisk(ctx context.Context, req *
 	}
 	defer gceCS.volumeLocks.Release(volumeID)
 
-	deleteDiskErrs := []error{}
+	// Check all the zonal disks before deleting any of them, so that a
+	// refused deletion leaves the volume whole.
+	zonalVolKeys := make([]*meta.Key, 0, len(zones))
+	zonalDisks := make([]*gce.CloudDisk, 0, len(zones))
 	for _, zone := range zones {
 		zonalVolKey := &meta.Key{
 			Name:   volKey.Name,
 			Region: volKey.Region,
 			Zone:   zone,
 		}
-		disk, _ := gceCS.CloudProvider.GetDisk(ctx, project, zonalVolKey)
+		disk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, zonalVolKey)
+		if err != nil && !gce.IsGCENotFoundError(err) {
+			// The disk may need a final snapshot before it is deleted.
+			return nil, common.LoggedError("Failed to get disk: ", err)
+		}
 		// TODO: Consolidate the parameters here, rather than taking the last.
 		metrics.UpdateRequestMetadataFromDisk(ctx, disk)
-		err := gceCS.CloudProvider.DeleteDisk(ctx, project, zonalVolKey)
 		if err != nil {
-			deleteDiskErrs = append(deleteDiskErrs, gceCS.CloudProvider.DeleteDisk(ctx, project, volKey))
+			disk = nil
+		} else if err := gceCS.checkDeletionProtection(ctx, volumeID, project, zonalVolKey, disk); err != nil {
+			return nil, err
+		}
+		zonalVolKeys = append(zonalVolKeys, zonalVolKey)
+		zonalDisks = append(zonalDisks, disk)
+	}
+
+	deleteDiskErrs := []error{}
+	finalSnapshotTaken := false
+	for i, zonalVolKey := range zonalVolKeys {
+		disk := zonalDisks[i]
+		if disk != nil && !finalSnapshotTaken {
+			// The disks of all the zones have the same content, one
+			// final snapshot is enough.
+			multiZoneVolumeID, err := common.KeyToVolumeID(volKey, project)
+			if err != nil {
+				return nil, common.LoggedError("Failed to get multi-zone volume ID: ", err)
+			}
+			if err := gceCS.takeFinalSnapshot(ctx, multiZoneVolumeID, project, zonalVolKey, disk); err != nil {
+				return nil, err
+			}
+			finalSnapshotTaken = true
+		}
+		err = gceCS.cloudProvider(ctx).DeleteDisk(ctx, project, zonalVolKey)
+		if err != nil {
+			deleteDiskErrs = append(deleteDiskErrs, gceCS.cloudProvider(ctx).DeleteDisk(ctx, project, volKey))
 		}
 	}
 
@@ -970,7 +1209,7 @@ func (gceCS *GCEControllerServer) deleteMultiZoneDisk(ctx context.Context, req *
 func (gceCS *GCEControllerServer) deleteSingleDeviceDisk(ctx context.Context, req *csi.DeleteVolumeRequest, project string, volKey *meta.Key) (*csi.DeleteVolumeResponse, error) {
 	var err error
 	volumeID := req.GetVolumeId()
-	project, volKey, err = gceCS.CloudProvider.RepairUnderspecifiedVolumeKey(ctx, project, volKey)
+	project, volKey, err = gceCS.cloudProvider(ctx).RepairUnderspecifiedVolumeKey(ctx, project, volKey)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			klog.Warningf("DeleteVolume treating volume as deleted because cannot find volume %v: %v", volumeID, err.Error())
@@ -983,12 +1222,47 @@ func (gceCS *GCEControllerServer) deleteSingleDeviceDisk(ctx context.Context, re
 		return nil, status.Errorf(codes.Aborted, constants.VolumeOperationAlreadyExistsFmt, volumeID)
 	}
 	defer gceCS.volumeLocks.Release(volumeID)
-	disk, _ := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
+	disk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
+	if err != nil && !gce.IsGCENotFoundError(err) {
+		// The disk may need a final snapshot before it is deleted.
+		return nil, common.LoggedError("Failed to get disk: ", err)
+	}
 	metrics.UpdateRequestMetadataFromDisk(ctx, disk)
-	err = gceCS.CloudProvider.DeleteDisk(ctx, project, volKey)
+	if err == nil {
+		if err := gceCS.checkDeletionProtection(ctx, volumeID, project, volKey, disk); err != nil {
+			return nil, err
+		}
+	}
+	if disk != nil {
+		diskVolumeID, err := common.KeyToVolumeID(volKey, project)
+		if err != nil {
+			return nil, common.LoggedError("Failed to get volume ID: ", err)
+		}
+		if err := gceCS.takeFinalSnapshot(ctx, diskVolumeID, project, volKey, disk); err != nil {
+			return nil, err
+		}
+		if err := gceCS.deleteAsyncSecondaryDisks(ctx, project, volKey, disk); err != nil {
+			return nil, common.LoggedError("Failed to delete secondary disks: ", err)
+		}
+	}
+	err = gceCS.cloudProvider(ctx).DeleteDisk(ctx, project, volKey)
 	if err != nil {
 		return nil, common.LoggedError("Failed to delete disk: ", err)
 	}
+	if volKey.Type() == meta.Zonal {
+		diskVolumeID, err := common.KeyToVolumeID(volKey, project)
+		if err != nil {
+			return nil, common.LoggedError("Failed to get volume ID: ", err)
+		}
+		_, migrating := gceCS.diskTypeMigrations.Load(diskVolumeID)
+		if migrating || (disk != nil && disk.GetLabels()[constants.DiskTypeMigrationLabel] != "") {
+			// The volume was deleted during a disk type migration.
+			if err := deleteMigrationSnapshot(ctx, gceCS.cloudProvider(ctx), project, migrationSnapshotName(diskVolumeID, volKey.Name)); err != nil {
+				return nil, err
+			}
+			gceCS.diskTypeMigrations.Delete(diskVolumeID)
+		}
+	}
 
 	klog.V(4).Infof("DeleteVolume succeeded for disk %v", volKey)
 	return &csi.DeleteVolumeResponse{}, nil
@@ -996,6 +1270,10 @@ func (gceCS *GCEControllerServer) deleteSingleDeviceDisk(ctx context.Context, re
 
 func (gceCS *GCEControllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	// Only valid requests will be accepted
 	_, _, _, err = gceCS.validateControllerPublishVolumeRequest(ctx, req)
 	if err != nil {
@@ -1146,7 +1424,7 @@ func (gceCS *GCEControllerServer) executeControllerPublishVolume(ctx context.Con
 		volKey = convertMultiZoneVolKeyToZoned(volKey, instanceZone)
 	}
 
-	project, volKey, err = gceCS.CloudProvider.RepairUnderspecifiedVolumeKey(ctx, project, volKey)
+	project, volKey, err = gceCS.cloudProvider(ctx).RepairUnderspecifiedVolumeKey(ctx, project, volKey)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			return nil, status.Errorf(codes.NotFound, "ControllerPublishVolume could not find volume with ID %v: %v", volumeID, err.Error()), nil
@@ -1161,17 +1439,21 @@ func (gceCS *GCEControllerServer) executeControllerPublishVolume(ctx context.Con
 		return nil, status.Errorf(codes.Aborted, constants.VolumeOperationAlreadyExistsFmt, lockingVolumeID), nil
 	}
 	defer gceCS.volumeLocks.Release(lockingVolumeID)
-	disk, err := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
+	disk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			return nil, status.Errorf(codes.NotFound, "Could not find disk %v: %v", volKey.String(), err.Error()), disk
 		}
 		return nil, common.LoggedError("Failed to getDisk: ", err), disk
 	}
+	if diskType, ok := disk.GetLabels()[constants.DiskTypeMigrationLabel]; ok {
+		// The disk is about to be replaced by a disk of another type.
+		return nil, status.Errorf(codes.Unavailable, "Disk %v is being migrated to disk type %s", volKey.String(), diskType), disk
+	}
 	if gceCS.EnableDiskSizeValidation && pubVolResp.GetPublishContext() != nil {
 		pubVolResp.PublishContext[constants.ContextDiskSizeGB] = strconv.FormatInt(disk.GetSizeGb(), 10)
 	}
-	instance, err := gceCS.CloudProvider.GetInstanceOrError(ctx, project, instanceZone, instanceName)
+	instance, err := gceCS.cloudProvider(ctx).GetInstanceOrError(ctx, project, instanceZone, instanceName)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			return nil, status.Errorf(codes.NotFound, "Could not find instance %v: %v", nodeID, err.Error()), disk
@@ -1211,7 +1493,11 @@ func (gceCS *GCEControllerServer) executeControllerPublishVolume(ctx context.Con
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "could not split nodeID: %v", err.Error()), disk
 	}
-	err = gceCS.CloudProvider.AttachDisk(ctx, project, volKey, readWrite, attachableDiskTypePersistent, instanceZone, instanceName, pdcsiContext.ForceAttach)
+	forceAttach := pdcsiContext.ForceAttach
+	if !forceAttach && gceCS.regionalFailover != nil {
+		forceAttach = gceCS.regionalFailover.allowForceAttach(volKey, instanceZone, instanceName)
+	}
+	err = gceCS.cloudProvider(ctx).AttachDisk(ctx, project, volKey, readWrite, attachableDiskTypePersistent, instanceZone, instanceName, forceAttach)
 	if err != nil {
 		var udErr *gce.UnsupportedDiskError
 		if errors.As(err, &udErr) {
@@ -1223,7 +1509,7 @@ func (gceCS *GCEControllerServer) executeControllerPublishVolume(ctx context.Con
 		return nil, common.LoggedError("Failed to Attach: ", err), disk
 	}
 
-	err = gceCS.CloudProvider.WaitForAttach(ctx, project, volKey, disk.GetPDType(), instanceZone, instanceName)
+	err = gceCS.cloudProvider(ctx).WaitForAttach(ctx, project, volKey, disk.GetPDType(), instanceZone, instanceName)
 	if err != nil {
 		return nil, common.LoggedError("Errored during WaitForAttach: ", err), disk
 	}
@@ -1234,6 +1520,10 @@ func (gceCS *GCEControllerServer) executeControllerPublishVolume(ctx context.Con
 
 func (gceCS *GCEControllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	_, _, err = gceCS.validateControllerUnpublishVolumeRequest(ctx, req)
 	if err != nil {
 		return nil, err
@@ -1294,7 +1584,7 @@ func (gceCS *GCEControllerServer) executeControllerUnpublishVolume(ctx context.C
 		volKey = convertMultiZoneVolKeyToZoned(volKey, instanceZone)
 	}
 
-	project, volKey, err = gceCS.CloudProvider.RepairUnderspecifiedVolumeKey(ctx, project, volKey)
+	project, volKey, err = gceCS.cloudProvider(ctx).RepairUnderspecifiedVolumeKey(ctx, project, volKey)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			klog.Warningf("Treating volume %v as unpublished because it could not be found", volumeID)
@@ -1310,8 +1600,12 @@ func (gceCS *GCEControllerServer) executeControllerUnpublishVolume(ctx context.C
 		return nil, status.Errorf(codes.Aborted, constants.VolumeOperationAlreadyExistsFmt, lockingVolumeID), nil
 	}
 	defer gceCS.volumeLocks.Release(lockingVolumeID)
-	diskToUnpublish, _ := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
-	instance, err := gceCS.CloudProvider.GetInstanceOrError(ctx, project, instanceZone, instanceName)
+	if gceCS.regionalFailover != nil && gceCS.regionalFailover.skipDetach(volKey, instanceZone, instanceName) {
+		klog.V(4).Infof("ControllerUnpublishVolume succeeded for disk %v from node %v in a failed zone without detaching it", volKey, nodeID)
+		return &csi.ControllerUnpublishVolumeResponse{}, nil, nil
+	}
+	diskToUnpublish, _ := gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
+	instance, err := gceCS.cloudProvider(ctx).GetInstanceOrError(ctx, project, instanceZone, instanceName)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			// Node not existing on GCE means that disk has been detached
@@ -1333,7 +1627,7 @@ func (gceCS *GCEControllerServer) executeControllerUnpublishVolume(ctx context.C
 		klog.V(4).Infof("ControllerUnpublishVolume succeeded for disk %v from node %v. Already not attached.", volKey, nodeID)
 		return &csi.ControllerUnpublishVolumeResponse{}, nil, diskToUnpublish
 	}
-	err = gceCS.CloudProvider.DetachDisk(ctx, project, deviceName, instanceZone, instanceName)
+	err = gceCS.cloudProvider(ctx).DetachDisk(ctx, project, deviceName, instanceZone, instanceName)
 	if err != nil {
 		return nil, common.LoggedError("Failed to detach: ", err), diskToUnpublish
 	}
@@ -1349,6 +1643,8 @@ func (gceCS *GCEControllerServer) parameterProcessor() *parameters.ParameterProc
 		EnableMultiZone:    gceCS.multiZoneVolumeHandleConfig.Enable,
 		EnableHdHA:         gceCS.enableHdHA,
 		EnableDiskTopology: gceCS.EnableDiskTopology,
+		AllowedProjects:    gceCS.allowedProjects,
+		ClusterName:        gceCS.clusterName,
 	}
 }
 
@@ -1366,7 +1662,7 @@ func (gceCS *GCEControllerServer) ValidateVolumeCapabilities(ctx context.Context
 		return nil, status.Errorf(codes.InvalidArgument, "Volume ID is invalid: %v", err.Error())
 	}
 
-	project, volKey, err = gceCS.CloudProvider.RepairUnderspecifiedVolumeKey(ctx, project, volKey)
+	project, volKey, err = gceCS.cloudProvider(ctx).RepairUnderspecifiedVolumeKey(ctx, project, volKey)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
 			return nil, status.Errorf(codes.NotFound, "ValidateVolumeCapabilities could not find volume with ID %v: %v", volumeID, err.Error())
@@ -1379,7 +1675,7 @@ func (gceCS *GCEControllerServer) ValidateVolumeCapabilities(ctx context.Context
 	}
 	defer gceCS.volumeLocks.Release(volumeID)
 
-	disk, err := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
+	disk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
 	metrics.UpdateRequestMetadataFromDisk(ctx, disk)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
@@ -1430,14 +1726,14 @@ func generateFailedValidationMessage(format string, a ...interface{}) *csi.Valid
 }
 
 func (gceCS *GCEControllerServer) listVolumeEntries(ctx context.Context) ([]*csi.ListVolumesResponse_Entry, error) {
-	diskList, _, err := gceCS.CloudProvider.ListDisks(ctx, gceCS.listVolumesConfig.listDisksFields())
+	diskList, _, err := gceCS.cloudProvider(ctx).ListDisks(ctx, gceCS.listVolumesConfig.listDisksFields())
 	if err != nil {
 		return nil, err
 	}
 
 	var instanceList []*compute.Instance = nil
 	if gceCS.listVolumesConfig.UseInstancesAPIForPublishedNodes {
-		instanceList, _, err = gceCS.CloudProvider.ListInstances(ctx, listInstancesFields)
+		instanceList, _, err = gceCS.cloudProvider(ctx).ListInstances(ctx, listInstancesFields)
 		if err != nil {
 			return nil, err
 		}
@@ -1597,6 +1893,10 @@ func (gceCS *GCEControllerServer) ControllerGetCapabilities(ctx context.Context,
 
 func (gceCS *GCEControllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	// Validate arguments
 	volumeID := req.GetSourceVolumeId()
 	if len(req.Name) == 0 {
@@ -1621,7 +1921,7 @@ func (gceCS *GCEControllerServer) CreateSnapshot(ctx context.Context, req *csi.C
 	defer gceCS.volumeLocks.Release(volumeID)
 
 	// Check if volume exists
-	disk, err := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
+	disk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
 	metrics.UpdateRequestMetadataFromDisk(ctx, disk)
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
@@ -1630,7 +1930,7 @@ func (gceCS *GCEControllerServer) CreateSnapshot(ctx context.Context, req *csi.C
 		return nil, common.LoggedError("CreateSnapshot, failed to getDisk: ", err)
 	}
 
-	snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(req.GetParameters(), gceCS.Driver.name, gceCS.Driver.extraTags)
+	snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(req.GetParameters(), gceCS.Driver.name, gceCS.clusterName, gceCS.Driver.extraTags)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid snapshot parameters: %v", err.Error())
 	}
@@ -1666,13 +1966,13 @@ func (gceCS *GCEControllerServer) createPDSnapshot(ctx context.Context, project
 
 	// Check if PD snapshot already exists
 	var snapshot *compute.Snapshot
-	snapshot, err = gceCS.CloudProvider.GetSnapshot(ctx, project, snapshotName)
+	snapshot, err = gceCS.cloudProvider(ctx).GetSnapshot(ctx, project, snapshotName)
 	if err != nil {
 		if !gce.IsGCEError(err, "notFound") {
 			return nil, common.LoggedError("Failed to get snapshot: ", err)
 		}
 		// If we could not find the snapshot, we create a new one
-		snapshot, err = gceCS.CloudProvider.CreateSnapshot(ctx, project, volKey, snapshotName, snapshotParams)
+		snapshot, err = gceCS.cloudProvider(ctx).CreateSnapshot(ctx, project, volKey, snapshotName, snapshotParams)
 		if err != nil {
 			if gce.IsGCEError(err, "notFound") {
 				return nil, status.Errorf(codes.NotFound, "Could not find volume with ID %v: %v", volKey.String(), err.Error())
@@ -1727,13 +2027,13 @@ func (gceCS *GCEControllerServer) createImage(ctx context.Context, project strin
 
 	// Check if image already exists
 	var image *compute.Image
-	image, err = gceCS.CloudProvider.GetImage(ctx, project, imageName)
+	image, err = gceCS.cloudProvider(ctx).GetImage(ctx, project, imageName)
 	if err != nil {
 		if !gce.IsGCEError(err, "notFound") {
 			return nil, common.LoggedError("Failed to get image: ", err)
 		}
 		// create a new image
-		image, err = gceCS.CloudProvider.CreateImage(ctx, project, volKey, imageName, snapshotParams)
+		image, err = gceCS.cloudProvider(ctx).CreateImage(ctx, project, volKey, imageName, snapshotParams)
 		if err != nil {
 			if gce.IsGCEError(err, "notFound") {
 				return nil, status.Errorf(codes.NotFound, "Could not find volume with ID %v: %v", volKey.String(), err.Error())
@@ -1863,6 +2163,10 @@ func isCSISnapshotReady(status string) (bool, error) {
 
 func (gceCS *GCEControllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	// Validate arguments
 	snapshotID := req.GetSnapshotId()
 	if len(snapshotID) == 0 {
@@ -1879,12 +2183,12 @@ func (gceCS *GCEControllerServer) DeleteSnapshot(ctx context.Context, req *csi.D
 
 	switch snapshotType {
 	case parameters.DiskSnapshotType:
-		err = gceCS.CloudProvider.DeleteSnapshot(ctx, project, key)
+		err = gceCS.cloudProvider(ctx).DeleteSnapshot(ctx, project, key)
 		if err != nil {
 			return nil, common.LoggedError("Failed to DeleteSnapshot: ", err)
 		}
 	case parameters.DiskImageType:
-		err = gceCS.CloudProvider.DeleteImage(ctx, project, key)
+		err = gceCS.cloudProvider(ctx).DeleteImage(ctx, project, key)
 		if err != nil {
 			return nil, common.LoggedError("Failed to DeleteImage error: ", err)
 		}
@@ -1896,9 +2200,21 @@ func (gceCS *GCEControllerServer) DeleteSnapshot(ctx context.Context, req *csi.D
 }
 
 func (gceCS *GCEControllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	ctx, err := gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	// case 1: SnapshotId is not empty, return snapshots that match the snapshot id.
 	if len(req.GetSnapshotId()) != 0 {
-		return gceCS.getSnapshotByID(ctx, req.GetSnapshotId())
+		resp, err := gceCS.getSnapshotByID(ctx, req.GetSnapshotId())
+		if err != nil || len(req.GetSourceVolumeId()) == 0 || len(resp.GetEntries()) == 0 {
+			return resp, err
+		}
+		// Both filters are set, the snapshot must be of the source volume.
+		if sourceVolumeID, ok := normalizeVolumeID(req.GetSourceVolumeId()); !ok || resp.GetEntries()[0].GetSnapshot().GetSourceVolumeId() != sourceVolumeID {
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+		return resp, nil
 	}
 
 	// case 2: no SnapshotId is set, so we return all the snapshots that satify the reqeust.
@@ -1908,46 +2224,30 @@ func (gceCS *GCEControllerServer) ListSnapshots(ctx context.Context, req *csi.Li
 			"ListSnapshots got max entries request %v. GCE only supports values >0", maxEntries)
 	}
 
-	var offset int
-	var length int
-	var ok bool
-	var nextToken string
-	if req.StartingToken == "" {
-		snapshotList, err := gceCS.getSnapshots(ctx, req)
-		if err != nil {
-			if gce.IsGCEInvalidError(err) {
-				return nil, status.Errorf(codes.Aborted, "ListSnapshots error with invalid request: %v", err.Error())
-			}
-			return nil, common.LoggedError("Failed to list snapshots: ", err)
-		}
-		gceCS.snapshots = snapshotList
-		gceCS.snapshotTokens = map[string]int{}
-	} else {
-		offset, ok = gceCS.snapshotTokens[req.StartingToken]
+	var filter string
+	if len(req.GetSourceVolumeId()) != 0 {
+		var ok bool
+		filter, ok = sourceDiskFilter(req.GetSourceVolumeId())
 		if !ok {
-			return nil, status.Errorf(codes.Aborted, "ListSnapshots error with invalid startingToken: %s", req.StartingToken)
+			return &csi.ListSnapshotsResponse{}, nil
 		}
 	}
-
-	if maxEntries == 0 {
-		maxEntries = len(gceCS.snapshots)
-	}
-	if maxEntries < len(gceCS.snapshots)-offset {
-		length = maxEntries
-		nextToken = string(uuid.NewUUID())
-		gceCS.snapshotTokens[nextToken] = length + offset
-	} else {
-		length = len(gceCS.snapshots) - offset
+	entries, nextToken, err := gceCS.listSnapshotEntries(ctx, filter, maxEntries, req.GetStartingToken())
+	if err != nil {
+		return nil, err
 	}
-
 	return &csi.ListSnapshotsResponse{
-		Entries:   gceCS.snapshots[offset : offset+length],
+		Entries:   entries,
 		NextToken: nextToken,
 	}, nil
 }
 
 func (gceCS *GCEControllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
 	var err error
+	ctx, err = gceCS.withSecrets(ctx, req.GetSecrets())
+	if err != nil {
+		return nil, err
+	}
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "ControllerExpandVolume volume ID must be provided")
@@ -1962,7 +2262,7 @@ func (gceCS *GCEControllerServer) ControllerExpandVolume(ctx context.Context, re
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "ControllerExpandVolume Volume ID is invalid: %v", err.Error())
 	}
-	project, volKey, err = gceCS.CloudProvider.RepairUnderspecifiedVolumeKey(ctx, project, volKey)
+	project, volKey, err = gceCS.cloudProvider(ctx).RepairUnderspecifiedVolumeKey(ctx, project, volKey)
 
 	if err != nil {
 		if gce.IsGCENotFoundError(err) {
@@ -1976,9 +2276,9 @@ func (gceCS *GCEControllerServer) ControllerExpandVolume(ctx context.Context, re
 		return nil, status.Errorf(codes.InvalidArgument, "ControllerExpandVolume is not supported with the multi-zone PVC volumeHandle feature. Please re-create the volume %v from source if you want a larger size", volumeID)
 	}
 
-	sourceDisk, err := gceCS.CloudProvider.GetDisk(ctx, project, volKey)
+	sourceDisk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, volKey)
 	metrics.UpdateRequestMetadataFromDisk(ctx, sourceDisk)
-	resizedGb, err := gceCS.CloudProvider.ResizeDisk(ctx, project, volKey, reqBytes)
+	resizedGb, err := gceCS.cloudProvider(ctx).ResizeDisk(ctx, project, volKey, reqBytes)
 
 	if err != nil {
 		return nil, common.LoggedError("ControllerExpandVolume failed to resize disk: ", err)
@@ -1991,51 +2291,6 @@ func (gceCS *GCEControllerServer) ControllerExpandVolume(ctx context.Context, re
 	}, nil
 }
 
-func (gceCS *GCEControllerServer) getSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) ([]*csi.ListSnapshotsResponse_Entry, error) {
-	var snapshots []*compute.Snapshot
-	var images []*compute.Image
-	var filter string
-	var err error
-	if len(req.GetSourceVolumeId()) != 0 {
-		filter = fmt.Sprintf("sourceDisk eq .*%s$", req.SourceVolumeId)
-	}
-	snapshots, _, err = gceCS.CloudProvider.ListSnapshots(ctx, filter)
-	if err != nil {
-		if gce.IsGCEError(err, "invalid") {
-			return nil, status.Errorf(codes.Aborted, "Invalid error: %v", err.Error())
-		}
-		return nil, common.LoggedError("Failed to list snapshot: ", err)
-	}
-
-	images, _, err = gceCS.CloudProvider.ListImages(ctx, filter)
-	if err != nil {
-		if gce.IsGCEError(err, "invalid") {
-			return nil, status.Errorf(codes.Aborted, "Invalid error: %v", err.Error())
-		}
-		return nil, common.LoggedError("Failed to list image: ", err)
-	}
-
-	entries := []*csi.ListSnapshotsResponse_Entry{}
-
-	for _, snapshot := range snapshots {
-		entry, err := generateDiskSnapshotEntry(snapshot)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate snapshot entry: %w", err)
-		}
-		entries = append(entries, entry)
-	}
-
-	for _, image := range images {
-		entry, err := generateDiskImageEntry(image)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate image entry: %w", err)
-		}
-		entries = append(entries, entry)
-	}
-
-	return entries, nil
-}
-
 func (gceCS *GCEControllerServer) getSnapshotByID(ctx context.Context, snapshotID string) (*csi.ListSnapshotsResponse, error) {
 	project, snapshotType, key, err := common.SnapshotIDToProjectKey(snapshotID)
 	if err != nil {
@@ -2047,7 +2302,7 @@ func (gceCS *GCEControllerServer) getSnapshotByID(ctx context.Context, snapshotI
 	var entries []*csi.ListSnapshotsResponse_Entry
 	switch snapshotType {
 	case parameters.DiskSnapshotType:
-		snapshot, err := gceCS.CloudProvider.GetSnapshot(ctx, project, key)
+		snapshot, err := gceCS.cloudProvider(ctx).GetSnapshot(ctx, project, key)
 		if err != nil {
 			if gce.IsGCEError(err, "notFound") {
 				// return empty list if no snapshot is found
@@ -2061,7 +2316,7 @@ func (gceCS *GCEControllerServer) getSnapshotByID(ctx context.Context, snapshotI
 		}
 		entries = []*csi.ListSnapshotsResponse_Entry{e}
 	case parameters.DiskImageType:
-		image, err := gceCS.CloudProvider.GetImage(ctx, project, key)
+		image, err := gceCS.cloudProvider(ctx).GetImage(ctx, project, key)
 		if err != nil {
 			if gce.IsGCEError(err, "notFound") {
 				// return empty list if no snapshot is found
@@ -2330,6 +2585,11 @@ func getZoneFromSegment(seg map[string]string) (string, error) {
 		case constants.TopologyKeyZone:
 			zone = v
 		default:
+			if strings.HasPrefix(k, constants.NodeDiskTypeKeyPrefix+"/") || strings.HasPrefix(k, constants.AttachLimitKeyPrefix+"/") {
+				// Disk type segments of the nodes are handled by
+				// compatibleTopology, attach limits are informational.
+				continue
+			}
 			return "", fmt.Errorf("topology segment has unknown key %v", k)
 		}
 	}
@@ -2339,16 +2599,62 @@ func getZoneFromSegment(seg map[string]string) (string, error) {
 	return zone, nil
 }
 
-func (gceCS *GCEControllerServer) pickZones(ctx context.Context, top *csi.TopologyRequirement, numZones int, locationTopReq *locationRequirements) ([]string, error) {
+// compatibleTopology returns top without the topologies whose nodes publish
+// diskType as not attachable by their machine series, see
+// GCENodeServer.diskTypeSegments. Topologies without disk type segments are
+// kept. It fails when none of the requisite topologies can attach diskType.
+func compatibleTopology(top *csi.TopologyRequirement, diskType string) (*csi.TopologyRequirement, error) {
+	if top == nil {
+		return nil, nil
+	}
+	key := common.NodeDiskTypeKey(diskType)
+	compatible := func(tops []*csi.Topology) []*csi.Topology {
+		var filtered []*csi.Topology
+		for _, t := range tops {
+			if v, ok := t.GetSegments()[key]; !ok || v == "true" {
+				filtered = append(filtered, t)
+			}
+		}
+		return filtered
+	}
+	filtered := &csi.TopologyRequirement{
+		Requisite: compatible(top.GetRequisite()),
+		Preferred: compatible(top.GetPreferred()),
+	}
+	if len(top.GetRequisite()) > 0 && len(filtered.Requisite) == 0 {
+		return nil, fmt.Errorf("no node in the requisite topology can attach disk type %s", diskType)
+	}
+	return filtered, nil
+}
+
+// hasDiskTypeTopology returns whether the nodes of top publish whether they
+// can attach diskType.
+func hasDiskTypeTopology(top *csi.TopologyRequirement, diskType string) bool {
+	key := common.NodeDiskTypeKey(diskType)
+	for _, tops := range [][]*csi.Topology{top.GetRequisite(), top.GetPreferred()} {
+		for _, t := range tops {
+			if _, ok := t.GetSegments()[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (gceCS *GCEControllerServer) pickZones(ctx context.Context, top *csi.TopologyRequirement, numZones int, locationTopReq *locationRequirements, diskType string) ([]string, error) {
 	var zones []string
 	var err error
 	if top != nil {
+		top, err = compatibleTopology(top, diskType)
+		if err != nil {
+			return nil, err
+		}
 		zones, err = pickZonesFromTopology(top, numZones, locationTopReq, gceCS.fallbackRequisiteZones)
 		if err != nil {
 			return nil, fmt.Errorf("failed to pick zones from topology: %w", err)
 		}
 	} else {
-		existingZones := []string{gceCS.CloudProvider.GetDefaultZone()}
+		existingZones := []string{gceCS.cloudProvider(ctx).GetDefaultZone()}
 		// We set existingZones to the source volume zone so that for zonal -> zonal cloning, the clone is provisioned
 		// in the same zone as the source volume, and for zonal -> regional, one of the replicated zones will always
 		// be the zone of the source volume. For regional -> regional cloning, the srcVolZone will not be set, so we
@@ -2372,7 +2678,7 @@ func getDefaultZonesInRegion(ctx context.Context, gceCS *GCEControllerServer, ex
 	if err != nil {
 		return nil, fmt.Errorf("failed to get region from zones: %w", err)
 	}
-	totZones, err := gceCS.CloudProvider.ListZones(ctx, region)
+	totZones, err := gceCS.cloudProvider(ctx).ListZones(ctx, region)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list zones from cloud provider: %w", err)
 	}
@@ -2403,6 +2709,12 @@ func paramsToVolumeContext(params parameters.DiskParameters) map[string]string {
 	if params.ForceAttach {
 		context[contextForceAttach] = "true"
 	}
+	if params.NodeEncryption != "" {
+		context[constants.ContextNodeEncryption] = params.NodeEncryption
+		if params.NodeEncryptionKMSKey != "" {
+			context[constants.ContextNodeEncryptionKMSKey] = params.NodeEncryptionKMSKey
+		}
+	}
 	if len(context) > 0 {
 		return context
 	}
@@ -2426,7 +2738,7 @@ func extractVolumeContext(context map[string]string) (*PDCSIContext, error) {
 	return info, nil
 }
 
-func (gceCS *GCEControllerServer) generateCreateVolumeResponseWithVolumeId(disk *gce.CloudDisk, zones []string, params parameters.DiskParameters, dataCacheParams parameters.DataCacheParameters, enableDataCache bool, volumeId string) *csi.CreateVolumeResponse {
+func (gceCS *GCEControllerServer) generateCreateVolumeResponseWithVolumeId(disk *gce.CloudDisk, zones []string, params parameters.DiskParameters, dataCacheParams parameters.DataCacheParameters, enableDataCache bool, volumeId string, diskTypeTopology bool) *csi.CreateVolumeResponse {
 	tops := []*csi.Topology{}
 	for _, zone := range zones {
 		top := &csi.Topology{
@@ -2442,6 +2754,11 @@ func (gceCS *GCEControllerServer) generateCreateVolumeResponseWithVolumeId(disk
 		if gceCS.EnableDiskTopology && params.UseAllowedDiskTopology {
 			top.Segments[common.DiskTypeLabelKey(params.DiskType)] = "true"
 		}
+		// When the nodes publish the disk types they can attach, the volume
+		// is only accessible from the nodes that can attach its disk type.
+		if diskTypeTopology {
+			top.Segments[common.NodeDiskTypeKey(params.DiskType)] = "true"
+		}
 
 		tops = append(tops, top)
 	}
@@ -2530,7 +2847,7 @@ func getResourceId(resourceLink string) (string, error) {
 }
 
 func createRegionalDisk(ctx context.Context, cloudProvider gce.GCECompute, name string, zones []string, params parameters.DiskParameters, capacityRange *csi.CapacityRange, capBytes int64, snapshotID string, volumeContentSourceVolumeID string, multiWriter bool, accessMode string) (*gce.CloudDisk, error) {
-	project := cloudProvider.GetDefaultProject()
+	project := diskProject(cloudProvider, params)
 	region, err := common.GetRegionFromZones(zones)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get region from zones: %w", err)
@@ -2556,7 +2873,7 @@ func createRegionalDisk(ctx context.Context, cloudProvider gce.GCECompute, name
 }
 
 func createSingleZoneDisk(ctx context.Context, cloudProvider gce.GCECompute, name string, zones []string, params parameters.DiskParameters, capacityRange *csi.CapacityRange, capBytes int64, snapshotID string, volumeContentSourceVolumeID string, multiWriter bool, accessMode string) (*gce.CloudDisk, error) {
-	project := cloudProvider.GetDefaultProject()
+	project := diskProject(cloudProvider, params)
 	if len(zones) != 1 {
 		return nil, fmt.Errorf("got wrong number of zones for zonal create volume: %v", len(zones))
 	}
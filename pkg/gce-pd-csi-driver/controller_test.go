@@ -699,6 +699,28 @@ func TestCreateVolumeArguments(t *testing.T) {
 				AccessibleTopology: stdTopology,
 			},
 		},
+		{
+			name: "success with node encryption",
+			req: &csi.CreateVolumeRequest{
+				Name:               "test-name",
+				CapacityRange:      stdCapRange,
+				VolumeCapabilities: stdVolCaps,
+				Parameters: map[string]string{
+					parameters.ParameterKeyType:                 stdDiskType,
+					parameters.ParameterKeyNodeEncryption:       "luks",
+					parameters.ParameterKeyNodeEncryptionKmsKey: "projects/my-project/locations/us-central1/keyRings/TestKeyRing/cryptoKeys/test-key",
+				},
+			},
+			expVol: &csi.Volume{
+				CapacityBytes: common.GbToBytes(20),
+				VolumeId:      testVolumeID,
+				VolumeContext: map[string]string{
+					constants.ContextNodeEncryption:       "luks",
+					constants.ContextNodeEncryptionKMSKey: "projects/my-project/locations/us-central1/keyRings/TestKeyRing/cryptoKeys/test-key",
+				},
+				AccessibleTopology: stdTopology,
+			},
+		},
 		{
 			name: "success with random secrets",
 			req: &csi.CreateVolumeRequest{
@@ -1797,7 +1819,7 @@ func TestMultiZoneVolumeCreation(t *testing.T) {
 		gceDriver.cs.fallbackRequisiteZones = tc.fallbackZones
 
 		if tc.req.VolumeContentSource.GetType() != nil {
-			snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(nil, gceDriver.name, nil)
+			snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(nil, gceDriver.name, "", nil)
 			if err != nil {
 				t.Errorf("Got error extracting snapshot parameters: %v", err)
 			}
@@ -2264,6 +2286,7 @@ func TestCreateVolumeWithVolumeAttributeClassParameters(t *testing.T) {
 		req           *csi.CreateVolumeRequest
 		expIops       int64
 		expThroughput int64
+		expLabels     map[string]string
 		wantErr       bool
 		expErrCode    codes.Code
 	}{
@@ -2334,6 +2357,20 @@ func TestCreateVolumeWithVolumeAttributeClassParameters(t *testing.T) {
 			wantErr:       true,
 			expErrCode:    codes.InvalidArgument,
 		},
+		{
+			name: "VolumeAttributesClass labels should be merged for disk types without dynamic provisioning",
+			req: &csi.CreateVolumeRequest{
+				Name:               "pd-ssd-labeled-vol",
+				CapacityRange:      stdCapRange,
+				VolumeCapabilities: stdVolCaps,
+				Parameters: map[string]string{
+					parameters.ParameterKeyType:   "pd-ssd",
+					parameters.ParameterKeyLabels: "team=storage,env=dev",
+				},
+				MutableParameters: map[string]string{parameters.ParameterKeyLabels: "env=prod"},
+			},
+			expLabels: map[string]string{"team": "storage", "env": "prod"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2376,6 +2413,11 @@ func TestCreateVolumeWithVolumeAttributeClassParameters(t *testing.T) {
 			if disk.GetProvisionedThroughput() != tc.expThroughput {
 				t.Errorf("Expected Throughput to be %d, got: %v", tc.expThroughput, disk.GetProvisionedThroughput())
 			}
+			for k, v := range tc.expLabels {
+				if got := disk.GetLabels()[k]; got != v {
+					t.Errorf("Expected label %s to be %s, got: %s", k, v, got)
+				}
+			}
 		}
 	}
 
@@ -2484,6 +2526,133 @@ func TestVolumeModifyOperation(t *testing.T) {
 	}
 }
 
+func TestVolumeModifyMetadata(t *testing.T) {
+	attachedDisk := gce.CloudDiskFromV1(&compute.Disk{
+		Name:       name,
+		Zone:       zone,
+		Type:       parameters.DiskTypeHdML,
+		AccessMode: constants.GCEReadWriteOnceAccessMode,
+		Users:      []string{fmt.Sprintf("%sprojects/%s/zones/%s/instances/%s", gce.BasePath, project, zone, node)},
+		SelfLink:   fmt.Sprintf("%sprojects/%s/zones/%s/disks/%s", gce.BasePath, project, zone, name),
+	})
+	testCases := []struct {
+		name              string
+		mutableParameters map[string]string
+		disk              *gce.CloudDisk
+		params            parameters.DiskParameters
+		accessMode        string
+		expLabels         map[string]string
+		expResourceTags   []string
+		expAccessMode     string
+		expErrCode        codes.Code
+	}{
+		{
+			name:              "merge labels",
+			mutableParameters: map[string]string{parameters.ParameterKeyLabels: "team=storage,env=prod"},
+			params:            parameters.DiskParameters{DiskType: stdDiskType, Labels: map[string]string{"env": "dev", "app": "db"}},
+			expLabels:         map[string]string{"team": "storage", "env": "prod", "app": "db"},
+		},
+		{
+			name:              "replace resource tags",
+			mutableParameters: map[string]string{parameters.ParameterKeyResourceTags: "parent1/key1/value2,parent2/key2/value2"},
+			params:            parameters.DiskParameters{DiskType: stdDiskType, ResourceTags: map[string]string{"parent1/key1": "value1"}},
+			expResourceTags:   []string{"parent1/key1/value2", "parent2/key2/value2"},
+		},
+		{
+			name:              "change access mode",
+			mutableParameters: map[string]string{parameters.ParameterAccessMode: constants.GCEReadOnlyManyAccessMode},
+			params:            parameters.DiskParameters{DiskType: parameters.DiskTypeHdML},
+			accessMode:        constants.GCEReadWriteOnceAccessMode,
+			expAccessMode:     constants.GCEReadOnlyManyAccessMode,
+		},
+		{
+			name:              "unchanged access mode of attached disk",
+			mutableParameters: map[string]string{parameters.ParameterAccessMode: constants.GCEReadWriteOnceAccessMode},
+			disk:              attachedDisk,
+			expAccessMode:     constants.GCEReadWriteOnceAccessMode,
+		},
+		{
+			name:              "change access mode of attached disk",
+			mutableParameters: map[string]string{parameters.ParameterAccessMode: constants.GCEReadOnlyManyAccessMode},
+			disk:              attachedDisk,
+			expErrCode:        codes.FailedPrecondition,
+		},
+		{
+			name:              "access mode of unsupported disk type",
+			mutableParameters: map[string]string{parameters.ParameterAccessMode: constants.GCEReadOnlyManyAccessMode},
+			params:            parameters.DiskParameters{DiskType: parameters.DiskTypeHdT},
+			accessMode:        constants.GCEReadWriteOnceAccessMode,
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:              "labels of disk type without dynamic provisioning",
+			mutableParameters: map[string]string{parameters.ParameterKeyLabels: "team=storage", "iops": "20000"},
+			params:            parameters.DiskParameters{DiskType: "pd-ssd"},
+			expErrCode:        codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var disks []*gce.CloudDisk
+			if tc.disk != nil {
+				disks = append(disks, tc.disk)
+			}
+			fcp, err := gce.CreateFakeCloudProvider(project, zone, disks)
+			if err != nil {
+				t.Fatalf("Failed to create mock cloud provider: %v", err)
+			}
+			gceDriver := initGCEDriverWithCloudProvider(t, fcp, &GCEControllerServerArgs{})
+			project, volKey, err := common.VolumeIDToKey(testVolumeID)
+			if err != nil {
+				t.Fatalf("Failed convert key: %v", err)
+			}
+			if tc.disk == nil {
+				err = fcp.InsertDisk(context.Background(), project, volKey, tc.params, 200000, nil, nil, "", "", false, tc.accessMode)
+				if err != nil {
+					t.Fatalf("Failed to insert disk: %v", err)
+				}
+			}
+
+			_, err = gceDriver.cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          testVolumeID,
+				MutableParameters: tc.mutableParameters,
+			})
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ControllerModifyVolume returned error: %v", err)
+			}
+
+			modifiedVol, err := fcp.GetDisk(context.Background(), project, volKey)
+			if err != nil {
+				t.Fatalf("Failed to get volume: %v", err)
+			}
+			if tc.expLabels != nil {
+				if diff := cmp.Diff(tc.expLabels, modifiedVol.GetLabels()); diff != "" {
+					t.Errorf("Unexpected labels (-want +got):\n%s", diff)
+				}
+			}
+			if tc.expResourceTags != nil {
+				tags, err := fcp.GetDiskResourceTags(context.Background(), project, volKey, modifiedVol)
+				if err != nil {
+					t.Fatalf("Failed to get resource tags: %v", err)
+				}
+				if diff := cmp.Diff(tc.expResourceTags, tags); diff != "" {
+					t.Errorf("Unexpected resource tags (-want +got):\n%s", diff)
+				}
+			}
+			if tc.expAccessMode != "" && modifiedVol.GetAccessMode() != tc.expAccessMode {
+				t.Errorf("Expected access mode %s, got %s", tc.expAccessMode, modifiedVol.GetAccessMode())
+			}
+		})
+	}
+}
+
 type FakeCloudProviderUpdateDiskErr struct {
 	*gce.FakeCloudProvider
 	updateDiskErrors map[string]error
@@ -3115,7 +3284,7 @@ func TestCreateVolumeWithVolumeSourceFromSnapshot(t *testing.T) {
 		// Setup new driver each time so no interference
 		gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
 
-		snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(nil, gceDriver.name, nil)
+		snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(nil, gceDriver.name, "", nil)
 		if err != nil {
 			t.Errorf("Got error extracting snapshot parameters: %v", err)
 		}
@@ -5727,7 +5896,7 @@ func TestCreateConfidentialVolume(t *testing.T) {
 			gceDriver := initGCEDriverWithCloudProvider(t, fcp, &GCEControllerServerArgs{})
 
 			if tc.req.VolumeContentSource.GetType() != nil {
-				snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(nil, gceDriver.name, nil)
+				snapshotParams, err := parameters.ExtractAndDefaultSnapshotParameters(nil, gceDriver.name, "", nil)
 				if err != nil {
 					t.Errorf("Got error extracting snapshot parameters: %v", err)
 				}
@@ -5843,3 +6012,132 @@ func mergeParameters(a map[string]string, b map[string]string) map[string]string
 	}
 	return merged
 }
+
+func TestCreateVolumeProject(t *testing.T) {
+	const storageProject = "storage-project"
+	testCases := []struct {
+		name       string
+		params     map[string]string
+		expProject string
+		expErrCode codes.Code
+	}{
+		{
+			name:       "default project",
+			params:     map[string]string{parameters.ParameterKeyType: stdDiskType},
+			expProject: project,
+		},
+		{
+			name:       "allowed project",
+			params:     map[string]string{parameters.ParameterKeyType: stdDiskType, parameters.ParameterKeyProject: storageProject},
+			expProject: storageProject,
+		},
+		{
+			name:       "project not allowed",
+			params:     map[string]string{parameters.ParameterKeyType: stdDiskType, parameters.ParameterKeyProject: "other-project"},
+			expErrCode: codes.InvalidArgument,
+		},
+		{
+			name: "resource tags of the project",
+			params: map[string]string{
+				parameters.ParameterKeyType:         stdDiskType,
+				parameters.ParameterKeyProject:      storageProject,
+				parameters.ParameterKeyResourceTags: storageProject + "/tag1/value1,123456/tag2/value2",
+			},
+			expProject: storageProject,
+		},
+		{
+			name: "resource tags of another project",
+			params: map[string]string{
+				parameters.ParameterKeyType:         stdDiskType,
+				parameters.ParameterKeyProject:      storageProject,
+				parameters.ParameterKeyResourceTags: project + "/tag1/value1",
+			},
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{AllowedProjects: []string{storageProject}})
+			resp, err := gceDriver.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:               name,
+				CapacityRange:      stdCapRange,
+				VolumeCapabilities: stdVolCaps,
+				Parameters:         tc.params,
+			})
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateVolume returned error: %v", err)
+			}
+			if want := fmt.Sprintf("projects/%s/zones/%s/disks/%s", tc.expProject, zone, name); resp.GetVolume().GetVolumeId() != want {
+				t.Errorf("Expected volume ID %s, got %s", want, resp.GetVolume().GetVolumeId())
+			}
+		})
+	}
+}
+
+func TestCreateVolumeDiskTypeTopology(t *testing.T) {
+	diskTypeKey := common.NodeDiskTypeKey(stdDiskType)
+	nodeTopology := func(zone, attachable string) *csi.Topology {
+		return &csi.Topology{Segments: map[string]string{constants.TopologyKeyZone: zone, diskTypeKey: attachable}}
+	}
+	testCases := []struct {
+		name        string
+		top         *csi.TopologyRequirement
+		expTopology []*csi.Topology
+		expErrCode  codes.Code
+	}{
+		{
+			name: "nodes without disk type segments",
+			top: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{{Segments: map[string]string{constants.TopologyKeyZone: zone}}},
+				Preferred: []*csi.Topology{{Segments: map[string]string{constants.TopologyKeyZone: zone}}},
+			},
+			expTopology: []*csi.Topology{{Segments: map[string]string{constants.TopologyKeyZone: zone}}},
+		},
+		{
+			name: "zone of incompatible nodes is skipped",
+			top: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{nodeTopology(zone, "false"), nodeTopology(secondZone, "true")},
+				Preferred: []*csi.Topology{nodeTopology(zone, "false"), nodeTopology(secondZone, "true")},
+			},
+			expTopology: []*csi.Topology{nodeTopology(secondZone, "true")},
+		},
+		{
+			name: "no compatible nodes",
+			top: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{nodeTopology(zone, "false"), nodeTopology(secondZone, "false")},
+				Preferred: []*csi.Topology{nodeTopology(zone, "false")},
+			},
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+			resp, err := gceDriver.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:                      name,
+				CapacityRange:             stdCapRange,
+				VolumeCapabilities:        stdVolCaps,
+				Parameters:                map[string]string{parameters.ParameterKeyType: stdDiskType},
+				AccessibilityRequirements: tc.top,
+			})
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateVolume returned error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expTopology, resp.GetVolume().GetAccessibleTopology(), protocmp.Transform()); diff != "" {
+				t.Errorf("Unexpected accessible topology: -want, +got \n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+)
+
+// secretKeyCredentials is the key in the CSI secrets of the GCP credentials
+// to use for the request, a service account key or a workload identity
+// federation configuration.
+const secretKeyCredentials = "credentials.json"
+
+type cloudProviderKey struct{}
+
+// withSecrets returns ctx carrying the cloud provider to use for the GCE
+// calls of the request, when its secrets contain GCP credentials. Without
+// credentials, the default cloud provider of the driver is used.
+func (gceCS *GCEControllerServer) withSecrets(ctx context.Context, secrets map[string]string) (context.Context, error) {
+	credentials := secrets[secretKeyCredentials]
+	if credentials == "" {
+		return ctx, nil
+	}
+	cloudProvider, err := gceCS.CloudProvider.WithCredentials(ctx, []byte(credentials))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid credentials in secret key %q: %v", secretKeyCredentials, err.Error())
+	}
+	return context.WithValue(ctx, cloudProviderKey{}, cloudProvider), nil
+}
+
+// cloudProvider returns the cloud provider to use for the GCE calls of the
+// request of ctx.
+func (gceCS *GCEControllerServer) cloudProvider(ctx context.Context) gce.GCECompute {
+	if cloudProvider, ok := ctx.Value(cloudProviderKey{}).(gce.GCECompute); ok {
+		return cloudProvider
+	}
+	return gceCS.CloudProvider
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const teamProject = "team-project"
+
+func TestVolumeCredentialsFromSecrets(t *testing.T) {
+	testCases := []struct {
+		name       string
+		secrets    map[string]string
+		expProject string
+		expErrCode codes.Code
+	}{
+		{
+			name:       "no secrets",
+			expProject: project,
+		},
+		{
+			name:       "service account key",
+			secrets:    map[string]string{secretKeyCredentials: fmt.Sprintf(`{"type": "service_account", "project_id": %q}`, teamProject)},
+			expProject: teamProject,
+		},
+		{
+			name:       "workload identity federation configuration",
+			secrets:    map[string]string{secretKeyCredentials: `{"type": "external_account"}`},
+			expErrCode: codes.InvalidArgument,
+		},
+		{
+			name:       "secrets without credentials",
+			secrets:    map[string]string{"other": "value"},
+			expProject: project,
+		},
+		{
+			name:       "invalid credentials",
+			secrets:    map[string]string{secretKeyCredentials: "not json"},
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+			cloudProvider := gceDriver.cs.CloudProvider
+
+			resp, err := gceDriver.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:               name,
+				CapacityRange:      stdCapRange,
+				VolumeCapabilities: stdVolCaps,
+				Parameters:         map[string]string{parameters.ParameterKeyType: stdDiskType},
+				Secrets:            tc.secrets,
+			})
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateVolume returned error: %v", err)
+			}
+			volumeID := resp.GetVolume().GetVolumeId()
+			if want := fmt.Sprintf("projects/%s/zones/%s/disks/%s", tc.expProject, zone, name); volumeID != want {
+				t.Errorf("Expected volume ID %s, got %s", want, volumeID)
+			}
+
+			_, err = gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID, Secrets: tc.secrets})
+			if err != nil {
+				t.Fatalf("DeleteVolume returned error: %v", err)
+			}
+			if _, err := cloudProvider.GetDisk(context.Background(), tc.expProject, meta.ZonalKey(name, zone)); !gce.IsGCENotFoundError(err) {
+				t.Errorf("Expected disk to be deleted, got: %v", err)
+			}
+		})
+	}
+}
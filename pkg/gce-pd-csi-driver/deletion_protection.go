@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+// Reasons of the DeleteVolume refusals, which are the reason label of the
+// delete_volume_refusals metric.
+const (
+	deleteRefusalProtectedLabel       = "protected_label"
+	deleteRefusalProtectedResourceTag = "protected_resource_tag"
+	deleteRefusalOtherCluster         = "other_cluster"
+	deleteRefusalOtherVolume          = "other_volume"
+	deleteRefusalNonKubernetesUser    = "non_kubernetes_user"
+)
+
+// DeletionProtection configures the checks of DeleteVolume, which refuses to
+// delete the disks failing them with FailedPrecondition.
+type DeletionProtection struct {
+	// Label is the GCE label of the protected disks, whose value is "true".
+	// Empty disables the check.
+	Label string
+	// ResourceTag is the namespaced value, <parent>/<key>/<value>, of the
+	// resource tag of the protected disks. Empty disables the check.
+	ResourceTag string
+	// CheckOwnership refuses the disks whose description tags name another
+	// cluster than the one of the driver, or another PV than the one of the
+	// volume.
+	CheckOwnership bool
+	// PVIndexer finds the PV of the volume for the ownership check, with
+	// the k8sclient.PVVolumeHandleIndex. If nil, only the cluster is checked.
+	PVIndexer cache.Indexer
+	// NodeLister, if set, refuses the disks attached to VMs which are not
+	// nodes of the cluster.
+	NodeLister corelisters.NodeLister
+}
+
+// checkDeletionProtection returns a FailedPrecondition error if the disk of
+// the volume must not be deleted.
+func (gceCS *GCEControllerServer) checkDeletionProtection(ctx context.Context, volumeID, project string, volKey *meta.Key, disk *gce.CloudDisk) error {
+	dp := gceCS.deletionProtection
+	if dp == nil {
+		return nil
+	}
+	refuse := func(reason, format string, args ...any) error {
+		gceCS.Metrics.RecordDeleteVolumeRefusalMetric(reason)
+		msg := fmt.Sprintf(format, args...)
+		klog.Warningf("Refusing to delete disk %v: %s", volKey, msg)
+		return status.Errorf(codes.FailedPrecondition, "refusing to delete disk %v: %s", volKey, msg)
+	}
+
+	if dp.Label != "" && disk.GetLabels()[dp.Label] == "true" {
+		return refuse(deleteRefusalProtectedLabel, "it has the deletion protection label %s=true", dp.Label)
+	}
+
+	if dp.ResourceTag != "" {
+		tags, err := gceCS.cloudProvider(ctx).GetDiskResourceTags(ctx, project, volKey, disk)
+		if err != nil {
+			return common.LoggedError("Failed to get the resource tags of the disk: ", err)
+		}
+		if slices.Contains(tags, dp.ResourceTag) {
+			return refuse(deleteRefusalProtectedResourceTag, "it has the deletion protection resource tag %s", dp.ResourceTag)
+		}
+	}
+
+	if dp.CheckOwnership {
+		diskTags := gce.DecodeTags(disk.GetDescription())
+		if cluster := parameters.CreatedForClusterName(diskTags); cluster != "" && gceCS.clusterName != "" && cluster != gceCS.clusterName {
+			return refuse(deleteRefusalOtherCluster, "it was created for cluster %s, not %s", cluster, gceCS.clusterName)
+		}
+		if pvName := parameters.CreatedForVolumeName(diskTags); pvName != "" && dp.PVIndexer != nil {
+			volumePVName, err := dp.volumePVName(volumeID, gceCS.Driver.name)
+			if err != nil {
+				return common.LoggedError("Failed to find the PV of the volume: ", err)
+			}
+			if volumePVName != "" && volumePVName != pvName {
+				return refuse(deleteRefusalOtherVolume, "it was created for PV %s, not %s", pvName, volumePVName)
+			}
+		}
+	}
+
+	if dp.NodeLister != nil && len(disk.GetUsers()) > 0 {
+		nodes, err := dp.NodeLister.List(labels.Everything())
+		if err != nil {
+			return common.LoggedError("Failed to list nodes: ", err)
+		}
+		instances := sets.New[string]()
+		for _, node := range nodes {
+			instanceZone, instanceName := nodeInstance(node)
+			instances.Insert(instanceZone + "/" + instanceName)
+		}
+		for _, user := range disk.GetUsers() {
+			instanceZone, instanceName, err := userInstance(user)
+			if err != nil || !instances.Has(instanceZone+"/"+instanceName) {
+				return refuse(deleteRefusalNonKubernetesUser, "it is attached to instance %s, which is not a node of the cluster", user)
+			}
+		}
+	}
+	return nil
+}
+
+// volumePVName returns the name of the PV of the volume, or "" if there is
+// none, as when the volume was not created for a PV.
+func (dp *DeletionProtection) volumePVName(volumeID, driverName string) (string, error) {
+	objs, err := dp.PVIndexer.ByIndex(k8sclient.PVVolumeHandleIndex, k8sclient.PVVolumeHandleKey(driverName, volumeID))
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range objs {
+		if pv, ok := obj.(*v1.PersistentVolume); ok {
+			return pv.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// userInstance returns the zone and name of the instance of a user URL of a
+// disk.
+func userInstance(user string) (string, string, error) {
+	userID, err := getResourceId(user)
+	if err != nil {
+		return "", "", err
+	}
+	return common.NodeIDToZoneAndName(userID)
+}
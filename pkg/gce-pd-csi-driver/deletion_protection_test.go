@@ -0,0 +1,200 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const (
+	protectionLabel       = "protected"
+	protectionResourceTag = "my-project/protected/true"
+	protectionCluster     = "my-cluster"
+	protectionNode        = "node-1"
+)
+
+func protectionPV(pvName, volumeID, driverName string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: pvName},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: volumeID},
+			},
+		},
+	}
+}
+
+func TestDeleteVolumeDeletionProtection(t *testing.T) {
+	volumeID := fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, name)
+	attachedDisk := func(instance string) *gce.CloudDisk {
+		return gce.CloudDiskFromV1(&computev1.Disk{
+			Name:     name,
+			Zone:     zone,
+			Users:    []string{fmt.Sprintf("%sprojects/%s/zones/%s/instances/%s", gce.BasePath, project, zone, instance)},
+			SelfLink: fmt.Sprintf("%sprojects/%s/zones/%s/disks/%s", gce.BasePath, project, zone, name),
+		})
+	}
+
+	testCases := []struct {
+		name string
+		// disk is used instead of creating the volume with params.
+		disk          *gce.CloudDisk
+		params        map[string]string
+		createCluster string
+		pvName        string
+		protection    DeletionProtection
+		expRefused    bool
+	}{
+		{
+			name:       "protected label",
+			params:     map[string]string{parameters.ParameterKeyLabels: protectionLabel + "=true"},
+			protection: DeletionProtection{Label: protectionLabel},
+			expRefused: true,
+		},
+		{
+			name:       "protected label set to false",
+			params:     map[string]string{parameters.ParameterKeyLabels: protectionLabel + "=false"},
+			protection: DeletionProtection{Label: protectionLabel},
+		},
+		{
+			name:       "protected resource tag",
+			params:     map[string]string{parameters.ParameterKeyResourceTags: protectionResourceTag},
+			protection: DeletionProtection{ResourceTag: protectionResourceTag},
+			expRefused: true,
+		},
+		{
+			name:       "other resource tag",
+			params:     map[string]string{parameters.ParameterKeyResourceTags: "my-project/protected/false"},
+			protection: DeletionProtection{ResourceTag: protectionResourceTag},
+		},
+		{
+			name:          "created for other cluster",
+			createCluster: "other-cluster",
+			protection:    DeletionProtection{CheckOwnership: true},
+			expRefused:    true,
+		},
+		{
+			name:          "created for same cluster",
+			createCluster: protectionCluster,
+			protection:    DeletionProtection{CheckOwnership: true},
+		},
+		{
+			name:       "created for other PV",
+			params:     map[string]string{parameters.ParameterKeyPVName: "other-pv"},
+			pvName:     "my-pv",
+			protection: DeletionProtection{CheckOwnership: true},
+			expRefused: true,
+		},
+		{
+			name:       "created for same PV",
+			params:     map[string]string{parameters.ParameterKeyPVName: "my-pv"},
+			pvName:     "my-pv",
+			protection: DeletionProtection{CheckOwnership: true},
+		},
+		{
+			name:       "attached to non-Kubernetes VM",
+			disk:       attachedDisk("other-vm"),
+			protection: DeletionProtection{NodeLister: corelisters.NewNodeLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))},
+			expRefused: true,
+		},
+		{
+			name: "attached to node",
+			disk: attachedDisk(protectionNode),
+		},
+		{
+			name:   "unprotected",
+			params: map[string]string{parameters.ParameterKeyLabels: protectionLabel + "=true"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var disks []*gce.CloudDisk
+			if tc.disk != nil {
+				disks = append(disks, tc.disk)
+			}
+			gceDriver := initGCEDriver(t, disks, &GCEControllerServerArgs{ClusterName: tc.createCluster})
+			if tc.disk == nil {
+				params := map[string]string{parameters.ParameterKeyType: stdDiskType}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				if _, err := gceDriver.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+					Name:               name,
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCaps,
+					Parameters:         params,
+				}); err != nil {
+					t.Fatalf("CreateVolume returned error: %v", err)
+				}
+			}
+
+			protection := tc.protection
+			pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{k8sclient.PVVolumeHandleIndex: k8sclient.PVVolumeHandleIndexFunc})
+			// The PV of another volume is not the one of the volume.
+			if err := pvIndexer.Add(protectionPV("pv-other", volumeID+"-other", gceDriver.cs.Driver.name)); err != nil {
+				t.Fatalf("Failed to add PV: %v", err)
+			}
+			if tc.pvName != "" {
+				if err := pvIndexer.Add(protectionPV(tc.pvName, volumeID, gceDriver.cs.Driver.name)); err != nil {
+					t.Fatalf("Failed to add PV: %v", err)
+				}
+			}
+			protection.PVIndexer = pvIndexer
+			if tc.disk != nil && protection.NodeLister == nil {
+				nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+				if err := nodeIndexer.Add(failoverNode(protectionNode, zone, true, 0)); err != nil {
+					t.Fatalf("Failed to add node: %v", err)
+				}
+				protection.NodeLister = corelisters.NewNodeLister(nodeIndexer)
+			}
+			gceDriver.cs.clusterName = protectionCluster
+			gceDriver.cs.deletionProtection = &protection
+
+			_, err := gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID})
+			_, getErr := gceDriver.cs.CloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, zone))
+			if tc.expRefused {
+				if status.Code(err) != codes.FailedPrecondition {
+					t.Fatalf("Expected FailedPrecondition, got: %v", err)
+				}
+				if getErr != nil {
+					t.Errorf("Expected refused disk to exist, got: %v", getErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeleteVolume returned error: %v", err)
+			}
+			if !gce.IsGCENotFoundError(getErr) {
+				t.Errorf("Expected disk to be deleted, got: %v", getErr)
+			}
+		})
+	}
+}
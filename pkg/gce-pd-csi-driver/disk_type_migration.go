@@ -0,0 +1,354 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const (
+	migrationSnapshotPrefix = "migrate-"
+
+	// Description tags of the migration snapshots, recording the settings
+	// of the migrated disk that its labels and tags don't hold. They are not
+	// copied to the new disk.
+	migrationTagAccessMode          = "storage.gke.io/disk-type-migration/access-mode"
+	migrationTagIOPS                = "storage.gke.io/disk-type-migration/provisioned-iops"
+	migrationTagThroughput          = "storage.gke.io/disk-type-migration/provisioned-throughput"
+	migrationTagKMSKey              = "storage.gke.io/disk-type-migration/kms-key"
+	migrationTagMultiWriter         = "storage.gke.io/disk-type-migration/multi-writer"
+	migrationTagConfidentialCompute = "storage.gke.io/disk-type-migration/confidential-compute"
+	// Followed by <parent>/<key> of each resource tag of the disk.
+	migrationTagResourceTagPrefix = "storage.gke.io/disk-type-migration/resource-tag/"
+)
+
+// migrationSnapshotName returns the name of the snapshot holding the data of
+// the volume while its disk is migrated to another disk type.
+func migrationSnapshotName(volumeID, diskName string) string {
+	return volumeSnapshotName(migrationSnapshotPrefix, volumeID, diskName)
+}
+
+// migrateDiskType replaces the disk of the volume with a disk of the same name
+// and params.DiskType, created from a snapshot of the disk. Each step is
+// recorded in GCE, as the disk-type-migration label of the disk and the
+// migration snapshot, so that a retried call resumes the migration:
+//
+//  1. the disk is labeled, snapshotted and deleted once the snapshot is ready,
+//  2. the new disk is created from the snapshot,
+//  3. the snapshot is deleted.
+//
+// It returns the new disk once the migration is done, or an error while it is
+// in progress.
+func (gceCS *GCEControllerServer) migrateDiskType(ctx context.Context, project string, volKey *meta.Key, params parameters.ModifyVolumeParameters) (*gce.CloudDisk, error) {
+	cloudProvider := gceCS.cloudProvider(ctx)
+	volumeID, err := common.KeyToVolumeID(volKey, project)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid volume key: %v", volKey)
+	}
+	snapshotName := migrationSnapshotName(volumeID, volKey.Name)
+
+	disk, err := cloudProvider.GetDisk(ctx, project, volKey)
+	if err != nil && !gce.IsGCENotFoundError(err) {
+		return nil, common.LoggedError("Failed to get disk: ", err)
+	}
+	if err == nil && disk.GetPDType() == params.DiskType {
+		// Migrated, possibly by a previous call which failed to delete the
+		// snapshot, or reverted before the disk was deleted.
+		if err := deleteMigrationSnapshot(ctx, cloudProvider, project, snapshotName); err != nil {
+			return nil, err
+		}
+		if _, ok := disk.GetLabels()[constants.DiskTypeMigrationLabel]; ok {
+			if err := removeMigrationLabel(ctx, cloudProvider, project, volKey, disk); err != nil {
+				return nil, err
+			}
+			// The disk is returned with its current labels and fingerprint.
+			if disk, err = cloudProvider.GetDisk(ctx, project, volKey); err != nil {
+				return nil, common.LoggedError("Failed to get disk: ", err)
+			}
+		}
+		return disk, nil
+	}
+	if err == nil {
+		if err := gceCS.validateDiskTypeMigration(ctx, project, volKey, disk, params); err != nil {
+			return nil, err
+		}
+		if err := gceCS.snapshotAndDeleteMigratedDisk(ctx, project, volKey, disk, snapshotName, params.DiskType); err != nil {
+			return nil, err
+		}
+	}
+
+	// The disk was deleted, by this call or a previous one.
+	gceCS.diskTypeMigrations.Store(volumeID, true)
+	disk, err = gceCS.createMigratedDisk(ctx, project, volKey, snapshotName, params)
+	if err != nil {
+		return nil, err
+	}
+	gceCS.diskTypeMigrations.Delete(volumeID)
+	if err := deleteMigrationSnapshot(ctx, cloudProvider, project, snapshotName); err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("Migrated disk %v to disk type %s", volKey, params.DiskType)
+	return disk, nil
+}
+
+// validateDiskTypeMigration checks that the disk can be migrated to
+// params.DiskType, before deleting it.
+func (gceCS *GCEControllerServer) validateDiskTypeMigration(ctx context.Context, project string, volKey *meta.Key, disk *gce.CloudDisk, params parameters.ModifyVolumeParameters) error {
+	if volKey.Type() != meta.Zonal || isMultiZoneVolKey(volKey) {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: disk type migration is only supported for zonal disks", volKey)
+	}
+	if disk.GetAsyncPrimaryDisk() != "" || len(disk.GetAsyncSecondaryDisks()) > 0 {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: disk type migration is not supported for asynchronously replicated disks", volKey)
+	}
+	if params.IOPS != nil && !gceCS.diskSupportsIopsChange(params.DiskType) {
+		return status.Errorf(codes.InvalidArgument, "Cannot specify IOPS for disk type %s", params.DiskType)
+	}
+	if params.Throughput != nil && !gceCS.diskSupportsThroughputChange(params.DiskType) {
+		return status.Errorf(codes.InvalidArgument, "Cannot specify throughput for disk type %s", params.DiskType)
+	}
+	if params.AccessMode != "" && !slices.Contains(disksWithModifiableAccessMode, params.DiskType) {
+		return status.Errorf(codes.InvalidArgument, "Cannot specify access mode for disk type %s", params.DiskType)
+	}
+	// Settings of the disk which the new disk cannot have are refused rather
+	// than dropped.
+	if disk.GetEnableStoragePools() {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: disks in a storage pool cannot change disk type", volKey)
+	}
+	if disk.GetMultiWriter() && common.IsHyperdisk(params.DiskType) {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: multi-writer is not supported for disk type %s", volKey, params.DiskType)
+	}
+	if accessMode := disk.GetAccessMode(); params.AccessMode == "" && accessMode != "" && accessMode != constants.GCEReadWriteOnceAccessMode &&
+		(!common.IsHyperdisk(params.DiskType) || disksWithUnsettableAccessMode[params.DiskType]) {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: access mode %s is not supported for disk type %s", volKey, accessMode, params.DiskType)
+	}
+	if disk.GetEnableConfidentialCompute() && params.DiskType != constants.DiskTypeHdBalanced {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: confidential storage is not supported for disk type %s", volKey, params.DiskType)
+	}
+	zones, err := gceCS.cloudProvider(ctx).ListCompatibleDiskTypeZones(ctx, project, []string{volKey.Zone}, params.DiskType)
+	if err != nil {
+		return common.LoggedError("Failed to list the zones of disk type: ", err)
+	}
+	if !slices.Contains(zones, volKey.Zone) {
+		return status.Errorf(codes.InvalidArgument, "Cannot migrate disk %v: disk type %s is not available in zone %s", volKey, params.DiskType, volKey.Zone)
+	}
+	// The disk is swapped while the volume is unpublished. It may still be
+	// detached later, so the migration is retried.
+	if users := disk.GetUsers(); len(users) > 0 {
+		return status.Errorf(codes.FailedPrecondition, "Cannot migrate disk %v to disk type %s while it is attached to %v", volKey, params.DiskType, users)
+	}
+	return nil
+}
+
+// snapshotAndDeleteMigratedDisk snapshots the disk and deletes it once the
+// snapshot is ready.
+func (gceCS *GCEControllerServer) snapshotAndDeleteMigratedDisk(ctx context.Context, project string, volKey *meta.Key, disk *gce.CloudDisk, snapshotName, diskType string) error {
+	cloudProvider := gceCS.cloudProvider(ctx)
+
+	// The label keeps the volume from being published during the migration.
+	labels, changed := mergeDiskLabels(disk.GetLabels(), map[string]string{constants.DiskTypeMigrationLabel: diskType})
+	if changed {
+		if err := cloudProvider.SetDiskLabels(ctx, project, volKey, disk, labels); err != nil {
+			return common.LoggedError("Failed to label disk for migration: ", err)
+		}
+	}
+
+	snapshot, err := cloudProvider.GetSnapshot(ctx, project, snapshotName)
+	if gce.IsGCENotFoundError(err) {
+		klog.V(4).Infof("Taking migration snapshot %s of disk %v", snapshotName, volKey)
+		tags := gce.DecodeTags(disk.GetDescription())
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		if accessMode := disk.GetAccessMode(); accessMode != "" {
+			tags[migrationTagAccessMode] = accessMode
+		}
+		if iops := disk.GetProvisionedIops(); iops > 0 {
+			tags[migrationTagIOPS] = strconv.FormatInt(iops, 10)
+		}
+		if throughput := disk.GetProvisionedThroughput(); throughput > 0 {
+			tags[migrationTagThroughput] = strconv.FormatInt(throughput, 10)
+		}
+		if kmsKey := disk.GetKMSKeyName(); kmsKey != "" {
+			tags[migrationTagKMSKey] = kmsKey
+		}
+		if disk.GetMultiWriter() {
+			tags[migrationTagMultiWriter] = "true"
+		}
+		if disk.GetEnableConfidentialCompute() {
+			tags[migrationTagConfidentialCompute] = "true"
+		}
+		resourceTags, err := cloudProvider.GetDiskResourceTags(ctx, project, volKey, disk)
+		if err != nil {
+			return common.LoggedError("Failed to get the resource tags of the migrated disk: ", err)
+		}
+		for _, tag := range resourceTags {
+			// Tag values are namespaced as <parent>/<key>/<value>.
+			if i := strings.LastIndex(tag, "/"); i > 0 {
+				tags[migrationTagResourceTagPrefix+tag[:i]] = tag[i+1:]
+			}
+		}
+		snapshotParams := parameters.SnapshotParameters{
+			StorageLocations: []string{},
+			SnapshotType:     parameters.DiskSnapshotType,
+			Tags:             tags,
+			Labels:           labels,
+			ResourceTags:     make(map[string]string),
+		}
+		snapshot, err = cloudProvider.CreateSnapshot(ctx, project, volKey, snapshotName, snapshotParams)
+		if err != nil {
+			return common.LoggedError(fmt.Sprintf("Failed to create migration snapshot %s: ", snapshotName), err)
+		}
+	} else if err != nil {
+		return common.LoggedError(fmt.Sprintf("Failed to get migration snapshot %s: ", snapshotName), err)
+	}
+
+	switch snapshot.Status {
+	case "READY":
+	case "FAILED":
+		// Delete the failed snapshot so that the retry takes a new one.
+		if err := cloudProvider.DeleteSnapshot(ctx, project, snapshotName); err != nil {
+			return common.LoggedError(fmt.Sprintf("Failed to delete failed migration snapshot %s: ", snapshotName), err)
+		}
+		return status.Errorf(codes.Unavailable, "migration snapshot %s of disk %v failed, retrying", snapshotName, volKey)
+	default:
+		return status.Errorf(codes.Unavailable, "migration snapshot %s of disk %v is %s", snapshotName, volKey, snapshot.Status)
+	}
+
+	klog.V(4).Infof("Deleting disk %v to migrate it to disk type %s", volKey, diskType)
+	if err := cloudProvider.DeleteDisk(ctx, project, volKey); err != nil {
+		return common.LoggedError("Failed to delete migrated disk: ", err)
+	}
+	return nil
+}
+
+// createMigratedDisk creates the disk of params.DiskType from the migration
+// snapshot, with the labels, description and settings of the deleted disk.
+func (gceCS *GCEControllerServer) createMigratedDisk(ctx context.Context, project string, volKey *meta.Key, snapshotName string, params parameters.ModifyVolumeParameters) (*gce.CloudDisk, error) {
+	cloudProvider := gceCS.cloudProvider(ctx)
+	snapshot, err := cloudProvider.GetSnapshot(ctx, project, snapshotName)
+	if gce.IsGCENotFoundError(err) {
+		return nil, status.Errorf(codes.NotFound, "Could not find disk %v or its migration snapshot %s", volKey, snapshotName)
+	} else if err != nil {
+		return nil, common.LoggedError(fmt.Sprintf("Failed to get migration snapshot %s: ", snapshotName), err)
+	}
+	if snapshot.Status != "READY" {
+		return nil, status.Errorf(codes.Unavailable, "migration snapshot %s of disk %v is %s", snapshotName, volKey, snapshot.Status)
+	}
+	snapshotID, err := getResourceId(snapshot.SelfLink)
+	if err != nil {
+		return nil, common.LoggedError(fmt.Sprintf("Cannot extract resource id from snapshot %s: ", snapshot.SelfLink), err)
+	}
+
+	diskParams := parameters.DiskParameters{
+		DiskType:     params.DiskType,
+		Tags:         make(map[string]string),
+		Labels:       make(map[string]string),
+		ResourceTags: make(map[string]string),
+	}
+	for k, v := range snapshot.Labels {
+		if k != constants.DiskTypeMigrationLabel {
+			diskParams.Labels[k] = v
+		}
+	}
+	var accessMode string
+	var multiWriter bool
+	for k, v := range gce.DecodeTags(snapshot.Description) {
+		if resourceTag, ok := strings.CutPrefix(k, migrationTagResourceTagPrefix); ok {
+			diskParams.ResourceTags[resourceTag] = v
+			continue
+		}
+		switch k {
+		case migrationTagAccessMode:
+			accessMode = v
+		case migrationTagIOPS:
+			if gceCS.diskSupportsIopsChange(params.DiskType) {
+				diskParams.ProvisionedIOPSOnCreate, _ = strconv.ParseInt(v, 10, 64)
+			}
+		case migrationTagThroughput:
+			if gceCS.diskSupportsThroughputChange(params.DiskType) {
+				diskParams.ProvisionedThroughputOnCreate, _ = strconv.ParseInt(v, 10, 64)
+			}
+		case migrationTagKMSKey:
+			diskParams.DiskEncryptionKMSKey = v
+		case migrationTagMultiWriter:
+			multiWriter = v == "true"
+		case migrationTagConfidentialCompute:
+			diskParams.EnableConfidentialCompute = v == "true"
+		default:
+			diskParams.Tags[k] = v
+		}
+	}
+	if params.IOPS != nil {
+		diskParams.ProvisionedIOPSOnCreate = *params.IOPS
+	}
+	if params.Throughput != nil {
+		diskParams.ProvisionedThroughputOnCreate = *params.Throughput
+	}
+	if params.AccessMode != "" {
+		accessMode = params.AccessMode
+	}
+	if !common.IsHyperdisk(params.DiskType) || disksWithUnsettableAccessMode[params.DiskType] {
+		accessMode = ""
+	}
+
+	klog.V(4).Infof("Creating disk %v of disk type %s from migration snapshot %s", volKey, params.DiskType, snapshotName)
+	capBytes := common.GbToBytes(snapshot.DiskSizeGb)
+	if err := cloudProvider.InsertDisk(ctx, project, volKey, diskParams, capBytes, nil, nil, snapshotID, "", multiWriter, accessMode); err != nil {
+		return nil, common.LoggedError("Failed to create migrated disk: ", err)
+	}
+	disk, err := cloudProvider.GetDisk(ctx, project, volKey)
+	if err != nil {
+		return nil, common.LoggedError("Failed to get migrated disk: ", err)
+	}
+	return disk, nil
+}
+
+// removeMigrationLabel removes the disk-type-migration label of a disk whose
+// migration was reverted, so that its volume can be published again.
+func removeMigrationLabel(ctx context.Context, cloudProvider gce.GCECompute, project string, volKey *meta.Key, disk *gce.CloudDisk) error {
+	labels := make(map[string]string, len(disk.GetLabels()))
+	for k, v := range disk.GetLabels() {
+		if k != constants.DiskTypeMigrationLabel {
+			labels[k] = v
+		}
+	}
+	if err := cloudProvider.SetDiskLabels(ctx, project, volKey, disk, labels); err != nil {
+		return common.LoggedError("Failed to remove the migration label of disk: ", err)
+	}
+	return nil
+}
+
+// deleteMigrationSnapshot deletes the migration snapshot of a disk, if any.
+func deleteMigrationSnapshot(ctx context.Context, cloudProvider gce.GCECompute, project, snapshotName string) error {
+	err := cloudProvider.DeleteSnapshot(ctx, project, snapshotName)
+	if err != nil && !gce.IsGCENotFoundError(err) {
+		return common.LoggedError(fmt.Sprintf("Failed to delete migration snapshot %s: ", snapshotName), err)
+	}
+	return nil
+}
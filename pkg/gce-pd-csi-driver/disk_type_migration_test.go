@@ -0,0 +1,434 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/go-cmp/cmp"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+// modifyVolumeUntilDone calls ControllerModifyVolume until the migration
+// snapshot is ready, as the external-resizer would.
+func modifyVolumeUntilDone(gceDriver *GCEDriver, mutableParameters map[string]string) error {
+	var err error
+	for range 3 {
+		_, err = gceDriver.cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+			VolumeId:          testVolumeID,
+			MutableParameters: mutableParameters,
+		})
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+	}
+	return err
+}
+
+func TestModifyVolumeDiskTypeMigration(t *testing.T) {
+	volKey := meta.ZonalKey(name, zone)
+	snapshotName := migrationSnapshotName(testVolumeID, name)
+	attachedDisk := gce.CloudDiskFromV1(&computev1.Disk{
+		Name:     name,
+		Zone:     zone,
+		Type:     "pd-balanced",
+		Users:    []string{fmt.Sprintf("%sprojects/%s/zones/%s/instances/%s", gce.BasePath, project, zone, node)},
+		SelfLink: fmt.Sprintf("%sprojects/%s/zones/%s/disks/%s", gce.BasePath, project, zone, name),
+	})
+	pooledDisk := gce.CloudDiskFromV1(&computev1.Disk{
+		Name:        name,
+		Zone:        zone,
+		Type:        "hyperdisk-balanced",
+		StoragePool: fmt.Sprintf("projects/%s/zones/%s/storagePools/pool", project, zone),
+		SelfLink:    fmt.Sprintf("%sprojects/%s/zones/%s/disks/%s", gce.BasePath, project, zone, name),
+	})
+
+	testCases := []struct {
+		name              string
+		mutableParameters map[string]string
+		disk              *gce.CloudDisk
+		params            parameters.DiskParameters
+		multiWriter       bool
+		accessMode        string
+		expDiskType       string
+		expLabels         map[string]string
+		expTags           map[string]string
+		expResourceTags   []string
+		expIops           int64
+		expMultiWriter    bool
+		expConfidential   bool
+		expErrCode        codes.Code
+	}{
+		{
+			name:              "pd-balanced to hyperdisk-balanced",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "hyperdisk-balanced", parameters.ParameterKeyLabels: "team=storage"},
+			params: parameters.DiskParameters{
+				DiskType: "pd-balanced",
+				Labels:   map[string]string{"app": "db"},
+				Tags:     map[string]string{"kubernetes.io/created-for/pv/name": "pv-1"},
+			},
+			expDiskType: "hyperdisk-balanced",
+			expLabels:   map[string]string{"app": "db", "team": "storage"},
+			expTags:     map[string]string{"kubernetes.io/created-for/pv/name": "pv-1"},
+		},
+		{
+			name:              "IOPS carried over",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "hyperdisk-extreme"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-balanced", ProvisionedIOPSOnCreate: 5000},
+			expDiskType:       "hyperdisk-extreme",
+			expIops:           5000,
+		},
+		{
+			name:              "IOPS changed",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "hyperdisk-extreme", "iops": "8000"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-balanced", ProvisionedIOPSOnCreate: 5000},
+			expDiskType:       "hyperdisk-extreme",
+			expIops:           8000,
+		},
+		{
+			name:              "IOPS dropped for disk type without provisioned IOPS",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-balanced", ProvisionedIOPSOnCreate: 5000},
+			expDiskType:       "pd-ssd",
+		},
+		{
+			name:              "already migrated",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd"},
+			params:            parameters.DiskParameters{DiskType: "pd-ssd"},
+			expDiskType:       "pd-ssd",
+		},
+		{
+			name:              "IOPS for disk type without provisioned IOPS",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd", "iops": "8000"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-balanced"},
+			expDiskType:       "hyperdisk-balanced",
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:              "resource tags carried over",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd"},
+			params:            parameters.DiskParameters{DiskType: "pd-balanced", ResourceTags: map[string]string{"test-project/env": "prod"}},
+			expDiskType:       "pd-ssd",
+			expResourceTags:   []string{"test-project/env/prod"},
+		},
+		{
+			name:              "multi-writer carried over",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-balanced"},
+			params:            parameters.DiskParameters{DiskType: "pd-ssd"},
+			multiWriter:       true,
+			expDiskType:       "pd-balanced",
+			expMultiWriter:    true,
+		},
+		{
+			name:              "multi-writer for hyperdisk",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "hyperdisk-balanced"},
+			params:            parameters.DiskParameters{DiskType: "pd-ssd"},
+			multiWriter:       true,
+			expDiskType:       "pd-ssd",
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:              "confidential storage carried over",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "hyperdisk-balanced"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-balanced-high-availability", EnableConfidentialCompute: true},
+			expDiskType:       "hyperdisk-balanced",
+			expConfidential:   true,
+		},
+		{
+			name:              "confidential storage for disk type without confidential storage",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-balanced", EnableConfidentialCompute: true},
+			expDiskType:       "hyperdisk-balanced",
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:              "access mode for disk type without access mode",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd"},
+			params:            parameters.DiskParameters{DiskType: "hyperdisk-ml"},
+			accessMode:        "READ_ONLY_MANY",
+			expDiskType:       "hyperdisk-ml",
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:              "disk in storage pool",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "hyperdisk-extreme"},
+			disk:              pooledDisk,
+			expDiskType:       "hyperdisk-balanced",
+			expErrCode:        codes.InvalidArgument,
+		},
+		{
+			name:              "attached disk",
+			mutableParameters: map[string]string{parameters.ParameterKeyType: "pd-ssd"},
+			disk:              attachedDisk,
+			expDiskType:       "pd-balanced",
+			expErrCode:        codes.FailedPrecondition,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var disks []*gce.CloudDisk
+			if tc.disk != nil {
+				disks = append(disks, tc.disk)
+			}
+			fcp, err := gce.CreateFakeCloudProvider(project, zone, disks)
+			if err != nil {
+				t.Fatalf("Failed to create fake cloud provider: %v", err)
+			}
+			gceDriver := initGCEDriverWithCloudProvider(t, fcp, &GCEControllerServerArgs{})
+			if tc.disk == nil {
+				if err := fcp.InsertDisk(context.Background(), project, volKey, tc.params, 200000, nil, nil, "", "", tc.multiWriter, tc.accessMode); err != nil {
+					t.Fatalf("Failed to insert disk: %v", err)
+				}
+			}
+
+			err = modifyVolumeUntilDone(gceDriver, tc.mutableParameters)
+			if status.Code(err) != tc.expErrCode {
+				t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+			}
+
+			disk, err := fcp.GetDisk(context.Background(), project, volKey)
+			if err != nil {
+				t.Fatalf("Failed to get disk: %v", err)
+			}
+			if disk.GetPDType() != tc.expDiskType {
+				t.Errorf("Expected disk type %s, got %s", tc.expDiskType, disk.GetPDType())
+			}
+			if _, err := fcp.GetSnapshot(context.Background(), project, snapshotName); !gce.IsGCENotFoundError(err) {
+				t.Errorf("Expected migration snapshot to be deleted, got: %v", err)
+			}
+			if tc.expErrCode != codes.OK {
+				return
+			}
+			if tc.expLabels != nil {
+				if diff := cmp.Diff(tc.expLabels, disk.GetLabels()); diff != "" {
+					t.Errorf("Unexpected labels (-want +got):\n%s", diff)
+				}
+			}
+			if tc.expTags != nil {
+				if diff := cmp.Diff(tc.expTags, gce.DecodeTags(disk.GetDescription())); diff != "" {
+					t.Errorf("Unexpected tags (-want +got):\n%s", diff)
+				}
+			}
+			if disk.GetProvisionedIops() != tc.expIops {
+				t.Errorf("Expected provisioned IOPS %d, got %d", tc.expIops, disk.GetProvisionedIops())
+			}
+			if disk.GetMultiWriter() != tc.expMultiWriter {
+				t.Errorf("Expected multi-writer %t, got %t", tc.expMultiWriter, disk.GetMultiWriter())
+			}
+			if disk.GetEnableConfidentialCompute() != tc.expConfidential {
+				t.Errorf("Expected confidential storage %t, got %t", tc.expConfidential, disk.GetEnableConfidentialCompute())
+			}
+			resourceTags, err := fcp.GetDiskResourceTags(context.Background(), project, volKey, disk)
+			if err != nil {
+				t.Fatalf("Failed to get resource tags: %v", err)
+			}
+			if diff := cmp.Diff(tc.expResourceTags, resourceTags); diff != "" {
+				t.Errorf("Unexpected resource tags (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiskTypeMigrationInProgress(t *testing.T) {
+	volKey := meta.ZonalKey(name, zone)
+	snapshotName := migrationSnapshotName(testVolumeID, name)
+	mutableParameters := map[string]string{parameters.ParameterKeyType: "pd-ssd"}
+
+	testCases := []struct {
+		name string
+		// run is called once the disk is snapshotted for its migration.
+		run func(t *testing.T, gceDriver *GCEDriver, fcp *gce.FakeCloudProvider)
+	}{
+		{
+			name: "publish refused",
+			run: func(t *testing.T, gceDriver *GCEDriver, fcp *gce.FakeCloudProvider) {
+				_, err := gceDriver.cs.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+					VolumeId:         testVolumeID,
+					NodeId:           testNodeID,
+					VolumeCapability: stdVolCaps[0],
+				})
+				if status.Code(err) != codes.Unavailable {
+					t.Errorf("Expected Unavailable, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "resumed after disk deletion",
+			run: func(t *testing.T, gceDriver *GCEDriver, fcp *gce.FakeCloudProvider) {
+				if _, err := fcp.GetSnapshot(context.Background(), project, snapshotName); err != nil {
+					t.Fatalf("Failed to get snapshot: %v", err)
+				}
+				if err := fcp.DeleteDisk(context.Background(), project, volKey); err != nil {
+					t.Fatalf("Failed to delete disk: %v", err)
+				}
+				if err := modifyVolumeUntilDone(gceDriver, mutableParameters); err != nil {
+					t.Fatalf("ControllerModifyVolume returned error: %v", err)
+				}
+				disk, err := fcp.GetDisk(context.Background(), project, volKey)
+				if err != nil {
+					t.Fatalf("Failed to get disk: %v", err)
+				}
+				if disk.GetPDType() != "pd-ssd" {
+					t.Errorf("Expected disk type pd-ssd, got %s", disk.GetPDType())
+				}
+				if _, ok := disk.GetLabels()[constants.DiskTypeMigrationLabel]; ok {
+					t.Errorf("Expected migrated disk without the %s label", constants.DiskTypeMigrationLabel)
+				}
+			},
+		},
+		{
+			name: "reverted before disk deletion",
+			run: func(t *testing.T, gceDriver *GCEDriver, fcp *gce.FakeCloudProvider) {
+				_, err := gceDriver.cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+					VolumeId:          testVolumeID,
+					MutableParameters: map[string]string{parameters.ParameterKeyType: "pd-balanced"},
+				})
+				if err != nil {
+					t.Fatalf("ControllerModifyVolume returned error: %v", err)
+				}
+				if _, err := fcp.GetSnapshot(context.Background(), project, snapshotName); !gce.IsGCENotFoundError(err) {
+					t.Errorf("Expected migration snapshot to be deleted, got: %v", err)
+				}
+				disk, err := fcp.GetDisk(context.Background(), project, volKey)
+				if err != nil {
+					t.Fatalf("Failed to get disk: %v", err)
+				}
+				if disk.GetPDType() != "pd-balanced" {
+					t.Errorf("Expected disk type pd-balanced, got %s", disk.GetPDType())
+				}
+				if _, ok := disk.GetLabels()[constants.DiskTypeMigrationLabel]; ok {
+					t.Errorf("Expected reverted disk without the %s label", constants.DiskTypeMigrationLabel)
+				}
+			},
+		},
+		{
+			name: "volume deleted",
+			run: func(t *testing.T, gceDriver *GCEDriver, fcp *gce.FakeCloudProvider) {
+				if _, err := gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID}); err != nil {
+					t.Fatalf("DeleteVolume returned error: %v", err)
+				}
+				if _, err := fcp.GetSnapshot(context.Background(), project, snapshotName); !gce.IsGCENotFoundError(err) {
+					t.Errorf("Expected migration snapshot to be deleted, got: %v", err)
+				}
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fcp, err := gce.CreateFakeCloudProvider(project, zone, nil)
+			if err != nil {
+				t.Fatalf("Failed to create fake cloud provider: %v", err)
+			}
+			gceDriver := initGCEDriverWithCloudProvider(t, fcp, &GCEControllerServerArgs{})
+			if err := fcp.InsertDisk(context.Background(), project, volKey, parameters.DiskParameters{DiskType: "pd-balanced"}, 200000, nil, nil, "", "", false, ""); err != nil {
+				t.Fatalf("Failed to insert disk: %v", err)
+			}
+
+			// The fake snapshot is uploading until it is read back.
+			_, err = gceDriver.cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          testVolumeID,
+				MutableParameters: mutableParameters,
+			})
+			if status.Code(err) != codes.Unavailable {
+				t.Fatalf("Expected Unavailable, got: %v", err)
+			}
+			tc.run(t, gceDriver, fcp)
+		})
+	}
+}
+
+// migrationCloudProvider fails to insert disks with insertErr, and counts the
+// calls to DeleteSnapshot.
+type migrationCloudProvider struct {
+	*gce.FakeCloudProvider
+	insertErr       error
+	snapshotDeletes int
+}
+
+func (c *migrationCloudProvider) InsertDisk(ctx context.Context, project string, volKey *meta.Key, params parameters.DiskParameters, capBytes int64, capacityRange *csi.CapacityRange, replicaZones []string, snapshotID string, volumeContentSourceVolumeID string, multiWriter bool, accessMode string) error {
+	if c.insertErr != nil {
+		return c.insertErr
+	}
+	return c.FakeCloudProvider.InsertDisk(ctx, project, volKey, params, capBytes, capacityRange, replicaZones, snapshotID, volumeContentSourceVolumeID, multiWriter, accessMode)
+}
+
+func (c *migrationCloudProvider) DeleteSnapshot(ctx context.Context, project, snapshotName string) error {
+	c.snapshotDeletes++
+	return c.FakeCloudProvider.DeleteSnapshot(ctx, project, snapshotName)
+}
+
+func TestDeleteVolumeMigrationSnapshot(t *testing.T) {
+	volKey := meta.ZonalKey(name, zone)
+	snapshotName := migrationSnapshotName(testVolumeID, name)
+
+	testCases := []struct {
+		name string
+		// migrate swaps the disk out for a migration whose new disk is not
+		// created.
+		migrate            bool
+		expSnapshotDeletes int
+	}{
+		{
+			name: "disk not found",
+		},
+		{
+			name:               "disk swapped out",
+			migrate:            true,
+			expSnapshotDeletes: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fcp, err := gce.CreateFakeCloudProvider(project, zone, nil)
+			if err != nil {
+				t.Fatalf("Failed to create fake cloud provider: %v", err)
+			}
+			cloudProvider := &migrationCloudProvider{FakeCloudProvider: fcp}
+			gceDriver := initGCEDriverWithCloudProvider(t, cloudProvider, &GCEControllerServerArgs{})
+			if tc.migrate {
+				if err := fcp.InsertDisk(context.Background(), project, volKey, parameters.DiskParameters{DiskType: "pd-balanced"}, 200000, nil, nil, "", "", false, ""); err != nil {
+					t.Fatalf("Failed to insert disk: %v", err)
+				}
+				cloudProvider.insertErr = fmt.Errorf("quota exceeded")
+				if err := modifyVolumeUntilDone(gceDriver, map[string]string{parameters.ParameterKeyType: "pd-ssd"}); err == nil {
+					t.Fatalf("Expected ControllerModifyVolume to fail")
+				}
+				if _, err := fcp.GetDisk(context.Background(), project, volKey); !gce.IsGCENotFoundError(err) {
+					t.Fatalf("Expected disk to be swapped out, got: %v", err)
+				}
+			}
+
+			if _, err := gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: testVolumeID}); err != nil {
+				t.Fatalf("DeleteVolume returned error: %v", err)
+			}
+			if cloudProvider.snapshotDeletes != tc.expSnapshotDeletes {
+				t.Errorf("Expected %d calls to DeleteSnapshot, got %d", tc.expSnapshotDeletes, cloudProvider.snapshotDeletes)
+			}
+			if _, err := fcp.GetSnapshot(context.Background(), project, snapshotName); !gce.IsGCENotFoundError(err) {
+				t.Errorf("Expected no migration snapshot, got: %v", err)
+			}
+		})
+	}
+}
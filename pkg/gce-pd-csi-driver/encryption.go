@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/encryption"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const (
+	// Keys in the node stage secret of encrypted volumes. Exactly one of them
+	// must be set; the wrapped key is required when a KMS key is configured.
+	secretKeyEncryptionPassphrase = "encryption-passphrase"
+	secretKeyEncryptionWrappedKey = "encryption-wrapped-key"
+)
+
+func isEncryptionRequested(volumeContext map[string]string) bool {
+	return volumeContext[constants.ContextNodeEncryption] == parameters.NodeEncryptionLuks
+}
+
+// getEncryptionPassphrase returns the LUKS passphrase from the node stage
+// secrets, unwrapping it with Cloud KMS if the volume was provisioned with a
+// KMS key.
+func (ns *GCENodeServer) getEncryptionPassphrase(ctx context.Context, volumeContext, secrets map[string]string) ([]byte, error) {
+	kmsKey := volumeContext[constants.ContextNodeEncryptionKMSKey]
+	if kmsKey == "" {
+		passphrase := secrets[secretKeyEncryptionPassphrase]
+		if passphrase == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "node stage secret must contain %q for encrypted volumes", secretKeyEncryptionPassphrase)
+		}
+		return []byte(passphrase), nil
+	}
+
+	wrapped := secrets[secretKeyEncryptionWrappedKey]
+	if wrapped == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "node stage secret must contain %q for volumes encrypted with a KMS key", secretKeyEncryptionWrappedKey)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%q in node stage secret is not valid base64: %v", secretKeyEncryptionWrappedKey, err.Error())
+	}
+	if ns.KeyUnwrapper == nil {
+		return nil, status.Error(codes.FailedPrecondition, "KMS wrapped encryption keys are not supported on this node")
+	}
+	passphrase, err := ns.KeyUnwrapper.Unwrap(ctx, kmsKey, ciphertext)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to unwrap encryption key: %v", err.Error())
+	}
+	return passphrase, nil
+}
+
+// openEncryptedDevice unlocks the LUKS device at devicePath, formatting it
+// first if the disk is blank, and returns the path of the plaintext device
+// that should be used in place of devicePath.
+func (ns *GCENodeServer) openEncryptedDevice(ctx context.Context, devicePath, diskName string, volumeContext, secrets map[string]string) (string, error) {
+	if ns.Luks == nil {
+		return "", status.Error(codes.FailedPrecondition, "node encryption is not supported on this node")
+	}
+	mapperName := encryption.MapperName(diskName)
+	mapperPath := encryption.MapperPath(mapperName)
+
+	open, err := ns.Luks.IsOpen(mapperName)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to check status of LUKS mapping %s: %v", mapperName, err.Error())
+	}
+	if open {
+		klog.V(4).Infof("LUKS mapping %s for %s is already open", mapperName, devicePath)
+		return mapperPath, nil
+	}
+
+	passphrase, err := ns.getEncryptionPassphrase(ctx, volumeContext, secrets)
+	if err != nil {
+		return "", err
+	}
+
+	isLuks, err := ns.Luks.IsLuks(devicePath)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to check if %s is a LUKS device: %v", devicePath, err.Error())
+	}
+	if !isLuks {
+		// Never encrypt over existing data: only blank disks get a LUKS header.
+		existingFormat, err := getDiskFormat(devicePath, ns.Mounter)
+		if err != nil {
+			return "", status.Errorf(codes.Internal, "failed to get disk format of %s: %v", devicePath, err.Error())
+		}
+		if existingFormat != "" {
+			return "", status.Errorf(codes.FailedPrecondition, "refusing to encrypt %s which already contains a %q filesystem", devicePath, existingFormat)
+		}
+		if err := ns.Luks.Format(devicePath, passphrase); err != nil {
+			return "", status.Errorf(codes.Internal, "failed to format %s as LUKS: %v", devicePath, err.Error())
+		}
+	}
+
+	if err := ns.Luks.Open(devicePath, mapperName, passphrase); err != nil {
+		return "", status.Errorf(codes.Internal, "failed to open LUKS device %s: %v", devicePath, err.Error())
+	}
+	klog.V(4).Infof("Opened LUKS device %s at %s", devicePath, mapperPath)
+	return mapperPath, nil
+}
+
+// closeEncryptedDevice closes the LUKS mapping of diskName if there is one.
+func (ns *GCENodeServer) closeEncryptedDevice(diskName string) error {
+	if ns.Luks == nil {
+		return nil
+	}
+	mapperName := encryption.MapperName(diskName)
+	open, err := ns.Luks.IsOpen(mapperName)
+	if err != nil {
+		return fmt.Errorf("failed to check status of LUKS mapping %s: %w", mapperName, err)
+	}
+	if !open {
+		return nil
+	}
+	return ns.Luks.Close(mapperName)
+}
+
+// resizeEncryptedDevice grows the LUKS mapping of diskName to the size of the
+// underlying disk. It returns the path of the plaintext device, or "" if the
+// disk is not encrypted.
+func (ns *GCENodeServer) resizeEncryptedDevice(diskName string) (string, error) {
+	if ns.Luks == nil {
+		return "", nil
+	}
+	mapperName := encryption.MapperName(diskName)
+	open, err := ns.Luks.IsOpen(mapperName)
+	if err != nil {
+		return "", fmt.Errorf("failed to check status of LUKS mapping %s: %w", mapperName, err)
+	}
+	if !open {
+		return "", nil
+	}
+	if err := ns.Luks.Resize(mapperName); err != nil {
+		return "", err
+	}
+	return encryption.MapperPath(mapperName), nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const (
+	finalSnapshotPrefix = "final-"
+	// maxSnapshotNameLen is the length limit of GCE resource names.
+	maxSnapshotNameLen = 63
+)
+
+// finalSnapshotName returns the name of the final snapshot of the volume.
+func finalSnapshotName(volumeID, diskName string) string {
+	return volumeSnapshotName(finalSnapshotPrefix, volumeID, diskName)
+}
+
+// volumeSnapshotName returns the name of a snapshot the driver takes of the
+// disk of a volume: the prefix and the disk name, truncated to fit, followed
+// by a hash of the volume ID so that disks of the same name in other zones or
+// projects get their own snapshot.
+func volumeSnapshotName(prefix, volumeID, diskName string) string {
+	hash := common.ShortString(volumeID)
+	if maxLen := maxSnapshotNameLen - len(prefix) - len(hash) - 1; len(diskName) > maxLen {
+		diskName = diskName[:maxLen]
+	}
+	return fmt.Sprintf("%s%s-%s", prefix, diskName, hash)
+}
+
+// takeFinalSnapshot snapshots the disk of the volume before it is deleted if
+// it was created with the snapshot-before-delete parameter. It returns once
+// the snapshot no longer needs the disk, and is idempotent so that a retried
+// DeleteVolume reuses the snapshot of the previous attempt.
+func (gceCS *GCEControllerServer) takeFinalSnapshot(ctx context.Context, volumeID, project string, volKey *meta.Key, disk *gce.CloudDisk) error {
+	diskLabels := disk.GetLabels()
+	if diskLabels[constants.FinalSnapshotLabel] != "true" {
+		return nil
+	}
+	cloudProvider := gceCS.cloudProvider(ctx)
+	snapshotName := finalSnapshotName(volumeID, volKey.Name)
+	if gceCS.finalSnapshotCollector != nil {
+		// The project may only be accessible with the credentials of a
+		// Secret.
+		gceCS.finalSnapshotCollector.AddProject(project, cloudProvider)
+	}
+
+	snapshot, err := cloudProvider.GetSnapshot(ctx, project, snapshotName)
+	if gce.IsGCENotFoundError(err) {
+		klog.V(4).Infof("Taking final snapshot %s of disk %v before deleting it", snapshotName, volKey)
+		snapshotParams := parameters.FinalSnapshotParameters(diskLabels, gce.DecodeTags(disk.GetDescription()), gceCS.Driver.name)
+		snapshot, err = cloudProvider.CreateSnapshot(ctx, project, volKey, snapshotName, snapshotParams)
+		if err != nil {
+			return common.LoggedError(fmt.Sprintf("Failed to create final snapshot %s: ", snapshotName), err)
+		}
+	} else if err != nil {
+		return common.LoggedError(fmt.Sprintf("Failed to get final snapshot %s: ", snapshotName), err)
+	}
+
+	switch snapshot.Status {
+	case "CREATING":
+		return status.Errorf(codes.Unavailable, "final snapshot %s of disk %v is still being created", snapshotName, volKey)
+	case "DELETING":
+		// The snapshot is as good as missing, the retry takes a new one once
+		// it is deleted.
+		return status.Errorf(codes.Unavailable, "final snapshot %s of disk %v is being deleted, retrying", snapshotName, volKey)
+	case "FAILED":
+		// Delete the failed snapshot so that the retry takes a new one.
+		if err := cloudProvider.DeleteSnapshot(ctx, project, snapshotName); err != nil {
+			return common.LoggedError(fmt.Sprintf("Failed to delete failed final snapshot %s: ", snapshotName), err)
+		}
+		return status.Errorf(codes.Unavailable, "final snapshot %s of disk %v failed, retrying", snapshotName, volKey)
+	}
+	klog.V(4).Infof("Final snapshot %s of disk %v is %s", snapshotName, volKey, snapshot.Status)
+	return nil
+}
+
+// FinalSnapshotCollector deletes the final snapshots of deleted volumes once
+// their retention has expired. It collects the final snapshots of its
+// projects, and of the projects the driver took final snapshots in with the
+// credentials of a Secret since it started.
+type FinalSnapshotCollector struct {
+	clock clock.PassiveClock
+
+	mutex sync.Mutex
+	// projects maps the projects whose final snapshots are collected to the
+	// cloud provider listing and deleting them.
+	projects map[string]gce.GCECompute
+}
+
+// NewFinalSnapshotCollector returns a FinalSnapshotCollector listing and
+// deleting the snapshots of the default project of cloudProvider and of
+// projects with cloudProvider.
+func NewFinalSnapshotCollector(cloudProvider gce.GCECompute, projects []string) *FinalSnapshotCollector {
+	c := &FinalSnapshotCollector{
+		clock:    clock.RealClock{},
+		projects: map[string]gce.GCECompute{cloudProvider.GetDefaultProject(): cloudProvider},
+	}
+	for _, project := range projects {
+		c.AddProject(project, cloudProvider)
+	}
+	return c
+}
+
+// AddProject makes the collector collect the final snapshots of project with
+// cloudProvider, unless it already collects them.
+func (c *FinalSnapshotCollector) AddProject(project string, cloudProvider gce.GCECompute) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.projects[project]; !ok {
+		klog.V(4).Infof("Collecting the final snapshots of project %s", project)
+		c.projects[project] = cloudProvider
+	}
+}
+
+// Run deletes the expired final snapshots every period until ctx is done.
+func (c *FinalSnapshotCollector) Run(ctx context.Context, period time.Duration) {
+	klog.V(2).Infof("Starting final snapshot collector with period %v", period)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.sync(ctx); err != nil {
+			klog.Errorf("Failed to collect final snapshots: %v", err)
+		}
+	}, period)
+}
+
+func (c *FinalSnapshotCollector) sync(ctx context.Context) error {
+	c.mutex.Lock()
+	projects := maps.Clone(c.projects)
+	c.mutex.Unlock()
+
+	var errs []error
+	for _, project := range slices.Sorted(maps.Keys(projects)) {
+		if err := c.syncProject(ctx, projects[project], project); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *FinalSnapshotCollector) syncProject(ctx context.Context, cloudProvider gce.GCECompute, project string) error {
+	filter := fmt.Sprintf("labels.%s eq [0-9]+", constants.FinalSnapshotRetentionDaysLabel)
+	var expired []string
+	pageToken := ""
+	for {
+		snapshots, nextPageToken, err := cloudProvider.ListSnapshots(ctx, project, filter, snapshotListPageSize, pageToken)
+		if err != nil {
+			return fmt.Errorf("failed to list final snapshots of project %s: %w", project, err)
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.Status != "DELETING" && c.isExpired(snapshot) {
+				expired = append(expired, snapshot.Name)
+			}
+		}
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+
+	var errs []error
+	for _, name := range expired {
+		klog.V(4).Infof("Deleting expired final snapshot %s of project %s", name, project)
+		if err := cloudProvider.DeleteSnapshot(ctx, project, name); err != nil && !gce.IsGCENotFoundError(err) {
+			errs = append(errs, fmt.Errorf("failed to delete final snapshot %s of project %s: %w", name, project, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isExpired returns whether the snapshot is a final snapshot older than its
+// retention days.
+func (c *FinalSnapshotCollector) isExpired(snapshot *computev1.Snapshot) bool {
+	if snapshot.Labels[constants.FinalSnapshotLabel] != "true" {
+		return false
+	}
+	days, err := strconv.Atoi(snapshot.Labels[constants.FinalSnapshotRetentionDaysLabel])
+	if err != nil || days <= 0 {
+		return false
+	}
+	created, err := time.Parse(time.RFC3339, snapshot.CreationTimestamp)
+	if err != nil {
+		klog.Warningf("Skipping final snapshot %s with invalid creation timestamp %q: %v", snapshot.Name, snapshot.CreationTimestamp, err)
+		return false
+	}
+	return c.clock.Since(created) > time.Duration(days)*24*time.Hour
+}
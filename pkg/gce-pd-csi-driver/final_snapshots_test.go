@@ -0,0 +1,311 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/go-cmp/cmp"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	clock "k8s.io/utils/clock/testing"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+func listFakeSnapshots(t *testing.T, cloudProvider gce.GCECompute) []*computev1.Snapshot {
+	snapshots, _, err := cloudProvider.ListSnapshots(context.Background(), project, "", 0, "")
+	if err != nil {
+		t.Fatalf("ListSnapshots returned error: %v", err)
+	}
+	return snapshots
+}
+
+func TestDeleteVolumeFinalSnapshot(t *testing.T) {
+	volumeID := fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, name)
+	claimParams := map[string]string{
+		parameters.ParameterKeyType:         stdDiskType,
+		parameters.ParameterKeyPVCName:      "claim",
+		parameters.ParameterKeyPVCNamespace: "default",
+		parameters.ParameterKeyPVName:       name,
+	}
+	withParams := func(params map[string]string) map[string]string {
+		merged := map[string]string{}
+		for k, v := range claimParams {
+			merged[k] = v
+		}
+		for k, v := range params {
+			merged[k] = v
+		}
+		return merged
+	}
+
+	testCases := []struct {
+		name                string
+		params              map[string]string
+		expSnapshot         bool
+		expStorageLocations []string
+		expLabels           map[string]string
+	}{
+		{
+			name:   "no final snapshot",
+			params: withParams(nil),
+		},
+		{
+			name:                "final snapshot",
+			params:              withParams(map[string]string{parameters.ParameterKeySnapshotBeforeDelete: "true"}),
+			expSnapshot:         true,
+			expStorageLocations: []string{},
+			expLabels:           map[string]string{constants.FinalSnapshotLabel: "true"},
+		},
+		{
+			name: "final snapshot with storage location and retention",
+			params: withParams(map[string]string{
+				parameters.ParameterKeySnapshotBeforeDelete:                "true",
+				parameters.ParameterKeySnapshotBeforeDeleteStorageLocation: "us-central1",
+				parameters.ParameterKeySnapshotBeforeDeleteRetentionDays:   "7",
+			}),
+			expSnapshot:         true,
+			expStorageLocations: []string{"us-central1"},
+			expLabels: map[string]string{
+				constants.FinalSnapshotLabel:              "true",
+				constants.FinalSnapshotRetentionDaysLabel: "7",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+			cloudProvider := gceDriver.cs.CloudProvider
+			_, err := gceDriver.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:               name,
+				CapacityRange:      stdCapRange,
+				VolumeCapabilities: stdVolCaps,
+				Parameters:         tc.params,
+			})
+			if err != nil {
+				t.Fatalf("CreateVolume returned error: %v", err)
+			}
+
+			// The retry of a DeleteVolume reuses the final snapshot.
+			for i := 0; i < 2; i++ {
+				if _, err := gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID}); err != nil {
+					t.Fatalf("DeleteVolume returned error: %v", err)
+				}
+			}
+			if _, err := cloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, zone)); !gce.IsGCENotFoundError(err) {
+				t.Errorf("Expected disk to be deleted, got: %v", err)
+			}
+
+			snapshots := listFakeSnapshots(t, cloudProvider)
+			if !tc.expSnapshot {
+				if len(snapshots) != 0 {
+					t.Errorf("Expected no snapshots, got %d", len(snapshots))
+				}
+				return
+			}
+			if len(snapshots) != 1 {
+				t.Fatalf("Expected 1 final snapshot, got %d", len(snapshots))
+			}
+			snapshot := snapshots[0]
+			if want := finalSnapshotName(volumeID, name); snapshot.Name != want {
+				t.Errorf("Expected final snapshot name %s, got %s", want, snapshot.Name)
+			}
+			if !strings.HasSuffix(snapshot.SourceDisk, volumeID) {
+				t.Errorf("Expected source disk %s, got %s", volumeID, snapshot.SourceDisk)
+			}
+			if diff := cmp.Diff(tc.expStorageLocations, snapshot.StorageLocations); diff != "" {
+				t.Errorf("Unexpected storage locations (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expLabels, snapshot.Labels); diff != "" {
+				t.Errorf("Unexpected labels (-want +got):\n%s", diff)
+			}
+			expTags := map[string]string{
+				"kubernetes.io/created-for/pvc/name":      "claim",
+				"kubernetes.io/created-for/pvc/namespace": "default",
+				"kubernetes.io/created-for/pv/name":       name,
+				"storage.gke.io/created-by":               gceDriver.cs.Driver.name,
+			}
+			if diff := cmp.Diff(expTags, gce.DecodeTags(snapshot.Description)); diff != "" {
+				t.Errorf("Unexpected description tags (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeleteVolumeFinalSnapshotBeingDeleted(t *testing.T) {
+	volumeID := fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, name)
+	snapshotName := finalSnapshotName(volumeID, name)
+	fakeCloudProvider, err := gce.CreateFakeCloudProvider(project, zone, nil)
+	if err != nil {
+		t.Fatalf("Failed to create fake cloud provider: %v", err)
+	}
+	gceDriver := initGCEDriverWithCloudProvider(t, fakeCloudProvider, &GCEControllerServerArgs{})
+	_, err = gceDriver.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               name,
+		CapacityRange:      stdCapRange,
+		VolumeCapabilities: stdVolCaps,
+		Parameters: map[string]string{
+			parameters.ParameterKeyType:                 stdDiskType,
+			parameters.ParameterKeySnapshotBeforeDelete: "true",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume returned error: %v", err)
+	}
+	snapshot, err := fakeCloudProvider.CreateSnapshot(context.Background(), project, meta.ZonalKey(name, zone), snapshotName, parameters.SnapshotParameters{})
+	if err != nil {
+		t.Fatalf("CreateSnapshot returned error: %v", err)
+	}
+	snapshot.Status = "DELETING"
+
+	// The disk is kept until the snapshot being deleted is replaced.
+	_, err = gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected Unavailable, got: %v", err)
+	}
+	if _, err := fakeCloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, zone)); err != nil {
+		t.Fatalf("Expected disk to be kept, got: %v", err)
+	}
+
+	if err := fakeCloudProvider.DeleteSnapshot(context.Background(), project, snapshotName); err != nil {
+		t.Fatalf("DeleteSnapshot returned error: %v", err)
+	}
+	if _, err := gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID}); err != nil {
+		t.Fatalf("DeleteVolume returned error: %v", err)
+	}
+	if snapshots := listFakeSnapshots(t, fakeCloudProvider); len(snapshots) != 1 || snapshots[0].Name != snapshotName {
+		t.Errorf("Expected final snapshot %s, got %v", snapshotName, snapshots)
+	}
+}
+
+func TestFinalSnapshotName(t *testing.T) {
+	longName := strings.Repeat("a", 63)
+	for _, volumeID := range []string{
+		fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, name),
+		fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, longName),
+	} {
+		diskName := volumeID[strings.LastIndex(volumeID, "/")+1:]
+		snapshotName := finalSnapshotName(volumeID, diskName)
+		if len(snapshotName) > 63 {
+			t.Errorf("Final snapshot name %s is longer than 63 characters", snapshotName)
+		}
+		if other := finalSnapshotName(strings.Replace(volumeID, zone, secondZone, 1), diskName); other == snapshotName {
+			t.Errorf("Expected different final snapshot names for disks in different zones, got %s", snapshotName)
+		}
+	}
+}
+
+func TestFinalSnapshotCollector(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	expiredLabels := map[string]string{constants.FinalSnapshotLabel: "true", constants.FinalSnapshotRetentionDaysLabel: "7"}
+	snapshots := []struct {
+		name       string
+		project    string
+		labels     map[string]string
+		created    time.Time
+		status     string
+		expDeleted bool
+	}{
+		{
+			name:       "expired",
+			labels:     map[string]string{constants.FinalSnapshotLabel: "true", constants.FinalSnapshotRetentionDaysLabel: "7"},
+			created:    now.Add(-8 * 24 * time.Hour),
+			expDeleted: true,
+		},
+		{
+			name:    "not expired",
+			labels:  map[string]string{constants.FinalSnapshotLabel: "true", constants.FinalSnapshotRetentionDaysLabel: "7"},
+			created: now.Add(-6 * 24 * time.Hour),
+		},
+		{
+			name:    "no retention",
+			labels:  map[string]string{constants.FinalSnapshotLabel: "true"},
+			created: now.Add(-365 * 24 * time.Hour),
+		},
+		{
+			name:    "not a final snapshot",
+			labels:  map[string]string{constants.FinalSnapshotRetentionDaysLabel: "7"},
+			created: now.Add(-8 * 24 * time.Hour),
+		},
+		{
+			name:    "being deleted",
+			labels:  expiredLabels,
+			created: now.Add(-8 * 24 * time.Hour),
+			status:  "DELETING",
+		},
+		{
+			name:       "expired in allowed project",
+			project:    "allowed-project",
+			labels:     expiredLabels,
+			created:    now.Add(-8 * 24 * time.Hour),
+			expDeleted: true,
+		},
+		{
+			name:       "expired in project of secret credentials",
+			project:    "secret-project",
+			labels:     expiredLabels,
+			created:    now.Add(-8 * 24 * time.Hour),
+			expDeleted: true,
+		},
+		{
+			name:    "expired in other project",
+			project: "other-project",
+			labels:  expiredLabels,
+			created: now.Add(-8 * 24 * time.Hour),
+		},
+	}
+
+	fakeCloudProvider, err := gce.CreateFakeCloudProvider(project, zone, nil)
+	if err != nil {
+		t.Fatalf("Failed to create fake cloud provider: %v", err)
+	}
+	for i, s := range snapshots {
+		snapshotProject := project
+		if s.project != "" {
+			snapshotProject = s.project
+		}
+		snapshot, err := fakeCloudProvider.CreateSnapshot(context.Background(), snapshotProject, meta.ZonalKey(name, zone), fmt.Sprintf("snapshot-%d", i), parameters.SnapshotParameters{Labels: s.labels})
+		if err != nil {
+			t.Fatalf("CreateSnapshot returned error: %v", err)
+		}
+		snapshot.CreationTimestamp = s.created.Format(time.RFC3339)
+		if s.status != "" {
+			snapshot.Status = s.status
+		}
+	}
+
+	collector := NewFinalSnapshotCollector(fakeCloudProvider, []string{"allowed-project"})
+	collector.clock = clock.NewFakePassiveClock(now)
+	collector.AddProject("secret-project", fakeCloudProvider)
+	if err := collector.sync(context.Background()); err != nil {
+		t.Fatalf("sync returned error: %v", err)
+	}
+	for i, s := range snapshots {
+		_, err := fakeCloudProvider.GetSnapshot(context.Background(), project, fmt.Sprintf("snapshot-%d", i))
+		if deleted := gce.IsGCENotFoundError(err); deleted != s.expDeleted {
+			t.Errorf("Snapshot %q: expected deleted %v, got: %v", s.name, s.expDeleted, err)
+		}
+	}
+}
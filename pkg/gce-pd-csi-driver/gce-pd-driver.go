@@ -15,6 +15,7 @@ limitations under the License.
 package gceGCEDriver
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -160,6 +161,14 @@ func NewNodeServer(gceDriver *GCEDriver, mounter *mount.SafeFormatAndMount, devi
 		SysfsPath:                args.SysfsPath,
 		metricsManager:           args.MetricsManager,
 		DeviceCache:              args.DeviceCache,
+		EnableProjectQuota:       args.EnableProjectQuota,
+		ProjectQuota:             args.ProjectQuota,
+		Luks:                     args.Luks,
+		KeyUnwrapper:             args.KeyUnwrapper,
+		LVM:                      args.LVM,
+		EventRecorder:            args.EventRecorder,
+		EnableDiskTypeTopology:   args.EnableDiskTypeTopology,
+		AttachLimits:             args.AttachLimits,
 	}
 }
 
@@ -179,20 +188,79 @@ func NewControllerServer(gceDriver *GCEDriver, cloudProvider gce.GCECompute, err
 		enableHdHA:                  enableHdHA,
 		EnableDiskTopology:          args.EnableDiskTopology,
 		EnableDiskSizeValidation:    args.EnableDiskSizeValidation,
+		regionalFailover:            args.RegionalFailover,
+		finalSnapshotCollector:      args.FinalSnapshotCollector,
+		allowedProjects:             args.AllowedProjects,
+		clusterName:                 args.ClusterName,
+		deletionProtection:          args.DeletionProtection,
 	}
 }
 
-func (gceDriver *GCEDriver) Run(endpoint string, grpcLogCharCap int, enableOtelTracing bool, metricsManager *metrics.MetricsManager) {
+// Run serves the CSI services on endpoint until ctx is done, and then shuts
+// down gracefully, see Serve.
+func (gceDriver *GCEDriver) Run(ctx context.Context, endpoint string, grpcLogCharCap int, enableOtelTracing bool, metricsManager *metrics.MetricsManager, drainTimeout time.Duration) {
 	maxLogChar = grpcLogCharCap
 
 	klog.V(4).Infof("Driver: %v", gceDriver.name)
 	// Start the nonblocking GRPC
 	s := NewNonBlockingGRPCServer(enableOtelTracing, metricsManager)
+	gceDriver.Serve(ctx, s, endpoint, drainTimeout)
+}
+
+// Serve starts s and blocks until it stops. Once ctx is done, s stops
+// accepting RPCs, and the volume operations in flight get up to drainTimeout
+// to finish before s is stopped forcefully.
+func (gceDriver *GCEDriver) Serve(ctx context.Context, s NonBlockingGRPCServer, endpoint string, drainTimeout time.Duration) {
 	// TODO(#34): Only start specific servers based on a flag.
 	// In the future have this only run specific combinations of servers depending on which version this is.
 	// The schema for that was in util. basically it was just s.start but with some nil servers.
 
 	s.Start(endpoint, gceDriver.ids, gceDriver.cs, gceDriver.ns)
 
-	s.Wait()
+	stopped := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return
+	case <-ctx.Done():
+	}
+
+	klog.Infof("Shutting down, waiting up to %v for in-flight volume operations", drainTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	// Stop refuses new RPCs right away and returns once the running ones
+	// are done.
+	gracefullyStopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(gracefullyStopped)
+	}()
+	if locked := gceDriver.waitForVolumeOperations(drainCtx); len(locked) > 0 {
+		klog.Warningf("Volume operations still in flight after %v: %v", drainTimeout, locked)
+	}
+	select {
+	case <-gracefullyStopped:
+		klog.Infof("All RPCs finished, stopped serving")
+	case <-drainCtx.Done():
+		klog.Warningf("RPCs still running after %v, stopping forcefully", drainTimeout)
+		s.ForceStop()
+		<-gracefullyStopped
+	}
+	<-stopped
+}
+
+// waitForVolumeOperations waits until the controller and node servers hold
+// no volume locks or ctx is done, and returns the volumes still locked.
+func (gceDriver *GCEDriver) waitForVolumeOperations(ctx context.Context) []string {
+	locked := []string{}
+	if gceDriver.cs != nil && gceDriver.cs.volumeLocks != nil {
+		locked = append(locked, gceDriver.cs.volumeLocks.WaitIdle(ctx)...)
+	}
+	if gceDriver.ns != nil && gceDriver.ns.volumeLocks != nil {
+		locked = append(locked, gceDriver.ns.volumeLocks.WaitIdle(ctx)...)
+	}
+	return locked
 }
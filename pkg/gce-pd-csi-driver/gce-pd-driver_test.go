@@ -15,9 +15,13 @@ limitations under the License.
 package gceGCEDriver
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
 )
 
@@ -68,3 +72,140 @@ func initGCEDriverWithCloudProvider(t *testing.T, cloudProvider gce.GCECompute,
 	}
 	return gceDriver
 }
+
+// fakeGRPCServer is a NonBlockingGRPCServer whose running RPCs finish when
+// rpcsDone is closed, or when it is stopped forcefully.
+type fakeGRPCServer struct {
+	mutex        sync.Mutex
+	stopCalled   bool
+	forceStopped bool
+
+	rpcsDone  chan struct{}
+	forceStop chan struct{}
+	done      chan struct{}
+}
+
+func newFakeGRPCServer() *fakeGRPCServer {
+	return &fakeGRPCServer{
+		rpcsDone:  make(chan struct{}),
+		forceStop: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (s *fakeGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+}
+
+func (s *fakeGRPCServer) Wait() {
+	<-s.done
+}
+
+func (s *fakeGRPCServer) Stop() {
+	s.mutex.Lock()
+	s.stopCalled = true
+	s.mutex.Unlock()
+	select {
+	case <-s.rpcsDone:
+	case <-s.forceStop:
+	}
+	close(s.done)
+}
+
+func (s *fakeGRPCServer) ForceStop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.forceStopped = true
+	close(s.forceStop)
+}
+
+func TestServeShutdown(t *testing.T) {
+	testCases := []struct {
+		name           string
+		lockedVolume   string
+		finishAfter    time.Duration
+		drainTimeout   time.Duration
+		expForceStop   bool
+		expStillLocked bool
+	}{
+		{
+			name:         "no operations in flight",
+			drainTimeout: time.Minute,
+		},
+		{
+			name:         "operations finish within the drain timeout",
+			lockedVolume: "vol-1",
+			finishAfter:  50 * time.Millisecond,
+			drainTimeout: time.Minute,
+		},
+		{
+			name:           "operations outlast the drain timeout",
+			lockedVolume:   "vol-1",
+			finishAfter:    time.Hour,
+			drainTimeout:   50 * time.Millisecond,
+			expForceStop:   true,
+			expStillLocked: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := GetGCEDriver()
+			gceDriver.cs = &GCEControllerServer{volumeLocks: common.NewVolumeLocks()}
+			gceDriver.ns = &GCENodeServer{volumeLocks: common.NewVolumeLocks()}
+			s := newFakeGRPCServer()
+
+			// An operation in flight holds its volume lock until it finishes.
+			if tc.lockedVolume == "" {
+				close(s.rpcsDone)
+			} else {
+				gceDriver.ns.volumeLocks.TryAcquire(tc.lockedVolume)
+				timer := time.AfterFunc(tc.finishAfter, func() {
+					gceDriver.ns.volumeLocks.Release(tc.lockedVolume)
+					close(s.rpcsDone)
+				})
+				defer timer.Stop()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			served := make(chan struct{})
+			go func() {
+				gceDriver.Serve(ctx, s, "unix:/tmp/csi.sock", tc.drainTimeout)
+				close(served)
+			}()
+			cancel()
+			select {
+			case <-served:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("Serve did not return after the context was canceled")
+			}
+
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			if !s.stopCalled {
+				t.Errorf("Expected server to stop accepting RPCs")
+			}
+			if s.forceStopped != tc.expForceStop {
+				t.Errorf("Expected force stop: %v, got: %v", tc.expForceStop, s.forceStopped)
+			}
+			stillLocked := !gceDriver.ns.volumeLocks.TryAcquire(tc.lockedVolume)
+			if tc.lockedVolume != "" && stillLocked != tc.expStillLocked {
+				t.Errorf("Expected volume %s still locked: %v, got: %v", tc.lockedVolume, tc.expStillLocked, stillLocked)
+			}
+		})
+	}
+}
+
+func TestServeReturnsWhenServerStops(t *testing.T) {
+	s := newFakeGRPCServer()
+	close(s.rpcsDone)
+	served := make(chan struct{})
+	go func() {
+		GetGCEDriver().Serve(context.Background(), s, "unix:/tmp/csi.sock", time.Minute)
+		close(served)
+	}()
+	close(s.done)
+	select {
+	case <-served:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Serve did not return after the server stopped")
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/health"
+)
+
+// RegisterControllerHealthChecks adds the checks of the controller service,
+// which needs a working token source and access to the GCE API.
+func RegisterControllerHealthChecks(c *health.Checker, cloudProvider gce.GCECompute) {
+	c.Register("gce-api", func(ctx context.Context) error {
+		region, err := common.GetRegionFromZones([]string{cloudProvider.GetDefaultZone()})
+		if err != nil {
+			return err
+		}
+		// Listing the zones of a region is about the cheapest authenticated
+		// call of the compute API.
+		if _, err := cloudProvider.ListZones(ctx, region); err != nil {
+			return fmt.Errorf("failed to list zones of region %s: %w", region, err)
+		}
+		return nil
+	})
+}
+
+// RegisterHealthChecks adds the checks of the node service. checkUdevTools
+// is set when disks are discovered through the by-id symlinks of udev.
+func (ns *GCENodeServer) RegisterHealthChecks(c *health.Checker, checkUdevTools bool) {
+	c.Register("metadata", func(ctx context.Context) error {
+		if _, err := ns.MetadataService.GetAttachedDiskDeviceNames(); err != nil {
+			return err
+		}
+		return nil
+	})
+	if runtime.GOOS != "windows" {
+		c.Register("filesystem-tools", health.BinariesCheck("mkfs.ext4", "mkfs.xfs", "blkid", "fsck"))
+	}
+	if checkUdevTools {
+		c.Register("udev-tools", func(ctx context.Context) error {
+			return deviceutils.CheckUdevTools()
+		})
+	}
+	if ns.LVM != nil && ns.EnableDataCache && ns.DataCacheEnabledNodePool {
+		lvmTools := health.BinariesCheck("lvm", "mdadm")
+		c.Register("data-cache", func(ctx context.Context) error {
+			if err := lvmTools(ctx); err != nil {
+				return err
+			}
+			raided, err := IsRaided(ns.LVM)
+			if err != nil {
+				return err
+			}
+			// The volume group is only created when the first cached volume
+			// is staged.
+			if !raided {
+				return fmt.Errorf("RAID array %s of the local SSDs not found", raidedLocalSsdName)
+			}
+			return nil
+		})
+	}
+}
@@ -20,10 +20,14 @@ import (
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/health"
 )
 
 type GCEIdentityServer struct {
 	Driver *GCEDriver
+	// HealthChecker, if set, decides whether Probe reports the driver ready.
+	HealthChecker *health.Checker
 
 	// Embed UnimplementedIdentityServer to ensure the driver returns Unimplemented for any
 	// new RPC methods that might be introduced in future versions of the spec.
@@ -78,5 +82,10 @@ func (gceIdentity *GCEIdentityServer) GetPluginCapabilities(ctx context.Context,
 }
 
 func (gceIdentity *GCEIdentityServer) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
-	return &csi.ProbeResponse{}, nil
+	if gceIdentity.HealthChecker == nil {
+		return &csi.ProbeResponse{}, nil
+	}
+	// The details of failing checks are logged by the checker and served
+	// on the metrics server.
+	return &csi.ProbeResponse{Ready: wrapperspb.Bool(gceIdentity.HealthChecker.Ready(ctx))}, nil
 }
@@ -15,11 +15,15 @@ limitations under the License.
 package gceGCEDriver
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"context"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/health"
 )
 
 func TestGetPluginInfo(t *testing.T) {
@@ -92,3 +96,59 @@ func TestProbe(t *testing.T) {
 		t.Fatalf("Probe returned unexpected error: %v", err)
 	}
 }
+
+func TestProbeHealthChecks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		checks   map[string]error
+		expReady bool
+	}{
+		{
+			name:     "all checks pass",
+			checks:   map[string]error{"a": nil, "b": nil},
+			expReady: true,
+		},
+		{
+			name:     "a check fails",
+			checks:   map[string]error{"a": nil, "b": errors.New("broken")},
+			expReady: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := GetGCEDriver()
+			identityServer := NewIdentityServer(gceDriver)
+			identityServer.HealthChecker = health.NewChecker(time.Minute, time.Second, 1)
+			for name, err := range tc.checks {
+				identityServer.HealthChecker.Register(name, func(context.Context) error { return err })
+			}
+			if err := gceDriver.SetupGCEDriver(driver, "test-vendor", nil, nil, identityServer, nil, nil); err != nil {
+				t.Fatalf("Failed to setup GCE Driver: %v", err)
+			}
+
+			resp, err := gceDriver.ids.Probe(context.Background(), &csi.ProbeRequest{})
+			if err != nil {
+				t.Fatalf("Probe returned unexpected error: %v", err)
+			}
+			if resp.GetReady() == nil {
+				t.Fatalf("Probe did not report readiness")
+			}
+			if got := resp.GetReady().GetValue(); got != tc.expReady {
+				t.Errorf("Expected ready: %v, got: %v", tc.expReady, got)
+			}
+		})
+	}
+}
+
+func TestControllerHealthChecks(t *testing.T) {
+	fakeCloudProvider, err := gce.CreateFakeCloudProvider(project, zone, nil)
+	if err != nil {
+		t.Fatalf("Failed to create fake cloud provider: %v", err)
+	}
+	c := health.NewChecker(time.Minute, time.Second, 1)
+	RegisterControllerHealthChecks(c, fakeCloudProvider)
+	results := c.Results(context.Background())
+	if len(results) != 1 || results[0].Name != "gce-api" || !results[0].Healthy {
+		t.Errorf("Unexpected health check results: %+v", results)
+	}
+}
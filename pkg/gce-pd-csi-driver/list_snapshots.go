@@ -0,0 +1,217 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+)
+
+// snapshotListPageSize is the largest page size of the GCE list calls of
+// ListSnapshots, and the page size when the request has no max entries.
+const snapshotListPageSize = 500
+
+// Resources listed by ListSnapshots, snapshots first.
+const (
+	snapshotCursorSnapshots = "snapshots"
+	snapshotCursorImages    = "images"
+)
+
+// snapshotCursor is the position of a ListSnapshots listing in the snapshots,
+// then the images, of each of the projects of snapshotListProjects. It is the
+// CSI token of the next page, so the controller keeps no state between the
+// pages of a listing.
+type snapshotCursor struct {
+	// Project is the project of the next entry.
+	Project string `json:"j"`
+	// Resource is snapshotCursorSnapshots or snapshotCursorImages.
+	Resource string `json:"r"`
+	// PageToken is the GCE page token of the page of Resource holding the
+	// next entry.
+	PageToken string `json:"p,omitempty"`
+	// Offset is the index of the next entry in that page.
+	Offset int `json:"o,omitempty"`
+	// PageSize is the page size of the GCE list calls. It is kept for the
+	// whole listing so that the offsets stay valid.
+	PageSize int64 `json:"s"`
+}
+
+func (c snapshotCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSnapshotCursor(token string) (snapshotCursor, error) {
+	var c snapshotCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, err
+	}
+	if c.Resource != snapshotCursorSnapshots && c.Resource != snapshotCursorImages {
+		return c, fmt.Errorf("unknown resource %q", c.Resource)
+	}
+	if c.PageSize <= 0 || c.PageSize > snapshotListPageSize || c.Offset < 0 || int64(c.Offset) >= c.PageSize {
+		return c, fmt.Errorf("invalid page size %d or offset %d", c.PageSize, c.Offset)
+	}
+	return c, nil
+}
+
+// snapshotListProjects returns the projects listed by ListSnapshots: the
+// default project, then the projects StorageClasses may provision disks in,
+// whose snapshots are created in the project of their disk.
+func (gceCS *GCEControllerServer) snapshotListProjects(ctx context.Context) []string {
+	projects := []string{gceCS.cloudProvider(ctx).GetDefaultProject()}
+	for _, project := range gceCS.allowedProjects {
+		if !slices.Contains(projects, project) {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// listSnapshotEntries returns up to maxEntries snapshots and images matching
+// filter from the position of startingToken, or all of them when maxEntries
+// is 0, and the token of the next entry.
+func (gceCS *GCEControllerServer) listSnapshotEntries(ctx context.Context, filter string, maxEntries int, startingToken string) ([]*csi.ListSnapshotsResponse_Entry, string, error) {
+	projects := gceCS.snapshotListProjects(ctx)
+	cursor := snapshotCursor{Project: projects[0], Resource: snapshotCursorSnapshots, PageSize: snapshotListPageSize}
+	if maxEntries > 0 && maxEntries < snapshotListPageSize {
+		cursor.PageSize = int64(maxEntries)
+	}
+	if startingToken != "" {
+		var err error
+		cursor, err = decodeSnapshotCursor(startingToken)
+		if err == nil && !slices.Contains(projects, cursor.Project) {
+			err = fmt.Errorf("project %q is not listed", cursor.Project)
+		}
+		if err != nil {
+			return nil, "", status.Errorf(codes.Aborted, "ListSnapshots error with invalid startingToken %s: %v", startingToken, err.Error())
+		}
+	}
+
+	entries := []*csi.ListSnapshotsResponse_Entry{}
+	for {
+		page, nextPageToken, err := gceCS.listSnapshotPage(ctx, cursor, filter)
+		if err != nil {
+			return nil, "", err
+		}
+		page = page[min(cursor.Offset, len(page)):]
+		if maxEntries > 0 && len(entries)+len(page) > maxEntries {
+			n := maxEntries - len(entries)
+			entries = append(entries, page[:n]...)
+			cursor.Offset += n
+			return entries, cursor.encode(), nil
+		}
+		entries = append(entries, page...)
+
+		cursor.PageToken = nextPageToken
+		cursor.Offset = 0
+		if nextPageToken == "" {
+			if cursor.Resource == snapshotCursorSnapshots {
+				cursor.Resource = snapshotCursorImages
+			} else if i := slices.Index(projects, cursor.Project); i+1 < len(projects) {
+				cursor.Project = projects[i+1]
+				cursor.Resource = snapshotCursorSnapshots
+			} else {
+				return entries, "", nil
+			}
+		}
+		if maxEntries > 0 && len(entries) == maxEntries {
+			return entries, cursor.encode(), nil
+		}
+	}
+}
+
+// listSnapshotPage returns the entries of the GCE page of the cursor.
+func (gceCS *GCEControllerServer) listSnapshotPage(ctx context.Context, cursor snapshotCursor, filter string) ([]*csi.ListSnapshotsResponse_Entry, string, error) {
+	var entries []*csi.ListSnapshotsResponse_Entry
+	var nextPageToken string
+	switch cursor.Resource {
+	case snapshotCursorSnapshots:
+		snapshots, token, err := gceCS.cloudProvider(ctx).ListSnapshots(ctx, cursor.Project, filter, cursor.PageSize, cursor.PageToken)
+		if err != nil {
+			return nil, "", listSnapshotsError("snapshots", err)
+		}
+		for _, snapshot := range snapshots {
+			entry, err := generateDiskSnapshotEntry(snapshot)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to generate snapshot entry: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+		nextPageToken = token
+	case snapshotCursorImages:
+		images, token, err := gceCS.cloudProvider(ctx).ListImages(ctx, cursor.Project, filter, cursor.PageSize, cursor.PageToken)
+		if err != nil {
+			return nil, "", listSnapshotsError("images", err)
+		}
+		for _, image := range images {
+			entry, err := generateDiskImageEntry(image)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to generate image entry: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+		nextPageToken = token
+	}
+	return entries, nextPageToken, nil
+}
+
+func listSnapshotsError(resource string, err error) error {
+	if gce.IsGCEInvalidError(err) {
+		return status.Errorf(codes.Aborted, "ListSnapshots error with invalid request: %v", err.Error())
+	}
+	return common.LoggedError(fmt.Sprintf("Failed to list %s: ", resource), err)
+}
+
+// sourceDiskFilter returns the GCE list filter of the snapshots and images of
+// the source volume, and false if the volume ID is invalid so nothing can
+// match it.
+func sourceDiskFilter(sourceVolumeID string) (string, bool) {
+	volumeID, ok := normalizeVolumeID(sourceVolumeID)
+	if !ok {
+		return "", false
+	}
+	// sourceDisk is the URL of the disk, whose host and API version depend
+	// on the compute endpoint.
+	return fmt.Sprintf("sourceDisk eq .*/%s", volumeID), true
+}
+
+// normalizeVolumeID returns the volume ID in the format of the source volume
+// IDs of the snapshot entries, and false if it is invalid.
+func normalizeVolumeID(volumeID string) (string, bool) {
+	project, volKey, err := common.VolumeIDToKey(volumeID)
+	if err != nil {
+		return "", false
+	}
+	volumeID, err = common.KeyToVolumeID(volKey, project)
+	if err != nil {
+		return "", false
+	}
+	return volumeID, true
+}
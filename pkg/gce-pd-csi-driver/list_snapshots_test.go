@@ -0,0 +1,225 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+// initSnapshotListDriver returns a driver with numSnapshots snapshots and
+// numImages images of each of two disks.
+func initSnapshotListDriver(t *testing.T, numSnapshots, numImages int) *GCEDriver {
+	diskNames := []string{"disk-a", "disk-b"}
+	disks := []*gce.CloudDisk{}
+	for _, diskName := range diskNames {
+		disks = append(disks, createZonalCloudDisk(diskName))
+	}
+	gceDriver := initGCEDriver(t, disks, &GCEControllerServerArgs{})
+	for _, diskName := range diskNames {
+		volumeID := fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, diskName)
+		for snapshotType, num := range map[string]int{parameters.DiskSnapshotType: numSnapshots, parameters.DiskImageType: numImages} {
+			for i := 0; i < num; i++ {
+				_, err := gceDriver.cs.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{
+					Name:           fmt.Sprintf("%s-%s-%d", diskName, snapshotType, i),
+					SourceVolumeId: volumeID,
+					Parameters:     map[string]string{parameters.ParameterKeySnapshotType: snapshotType},
+				})
+				if err != nil {
+					t.Fatalf("CreateSnapshot returned error: %v", err)
+				}
+			}
+		}
+	}
+	return gceDriver
+}
+
+// listAllSnapshots pages through ListSnapshots with the max entries of each
+// call, the last one being repeated.
+func listAllSnapshots(t *testing.T, cs csi.ControllerServer, req *csi.ListSnapshotsRequest, maxEntries ...int32) []string {
+	var ids []string
+	for i := 0; ; i++ {
+		req.MaxEntries = maxEntries[min(i, len(maxEntries)-1)]
+		resp, err := cs.ListSnapshots(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ListSnapshots returned error: %v", err)
+		}
+		if req.MaxEntries > 0 && len(resp.GetEntries()) > int(req.MaxEntries) {
+			t.Fatalf("ListSnapshots returned %d entries, more than max entries %d", len(resp.GetEntries()), req.MaxEntries)
+		}
+		for _, entry := range resp.GetEntries() {
+			ids = append(ids, entry.GetSnapshot().GetSnapshotId())
+		}
+		if resp.GetNextToken() == "" {
+			return ids
+		}
+		req.StartingToken = resp.GetNextToken()
+		if i > 100 {
+			t.Fatalf("ListSnapshots did not finish after %d pages", i)
+		}
+	}
+}
+
+func TestListSnapshotsPagination(t *testing.T) {
+	const numSnapshots, numImages = 4, 3
+	var all, diskB []string
+	for _, diskName := range []string{"disk-a", "disk-b"} {
+		for i := 0; i < numSnapshots; i++ {
+			id := fmt.Sprintf("projects/%s/global/snapshots/%s-%s-%d", project, diskName, parameters.DiskSnapshotType, i)
+			all = append(all, id)
+			if diskName == "disk-b" {
+				diskB = append(diskB, id)
+			}
+		}
+	}
+	for _, diskName := range []string{"disk-a", "disk-b"} {
+		for i := 0; i < numImages; i++ {
+			id := fmt.Sprintf("projects/%s/global/images/%s-%s-%d", project, diskName, parameters.DiskImageType, i)
+			all = append(all, id)
+			if diskName == "disk-b" {
+				diskB = append(diskB, id)
+			}
+		}
+	}
+	diskBVolumeID := fmt.Sprintf("projects/%s/zones/%s/disks/disk-b", project, zone)
+
+	testCases := []struct {
+		name       string
+		req        *csi.ListSnapshotsRequest
+		maxEntries []int32
+		expIDs     []string
+	}{
+		{
+			name:       "all entries",
+			req:        &csi.ListSnapshotsRequest{},
+			maxEntries: []int32{0},
+			expIDs:     all,
+		},
+		{
+			name:       "pages of one",
+			req:        &csi.ListSnapshotsRequest{},
+			maxEntries: []int32{1},
+			expIDs:     all,
+		},
+		{
+			name:       "pages across snapshots and images",
+			req:        &csi.ListSnapshotsRequest{},
+			maxEntries: []int32{3},
+			expIDs:     all,
+		},
+		{
+			name:       "max entries changing between pages",
+			req:        &csi.ListSnapshotsRequest{},
+			maxEntries: []int32{5, 2, 7, 0},
+			expIDs:     all,
+		},
+		{
+			name:       "source volume",
+			req:        &csi.ListSnapshotsRequest{SourceVolumeId: diskBVolumeID},
+			maxEntries: []int32{3},
+			expIDs:     diskB,
+		},
+		{
+			name:       "source volume without snapshots",
+			req:        &csi.ListSnapshotsRequest{SourceVolumeId: fmt.Sprintf("projects/%s/zones/%s/disks/disk-c", project, zone)},
+			maxEntries: []int32{3},
+		},
+		{
+			name:       "invalid source volume",
+			req:        &csi.ListSnapshotsRequest{SourceVolumeId: "invalid"},
+			maxEntries: []int32{3},
+		},
+		{
+			name:       "snapshot of the source volume",
+			req:        &csi.ListSnapshotsRequest{SnapshotId: diskB[0], SourceVolumeId: diskBVolumeID},
+			maxEntries: []int32{0},
+			expIDs:     diskB[:1],
+		},
+		{
+			name:       "snapshot of another source volume",
+			req:        &csi.ListSnapshotsRequest{SnapshotId: all[0], SourceVolumeId: diskBVolumeID},
+			maxEntries: []int32{0},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := initSnapshotListDriver(t, numSnapshots, numImages)
+			ids := listAllSnapshots(t, gceDriver.cs, tc.req, tc.maxEntries...)
+			if diff := cmp.Diff(tc.expIDs, ids); diff != "" {
+				t.Errorf("Unexpected snapshot IDs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListSnapshotsAllowedProjects(t *testing.T) {
+	const otherProject = "other-project"
+	gceDriver := initGCEDriver(t, []*gce.CloudDisk{createZonalCloudDisk("disk-a"), createZonalCloudDisk("disk-b")}, &GCEControllerServerArgs{
+		AllowedProjects: []string{otherProject, project},
+	})
+	disks := []struct{ project, name string }{{project, "disk-a"}, {otherProject, "disk-b"}}
+	// The snapshots then the images of the default project come first.
+	var expIDs []string
+	for _, disk := range disks {
+		for _, snapshotType := range []string{parameters.DiskSnapshotType, parameters.DiskImageType} {
+			name := fmt.Sprintf("%s-%s", disk.name, snapshotType)
+			_, err := gceDriver.cs.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{
+				Name:           name,
+				SourceVolumeId: fmt.Sprintf("projects/%s/zones/%s/disks/%s", disk.project, zone, disk.name),
+				Parameters:     map[string]string{parameters.ParameterKeySnapshotType: snapshotType},
+			})
+			if err != nil {
+				t.Fatalf("CreateSnapshot returned error: %v", err)
+			}
+			resource := "snapshots"
+			if snapshotType == parameters.DiskImageType {
+				resource = "images"
+			}
+			expIDs = append(expIDs, fmt.Sprintf("projects/%s/global/%s/%s", disk.project, resource, name))
+		}
+	}
+
+	for _, maxEntries := range []int32{0, 1, 3} {
+		ids := listAllSnapshots(t, gceDriver.cs, &csi.ListSnapshotsRequest{}, maxEntries)
+		if diff := cmp.Diff(expIDs, ids); diff != "" {
+			t.Errorf("Max entries %d: unexpected snapshot IDs (-want +got):\n%s", maxEntries, diff)
+		}
+	}
+}
+
+func TestListSnapshotsInvalidToken(t *testing.T) {
+	gceDriver := initSnapshotListDriver(t, 2, 2)
+	for _, token := range []string{
+		"invalid",
+		snapshotCursor{Project: project, Resource: "disks", PageSize: 10}.encode(),
+		snapshotCursor{Project: "unknown-project", Resource: snapshotCursorSnapshots, PageSize: 10}.encode(),
+		snapshotCursor{Project: project, Resource: snapshotCursorImages, PageSize: 10, Offset: 10}.encode(),
+		snapshotCursor{Project: project, Resource: snapshotCursorImages, PageSize: 10, PageToken: "invalid"}.encode(),
+	} {
+		_, err := gceDriver.cs.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{StartingToken: token})
+		if status.Code(err) != codes.Aborted {
+			t.Errorf("ListSnapshots with token %q: expected error code %v, got: %v", token, codes.Aborted, err)
+		}
+	}
+}
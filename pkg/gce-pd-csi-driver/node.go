@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -32,17 +33,21 @@ import (
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/mount-utils"
 
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/encryption"
 	metadataservice "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/metadata"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/linkcache"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
 	mountmanager "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/mount-manager"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/quota"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/resizefs"
 )
 
@@ -83,6 +88,29 @@ type GCENodeServer struct {
 	metricsManager *metrics.MetricsManager
 	// A cache of the device paths for the volumes that are attached to the node.
 	DeviceCache *linkcache.DeviceCache
+
+	// EnableProjectQuota allows volumes to be published as project quota
+	// limited subdirectories, see constants.VolumeAttributeQuotaSubPath.
+	EnableProjectQuota bool
+	ProjectQuota       quota.ProjectQuota
+
+	// Luks and KeyUnwrapper handle volumes provisioned with node-side
+	// encryption, see parameters.ParameterKeyNodeEncryption.
+	Luks         encryption.Luks
+	KeyUnwrapper encryption.KeyUnwrapper
+
+	// LVM manages the logical volumes and RAIDed local SSDs of Data Cache.
+	LVM lvm.LVM
+
+	// EventRecorder reports changes to the Data Cache of staged volumes.
+	EventRecorder k8sclient.EventRecorder
+
+	// EnableDiskTypeTopology publishes the disk types the machine type of
+	// the node can attach, and the attach limits of each disk family, in its
+	// topology.
+	EnableDiskTypeTopology bool
+	// AttachLimits override constants.MachineAttachLimits.
+	AttachLimits map[string]constants.DiskFamilyAttachLimits
 }
 
 type NodeServerArgs struct {
@@ -101,6 +129,27 @@ type NodeServerArgs struct {
 
 	MetricsManager *metrics.MetricsManager
 	DeviceCache    *linkcache.DeviceCache
+
+	EnableProjectQuota bool
+	ProjectQuota       quota.ProjectQuota
+
+	Luks         encryption.Luks
+	KeyUnwrapper encryption.KeyUnwrapper
+
+	// LVM manages the logical volumes and RAIDed local SSDs of Data Cache.
+	LVM lvm.LVM
+
+	// EventRecorder reports changes to the Data Cache of staged volumes.
+	EventRecorder k8sclient.EventRecorder
+
+	// EnableDiskTypeTopology publishes the disk types the machine type of
+	// the node can attach as disk-type.pd.csi.storage.gke.io/[disk-type] topology segments,
+	// and the attach limits of each disk family as
+	// attach-limit.pd.csi.storage.gke.io/[family] segments.
+	EnableDiskTypeTopology bool
+	// AttachLimits override the attach limits of constants.MachineAttachLimits
+	// by machine type or series.
+	AttachLimits map[string]constants.DiskFamilyAttachLimits
 }
 
 var _ csi.NodeServer = &GCENodeServer{}
@@ -112,18 +161,12 @@ var _ csi.NodeServer = &GCENodeServer{}
 // node boot disk is considered an attachable disk so effective attach limit is
 // one less.
 const (
-	volumeLimitSmall int64 = 15
-	volumeLimitBig   int64 = 127
-	// doc https://cloud.google.com/compute/docs/memory-optimized-machines#x4_disks
-	x4HyperdiskLimit int64 = 39
-	// doc https://cloud.google.com/compute/docs/accelerator-optimized-machines#a4-disks
-	a4HyperdiskLimit       int64 = 127
-	a4xMetalHyperdiskLimit int64 = 31
-	c3MetalHyperdiskLimit  int64 = 15
-	defaultLinuxFsType           = "ext4"
-	defaultWindowsFsType         = "ntfs"
-	fsTypeExt3                   = "ext3"
-	fsTypeBtrfs                  = "btrfs"
+	volumeLimitSmall     int64 = 15
+	volumeLimitBig       int64 = 127
+	defaultLinuxFsType         = "ext4"
+	defaultWindowsFsType       = "ntfs"
+	fsTypeExt3                 = "ext3"
+	fsTypeBtrfs                = "btrfs"
 
 	readAheadKBMountFlagRegexPattern        = "^read_ahead_kb=(.+)$"
 	btrfsReclaimDataRegexPattern            = "^btrfs-allocation-data-bg_reclaim_threshold=(\\d{1,2})$"     // 0-99 are valid, incl. 00
@@ -215,6 +258,12 @@ func (ns *GCENodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePub
 		return &csi.NodePublishVolumeResponse{}, nil
 	}
 
+	if readOnly {
+		if err := checkReadOnlyPublish(ns, volumeID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Perform a bind mount to the full path to allow duplicate mounts of the same PD.
 	fstype := ""
 	sourcePath := ""
@@ -236,6 +285,12 @@ func (ns *GCENodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePub
 		options = append(options, collectMountOptions(fstype, mnt.MountFlags)...)
 
 		sourcePath = stagingTargetPath
+		if isQuotaRequested(req.GetVolumeContext()) {
+			sourcePath, err = ns.setupQuotaSubPath(stagingTargetPath, fstype, req.GetVolumeContext())
+			if err != nil {
+				return nil, err
+			}
+		}
 		if err := preparePublishPath(targetPath, ns.Mounter); err != nil {
 			return nil, status.Error(codes.Internal, fmt.Sprintf("mkdir failed on disk %s (%v)", targetPath, err.Error()))
 		}
@@ -243,6 +298,10 @@ func (ns *GCENodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePub
 	} else if blk := volumeCapability.GetBlock(); blk != nil {
 		klog.V(4).Infof("NodePublishVolume with block volume mode")
 
+		if isQuotaRequested(req.GetVolumeContext()) {
+			return nil, status.Error(codes.InvalidArgument, "NodePublishVolume project quota is not supported for block volumes")
+		}
+
 		partition := ""
 		if part, ok := req.GetVolumeContext()[constants.VolumeAttributePartition]; ok {
 			partition = part
@@ -253,13 +312,9 @@ func (ns *GCENodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePub
 			return nil, status.Error(codes.Internal, fmt.Sprintf("Error when getting device path: %v", err.Error()))
 		}
 
-		// Expose block volume as file at target path
-		err = makeFile(targetPath)
+		sourcePath, err = prepareBlockPublishPath(sourcePath, targetPath, ns.Mounter)
 		if err != nil {
-			if removeErr := os.Remove(targetPath); removeErr != nil {
-				return nil, status.Error(codes.Internal, fmt.Sprintf("Error removing block file at target path %v: %v, mounti error: %v", targetPath, removeErr, err.Error()))
-			}
-			return nil, status.Error(codes.Internal, fmt.Sprintf("Failed to create block file at target path %v: %v", targetPath, err.Error()))
+			return nil, status.Error(codes.Internal, err.Error())
 		}
 	} else {
 		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("NodePublishVolume volume capability must specify either mount or block mode"))
@@ -362,6 +417,11 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("VolumeCapability is invalid: %v", err.Error()))
 	}
 
+	encrypted := isEncryptionRequested(req.GetVolumeContext())
+	if encrypted && volumeCapability.GetBlock() != nil {
+		return nil, status.Error(codes.InvalidArgument, "NodeStageVolume node encryption is not supported for block volumes")
+	}
+
 	// TODO(#253): Check volume capability matches for ALREADY_EXISTS
 
 	_, volumeKey, err := common.VolumeIDToKey(volumeID)
@@ -390,14 +450,17 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 		if err != nil {
 			klog.Errorf("filepath.EvalSymlinks(%q) failed when trying to create volume group: %v", devicePath, err)
 		}
-		configError := ValidateDataCacheConfig(req.GetPublishContext()[constants.ContextDataCacheMode], req.GetPublishContext()[constants.ContextDataCacheSize], ctx)
+		configError := ValidateDataCacheConfig(ns.LVM, req.GetPublishContext()[constants.ContextDataCacheMode], req.GetPublishContext()[constants.ContextDataCacheSize], ctx)
 		if configError != nil {
 			if ns.DataCacheEnabledNodePool {
 				return nil, status.Error(codes.DataLoss, fmt.Sprintf("Error validate configuration for Data Cache: %v", configError.Error()))
 			}
 			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("The Data Cache PVC is scheduled on an incompatible node pool. Please select a node pool with data cache configured: %v", configError.Error()))
 		}
-		devicePath, err = setupCaching(devFsPath, req, nodeId)
+		var reconfiguration *cacheReconfiguration
+		staged := ns.isVolumePathMounted(stagingTargetPath)
+		devicePath, reconfiguration, err = setupCaching(ns.LVM, devFsPath, req, nodeId, staged)
+		ns.reportCacheReconfiguration(volumeID, reconfiguration)
 		if err != nil {
 			errStatus, _ := status.FromError(err)
 			if errStatus.Code() == codes.InvalidArgument {
@@ -417,6 +480,16 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 		return nil, status.Error(codes.Internal, fmt.Sprintf("mkdir failed on disk %s (%v)", stagingTargetPath, err.Error()))
 	}
 
+	// The disk size is checked against the raw device, as the LUKS header takes
+	// up some room at the start of an encrypted device.
+	rawDevicePath := devicePath
+	if encrypted {
+		devicePath, err = ns.openEncryptedDevice(ctx, devicePath, volumeKey.Name, req.GetVolumeContext(), req.GetSecrets())
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Part 3: Mount device to stagingTargetPath
 	fstype := getDefaultFsType()
 
@@ -424,12 +497,23 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 	shouldUpdateReadAhead := false
 	var readAheadKB int64
 	options := []string{}
+	var formatOptions []string
+	readonly, _ := getReadOnlyFromCapability(volumeCapability)
 	if mnt := volumeCapability.GetMount(); mnt != nil {
 		if mnt.FsType != "" {
 			fstype = mnt.FsType
 		}
 		options = collectMountOptions(fstype, mnt.MountFlags)
 
+		if isQuotaRequested(req.GetVolumeContext()) {
+			quotaOptions, quotaFormatOptions, err := ns.prepareQuotaStaging(devicePath, fstype, readonly)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, quotaOptions...)
+			formatOptions = quotaFormatOptions
+		}
+
 		readAheadKB, shouldUpdateReadAhead, err = extractReadAheadKBMountFlag(mnt.MountFlags)
 		if err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "failure parsing mount flags: %v", err.Error())
@@ -444,7 +528,6 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
-	readonly, _ := getReadOnlyFromCapability(volumeCapability)
 	if readonly {
 		options = append(options, "ro")
 		klog.V(4).Infof("CSI volume is read-only, mounting with extra option ro")
@@ -452,16 +535,16 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 
 	// If a disk size is provided in the publish context, ensure it matches the actual device size.
 	if expectedSize := req.GetPublishContext()[constants.ContextDiskSizeGB]; expectedSize != "" {
-		actualSize, err := getBlockSizeBytes(devicePath, ns.Mounter)
+		actualSize, err := getBlockSizeBytes(rawDevicePath, ns.Mounter)
 		if err != nil {
-			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get block size for '%s': %v", devicePath, err.Error()))
+			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get block size for '%s': %v", rawDevicePath, err.Error()))
 		}
 		if expectedSize != strconv.FormatInt(actualSize, 10) {
 			return nil, status.Error(codes.Internal, fmt.Sprintf("expected block size %q, got %q", expectedSize, strconv.FormatInt(actualSize, 10)))
 		}
 	}
 
-	err = ns.formatAndMount(devicePath, stagingTargetPath, fstype, options, ns.Mounter)
+	err = ns.formatAndMount(devicePath, stagingTargetPath, fstype, options, formatOptions, ns.Mounter)
 	if err != nil {
 		// If a volume is created from a content source like snapshot or cloning, the filesystem might get marked
 		// as "dirty" even if it is otherwise consistent and ext3/4 will try to restore to a consistent state by replaying
@@ -472,7 +555,7 @@ func (ns *GCENodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStage
 			klog.V(4).Infof("Failed to mount CSI volume read-only, retry mounting with extra option noload")
 
 			options = append(options, "noload")
-			err = ns.formatAndMount(devicePath, stagingTargetPath, fstype, options, ns.Mounter)
+			err = ns.formatAndMount(devicePath, stagingTargetPath, fstype, options, formatOptions, ns.Mounter)
 			if err == nil {
 				klog.V(4).Infof("NodeStageVolume succeeded with \"noload\" option on %v to %s", volumeID, stagingTargetPath)
 				return &csi.NodeStageVolumeResponse{}, nil
@@ -665,11 +748,19 @@ func (ns *GCENodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUns
 		ns.deviceInUseErrors.deleteDevice(volumeID)
 	}
 
+	_, volumeKey, err := common.VolumeIDToKey(volumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "NodeUnstageVolume Volume ID is invalid: %v", err.Error())
+	}
+	if err := ns.closeEncryptedDevice(volumeKey.Name); err != nil {
+		return nil, status.Errorf(codes.Internal, "NodeUnstageVolume failed to close encrypted device for volume %s: %v", volumeID, err.Error())
+	}
+
 	// The NodeUnstageVolume does not have any volume or publish context, we need to get the info from LVM locally
 	// Check if cache group cache-{volumeID} exist in LVM
 	if ns.EnableDataCache && ns.DataCacheEnabledNodePool {
 		nodeId := ns.MetadataService.GetName()
-		err := cleanupCache(volumeID, nodeId)
+		err := cleanupCache(ns.LVM, volumeID, nodeId)
 		if err != nil {
 			return nil, status.Errorf(codes.DataLoss, "Failed to cleanup cache for volume %s: %v", volumeID, err)
 		}
@@ -716,6 +807,14 @@ func (ns *GCENodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRe
 	top := &csi.Topology{
 		Segments: map[string]string{constants.TopologyKeyZone: ns.MetadataService.GetZone()},
 	}
+	if ns.EnableDiskTypeTopology {
+		for k, v := range ns.diskTypeSegments() {
+			top.Segments[k] = v
+		}
+		for k, v := range ns.attachLimitSegments() {
+			top.Segments[k] = v
+		}
+	}
 
 	nodeID := common.CreateNodeID(ns.MetadataService.GetProject(), ns.MetadataService.GetZone(), ns.MetadataService.GetName())
 
@@ -734,6 +833,25 @@ func (ns *GCENodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRe
 	return resp, err
 }
 
+// diskTypeSegments returns a disk-type.pd.csi.storage.gke.io/[disk-type] topology segment
+// for every disk type of the compatibility table, "true" when the machine
+// type of the node can attach it and "false" otherwise. All nodes publish the
+// same keys, as the CSI provisioner requires. Machine series missing from the
+// table are assumed to attach all disk types.
+func (ns *GCENodeServer) diskTypeSegments() map[string]string {
+	machineType := ns.MetadataService.GetMachineType()
+	diskTypes, ok := common.AttachableDiskTypes(machineType)
+	if !ok {
+		klog.Warningf("Machine type %q is not in the disk compatibility table, publishing all disk types as attachable", machineType)
+		diskTypes = constants.CompatibilityDiskTypes
+	}
+	segments := map[string]string{}
+	for _, diskType := range constants.CompatibilityDiskTypes {
+		segments[common.NodeDiskTypeKey(diskType)] = strconv.FormatBool(slices.Contains(diskTypes, diskType))
+	}
+	return segments
+}
+
 func (ns *GCENodeServer) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
 	if len(req.VolumeId) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "NodeGetVolumeStats volume ID was empty")
@@ -768,6 +886,15 @@ func (ns *GCENodeServer) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGe
 			},
 		}, nil
 	}
+	if ns.EnableProjectQuota {
+		resp, err := ns.getProjectQuotaVolumeStats(req.VolumePath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get project quota stats on path %s: %v", req.VolumePath, err.Error())
+		}
+		if resp != nil {
+			return resp, nil
+		}
+	}
 	available, capacity, used, inodesFree, inodes, inodesUsed, err := ns.VolumeStatter.StatFS(req.VolumePath)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get fs info on path %s: %v", req.VolumePath, err.Error())
@@ -840,11 +967,34 @@ func (ns *GCENodeServer) NodeExpandVolume(ctx context.Context, req *csi.NodeExpa
 		}
 	}
 
+	// A volume staged with Data Cache has its filesystem on an LV which has to
+	// grow along with the PD first.
+	fsDevicePath := devicePath
+	if ns.EnableDataCache && ns.DataCacheEnabledNodePool {
+		lvPath, err := expandCachedVolume(ns.LVM, devicePath, volumeID, ns.MetadataService.GetName())
+		if err != nil {
+			return nil, status.Error(codes.Internal, fmt.Sprintf("error when resizing cached volume %s: %v", volKey.String(), err.Error()))
+		}
+		if lvPath != "" {
+			fsDevicePath = lvPath
+		}
+	}
+
+	// An encrypted disk has to grow its LUKS mapping before the filesystem on
+	// top of it can be resized.
+	mapperPath, err := ns.resizeEncryptedDevice(volKey.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("error when resizing encrypted device of volume %s: %v", volKey.String(), err.Error()))
+	}
+	if mapperPath != "" {
+		fsDevicePath = mapperPath
+	}
+
 	// TODO(#328): Use requested size in resize if provided
 	resizer := resizefs.NewResizeFs(ns.Mounter)
-	_, err = resizer.Resize(devicePath, volumePath)
+	_, err = resizer.Resize(fsDevicePath, volumePath)
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("error when resizing volume %s from device '%s' at path '%s': %v", volKey.String(), devicePath, volumePath, err.Error()))
+		return nil, status.Error(codes.Internal, fmt.Sprintf("error when resizing volume %s from device '%s' at path '%s': %v", volKey.String(), fsDevicePath, volumePath, err.Error()))
 
 	}
 
@@ -906,53 +1056,29 @@ func (ns *GCENodeServer) GetVolumeLimits(ctx context.Context) (int64, error) {
 		}
 	}
 
-	// Process gen4 machine attach limits which include vCPUs in the machine type
-	gen4MachineTypesPrefix := []string{"c4a-", "c4-", "n4-", "c4d-"}
-	for _, gen4Prefix := range gen4MachineTypesPrefix {
-		if strings.HasPrefix(machineType, gen4Prefix) {
-			machineTypeSlice := strings.Split(machineType, "-")
-			if len(machineTypeSlice) > 2 {
-				cpuString := machineTypeSlice[2]
-				cpus, err := strconv.ParseInt(cpuString, 10, 64)
-				if err != nil {
-					return volumeLimitBig, fmt.Errorf("invalid cpuString %s for machine type: %v", cpuString, machineType)
-				}
-				// Extract the machine type prefix (e.g., "c4", "c4a", "n4")
-				prefix := strings.TrimSuffix(gen4Prefix, "-")
-				return common.GetHyperdiskAttachLimit(prefix, cpus), nil
-			} else {
-				return volumeLimitBig, fmt.Errorf("unconventional machine type: %v", machineType)
-			}
-		}
-	}
-	// Process gen4 A4X machine attach limits, which have a -1g/-2g/-4g/metal suffix
-	if strings.HasPrefix(machineType, "a4x-") {
-		machineTypeSlice := strings.Split(machineType, "-")
-		if len(machineTypeSlice) < 3 {
-			return volumeLimitBig, fmt.Errorf("unconventional machine type: %v", machineType)
-		}
-		gpuString := machineTypeSlice[2]
-		if gpuString == "metal" {
-			return a4xMetalHyperdiskLimit, nil
-		}
-		gpuString = gpuString[0 : len(gpuString)-1] // Remove the 'g' suffix
-		gpus, err := strconv.ParseInt(gpuString, 10, 64)
-		if err != nil {
-			return volumeLimitBig, fmt.Errorf("invalid gpuString %s for machine type: %v", gpuString, machineType)
-		}
-		return common.GetHyperdiskAttachLimit("a4x", gpus), nil
-	}
-	if strings.HasPrefix(machineType, "x4-") {
-		return x4HyperdiskLimit, nil
+	pd, hyperdisk, err := common.GetDiskFamilyAttachLimits(machineType, ns.AttachLimits)
+	if err != nil {
+		return volumeLimitBig, err
 	}
-	if strings.HasPrefix(machineType, "a4-") {
-		return a4HyperdiskLimit, nil
+	// Disks of both families can be attached, up to the larger limit.
+	return max(pd, hyperdisk), nil
+}
+
+// attachLimitSegments returns the attach limit topology segments of every
+// disk family. Like the volume limit of GetVolumeLimits, they fall back to
+// volumeLimitBig for machine types whose limits are unknown, so that the
+// segments of every node can be relied on.
+func (ns *GCENodeServer) attachLimitSegments() map[string]string {
+	machineType := ns.MetadataService.GetMachineType()
+	pd, hyperdisk, err := common.GetDiskFamilyAttachLimits(machineType, ns.AttachLimits)
+	if err != nil {
+		klog.Warningf("Failed to get the attach limits of machine type %q, publishing %d for every disk family: %v", machineType, volumeLimitBig, err.Error())
+		pd, hyperdisk = volumeLimitBig, volumeLimitBig
 	}
-	if strings.HasPrefix(machineType, "c3-") && strings.HasSuffix(machineType, "-metal") {
-		return c3MetalHyperdiskLimit, nil
+	return map[string]string{
+		common.AttachLimitKey(constants.DiskFamilyPD):        strconv.FormatInt(pd, 10),
+		common.AttachLimitKey(constants.DiskFamilyHyperdisk): strconv.FormatInt(hyperdisk, 10),
 	}
-
-	return volumeLimitBig, nil
 }
 
 func GetAttachLimitsOverrideFromNodeLabel(ctx context.Context, nodeName string) (int64, error) {
@@ -970,3 +1096,25 @@ func GetAttachLimitsOverrideFromNodeLabel(ctx context.Context, nodeName string)
 	}
 	return 0, nil
 }
+
+// reportCacheReconfiguration records an event and a metric when setupCaching
+// tried to change the cache of an already cached volume.
+func (ns *GCENodeServer) reportCacheReconfiguration(volumeID string, r *cacheReconfiguration) {
+	if r == nil || r.outcome == cacheReconfigureUnchanged {
+		return
+	}
+	if ns.metricsManager != nil {
+		ns.metricsManager.RecordDataCacheReconfigureMetric(r.outcome)
+	}
+	if ns.EventRecorder == nil {
+		return
+	}
+	switch r.outcome {
+	case cacheReconfigured:
+		ns.EventRecorder.Eventf(v1.EventTypeNormal, "DataCacheReconfigured", "Volume %s: %s", volumeID, r.message)
+	case cacheReconfigureRefused:
+		ns.EventRecorder.Eventf(v1.EventTypeWarning, "DataCacheReconfigureRefused", "Volume %s: %s", volumeID, r.message)
+	default:
+		ns.EventRecorder.Eventf(v1.EventTypeWarning, "DataCacheReconfigureFailed", "Volume %s: %s", volumeID, r.message)
+	}
+}
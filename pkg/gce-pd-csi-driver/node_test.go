@@ -17,6 +17,7 @@ package gceGCEDriver
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path"
@@ -32,12 +33,15 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
 	"k8s.io/mount-utils"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/encryption"
 	metadataservice "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/metadata"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/linkcache"
 	mountmanager "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/mount-manager"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/quota"
 )
 
 const (
@@ -330,12 +334,12 @@ func TestNodeGetVolumeLimits(t *testing.T) {
 		{
 			name:           "x4-megamem-960-metal",
 			machineType:    "x4-megamem-960-metal",
-			expVolumeLimit: x4HyperdiskLimit,
+			expVolumeLimit: 39,
 		},
 		{
 			name:           "a4-highgpu-8g",
 			machineType:    "a4-highgpu-8g",
-			expVolumeLimit: a4HyperdiskLimit,
+			expVolumeLimit: 127,
 		},
 		{
 			name:           "c3-standard-4",
@@ -360,17 +364,17 @@ func TestNodeGetVolumeLimits(t *testing.T) {
 		{
 			name:           "c3-highcpu-192-metal",
 			machineType:    "c3-highcpu-192-metal",
-			expVolumeLimit: c3MetalHyperdiskLimit,
+			expVolumeLimit: 15,
 		},
 		{
 			name:           "c3-standard-192-metal",
 			machineType:    "c3-standard-192-metal",
-			expVolumeLimit: c3MetalHyperdiskLimit,
+			expVolumeLimit: 15,
 		},
 		{
 			name:           "c3-highmem-192-metal",
 			machineType:    "c3-highmem-192-metal",
-			expVolumeLimit: c3MetalHyperdiskLimit,
+			expVolumeLimit: 15,
 		},
 		{
 			name:           "a4x-highgpu-1g",
@@ -400,12 +404,12 @@ func TestNodeGetVolumeLimits(t *testing.T) {
 		{
 			name:           "a4x-highgpu-metal",
 			machineType:    "a4x-highgpu-metal",
-			expVolumeLimit: a4xMetalHyperdiskLimit,
+			expVolumeLimit: 31,
 		},
 		{
 			name:           "a4x-max-metal",
 			machineType:    "a4x-max-metal",
-			expVolumeLimit: a4xMetalHyperdiskLimit,
+			expVolumeLimit: 31,
 		},
 		{
 			name:           "a4x-max-1g",
@@ -453,6 +457,114 @@ func TestNodeGetVolumeLimits(t *testing.T) {
 	}
 }
 
+func TestNodeGetInfoDiskTypeTopology(t *testing.T) {
+	defer metadataservice.SetMachineType(metadataservice.FakeMachineType)
+
+	testCases := []struct {
+		name                   string
+		enableDiskTypeTopology bool
+		machineType            string
+		expSegments            map[string]string
+	}{
+		{
+			name:        "disabled",
+			machineType: "c4-standard-8",
+			expSegments: map[string]string{constants.TopologyKeyZone: metadataservice.FakeZone},
+		},
+		{
+			name:                   "n1 machine",
+			enableDiskTypeTopology: true,
+			machineType:            "n1-standard-4",
+			expSegments: map[string]string{
+				constants.TopologyKeyZone:                                              metadataservice.FakeZone,
+				"disk-type.pd.csi.storage.gke.io/pd-standard":                          "true",
+				"disk-type.pd.csi.storage.gke.io/pd-balanced":                          "true",
+				"disk-type.pd.csi.storage.gke.io/pd-ssd":                               "true",
+				"disk-type.pd.csi.storage.gke.io/pd-extreme":                           "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced":                   "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced-high-availability": "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-extreme":                    "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-throughput":                 "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-ml":                         "false",
+				"attach-limit.pd.csi.storage.gke.io/pd":                                "127",
+				"attach-limit.pd.csi.storage.gke.io/hyperdisk":                         "0",
+			},
+		},
+		{
+			name:                   "c4 machine",
+			enableDiskTypeTopology: true,
+			machineType:            "c4-standard-8",
+			expSegments: map[string]string{
+				constants.TopologyKeyZone:                                              metadataservice.FakeZone,
+				"disk-type.pd.csi.storage.gke.io/pd-standard":                          "false",
+				"disk-type.pd.csi.storage.gke.io/pd-balanced":                          "false",
+				"disk-type.pd.csi.storage.gke.io/pd-ssd":                               "false",
+				"disk-type.pd.csi.storage.gke.io/pd-extreme":                           "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced":                   "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced-high-availability": "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-extreme":                    "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-throughput":                 "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-ml":                         "true",
+				"attach-limit.pd.csi.storage.gke.io/pd":                                "0",
+				"attach-limit.pd.csi.storage.gke.io/hyperdisk":                         "31",
+			},
+		},
+		{
+			name:                   "machine type without vCPUs",
+			enableDiskTypeTopology: true,
+			machineType:            "c4-standard",
+			expSegments: map[string]string{
+				constants.TopologyKeyZone:                                              metadataservice.FakeZone,
+				"disk-type.pd.csi.storage.gke.io/pd-standard":                          "false",
+				"disk-type.pd.csi.storage.gke.io/pd-balanced":                          "false",
+				"disk-type.pd.csi.storage.gke.io/pd-ssd":                               "false",
+				"disk-type.pd.csi.storage.gke.io/pd-extreme":                           "false",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced":                   "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced-high-availability": "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-extreme":                    "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-throughput":                 "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-ml":                         "true",
+				"attach-limit.pd.csi.storage.gke.io/pd":                                "127",
+				"attach-limit.pd.csi.storage.gke.io/hyperdisk":                         "127",
+			},
+		},
+		{
+			name:                   "unknown machine series",
+			enableDiskTypeTopology: true,
+			machineType:            "zz9-standard-8",
+			expSegments: map[string]string{
+				constants.TopologyKeyZone:                                              metadataservice.FakeZone,
+				"disk-type.pd.csi.storage.gke.io/pd-standard":                          "true",
+				"disk-type.pd.csi.storage.gke.io/pd-balanced":                          "true",
+				"disk-type.pd.csi.storage.gke.io/pd-ssd":                               "true",
+				"disk-type.pd.csi.storage.gke.io/pd-extreme":                           "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced":                   "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-balanced-high-availability": "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-extreme":                    "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-throughput":                 "true",
+				"disk-type.pd.csi.storage.gke.io/hyperdisk-ml":                         "true",
+				"attach-limit.pd.csi.storage.gke.io/pd":                                "127",
+				"attach-limit.pd.csi.storage.gke.io/hyperdisk":                         "127",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metadataservice.SetMachineType(tc.machineType)
+			gceDriver := getTestGCEDriverWithCustomMounter(t, mountmanager.NewFakeSafeMounter(), &NodeServerArgs{
+				EnableDiskTypeTopology: tc.enableDiskTypeTopology,
+			})
+			res, err := gceDriver.ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			if err != nil {
+				t.Fatalf("Failed to get node info: %v", err)
+			}
+			if diff := cmp.Diff(tc.expSegments, res.GetAccessibleTopology().GetSegments()); diff != "" {
+				t.Errorf("NodeGetInfo topology: -want, +got \n%s", diff)
+			}
+		})
+	}
+}
+
 func TestNodePublishVolume(t *testing.T) {
 	gceDriver := getTestGCEDriver(t)
 	ns := gceDriver.ns
@@ -552,6 +664,460 @@ func TestNodePublishVolume(t *testing.T) {
 	}
 }
 
+func TestNodePublishVolumeProjectQuota(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "npvq")
+	if err != nil {
+		t.Fatalf("Failed to set up temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	stagingPath := filepath.Join(tempDir, defaultStagingPath)
+	if err := os.Mkdir(stagingPath, 0750); err != nil {
+		t.Fatalf("Failed to create staging path: %v", err)
+	}
+
+	xfsVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{FsType: "xfs"},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+	btrfsVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{FsType: "btrfs"},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		disableQuota     bool
+		volumeContext    map[string]string
+		volumeCapability *csi.VolumeCapability
+		// symlinks maps paths in the staging path to the targets of symlinks
+		// created there, "" being the temp dir.
+		symlinks map[string]string
+		// usedProjects maps project IDs to the directories of the staging
+		// path already using them.
+		usedProjects       map[uint32]string
+		expSourceSubPath   string
+		expProjectID       uint32
+		expLimitBytes      int64
+		expErrCode         codes.Code
+		expNoProjectConfig bool
+	}{
+		{
+			name: "ext4 subpath with derived project ID",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-a",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			volumeCapability: stdVolCap,
+			expSourceSubPath: "tenant-a",
+			expProjectID:     quota.ProjectIDForSubPath("tenant-a"),
+			expLimitBytes:    10 * 1024 * 1024 * 1024,
+		},
+		{
+			name: "xfs nested subpath with explicit project ID",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath:   "tenants/b/",
+				constants.VolumeAttributeQuotaLimit:     "500Mi",
+				constants.VolumeAttributeQuotaProjectID: "4242",
+			},
+			volumeCapability: xfsVolCap,
+			expSourceSubPath: "tenants/b",
+			expProjectID:     4242,
+			expLimitBytes:    500 * 1024 * 1024,
+		},
+		{
+			name: "subpath already in its project",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-c",
+				constants.VolumeAttributeQuotaLimit:   "1Gi",
+			},
+			usedProjects:     map[uint32]string{quota.ProjectIDForSubPath("tenant-c"): "tenant-c"},
+			volumeCapability: stdVolCap,
+			expSourceSubPath: "tenant-c",
+			expProjectID:     quota.ProjectIDForSubPath("tenant-c"),
+			expLimitBytes:    1024 * 1024 * 1024,
+		},
+		{
+			name: "derived project ID used by another subpath",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-d",
+				constants.VolumeAttributeQuotaLimit:   "1Gi",
+			},
+			usedProjects:     map[uint32]string{quota.ProjectIDForSubPath("tenant-d"): "tenant-e"},
+			volumeCapability: stdVolCap,
+			expErrCode:       codes.FailedPrecondition,
+		},
+		{
+			name: "quota not enabled on node",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-a",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			disableQuota:       true,
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "subpath escapes the volume",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "../other",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "absolute subpath",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "/etc",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "subpath through symlink",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "escape/etc",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			symlinks:           map[string]string{"escape": ""},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "subpath is a symlink",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenants/escape",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			symlinks:           map[string]string{"tenants/escape": ""},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "missing limit",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-a",
+			},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "invalid limit",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-a",
+				constants.VolumeAttributeQuotaLimit:   "lots",
+			},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "project ID in reserved range",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath:   "tenant-a",
+				constants.VolumeAttributeQuotaLimit:     "10Gi",
+				constants.VolumeAttributeQuotaProjectID: "7",
+			},
+			volumeCapability:   stdVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "unsupported filesystem",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-a",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			volumeCapability:   btrfsVolCap,
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+		{
+			name: "block volume",
+			volumeContext: map[string]string{
+				constants.VolumeAttributeQuotaSubPath: "tenant-a",
+				constants.VolumeAttributeQuotaLimit:   "10Gi",
+			},
+			volumeCapability:   createBlockVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+			expErrCode:         codes.InvalidArgument,
+			expNoProjectConfig: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeMounter := &mount.FakeMounter{MountPoints: []mount.MountPoint{}}
+			mounter := mountmanager.NewCustomFakeSafeMounter(fakeMounter, &testingexec.FakeExec{DisableScripts: true})
+			fakeQuota := quota.NewFakeProjectQuota()
+			gceDriver := getTestGCEDriverWithCustomMounter(t, mounter, &NodeServerArgs{
+				EnableProjectQuota: !tc.disableQuota,
+				ProjectQuota:       fakeQuota,
+			})
+			for id, dir := range tc.usedProjects {
+				fakeQuota.Projects[filepath.Join(stagingPath, dir)] = id
+				fakeQuota.Usage[id] = &quota.ProjectUsage{UsedInodes: 1}
+			}
+			targetPath := filepath.Join(tempDir, tc.name)
+			for link, target := range tc.symlinks {
+				link = filepath.Join(stagingPath, link)
+				if err := os.MkdirAll(filepath.Dir(link), 0750); err != nil {
+					t.Fatalf("Failed to create %s: %v", filepath.Dir(link), err)
+				}
+				if err := os.Symlink(filepath.Join(tempDir, target), link); err != nil {
+					t.Fatalf("Failed to create symlink %s: %v", link, err)
+				}
+				defer os.Remove(link)
+			}
+
+			_, err := gceDriver.ns.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+				VolumeId:          defaultVolumeID,
+				TargetPath:        targetPath,
+				StagingTargetPath: stagingPath,
+				VolumeCapability:  tc.volumeCapability,
+				VolumeContext:     tc.volumeContext,
+			})
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				if tc.expNoProjectConfig && len(fakeQuota.Projects) != 0 {
+					t.Errorf("Expected no project to be configured, got %v", fakeQuota.Projects)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expSource := filepath.Join(stagingPath, tc.expSourceSubPath)
+			if fi, err := os.Stat(expSource); err != nil || !fi.IsDir() {
+				t.Errorf("Expected quota subpath %s to be created: %v", expSource, err)
+			}
+			if got := fakeQuota.Projects[expSource]; got != tc.expProjectID {
+				t.Errorf("Expected project %d on %s, got %d", tc.expProjectID, expSource, got)
+			}
+			if got := fakeQuota.Usage[tc.expProjectID].LimitBytes; got != tc.expLimitBytes {
+				t.Errorf("Expected limit of %d bytes, got %d", tc.expLimitBytes, got)
+			}
+			if len(fakeMounter.MountPoints) != 1 {
+				t.Fatalf("Expected a single bind mount, got %v", fakeMounter.MountPoints)
+			}
+			if got := fakeMounter.MountPoints[0].Device; got != expSource {
+				t.Errorf("Expected bind mount from %s, got %s", expSource, got)
+			}
+		})
+	}
+}
+
+func TestPrepareQuotaStaging(t *testing.T) {
+	testCases := []struct {
+		name             string
+		fstype           string
+		existingFormat   string
+		featureEnabled   bool
+		readonly         bool
+		expOptions       []string
+		expFormatOptions []string
+		expFeatureEnable bool
+		expErrCode       codes.Code
+	}{
+		{
+			name:       "xfs",
+			fstype:     "xfs",
+			expOptions: []string{"prjquota"},
+		},
+		{
+			name:             "unformatted ext4",
+			fstype:           "ext4",
+			expOptions:       []string{"prjquota"},
+			expFormatOptions: []string{"-O", "project,quota"},
+		},
+		{
+			name:             "existing ext4",
+			fstype:           "ext4",
+			existingFormat:   "ext4",
+			expOptions:       []string{"prjquota"},
+			expFeatureEnable: true,
+		},
+		{
+			name:           "existing ext4 with project feature",
+			fstype:         "ext4",
+			existingFormat: "ext4",
+			featureEnabled: true,
+			expOptions:     []string{"prjquota"},
+		},
+		{
+			name:           "existing ext4 staged read-only",
+			fstype:         "ext4",
+			existingFormat: "ext4",
+			readonly:       true,
+		},
+		{
+			name:           "existing ext4 with project feature staged read-only",
+			fstype:         "ext4",
+			existingFormat: "ext4",
+			featureEnabled: true,
+			readonly:       true,
+			expOptions:     []string{"prjquota"},
+		},
+		{
+			name:       "ext3",
+			fstype:     "ext3",
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			blkidOutput := ""
+			if tc.existingFormat != "" {
+				blkidOutput = fmt.Sprintf("DEVNAME=/dev/sdb\nTYPE=%s", tc.existingFormat)
+			}
+			actionList := []testingexec.FakeCommandAction{
+				makeFakeCmd(
+					&testingexec.FakeCmd{
+						CombinedOutputScript: []testingexec.FakeAction{
+							func() ([]byte, []byte, error) {
+								return []byte(blkidOutput), nil, nil
+							},
+						},
+					},
+					"blkid",
+					strings.Split("-p -s TYPE -s PTTYPE -o export /dev/disk/fake-path", " ")...,
+				),
+			}
+			mounter := mountmanager.NewFakeSafeMounterWithCustomExec(&testingexec.FakeExec{CommandScript: actionList})
+			fakeQuota := quota.NewFakeProjectQuota()
+			if tc.featureEnabled {
+				fakeQuota.FeatureEnabledDevices = []string{"/dev/disk/fake-path"}
+			}
+			gceDriver := getTestGCEDriverWithCustomMounter(t, mounter, &NodeServerArgs{
+				EnableProjectQuota: true,
+				ProjectQuota:       fakeQuota,
+			})
+
+			options, formatOptions, err := gceDriver.ns.prepareQuotaStaging("/dev/disk/fake-path", tc.fstype, tc.readonly)
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expOptions, options); diff != "" {
+				t.Errorf("Unexpected mount options (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expFormatOptions, formatOptions); diff != "" {
+				t.Errorf("Unexpected format options (-want +got):\n%s", diff)
+			}
+			enabledDevices := 0
+			if tc.featureEnabled {
+				enabledDevices = 1
+			}
+			if got := len(fakeQuota.FeatureEnabledDevices) > enabledDevices; got != tc.expFeatureEnable {
+				t.Errorf("Expected project feature enabled %t, got %t", tc.expFeatureEnable, got)
+			}
+		})
+	}
+}
+
+func TestNodeGetVolumeStatsProjectQuota(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "ngvsq")
+	if err != nil {
+		t.Fatalf("Failed to set up temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	const projectID = 4242
+	testCases := []struct {
+		name         string
+		projectID    uint32
+		usage        *quota.ProjectUsage
+		expectedResp *csi.NodeGetVolumeStatsResponse
+		expectErr    bool
+	}{
+		{
+			name:      "quota limited subpath",
+			projectID: projectID,
+			usage: &quota.ProjectUsage{
+				UsedBytes:   1,
+				LimitBytes:  1024,
+				UsedInodes:  1,
+				LimitInodes: 100,
+			},
+			// The fake statter reports 1 byte and 1 inode available on the
+			// filesystem, which caps what the quota allows.
+			expectedResp: &csi.NodeGetVolumeStatsResponse{
+				Usage: []*csi.VolumeUsage{
+					{Unit: csi.VolumeUsage_BYTES, Available: 1, Total: 1024, Used: 1},
+					{Unit: csi.VolumeUsage_INODES, Available: 1, Total: 100, Used: 1},
+				},
+			},
+		},
+		{
+			name: "not quota limited",
+			expectedResp: &csi.NodeGetVolumeStatsResponse{
+				Usage: []*csi.VolumeUsage{
+					{Unit: csi.VolumeUsage_BYTES, Available: 1, Total: 1, Used: 1},
+					{Unit: csi.VolumeUsage_INODES, Available: 1, Total: 1, Used: 1},
+				},
+			},
+		},
+		{
+			name:      "project missing from report",
+			projectID: projectID,
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			volumePath := filepath.Join(tempDir, tc.name)
+			if err := os.MkdirAll(volumePath, 0750); err != nil {
+				t.Fatalf("Failed to create volume path: %v", err)
+			}
+			fakeQuota := quota.NewFakeProjectQuota()
+			if tc.projectID != 0 {
+				fakeQuota.Projects[volumePath] = tc.projectID
+			}
+			if tc.usage != nil {
+				fakeQuota.Usage[tc.projectID] = tc.usage
+			}
+			mounter := mountmanager.NewFakeSafeMounter()
+			gceDriver := getTestGCEDriverWithCustomMounter(t, mounter, &NodeServerArgs{
+				EnableProjectQuota: true,
+				ProjectQuota:       fakeQuota,
+			})
+			gceDriver.ns.VolumeStatter = mountmanager.NewFakeStatterWithOptions(mounter, mountmanager.FakeStatterOptions{IsBlock: false})
+
+			resp, err := gceDriver.ns.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{
+				VolumeId:   defaultVolumeID,
+				VolumePath: volumePath,
+			})
+			if gotErr := err != nil; gotErr != tc.expectErr {
+				t.Fatalf("Expected error %t, got: %v", tc.expectErr, err)
+			}
+			if diff := cmp.Diff(tc.expectedResp, resp, protocmp.Transform()); diff != "" {
+				t.Errorf("NodeGetVolumeStats returned unexpected response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestNodeUnpublishVolume(t *testing.T) {
 	gceDriver := getTestGCEDriver(t)
 	ns := gceDriver.ns
@@ -1368,6 +1934,167 @@ func TestNodeStageVolume(t *testing.T) {
 	}
 }
 
+func TestNodeStageVolumeEncryption(t *testing.T) {
+	const (
+		kmsKey     = "projects/my-project/locations/us-central1/keyRings/TestKeyRing/cryptoKeys/test-key"
+		mapperPath = "/dev/mapper/luks-testDisk"
+	)
+	luksContext := map[string]string{constants.ContextNodeEncryption: "luks"}
+	kmsContext := map[string]string{
+		constants.ContextNodeEncryption:       "luks",
+		constants.ContextNodeEncryptionKMSKey: kmsKey,
+	}
+
+	testCases := []struct {
+		name             string
+		volumeContext    map[string]string
+		secrets          map[string]string
+		volumeCapability *csi.VolumeCapability
+		existingFormat   string
+		existingKey      string
+		alreadyOpen      bool
+		expFormatted     bool
+		expErrCode       codes.Code
+	}{
+		{
+			name:          "blank disk is formatted and opened",
+			volumeContext: luksContext,
+			secrets:       map[string]string{"encryption-passphrase": "secret"},
+			expFormatted:  true,
+		},
+		{
+			name:          "existing LUKS device is opened",
+			volumeContext: luksContext,
+			secrets:       map[string]string{"encryption-passphrase": "secret"},
+			existingKey:   "secret",
+		},
+		{
+			name:          "already open mapping is reused",
+			volumeContext: luksContext,
+			existingKey:   "secret",
+			alreadyOpen:   true,
+		},
+		{
+			name:          "wrong passphrase",
+			volumeContext: luksContext,
+			secrets:       map[string]string{"encryption-passphrase": "wrong"},
+			existingKey:   "secret",
+			expErrCode:    codes.Internal,
+		},
+		{
+			name:          "missing passphrase",
+			volumeContext: luksContext,
+			expErrCode:    codes.InvalidArgument,
+		},
+		{
+			name:           "unencrypted filesystem is not encrypted",
+			volumeContext:  luksContext,
+			secrets:        map[string]string{"encryption-passphrase": "secret"},
+			existingFormat: "ext4",
+			expErrCode:     codes.FailedPrecondition,
+		},
+		{
+			name:          "KMS wrapped key",
+			volumeContext: kmsContext,
+			secrets:       map[string]string{"encryption-wrapped-key": base64.StdEncoding.EncodeToString([]byte("wrapped"))},
+			expFormatted:  true,
+		},
+		{
+			name:          "KMS wrapped key with unknown ciphertext",
+			volumeContext: kmsContext,
+			secrets:       map[string]string{"encryption-wrapped-key": base64.StdEncoding.EncodeToString([]byte("other"))},
+			expErrCode:    codes.Internal,
+		},
+		{
+			name:          "KMS key without wrapped key",
+			volumeContext: kmsContext,
+			secrets:       map[string]string{"encryption-passphrase": "secret"},
+			expErrCode:    codes.InvalidArgument,
+		},
+		{
+			name:             "block volume",
+			volumeContext:    luksContext,
+			secrets:          map[string]string{"encryption-passphrase": "secret"},
+			volumeCapability: createBlockVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+			expErrCode:       codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			fakeExec := &testingexec.FakeExec{DisableScripts: true}
+			if tc.existingFormat != "" {
+				fakeExec = &testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{
+					makeFakeCmd(
+						&testingexec.FakeCmd{
+							CombinedOutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) {
+									return []byte("DEVNAME=/dev/sdb\nTYPE=" + tc.existingFormat), nil, nil
+								},
+							},
+						},
+						"blkid",
+						strings.Split("-p -s TYPE -s PTTYPE -o export /dev/disk/fake-path", " ")...,
+					),
+				}}
+			}
+			fakeMounter := &mount.FakeMounter{MountPoints: []mount.MountPoint{}}
+			mounter := mountmanager.NewCustomFakeSafeMounter(fakeMounter, fakeExec)
+			fakeLuks := encryption.NewFakeLuks()
+			if tc.existingKey != "" {
+				fakeLuks.Formatted["/dev/disk/fake-path"] = []byte(tc.existingKey)
+			}
+			if tc.alreadyOpen {
+				fakeLuks.Opened["luks-testDisk"] = "/dev/disk/fake-path"
+			}
+			fakeUnwrapper := encryption.NewFakeKeyUnwrapper()
+			fakeUnwrapper.Keys[kmsKey] = map[string][]byte{"wrapped": []byte("unwrapped")}
+			gceDriver := getCustomTestGCEDriver(t, mounter, deviceutils.NewFakeDeviceUtils(true), metadataservice.NewFakeService(), &NodeServerArgs{
+				Luks:         fakeLuks,
+				KeyUnwrapper: fakeUnwrapper,
+			})
+			volumeCapability := tc.volumeCapability
+			if volumeCapability == nil {
+				volumeCapability = stdVolCap
+			}
+
+			_, err := gceDriver.ns.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{
+				VolumeId:          defaultVolumeID,
+				StagingTargetPath: filepath.Join(tempDir, defaultStagingPath),
+				VolumeCapability:  volumeCapability,
+				VolumeContext:     tc.volumeContext,
+				Secrets:           tc.secrets,
+			})
+			if tc.expErrCode != codes.OK {
+				if status.Code(err) != tc.expErrCode {
+					t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+				}
+				if len(fakeLuks.Opened) != 0 && !tc.alreadyOpen {
+					t.Errorf("Expected no LUKS mapping to be opened, got %v", fakeLuks.Opened)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, formatted := fakeLuks.Formatted["/dev/disk/fake-path"]
+			if tc.existingKey == "" && formatted != tc.expFormatted {
+				t.Errorf("Expected LUKS format: %v, got: %v", tc.expFormatted, formatted)
+			}
+			if got := fakeLuks.Opened["luks-testDisk"]; got != "/dev/disk/fake-path" {
+				t.Errorf("Expected luks-testDisk to map /dev/disk/fake-path, got %q", got)
+			}
+			if len(fakeMounter.MountPoints) != 1 {
+				t.Fatalf("Expected a single mount, got %v", fakeMounter.MountPoints)
+			}
+			if got := fakeMounter.MountPoints[0].Device; got != mapperPath {
+				t.Errorf("Expected %s to be mounted, got %s", mapperPath, got)
+			}
+		})
+	}
+}
+
 // TODO: This test is too brittle due to the fakeexec package not being
 // expressive enough for our purposes. The main issue being that the actions
 // executed by fakeexec are executed in order of definition instead of by
@@ -1599,6 +2326,29 @@ func TestNodeUnstageVolume(t *testing.T) {
 	}
 }
 
+func TestNodeUnstageVolumeEncryption(t *testing.T) {
+	fakeLuks := encryption.NewFakeLuks()
+	fakeLuks.Opened["luks-testDisk"] = "/dev/disk/fake-path"
+	fakeLuks.Opened["luks-otherDisk"] = "/dev/disk/other-path"
+	gceDriver := getTestGCEDriverWithCustomMounter(t, mountmanager.NewFakeSafeMounter(), &NodeServerArgs{
+		Luks: fakeLuks,
+	})
+
+	_, err := gceDriver.ns.NodeUnstageVolume(context.Background(), &csi.NodeUnstageVolumeRequest{
+		VolumeId:          defaultVolumeID,
+		StagingTargetPath: filepath.Join(t.TempDir(), defaultStagingPath),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := fakeLuks.Opened["luks-testDisk"]; ok {
+		t.Errorf("Expected luks-testDisk to be closed")
+	}
+	if _, ok := fakeLuks.Opened["luks-otherDisk"]; !ok {
+		t.Errorf("Expected luks-otherDisk to stay open")
+	}
+}
+
 func TestNodeGetCapabilities(t *testing.T) {
 	gceDriver := getTestGCEDriver(t)
 	ns := gceDriver.ns
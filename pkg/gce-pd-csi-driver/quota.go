@@ -0,0 +1,247 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/quota"
+)
+
+// ext4 only supports project quotas if the filesystem was created with the
+// project and quota features.
+var ext4ProjectQuotaFormatOptions = []string{"-O", "project,quota"}
+
+func isQuotaRequested(volumeContext map[string]string) bool {
+	return volumeContext[constants.VolumeAttributeQuotaSubPath] != ""
+}
+
+// prepareQuotaStaging makes sure the device will be mounted with project quota
+// support. It returns the extra mount options and, for ext4 devices that still
+// need to be formatted, the extra mkfs options. The project feature of
+// existing ext4 filesystems is only enabled if they don't have it yet, and not
+// on read-only stages, which must not modify the device: these are mounted
+// without project quota, which they could not be mounted with.
+func (ns *GCENodeServer) prepareQuotaStaging(devicePath, fstype string, readonly bool) ([]string, []string, error) {
+	if !ns.EnableProjectQuota {
+		return nil, nil, status.Error(codes.InvalidArgument, "project quota was requested but is not enabled on this node")
+	}
+	if !quota.SupportsFsType(fstype) {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "project quota is not supported on %q filesystems", fstype)
+	}
+
+	var formatOptions []string
+	if fstype == quota.FsTypeExt4 {
+		existingFormat, err := getDiskFormat(devicePath, ns.Mounter)
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Internal, "failed to get disk format of %s: %v", devicePath, err.Error())
+		}
+		if existingFormat == "" {
+			formatOptions = ext4ProjectQuotaFormatOptions
+		} else {
+			enabled, err := ns.ProjectQuota.HasProjectFeature(devicePath, existingFormat)
+			if err != nil {
+				return nil, nil, status.Errorf(codes.Internal, "failed to check project quota support of %s: %v", devicePath, err.Error())
+			}
+			if !enabled && readonly {
+				klog.V(4).Infof("Not enabling project quota on read-only device %s", devicePath)
+				return nil, nil, nil
+			}
+			if !enabled {
+				if err := ns.ProjectQuota.EnableProjectFeature(devicePath, existingFormat); err != nil {
+					return nil, nil, status.Errorf(codes.Internal, "failed to enable project quota on %s: %v", devicePath, err.Error())
+				}
+			}
+		}
+	}
+	return []string{quota.MountOptionPrjQuota}, formatOptions, nil
+}
+
+// setupQuotaSubPath creates the subdirectory requested in the volume context
+// under the staging path, confines it to a project quota and returns its path
+// so that it can be used as the source of the bind mount.
+func (ns *GCENodeServer) setupQuotaSubPath(stagingTargetPath, fstype string, volumeContext map[string]string) (string, error) {
+	if !ns.EnableProjectQuota {
+		return "", status.Error(codes.InvalidArgument, "project quota was requested but is not enabled on this node")
+	}
+	if !quota.SupportsFsType(fstype) {
+		return "", status.Errorf(codes.InvalidArgument, "project quota is not supported on %q filesystems", fstype)
+	}
+
+	subPath := volumeContext[constants.VolumeAttributeQuotaSubPath]
+	if !filepath.IsLocal(subPath) {
+		return "", status.Errorf(codes.InvalidArgument, "%s %q must be a relative path inside the volume", constants.VolumeAttributeQuotaSubPath, subPath)
+	}
+	subPath = filepath.Clean(subPath)
+
+	limitStr := volumeContext[constants.VolumeAttributeQuotaLimit]
+	if limitStr == "" {
+		return "", status.Errorf(codes.InvalidArgument, "%s must be set when %s is set", constants.VolumeAttributeQuotaLimit, constants.VolumeAttributeQuotaSubPath)
+	}
+	limit, err := resource.ParseQuantity(limitStr)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid %s %q: %v", constants.VolumeAttributeQuotaLimit, limitStr, err.Error())
+	}
+	if limit.Value() <= 0 {
+		return "", status.Errorf(codes.InvalidArgument, "%s must be positive, got %q", constants.VolumeAttributeQuotaLimit, limitStr)
+	}
+
+	projectID := quota.ProjectIDForSubPath(subPath)
+	if idStr, ok := volumeContext[constants.VolumeAttributeQuotaProjectID]; ok {
+		projectID, err = quota.ParseProjectID(idStr)
+		if err != nil {
+			return "", status.Errorf(codes.InvalidArgument, "invalid %s: %v", constants.VolumeAttributeQuotaProjectID, err.Error())
+		}
+	}
+
+	dir, err := mkdirQuotaSubPath(stagingTargetPath, subPath)
+	if err != nil {
+		return "", err
+	}
+	if err := ns.checkProjectIDUnused(dir, projectID); err != nil {
+		return "", err
+	}
+	if err := ns.ProjectQuota.SetProjectQuota(stagingTargetPath, dir, projectID, limit.Value()); err != nil {
+		return "", status.Errorf(codes.Internal, "failed to set project quota %d on %s: %v", projectID, dir, err.Error())
+	}
+	klog.V(4).Infof("Quota subpath %s is limited to %s by project %d", dir, limitStr, projectID)
+	return dir, nil
+}
+
+// checkProjectIDUnused makes sure that projectID, which may be derived from
+// the subpath of another directory, is either the project of dir already or
+// not used by any other directory of the filesystem. Sharing it would make
+// both directories share a single limit.
+func (ns *GCENodeServer) checkProjectIDUnused(dir string, projectID uint32) error {
+	currentID, err := ns.ProjectQuota.GetProjectID(dir)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get project ID of %s: %v", dir, err.Error())
+	}
+	if currentID == projectID {
+		return nil
+	}
+	usage, err := ns.ProjectQuota.GetProjectUsage(dir, projectID)
+	if errors.Is(err, quota.ErrProjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get usage of project %d: %v", projectID, err.Error())
+	}
+	if usage.UsedInodes > 0 {
+		return status.Errorf(codes.FailedPrecondition, "project %d of %s is already used by another directory of the volume, set %s to another project ID", projectID, dir, constants.VolumeAttributeQuotaProjectID)
+	}
+	return nil
+}
+
+// mkdirQuotaSubPath creates the directories of subPath under
+// stagingTargetPath and returns its path. The volume is writable by its pods,
+// so subPath must not go through symlinks: creating directories or bind
+// mounting through them could reach any path of the node.
+func mkdirQuotaSubPath(stagingTargetPath, subPath string) (string, error) {
+	dir := stagingTargetPath
+	for _, name := range strings.Split(subPath, string(filepath.Separator)) {
+		dir = filepath.Join(dir, name)
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(dir, 0750); err != nil && !os.IsExist(err) {
+				return "", status.Errorf(codes.Internal, "failed to create quota subpath %s: %v", dir, err.Error())
+			}
+			fi, err = os.Lstat(dir)
+		}
+		if err != nil {
+			return "", status.Errorf(codes.Internal, "failed to stat quota subpath %s: %v", dir, err.Error())
+		}
+		if !fi.IsDir() {
+			return "", status.Errorf(codes.InvalidArgument, "%s %q must only contain directories, %s is not one", constants.VolumeAttributeQuotaSubPath, subPath, dir)
+		}
+	}
+
+	// Check the resolved path too, in case a component was replaced since.
+	resolvedRoot, err := filepath.EvalSymlinks(stagingTargetPath)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to resolve staging path %s: %v", stagingTargetPath, err.Error())
+	}
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to resolve quota subpath %s: %v", dir, err.Error())
+	}
+	if rel, err := filepath.Rel(resolvedRoot, resolvedDir); err != nil || !filepath.IsLocal(rel) {
+		return "", status.Errorf(codes.InvalidArgument, "%s %q resolves to %s, outside of the volume", constants.VolumeAttributeQuotaSubPath, subPath, resolvedDir)
+	}
+	return dir, nil
+}
+
+// getProjectQuotaVolumeStats reports the usage of the quota project volumePath
+// belongs to. It returns nil if volumePath is not a quota limited subpath.
+func (ns *GCENodeServer) getProjectQuotaVolumeStats(volumePath string) (*csi.NodeGetVolumeStatsResponse, error) {
+	projectID, err := ns.ProjectQuota.GetProjectID(volumePath)
+	if err != nil {
+		// The filesystem might not support project IDs at all, in which case the
+		// volume is not quota limited.
+		klog.V(4).Infof("Could not get project ID of %s, reporting filesystem stats: %v", volumePath, err)
+		return nil, nil
+	}
+	if projectID == 0 {
+		return nil, nil
+	}
+
+	usage, err := ns.ProjectQuota.GetProjectUsage(volumePath, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage of project %d: %w", projectID, err)
+	}
+	available, capacity, _, inodesFree, inodes, _, err := ns.VolumeStatter.StatFS(volumePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The project can never use more than what is left on the filesystem, so
+	// the filesystem stats cap what the quota would otherwise allow.
+	if usage.LimitBytes > 0 {
+		capacity = usage.LimitBytes
+		available = min(available, max(usage.LimitBytes-usage.UsedBytes, 0))
+	}
+	if usage.LimitInodes > 0 {
+		inodes = usage.LimitInodes
+		inodesFree = min(inodesFree, max(usage.LimitInodes-usage.UsedInodes, 0))
+	}
+
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{
+				Unit:      csi.VolumeUsage_BYTES,
+				Available: available,
+				Total:     capacity,
+				Used:      usage.UsedBytes,
+			},
+			{
+				Unit:      csi.VolumeUsage_INODES,
+				Available: inodesFree,
+				Total:     inodes,
+				Used:      usage.UsedInodes,
+			},
+		},
+	}, nil
+}
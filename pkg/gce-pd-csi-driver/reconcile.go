@@ -0,0 +1,318 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
+)
+
+const (
+	reconcileUnmount        = "unmount"
+	reconcileForgetDevice   = "forget-device"
+	reconcileRemoveSymlink  = "remove-symlink"
+	reconcileRemoveMainLv   = "remove-main-lv"
+	reconcileRemoveCacheLv  = "remove-cache-lv"
+	reconcileOutcomeDryRun  = "dry-run"
+	reconcileOutcomeSuccess = "succeeded"
+	reconcileOutcomeFailure = "failed"
+
+	// volDataFileName is the file kubelet writes next to the staging path of
+	// a CSI volume, with the volume handle among others.
+	volDataFileName = "vol_data.json"
+)
+
+// ReconcileOptions configures ReconcileNode.
+type ReconcileOptions struct {
+	// StagingDir is the directory kubelet stages the volumes of the driver
+	// in, e.g. /var/lib/kubelet/plugins/kubernetes.io/csi/pd.csi.storage.gke.io.
+	StagingDir string
+	// ByIdDir is the directory of the by-id symlinks of the disks.
+	ByIdDir string
+	// DryRun only reports the actions that would be taken.
+	DryRun bool
+}
+
+// ReconcileAction is a change ReconcileNode made, or would make in a dry run,
+// to clean up after a volume that is no longer attached to the node.
+type ReconcileAction struct {
+	Action   string
+	Target   string
+	VolumeID string
+	Err      error
+}
+
+func (a ReconcileAction) outcome(dryRun bool) string {
+	switch {
+	case dryRun:
+		return reconcileOutcomeDryRun
+	case a.Err != nil:
+		return reconcileOutcomeFailure
+	default:
+		return reconcileOutcomeSuccess
+	}
+}
+
+// ReconcileNode cleans up the node state left behind by volumes that were
+// detached while the node plugin was not running, e.g. across a node reboot:
+// staging mounts and device cache entries of volumes whose disk is gone,
+// dangling by-id symlinks, and Data Cache logical volumes of detached disks.
+// The attached disks are taken from the metadata server, and nothing is
+// changed if they can't be listed.
+func (ns *GCENodeServer) ReconcileNode(opts ReconcileOptions) ([]ReconcileAction, error) {
+	deviceNames, err := ns.MetadataService.GetAttachedDiskDeviceNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attached disks: %w", err)
+	}
+	attached := sets.New(deviceNames...)
+	klog.V(2).Infof("Reconciling node state with attached disks %v (dry run: %v)", sets.List(attached), opts.DryRun)
+
+	r := &nodeReconciler{ns: ns, opts: opts, attached: attached}
+	var errs []error
+	if err := r.reconcileStagingMounts(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := r.reconcileSymlinks(); err != nil {
+		errs = append(errs, err)
+	}
+	if ns.LVM != nil && ns.EnableDataCache && ns.DataCacheEnabledNodePool {
+		if err := r.reconcileDataCache(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return r.actions, errors.Join(errs...)
+}
+
+type nodeReconciler struct {
+	ns       *GCENodeServer
+	opts     ReconcileOptions
+	attached sets.Set[string]
+	actions  []ReconcileAction
+}
+
+// do runs fn unless this is a dry run, and reports the action.
+func (r *nodeReconciler) do(action, target, volumeID string, fn func() error) {
+	a := ReconcileAction{Action: action, Target: target, VolumeID: volumeID}
+	if !r.opts.DryRun {
+		a.Err = fn()
+	}
+	outcome := a.outcome(r.opts.DryRun)
+	if a.Err != nil {
+		klog.Errorf("Startup reconciliation: %s %s of volume %q %s: %v", action, target, volumeID, outcome, a.Err)
+	} else {
+		klog.Infof("Startup reconciliation: %s %s of volume %q %s", action, target, volumeID, outcome)
+	}
+	if r.ns.metricsManager != nil {
+		r.ns.metricsManager.RecordStartupReconcileActionMetric(action, outcome)
+	}
+	r.actions = append(r.actions, a)
+}
+
+// isVolumeAttached reports whether the disk of volumeID is attached.
+func (r *nodeReconciler) isVolumeAttached(volumeID string) (bool, error) {
+	_, volumeKey, err := common.VolumeIDToKey(volumeID)
+	if err != nil {
+		return false, err
+	}
+	deviceName, err := common.GetDeviceName(volumeKey)
+	if err != nil {
+		return false, err
+	}
+	return r.attached.Has(deviceName), nil
+}
+
+// isDiskAttached reports whether the zonal or regional disk diskName is
+// attached, for state that only records the disk name.
+func (r *nodeReconciler) isDiskAttached(diskName string) bool {
+	for deviceName := range r.attached {
+		if common.DiskNameFromDeviceName(deviceName) == diskName {
+			return true
+		}
+	}
+	return false
+}
+
+// stagedVolumes returns the volume IDs of the staging paths in StagingDir,
+// which kubelet records in a vol_data.json next to each staging path.
+func (r *nodeReconciler) stagedVolumes() (map[string]string, error) {
+	entries, err := os.ReadDir(r.opts.StagingDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list staging directory %s: %w", r.opts.StagingDir, err)
+	}
+	volumes := map[string]string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(r.opts.StagingDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, volDataFileName))
+		if err != nil {
+			klog.V(4).Infof("Skipping staging directory %s: %v", dir, err)
+			continue
+		}
+		var volData struct {
+			VolumeHandle string `json:"volumeHandle"`
+		}
+		if err := json.Unmarshal(data, &volData); err != nil || volData.VolumeHandle == "" {
+			klog.Warningf("Skipping staging directory %s with invalid %s: %v", dir, volDataFileName, err)
+			continue
+		}
+		volumes[filepath.Join(dir, "globalmount")] = volData.VolumeHandle
+	}
+	return volumes, nil
+}
+
+// reconcileStagingMounts unmounts the staging paths of volumes that are not
+// attached, and drops them from the device cache.
+func (r *nodeReconciler) reconcileStagingMounts() error {
+	volumes, err := r.stagedVolumes()
+	if err != nil {
+		return err
+	}
+	mountPoints, err := r.ns.Mounter.List()
+	if err != nil {
+		return fmt.Errorf("failed to list mounts: %w", err)
+	}
+	mounted := sets.New[string]()
+	for _, mp := range mountPoints {
+		mounted.Insert(mp.Path)
+	}
+
+	for stagingPath, volumeID := range volumes {
+		isAttached, err := r.isVolumeAttached(volumeID)
+		if err != nil {
+			klog.Warningf("Skipping staging path %s of invalid volume %q: %v", stagingPath, volumeID, err)
+			continue
+		}
+		if isAttached {
+			continue
+		}
+		if mounted.Has(stagingPath) {
+			r.do(reconcileUnmount, stagingPath, volumeID, func() error {
+				return r.ns.Mounter.Unmount(stagingPath)
+			})
+		}
+		if r.ns.DeviceCache != nil {
+			r.do(reconcileForgetDevice, volumeID, volumeID, func() error {
+				r.ns.DeviceCache.RemoveVolume(volumeID)
+				return nil
+			})
+		}
+	}
+	return nil
+}
+
+// reconcileSymlinks removes the by-id symlinks of disks whose device is gone.
+func (r *nodeReconciler) reconcileSymlinks() error {
+	entries, err := os.ReadDir(r.opts.ByIdDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list %s: %w", r.opts.ByIdDir, err)
+	}
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink == 0 || !deviceutils.IsDiskByIdName(entry.Name()) {
+			continue
+		}
+		symlink := filepath.Join(r.opts.ByIdDir, entry.Name())
+		if _, err := os.Stat(symlink); !os.IsNotExist(err) {
+			continue
+		}
+		r.do(reconcileRemoveSymlink, symlink, "", func() error {
+			return os.Remove(symlink)
+		})
+	}
+	return nil
+}
+
+// reconcileDataCache removes the main logical volumes of detached disks from
+// the Data Cache volume group of the node, and the caches left without a
+// main logical volume, which frees their space on the local SSDs.
+func (r *nodeReconciler) reconcileDataCache() error {
+	l := r.ns.LVM
+	volumeGroupName := getVolumeGroupName(r.ns.MetadataService.GetName())
+	if !checkVgExists(l, volumeGroupName) {
+		return nil
+	}
+	lvs, err := l.ListLogicalVolumes(volumeGroupName)
+	if err != nil {
+		return fmt.Errorf("failed to list logical volumes of %s: %w", volumeGroupName, err)
+	}
+
+	staleMainLvs := sets.New[string]()
+	for _, lv := range lvs {
+		diskName, ok := strings.CutPrefix(lv.Name, mainLvSuffix+"-")
+		if ok && !r.isDiskAttached(diskName) {
+			staleMainLvs.Insert(lv.Name)
+		}
+	}
+	if staleMainLvs.Len() > 0 {
+		// The main logical volumes of detached disks are on missing physical
+		// volumes, which LVM only removes along with the physical volumes.
+		var removeErr error
+		if !r.opts.DryRun {
+			removeErr = l.RemoveMissingPhysicalVolumes(volumeGroupName, true /* force */)
+		}
+		for _, lvName := range sets.List(staleMainLvs) {
+			r.do(reconcileRemoveMainLv, volumeGroupName+"/"+lvName, strings.TrimPrefix(lvName, mainLvSuffix+"-"), func() error {
+				return removeErr
+			})
+		}
+		if !r.opts.DryRun {
+			if lvs, err = l.ListLogicalVolumes(volumeGroupName); err != nil {
+				return fmt.Errorf("failed to list logical volumes of %s: %w", volumeGroupName, err)
+			}
+		}
+	}
+
+	mainLvs := map[string]lvm.LogicalVolume{}
+	for _, lv := range lvs {
+		if strings.HasPrefix(lv.Name, mainLvSuffix+"-") && !staleMainLvs.Has(lv.Name) {
+			mainLvs[lv.Name] = lv
+		}
+	}
+	for _, lv := range lvs {
+		diskName, ok := strings.CutPrefix(strings.TrimSuffix(lv.Name, lvm.CacheVolSuffix), cacheSuffix+"-")
+		if !ok {
+			continue
+		}
+		// The cache of a staged volume is attached to its main logical
+		// volume, or about to be if setupCaching didn't complete.
+		if mainLv, ok := mainLvs[getLvName(mainLvSuffix, diskName)]; ok && (mainLv.PoolLV == lv.Name || mainLv.PoolLV == "") {
+			continue
+		}
+		lvName := lv.Name
+		r.do(reconcileRemoveCacheLv, volumeGroupName+"/"+lvName, diskName, func() error {
+			return l.RemoveLogicalVolume(volumeGroupName, lvName)
+		})
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/mount-utils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	metadataservice "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/metadata"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/linkcache"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/lvm"
+	mountmanager "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/mount-manager"
+)
+
+type reconcileTestNode struct {
+	stagingDir string
+	byIdDir    string
+	mounter    *mount.FakeMounter
+	lvm        *lvm.FakeLVM
+	ns         *GCENodeServer
+}
+
+// stage records volumeID in a staging directory the way kubelet does, and
+// mounts its staging path if mounted is set.
+func (n *reconcileTestNode) stage(t *testing.T, dirName, volumeID string, mounted bool) string {
+	t.Helper()
+	dir := filepath.Join(n.stagingDir, dirName)
+	stagingPath := filepath.Join(dir, "globalmount")
+	if err := os.MkdirAll(stagingPath, 0755); err != nil {
+		t.Fatalf("Failed to create staging path: %v", err)
+	}
+	if volumeID != "" {
+		volData := `{"driverName":"pd.csi.storage.gke.io","volumeHandle":"` + volumeID + `"}`
+		if err := os.WriteFile(filepath.Join(dir, volDataFileName), []byte(volData), 0644); err != nil {
+			t.Fatalf("Failed to write vol_data.json: %v", err)
+		}
+	}
+	if mounted {
+		n.mounter.MountPoints = append(n.mounter.MountPoints, mount.MountPoint{Device: "/dev/sdx", Path: stagingPath, Type: "ext4"})
+	}
+	return stagingPath
+}
+
+func (n *reconcileTestNode) link(t *testing.T, name, target string) string {
+	t.Helper()
+	symlink := filepath.Join(n.byIdDir, name)
+	if err := os.Symlink(target, symlink); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	return symlink
+}
+
+func newReconcileTestNode(t *testing.T) *reconcileTestNode {
+	n := &reconcileTestNode{
+		stagingDir: t.TempDir(),
+		byIdDir:    t.TempDir(),
+		mounter:    &mount.FakeMounter{MountPoints: []mount.MountPoint{}},
+		lvm:        lvm.NewFakeLVM(),
+	}
+	gceDriver := getCustomTestGCEDriver(t, mountmanager.NewCustomFakeSafeMounter(n.mounter, nil), deviceutils.NewFakeDeviceUtils(false), metadataservice.NewFakeService(), &NodeServerArgs{
+		EnableDataCache:          true,
+		DataCacheEnabledNodePool: true,
+		LVM:                      n.lvm,
+		DeviceCache:              linkcache.NewTestDeviceCache(time.Minute, linkcache.NewTestNodeWithVolumes(nil)),
+	})
+	n.ns = gceDriver.ns
+	return n
+}
+
+func TestReconcileNode(t *testing.T) {
+	const (
+		attachedVolume = "projects/test-project/zones/us-central1-c/disks/disk-attached"
+		detachedVolume = "projects/test-project/zones/us-central1-c/disks/disk-detached"
+		regionalVolume = "projects/test-project/regions/us-central1/disks/disk-regional"
+		unstagedVolume = "projects/test-project/zones/us-central1-c/disks/disk-unstaged"
+	)
+	metadataservice.SetAttachedDiskDeviceNames([]string{"persistent-disk-0", "disk-attached", "disk-regional_regional"})
+	t.Cleanup(func() { metadataservice.SetAttachedDiskDeviceNames([]string{}) })
+
+	for _, dryRun := range []bool{false, true} {
+		n := newReconcileTestNode(t)
+		attachedPath := n.stage(t, "attached", attachedVolume, true)
+		detachedPath := n.stage(t, "detached", detachedVolume, true)
+		regionalPath := n.stage(t, "regional", regionalVolume, true)
+		n.stage(t, "unstaged", unstagedVolume, false)
+		// Staging paths without vol_data.json are left alone.
+		unknownPath := n.stage(t, "unknown", "", true)
+
+		device := filepath.Join(t.TempDir(), "sdb")
+		if err := os.WriteFile(device, nil, 0644); err != nil {
+			t.Fatalf("Failed to create device: %v", err)
+		}
+		liveLink := n.link(t, "google-disk-attached", device)
+		danglingLinks := []string{
+			n.link(t, "google-disk-detached", "../../sdc"),
+			n.link(t, "scsi-0Google_PersistentDisk_disk-detached", "../../sdc"),
+		}
+		otherLink := n.link(t, "wwn-0x5000", "../../sdc")
+
+		// Data Cache of the attached and the detached disk, and a cache left
+		// behind without its main logical volume.
+		nodeId := metadataservice.FakeName
+		vgName := getVolumeGroupName(nodeId)
+		n.lvm.AddDevice("/dev/nvme0n1", int64(375*GiB))
+		n.lvm.AddDevice("/dev/sdb", int64(100*GiB))
+		n.lvm.AddDevice("/dev/sdc", int64(100*GiB))
+		if err := RaidLocalSsds(n.lvm, []string{"/dev/nvme0n1"}); err != nil {
+			t.Fatalf("RaidLocalSsds failed: %v", err)
+		}
+		if err := InitializeDataCacheNode(n.lvm, nodeId); err != nil {
+			t.Fatalf("InitializeDataCacheNode failed: %v", err)
+		}
+		for device, volumeID := range map[string]string{"/dev/sdb": attachedVolume, "/dev/sdc": detachedVolume} {
+			req := cacheStageRequest("10", constants.DataCacheModeWriteThrough)
+			req.VolumeId = volumeID
+			if _, _, err := setupCaching(n.lvm, device, req, nodeId, false); err != nil {
+				t.Fatalf("setupCaching failed: %v", err)
+			}
+		}
+		if err := n.lvm.CreateLogicalVolume(lvm.CreateLogicalVolumeOptions{Name: "csi-fast-disk-gone", VGName: vgName, SizeGiB: 10, PVs: []string{"/dev/md/" + raidedLocalSsdName}}); err != nil {
+			t.Fatalf("CreateLogicalVolume failed: %v", err)
+		}
+		n.lvm.RemoveDevice("/dev/sdc")
+
+		actions, err := n.ns.ReconcileNode(ReconcileOptions{StagingDir: n.stagingDir, ByIdDir: n.byIdDir, DryRun: dryRun})
+		if err != nil {
+			t.Fatalf("ReconcileNode failed: %v", err)
+		}
+		got := []string{}
+		for _, a := range actions {
+			if a.Err != nil {
+				t.Errorf("Action %s %s failed: %v", a.Action, a.Target, a.Err)
+			}
+			got = append(got, a.Action+" "+a.Target)
+		}
+		sort.Strings(got)
+		want := []string{
+			reconcileForgetDevice + " " + detachedVolume,
+			reconcileForgetDevice + " " + unstagedVolume,
+			reconcileRemoveCacheLv + " " + vgName + "/csi-fast-disk-detached" + lvm.CacheVolSuffix,
+			reconcileRemoveCacheLv + " " + vgName + "/csi-fast-disk-gone",
+			reconcileRemoveMainLv + " " + vgName + "/csi-main-disk-detached",
+			reconcileRemoveSymlink + " " + danglingLinks[0],
+			reconcileRemoveSymlink + " " + danglingLinks[1],
+			reconcileUnmount + " " + detachedPath,
+		}
+		sort.Strings(want)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Dry run %v: unexpected actions (-want +got):\n%s", dryRun, diff)
+		}
+
+		// Check what was actually cleaned up.
+		wantMounted := []string{attachedPath, regionalPath, unknownPath}
+		wantLinks := []string{liveLink, otherLink}
+		wantLvs := []string{"csi-fast-disk-attached" + lvm.CacheVolSuffix, "csi-main-disk-attached"}
+		if dryRun {
+			wantMounted = append(wantMounted, detachedPath)
+			wantLinks = append(wantLinks, danglingLinks...)
+			wantLvs = append(wantLvs, "csi-fast-disk-detached"+lvm.CacheVolSuffix, "csi-fast-disk-gone", "csi-main-disk-detached")
+		}
+		gotMounted := []string{}
+		for _, mp := range n.mounter.MountPoints {
+			gotMounted = append(gotMounted, mp.Path)
+		}
+		gotLinks := []string{}
+		entries, err := os.ReadDir(n.byIdDir)
+		if err != nil {
+			t.Fatalf("Failed to list by-id directory: %v", err)
+		}
+		for _, entry := range entries {
+			gotLinks = append(gotLinks, filepath.Join(n.byIdDir, entry.Name()))
+		}
+		gotLvs := []string{}
+		lvs, err := n.lvm.ListLogicalVolumes(vgName)
+		if err != nil {
+			t.Fatalf("ListLogicalVolumes failed: %v", err)
+		}
+		for _, lv := range lvs {
+			gotLvs = append(gotLvs, lv.Name)
+		}
+		sortStrings := cmp.Transformer("sort", func(in []string) []string {
+			out := append([]string{}, in...)
+			sort.Strings(out)
+			return out
+		})
+		if diff := cmp.Diff(wantMounted, gotMounted, sortStrings); diff != "" {
+			t.Errorf("Dry run %v: unexpected mounts (-want +got):\n%s", dryRun, diff)
+		}
+		if diff := cmp.Diff(wantLinks, gotLinks, sortStrings); diff != "" {
+			t.Errorf("Dry run %v: unexpected by-id symlinks (-want +got):\n%s", dryRun, diff)
+		}
+		if diff := cmp.Diff(wantLvs, gotLvs, sortStrings); diff != "" {
+			t.Errorf("Dry run %v: unexpected logical volumes (-want +got):\n%s", dryRun, diff)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+)
+
+const (
+	// Reasons of the events of regional failover, which are recorded on the
+	// NotReady node the disk is attached to.
+	eventReasonFailoverAllowed      = "RegionalFailoverAllowed"
+	eventReasonFailoverForceAttach  = "RegionalFailoverForceAttach"
+	eventReasonFailoverDetachSkip   = "RegionalFailoverDetachSkipped"
+	eventReasonFailoverRevoked      = "RegionalFailoverRevoked"
+	eventReasonFailoverDetachFailed = "RegionalFailoverDetachFailed"
+
+	gceProviderIDPrefix = "gce://"
+)
+
+// failoverListDisksFields are the fields of the disks needed to find the
+// regional disks attached in a failed zone.
+var failoverListDisksFields = []googleapi.Field{"items/selfLink", "items/replicaZones", "items/users", "nextPageToken"}
+
+// RegionalFailover lets regional disks attached in a failed zone move to
+// their other replica zone without waiting for Kubernetes to detach them
+// from the nodes of the failed zone, which can take the 6 minutes of the
+// force detach timeout or longer.
+//
+// A zone is failed when all its nodes have been NotReady for the grace
+// period while another zone has Ready nodes. A regional disk with a single
+// user in a failed zone is fenced: ControllerPublishVolume force attaches it
+// to nodes in its other replica zone, and ControllerUnpublishVolume treats it
+// as detached from the NotReady node without calling the zone. Both check
+// again that the node is still NotReady, as the fenced disks are only synced
+// periodically.
+type RegionalFailover struct {
+	cloudProvider gce.GCECompute
+	nodeLister    corelisters.NodeLister
+	recorder      k8sclient.NodesEventRecorder
+	gracePeriod   time.Duration
+	clock         clock.PassiveClock
+
+	mu sync.Mutex
+	// fenced are the fenced disks by volKey.String().
+	fenced map[string]*fencedDisk
+}
+
+type fencedDisk struct {
+	project      string
+	volKey       *meta.Key
+	nodeName     string
+	instanceZone string
+	instanceName string
+	// zones are the replica zones of the disk the disk may be force
+	// attached in.
+	zones []string
+	// detachSkipped is set once ControllerUnpublishVolume skipped the
+	// detach from the NotReady node.
+	detachSkipped bool
+}
+
+// NewRegionalFailover returns a RegionalFailover that considers a zone failed
+// once all its nodes have been NotReady for gracePeriod.
+func NewRegionalFailover(cloudProvider gce.GCECompute, nodeLister corelisters.NodeLister, recorder k8sclient.NodesEventRecorder, gracePeriod time.Duration) *RegionalFailover {
+	return &RegionalFailover{
+		cloudProvider: cloudProvider,
+		nodeLister:    nodeLister,
+		recorder:      recorder,
+		gracePeriod:   gracePeriod,
+		clock:         clock.RealClock{},
+		fenced:        map[string]*fencedDisk{},
+	}
+}
+
+// Run syncs the fenced disks every period until ctx is done.
+func (rf *RegionalFailover) Run(ctx context.Context, period time.Duration) {
+	klog.V(2).Infof("Starting regional failover with grace period %v", rf.gracePeriod)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := rf.sync(ctx); err != nil {
+			klog.Errorf("Failed to sync regional failover: %v", err)
+		}
+	}, period)
+}
+
+func (rf *RegionalFailover) sync(ctx context.Context) error {
+	nodes, err := rf.nodeLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	failedZones, healthyZones, instances := rf.zones(nodes)
+
+	fenced := map[string]*fencedDisk{}
+	if failedZones.Len() > 0 {
+		disks, _, err := rf.cloudProvider.ListDisks(ctx, failoverListDisksFields)
+		if err != nil {
+			return fmt.Errorf("failed to list disks: %w", err)
+		}
+		for _, disk := range disks {
+			if f := fenceDisk(disk, healthyZones, instances); f != nil {
+				fenced[f.volKey.String()] = f
+			}
+		}
+	}
+
+	rf.mu.Lock()
+	previous := rf.fenced
+	for key, f := range fenced {
+		if p, ok := previous[key]; ok && p.nodeName == f.nodeName {
+			f.detachSkipped = p.detachSkipped
+			continue
+		}
+		klog.Warningf("Allowing force attach of regional disk %v in zones %v, it is attached to node %s in failed zone %s", f.volKey, f.zones, f.nodeName, f.instanceZone)
+		rf.recorder.NodeEventf(f.nodeName, v1.EventTypeWarning, eventReasonFailoverAllowed, "Allowing force attach of regional disk %s in zones %v, all nodes of zone %s are NotReady", f.volKey.Name, f.zones, f.instanceZone)
+	}
+	rf.fenced = fenced
+	rf.mu.Unlock()
+
+	for key, p := range previous {
+		if f, ok := fenced[key]; !ok || f.nodeName != p.nodeName {
+			rf.revoke(ctx, p)
+		}
+	}
+	return nil
+}
+
+// zones returns the failed zones, the zones with Ready nodes, and the nodes
+// of the failed zones by "<zone>/<instance name>".
+func (rf *RegionalFailover) zones(nodes []*v1.Node) (sets.Set[string], sets.Set[string], map[string]string) {
+	zoneNodes := map[string][]*v1.Node{}
+	for _, node := range nodes {
+		if zone := node.Labels[v1.LabelTopologyZone]; zone != "" {
+			zoneNodes[zone] = append(zoneNodes[zone], node)
+		}
+	}
+
+	failedZones, healthyZones := sets.New[string](), sets.New[string]()
+	for zone, nodes := range zoneNodes {
+		failed := true
+		for _, node := range nodes {
+			if isNodeReady(node) {
+				healthyZones.Insert(zone)
+			}
+			if !rf.isNodeDown(node) {
+				failed = false
+			}
+		}
+		if failed {
+			failedZones.Insert(zone)
+		}
+	}
+	if healthyZones.Len() == 0 {
+		// No Ready node anywhere looks like a problem of the control plane
+		// rather than an outage of the zones.
+		if failedZones.Len() > 0 {
+			klog.Warningf("Not failing over zones %v, no zone has Ready nodes", sets.List(failedZones))
+		}
+		return sets.New[string](), healthyZones, nil
+	}
+
+	instances := map[string]string{}
+	for _, zone := range failedZones.UnsortedList() {
+		for _, node := range zoneNodes[zone] {
+			instanceZone, instanceName := nodeInstance(node)
+			instances[instanceZone+"/"+instanceName] = node.Name
+		}
+	}
+	return failedZones, healthyZones, instances
+}
+
+// fenceDisk returns the fenced disk of disk if it is a regional disk with a
+// single user in instances, and a replica zone in healthyZones.
+func fenceDisk(disk *computev1.Disk, healthyZones sets.Set[string], instances map[string]string) *fencedDisk {
+	if len(disk.ReplicaZones) == 0 || len(disk.Users) != 1 {
+		return nil
+	}
+	userID, err := getResourceId(disk.Users[0])
+	if err != nil {
+		return nil
+	}
+	instanceZone, instanceName, err := common.NodeIDToZoneAndName(userID)
+	if err != nil {
+		return nil
+	}
+	nodeName, ok := instances[instanceZone+"/"+instanceName]
+	if !ok {
+		return nil
+	}
+	zones := []string{}
+	for _, replicaZone := range disk.ReplicaZones {
+		if zone := path.Base(replicaZone); zone != instanceZone && healthyZones.Has(zone) {
+			zones = append(zones, zone)
+		}
+	}
+	if len(zones) == 0 {
+		return nil
+	}
+	volumeID, err := getResourceId(disk.SelfLink)
+	if err != nil {
+		klog.Warningf("Not failing over disk %s: %v", disk.SelfLink, err)
+		return nil
+	}
+	project, volKey, err := common.VolumeIDToKey(volumeID)
+	if err != nil {
+		klog.Warningf("Not failing over disk %s: %v", disk.SelfLink, err)
+		return nil
+	}
+	return &fencedDisk{
+		project:      project,
+		volKey:       volKey,
+		nodeName:     nodeName,
+		instanceZone: instanceZone,
+		instanceName: instanceName,
+		zones:        zones,
+	}
+}
+
+// revoke records that the disk f is no longer fenced. If the detach from the
+// NotReady node was skipped and the disk is still attached to it, the node
+// came back, and the disk is detached to match what Kubernetes expects.
+func (rf *RegionalFailover) revoke(ctx context.Context, f *fencedDisk) {
+	klog.Infof("Revoking force attach of regional disk %v, it is no longer attached to node %s in a failed zone", f.volKey, f.nodeName)
+	rf.recorder.NodeEventf(f.nodeName, v1.EventTypeNormal, eventReasonFailoverRevoked, "Revoked force attach of regional disk %s", f.volKey.Name)
+	if !f.detachSkipped {
+		return
+	}
+	disk, err := rf.cloudProvider.GetDisk(ctx, f.project, f.volKey)
+	if err != nil {
+		if !gce.IsGCENotFoundError(err) {
+			klog.Errorf("Failed to get regional disk %v to detach it from node %s: %v", f.volKey, f.nodeName, err)
+		}
+		return
+	}
+	if !slices.ContainsFunc(disk.GetUsers(), func(user string) bool {
+		return strings.HasSuffix(user, fmt.Sprintf("/zones/%s/instances/%s", f.instanceZone, f.instanceName))
+	}) {
+		return
+	}
+	deviceName, err := common.GetDeviceName(f.volKey)
+	if err == nil {
+		err = rf.cloudProvider.DetachDisk(ctx, f.project, deviceName, f.instanceZone, f.instanceName)
+	}
+	if err != nil {
+		klog.Errorf("Failed to detach regional disk %v from node %s: %v", f.volKey, f.nodeName, err)
+		rf.recorder.NodeEventf(f.nodeName, v1.EventTypeWarning, eventReasonFailoverDetachFailed, "Failed to detach regional disk %s, which Kubernetes considers detached: %v", f.volKey.Name, err)
+	}
+}
+
+// allowForceAttach returns whether the disk volKey may be force attached to
+// the instance, and records it on the NotReady node.
+func (rf *RegionalFailover) allowForceAttach(volKey *meta.Key, instanceZone, instanceName string) bool {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	f, ok := rf.fenced[volKey.String()]
+	if !ok || !slices.Contains(f.zones, instanceZone) || !rf.isNodeStillDown(f.nodeName) {
+		return false
+	}
+	klog.Warningf("Force attaching regional disk %v to instance %s in zone %s, it is attached to node %s in failed zone %s", volKey, instanceName, instanceZone, f.nodeName, f.instanceZone)
+	rf.recorder.NodeEventf(f.nodeName, v1.EventTypeWarning, eventReasonFailoverForceAttach, "Force attaching regional disk %s to instance %s in zone %s", volKey.Name, instanceName, instanceZone)
+	return true
+}
+
+// skipDetach returns whether the detach of the disk volKey from the instance
+// can be skipped, because the instance is a NotReady node of a failed zone
+// and the disk will be force attached elsewhere.
+func (rf *RegionalFailover) skipDetach(volKey *meta.Key, instanceZone, instanceName string) bool {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	f, ok := rf.fenced[volKey.String()]
+	if !ok || f.instanceZone != instanceZone || f.instanceName != instanceName || !rf.isNodeStillDown(f.nodeName) {
+		return false
+	}
+	f.detachSkipped = true
+	klog.Warningf("Skipping detach of regional disk %v from node %s in failed zone %s", volKey, f.nodeName, instanceZone)
+	rf.recorder.NodeEventf(f.nodeName, v1.EventTypeWarning, eventReasonFailoverDetachSkip, "Skipped detach of regional disk %s, zone %s has failed", volKey.Name, instanceZone)
+	return true
+}
+
+// isNodeStillDown is the fencing guard of force attach: the node must still
+// be NotReady, or deleted.
+func (rf *RegionalFailover) isNodeStillDown(nodeName string) bool {
+	node, err := rf.nodeLister.Get(nodeName)
+	if apierrors.IsNotFound(err) {
+		return true
+	}
+	if err != nil {
+		klog.Warningf("Failed to get node %s: %v", nodeName, err)
+		return false
+	}
+	return rf.isNodeDown(node)
+}
+
+// isNodeDown returns whether node has been NotReady for the grace period.
+func (rf *RegionalFailover) isNodeDown(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status != v1.ConditionTrue && rf.clock.Since(condition.LastTransitionTime.Time) >= rf.gracePeriod
+		}
+	}
+	return false
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeInstance returns the zone and name of the instance of node, from its
+// provider ID "gce://<project>/<zone>/<name>" if set.
+func nodeInstance(node *v1.Node) (string, string) {
+	if id, ok := strings.CutPrefix(node.Spec.ProviderID, gceProviderIDPrefix); ok {
+		if parts := strings.Split(id, "/"); len(parts) == 3 {
+			return parts[1], parts[2]
+		}
+	}
+	return node.Labels[v1.LabelTopologyZone], node.Name
+}
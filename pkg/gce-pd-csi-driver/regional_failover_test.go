@@ -0,0 +1,261 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/go-cmp/cmp"
+	computev1 "google.golang.org/api/compute/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	clock "k8s.io/utils/clock/testing"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+)
+
+const (
+	failoverGracePeriod = 2 * time.Minute
+	failedNode          = "failed-node"
+	healthyNode         = "healthy-node"
+)
+
+var failoverNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func failoverNode(name, zone string, ready bool, since time.Duration) *v1.Node {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{v1.LabelTopologyZone: zone},
+		},
+		Spec: v1.NodeSpec{ProviderID: fmt.Sprintf("gce://%s/%s/%s", project, zone, name)},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{
+				Type:               v1.NodeReady,
+				Status:             status,
+				LastTransitionTime: metav1.NewTime(failoverNow.Add(-since)),
+			}},
+		},
+	}
+}
+
+func failoverDisk(users ...string) *gce.CloudDisk {
+	userURIs := []string{}
+	for _, user := range users {
+		userURIs = append(userURIs, fmt.Sprintf("%sprojects/%s/zones/%s/instances/%s", gce.BasePath, project, zone, user))
+	}
+	return gce.CloudDiskFromV1(&computev1.Disk{
+		Name:   name,
+		Region: region,
+		ReplicaZones: []string{
+			fmt.Sprintf("%sprojects/%s/zones/%s", gce.BasePath, project, zone),
+			fmt.Sprintf("%sprojects/%s/zones/%s", gce.BasePath, project, secondZone),
+		},
+		Users:    userURIs,
+		SelfLink: fmt.Sprintf("%sprojects/%s/regions/%s/disks/%s", gce.BasePath, project, region, name),
+	})
+}
+
+func newTestRegionalFailover(t *testing.T, disks []*gce.CloudDisk, nodes []*v1.Node) (*RegionalFailover, *gce.FakeCloudProvider, cache.Indexer, *k8sclient.FakeEventRecorder) {
+	fakeCloudProvider, err := gce.CreateFakeCloudProvider(project, zone, disks)
+	if err != nil {
+		t.Fatalf("Failed to create fake cloud provider: %v", err)
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, node := range nodes {
+		if err := indexer.Add(node); err != nil {
+			t.Fatalf("Failed to add node: %v", err)
+		}
+	}
+	recorder := k8sclient.NewFakeEventRecorder()
+	rf := NewRegionalFailover(fakeCloudProvider, corelisters.NewNodeLister(indexer), recorder, failoverGracePeriod)
+	rf.clock = clock.NewFakePassiveClock(failoverNow)
+	return rf, fakeCloudProvider, indexer, recorder
+}
+
+func TestRegionalFailoverSync(t *testing.T) {
+	testCases := []struct {
+		name      string
+		disk      *gce.CloudDisk
+		nodes     []*v1.Node
+		expFenced bool
+	}{
+		{
+			name: "all nodes of the zone NotReady",
+			disk: failoverDisk(failedNode),
+			nodes: []*v1.Node{
+				failoverNode(failedNode, zone, false, 5*time.Minute),
+				failoverNode("other-failed-node", zone, false, 3*time.Minute),
+				failoverNode(healthyNode, secondZone, true, time.Hour),
+			},
+			expFenced: true,
+		},
+		{
+			name: "nodes NotReady for less than the grace period",
+			disk: failoverDisk(failedNode),
+			nodes: []*v1.Node{
+				failoverNode(failedNode, zone, false, 5*time.Minute),
+				failoverNode("other-failed-node", zone, false, time.Minute),
+				failoverNode(healthyNode, secondZone, true, time.Hour),
+			},
+		},
+		{
+			name: "Ready node in the zone",
+			disk: failoverDisk(failedNode),
+			nodes: []*v1.Node{
+				failoverNode(failedNode, zone, false, 5*time.Minute),
+				failoverNode("other-node", zone, true, time.Hour),
+				failoverNode(healthyNode, secondZone, true, time.Hour),
+			},
+		},
+		{
+			name: "no Ready node in the other replica zone",
+			disk: failoverDisk(failedNode),
+			nodes: []*v1.Node{
+				failoverNode(failedNode, zone, false, 5*time.Minute),
+				failoverNode(healthyNode, secondZone, false, 5*time.Minute),
+				failoverNode("third-zone-node", "country-region-thirdzone", true, time.Hour),
+			},
+		},
+		{
+			name: "no Ready node in any zone",
+			disk: failoverDisk(failedNode),
+			nodes: []*v1.Node{
+				failoverNode(failedNode, zone, false, 5*time.Minute),
+				failoverNode(healthyNode, secondZone, false, 5*time.Minute),
+			},
+		},
+		{
+			name: "disk with several users",
+			disk: failoverDisk(failedNode, "other-failed-node"),
+			nodes: []*v1.Node{
+				failoverNode(failedNode, zone, false, 5*time.Minute),
+				failoverNode("other-failed-node", zone, false, 5*time.Minute),
+				failoverNode(healthyNode, secondZone, true, time.Hour),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rf, _, _, recorder := newTestRegionalFailover(t, []*gce.CloudDisk{tc.disk}, tc.nodes)
+			if err := rf.sync(context.Background()); err != nil {
+				t.Fatalf("sync returned error: %v", err)
+			}
+			volKey := meta.RegionalKey(name, region)
+			if got := rf.allowForceAttach(volKey, secondZone, healthyNode); got != tc.expFenced {
+				t.Errorf("Expected force attach allowed %v, got %v", tc.expFenced, got)
+			}
+			var expEvents []string
+			if tc.expFenced {
+				expEvents = []string{
+					fmt.Sprintf("Warning RegionalFailoverAllowed %s: Allowing force attach of regional disk %s in zones [%s], all nodes of zone %s are NotReady", failedNode, name, secondZone, zone),
+					fmt.Sprintf("Warning RegionalFailoverForceAttach %s: Force attaching regional disk %s to instance %s in zone %s", failedNode, name, healthyNode, secondZone),
+				}
+			}
+			if diff := cmp.Diff(expEvents, recorder.Events()); diff != "" {
+				t.Errorf("Unexpected events (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegionalFailoverPublish(t *testing.T) {
+	nodes := []*v1.Node{
+		failoverNode(failedNode, zone, false, 5*time.Minute),
+		failoverNode(healthyNode, secondZone, true, time.Hour),
+	}
+	rf, fakeCloudProvider, indexer, recorder := newTestRegionalFailover(t, []*gce.CloudDisk{failoverDisk(failedNode)}, nodes)
+	deviceName, _ := common.GetDeviceName(meta.RegionalKey(name, region))
+	fakeCloudProvider.InsertInstance(&computev1.Instance{
+		Name: failedNode,
+		Zone: zone,
+		Disks: []*computev1.AttachedDisk{{
+			DeviceName: deviceName,
+			Source:     fmt.Sprintf("%sprojects/%s/regions/%s/disks/%s", gce.BasePath, project, region, name),
+		}},
+	}, zone, failedNode)
+	fakeCloudProvider.InsertInstance(&computev1.Instance{Name: healthyNode, Zone: secondZone}, secondZone, healthyNode)
+	gceDriver := initGCEDriverWithCloudProvider(t, fakeCloudProvider, &GCEControllerServerArgs{RegionalFailover: rf})
+	if err := rf.sync(context.Background()); err != nil {
+		t.Fatalf("sync returned error: %v", err)
+	}
+
+	// The detach from the node of the failed zone is skipped.
+	_, err := gceDriver.cs.ControllerUnpublishVolume(context.Background(), &csi.ControllerUnpublishVolumeRequest{
+		VolumeId: testRegionalID,
+		NodeId:   fmt.Sprintf("projects/%s/zones/%s/instances/%s", project, zone, failedNode),
+	})
+	if err != nil {
+		t.Fatalf("ControllerUnpublishVolume returned error: %v", err)
+	}
+	failedInstance, _ := fakeCloudProvider.GetInstanceOrError(context.Background(), project, zone, failedNode)
+	if len(failedInstance.Disks) != 1 {
+		t.Errorf("Expected disk to stay attached to %s, got %v", failedNode, failedInstance.Disks)
+	}
+
+	// The disk is force attached in the other replica zone.
+	_, err = gceDriver.cs.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+		VolumeId:         testRegionalID,
+		NodeId:           fmt.Sprintf("projects/%s/zones/%s/instances/%s", project, secondZone, healthyNode),
+		VolumeCapability: stdVolCap,
+	})
+	if err != nil {
+		t.Fatalf("ControllerPublishVolume returned error: %v", err)
+	}
+	healthyInstance, _ := fakeCloudProvider.GetInstanceOrError(context.Background(), project, secondZone, healthyNode)
+	if len(healthyInstance.Disks) != 1 || !healthyInstance.Disks[0].ForceAttach {
+		t.Errorf("Expected disk to be force attached to %s, got %v", healthyNode, healthyInstance.Disks)
+	}
+
+	// Once the node is Ready again, force attach is no longer allowed, and
+	// the disk that was left attached is detached on the next sync.
+	if err := indexer.Update(failoverNode(failedNode, zone, true, 0)); err != nil {
+		t.Fatalf("Failed to update node: %v", err)
+	}
+	if rf.allowForceAttach(meta.RegionalKey(name, region), secondZone, healthyNode) {
+		t.Errorf("Expected force attach to be denied for a Ready node")
+	}
+	if err := rf.sync(context.Background()); err != nil {
+		t.Fatalf("sync returned error: %v", err)
+	}
+	if len(failedInstance.Disks) != 0 {
+		t.Errorf("Expected disk to be detached from %s, got %v", failedNode, failedInstance.Disks)
+	}
+
+	reasons := []string{}
+	for _, event := range recorder.Events() {
+		reasons = append(reasons, strings.Fields(event)[1])
+	}
+	expReasons := []string{eventReasonFailoverAllowed, eventReasonFailoverDetachSkip, eventReasonFailoverForceAttach, eventReasonFailoverRevoked}
+	if diff := cmp.Diff(expReasons, reasons); diff != "" {
+		t.Errorf("Unexpected events (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const (
+	// States of the replication to a secondary disk.
+	asyncReplicationStateActive   = "ACTIVE"
+	asyncReplicationStateCreated  = "CREATED"
+	asyncReplicationStateStopped  = "STOPPED"
+	asyncReplicationStateStopping = "STOPPING"
+)
+
+// asyncSecondaryKey returns the key of the secondary disk of the disk volKey
+// in the secondary zones of params. The secondary disk has the name of the
+// primary disk, which DeleteVolume relies on to only delete the secondary
+// disks it created.
+func asyncSecondaryKey(volKey *meta.Key, params parameters.DiskParameters) (*meta.Key, error) {
+	zones := params.AsyncReplicationSecondaryZones
+	region, err := common.GetRegionFromZones(zones)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to get region of asynchronous replication secondary zones: %v", err.Error())
+	}
+	primaryRegion := volKey.Region
+	if volKey.Type() == meta.Zonal {
+		primaryRegion, err = common.GetRegionFromZones([]string{volKey.Zone})
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to get region of zone %s: %v", volKey.Zone, err.Error())
+		}
+	}
+	if region == primaryRegion {
+		return nil, status.Errorf(codes.InvalidArgument, "asynchronous replication secondary zones %v must be in another region than the disk in %s", zones, primaryRegion)
+	}
+	if volKey.Type() == meta.Regional {
+		return meta.RegionalKey(volKey.Name, region), nil
+	}
+	return meta.ZonalKey(volKey.Name, zones[0]), nil
+}
+
+// setupAsyncReplication creates the secondary disk of the disk volKey and
+// starts the asynchronous replication to it. Both steps are skipped when
+// already done, so that a retried CreateVolume completes the setup. It
+// returns the volume ID of the secondary disk.
+func (gceCS *GCEControllerServer) setupAsyncReplication(ctx context.Context, req *csi.CreateVolumeRequest, params parameters.DiskParameters, volKey *meta.Key, accessMode string) (string, error) {
+	project := diskProject(gceCS.cloudProvider(ctx), params)
+	secondaryKey, err := asyncSecondaryKey(volKey, params)
+	if err != nil {
+		return "", err
+	}
+	volumeID, err := common.KeyToVolumeID(volKey, project)
+	if err != nil {
+		return "", err
+	}
+	secondaryVolumeID, err := common.KeyToVolumeID(secondaryKey, project)
+	if err != nil {
+		return "", err
+	}
+
+	secondary, err := gceCS.cloudProvider(ctx).GetDisk(ctx, project, secondaryKey)
+	switch {
+	case err == nil:
+		if !strings.HasSuffix(secondary.GetAsyncPrimaryDisk(), volumeID) {
+			return "", status.Errorf(codes.AlreadyExists, "disk %v already exists and is not a secondary disk of %v", secondaryKey, volKey)
+		}
+	case gce.IsGCEError(err, "notFound"):
+		secondaryParams := params
+		secondaryParams.AsyncReplicationSecondaryZones = nil
+		secondaryParams.AsyncPrimaryDisk = volumeID
+		secondaryParams.StoragePools = nil
+
+		capBytes, _ := getRequestCapacity(req.GetCapacityRange())
+		multiWriter := false
+		if !common.IsHyperdisk(params.DiskType) {
+			multiWriter, _ = getMultiWriterFromCapabilities(req.GetVolumeCapabilities())
+		}
+		if secondaryKey.Type() == meta.Regional {
+			secondary, err = createRegionalDisk(ctx, gceCS.cloudProvider(ctx), secondaryKey.Name, params.AsyncReplicationSecondaryZones, secondaryParams, req.GetCapacityRange(), capBytes, "", "", multiWriter, accessMode)
+		} else {
+			secondary, err = createSingleZoneDisk(ctx, gceCS.cloudProvider(ctx), secondaryKey.Name, []string{secondaryKey.Zone}, secondaryParams, req.GetCapacityRange(), capBytes, "", "", multiWriter, accessMode)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create secondary disk %v: %w", secondaryKey, err)
+		}
+	default:
+		return "", status.Errorf(codes.Unavailable, "failed to get secondary disk %v: %v", secondaryKey, err.Error())
+	}
+
+	if state := secondary.GetAsyncReplicationState(); state == "" || state == asyncReplicationStateCreated {
+		if err := gceCS.cloudProvider(ctx).StartAsyncReplication(ctx, project, volKey, secondaryKey); err != nil {
+			return "", fmt.Errorf("failed to start asynchronous replication of disk %v: %w", volKey, err)
+		}
+		klog.V(4).Infof("Started asynchronous replication of disk %v to %v", volKey, secondaryKey)
+	}
+	return secondaryVolumeID, nil
+}
+
+// asyncSecondaryDisk is a secondary disk of asynchronous replication.
+type asyncSecondaryDisk struct {
+	project string
+	key     *meta.Key
+}
+
+// deleteAsyncSecondaryDisks deletes the secondary disks of disk that were
+// created with it by CreateVolume, stopping the replication to them first.
+// Secondary disks with other names were set up outside of the driver, and
+// secondary disks whose replication was stopped, e.g. by a failover, may be
+// the volumes of a recovery cluster: both are kept. The replications stopped
+// by a previous attempt are told apart by a label on disk.
+func (gceCS *GCEControllerServer) deleteAsyncSecondaryDisks(ctx context.Context, project string, volKey *meta.Key, disk *gce.CloudDisk) error {
+	secondaryDisks := disk.GetAsyncSecondaryDisks()
+	if len(secondaryDisks) == 0 {
+		return nil
+	}
+	volumeID, err := common.KeyToVolumeID(volKey, project)
+	if err != nil {
+		return err
+	}
+	stoppedForDelete := disk.GetLabels()[constants.AsyncReplicationDeleteLabel] != ""
+
+	var secondaries []asyncSecondaryDisk
+	running := false
+	for _, secondaryDisk := range secondaryDisks {
+		secondaryVolumeID, err := getResourceId(secondaryDisk)
+		if err != nil {
+			klog.Warningf("Ignoring secondary disk %s of disk %v: %v", secondaryDisk, volKey, err)
+			continue
+		}
+		secondaryProject, secondaryKey, err := common.VolumeIDToKey(secondaryVolumeID)
+		if err != nil || secondaryKey.Name != volKey.Name {
+			continue
+		}
+		secondary, err := gceCS.cloudProvider(ctx).GetDisk(ctx, secondaryProject, secondaryKey)
+		if gce.IsGCENotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get secondary disk %v: %w", secondaryKey, err)
+		}
+		if !strings.HasSuffix(secondary.GetAsyncPrimaryDisk(), volumeID) {
+			continue
+		}
+		switch state := secondary.GetAsyncReplicationState(); state {
+		case asyncReplicationStateCreated:
+		case asyncReplicationStateStopping, asyncReplicationStateStopped:
+			if !stoppedForDelete {
+				klog.Infof("Keeping secondary disk %v of disk %v whose replication was stopped", secondaryKey, volKey)
+				continue
+			}
+		case asyncReplicationStateActive:
+			running = true
+		default:
+			klog.Infof("Keeping secondary disk %v of disk %v in replication state %q", secondaryKey, volKey, state)
+			continue
+		}
+		secondaries = append(secondaries, asyncSecondaryDisk{project: secondaryProject, key: secondaryKey})
+	}
+	if len(secondaries) == 0 {
+		return nil
+	}
+
+	if running {
+		if !stoppedForDelete {
+			labels, _ := mergeDiskLabels(disk.GetLabels(), map[string]string{constants.AsyncReplicationDeleteLabel: "true"})
+			if err := gceCS.cloudProvider(ctx).SetDiskLabels(ctx, project, volKey, disk, labels); err != nil {
+				return fmt.Errorf("failed to label disk for deletion of its secondary disks: %w", err)
+			}
+		}
+		if err := gceCS.cloudProvider(ctx).StopAsyncReplication(ctx, project, volKey); err != nil && !gce.IsGCENotFoundError(err) {
+			// GCE fails to stop a replication that was stopped meanwhile.
+			if running, checkErr := gceCS.asyncReplicationRunning(ctx, secondaries); checkErr != nil || running {
+				return fmt.Errorf("failed to stop asynchronous replication: %w", err)
+			}
+		}
+	}
+	for _, secondary := range secondaries {
+		if err := gceCS.cloudProvider(ctx).DeleteDisk(ctx, secondary.project, secondary.key); err != nil && !gce.IsGCENotFoundError(err) {
+			return fmt.Errorf("failed to delete secondary disk %v: %w", secondary.key, err)
+		}
+		klog.V(4).Infof("Deleted secondary disk %v of disk %v", secondary.key, volKey)
+	}
+	return nil
+}
+
+// asyncReplicationRunning returns whether the replication to one of the
+// secondary disks is still active. Deleted secondary disks don't replicate.
+func (gceCS *GCEControllerServer) asyncReplicationRunning(ctx context.Context, secondaries []asyncSecondaryDisk) (bool, error) {
+	for _, secondary := range secondaries {
+		disk, err := gceCS.cloudProvider(ctx).GetDisk(ctx, secondary.project, secondary.key)
+		if gce.IsGCENotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get secondary disk %v: %w", secondary.key, err)
+		}
+		if disk.GetAsyncReplicationState() == asyncReplicationStateActive {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FailoverPersistentVolume fails the volume of pv over to its secondary disk
+// of asynchronous replication, for disaster recovery when the region of the
+// disk is lost. It stops the replication on the secondary disk, and returns a
+// PersistentVolume named name for the secondary disk, to be created in the
+// recovery cluster. The claim reference only keeps the namespace and name of
+// the claim, so that a claim re-created in the recovery cluster binds to it.
+func FailoverPersistentVolume(ctx context.Context, cloudProvider gce.GCECompute, pv *v1.PersistentVolume, name string) (*v1.PersistentVolume, error) {
+	if pv.Spec.CSI == nil {
+		return nil, fmt.Errorf("persistent volume %s is not a CSI volume", pv.Name)
+	}
+	secondaryVolumeID := pv.Spec.CSI.VolumeAttributes[constants.ContextAsyncReplicationSecondaryVolume]
+	if secondaryVolumeID == "" {
+		return nil, fmt.Errorf("persistent volume %s has no asynchronous replication secondary disk", pv.Name)
+	}
+	zones, err := parameters.ParseAsyncReplicationSecondaryZones(pv.Spec.CSI.VolumeAttributes[constants.ContextAsyncReplicationSecondaryZones])
+	if err != nil {
+		return nil, fmt.Errorf("persistent volume %s has invalid asynchronous replication secondary zones: %w", pv.Name, err)
+	}
+	project, secondaryKey, err := common.VolumeIDToKey(secondaryVolumeID)
+	if err != nil {
+		return nil, fmt.Errorf("persistent volume %s has invalid asynchronous replication secondary disk: %w", pv.Name, err)
+	}
+
+	secondary, err := cloudProvider.GetDisk(ctx, project, secondaryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secondary disk %v: %w", secondaryKey, err)
+	}
+	if secondary.GetAsyncReplicationState() != asyncReplicationStateStopped {
+		if err := cloudProvider.StopAsyncReplication(ctx, project, secondaryKey); err != nil {
+			return nil, fmt.Errorf("failed to stop asynchronous replication to disk %v: %w", secondaryKey, err)
+		}
+		klog.V(4).Infof("Stopped asynchronous replication to disk %v", secondaryKey)
+	}
+
+	failover := &v1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: pv.Labels,
+		},
+		Spec: *pv.Spec.DeepCopy(),
+	}
+	failover.Spec.CSI.VolumeHandle = secondaryVolumeID
+	delete(failover.Spec.CSI.VolumeAttributes, constants.ContextAsyncReplicationSecondaryVolume)
+	delete(failover.Spec.CSI.VolumeAttributes, constants.ContextAsyncReplicationSecondaryZones)
+	failover.Spec.NodeAffinity = &v1.VolumeNodeAffinity{
+		Required: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{{
+				MatchExpressions: []v1.NodeSelectorRequirement{{
+					Key:      constants.TopologyKeyZone,
+					Operator: v1.NodeSelectorOpIn,
+					Values:   zones,
+				}},
+			}},
+		},
+	}
+	if ref := pv.Spec.ClaimRef; ref != nil {
+		failover.Spec.ClaimRef = &v1.ObjectReference{
+			Kind:       ref.Kind,
+			APIVersion: ref.APIVersion,
+			Namespace:  ref.Namespace,
+			Name:       ref.Name,
+		}
+	}
+	return failover, nil
+}
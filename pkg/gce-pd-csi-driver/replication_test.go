@@ -0,0 +1,406 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gceGCEDriver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/k8s-cloud-provider/pkg/cloud/meta"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/go-cmp/cmp"
+	computebeta "google.golang.org/api/compute/v0.beta"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
+)
+
+const (
+	secondaryZone       = "country-other-zone"
+	secondarySecondZone = "country-other-secondzone"
+)
+
+func asyncReplicationCreateVolumeRequest(params map[string]string, zones ...string) *csi.CreateVolumeRequest {
+	requisite := []*csi.Topology{}
+	for _, z := range zones {
+		requisite = append(requisite, &csi.Topology{Segments: map[string]string{constants.TopologyKeyZone: z}})
+	}
+	return &csi.CreateVolumeRequest{
+		Name:                      name,
+		CapacityRange:             stdCapRange,
+		VolumeCapabilities:        stdVolCaps,
+		Parameters:                params,
+		AccessibilityRequirements: &csi.TopologyRequirement{Requisite: requisite, Preferred: requisite},
+	}
+}
+
+func TestCreateVolumeAsyncReplication(t *testing.T) {
+	testCases := []struct {
+		name              string
+		req               *csi.CreateVolumeRequest
+		expSecondaryKey   *meta.Key
+		expSecondaryZones string
+		expErrCode        codes.Code
+	}{
+		{
+			name: "zonal disk",
+			req: asyncReplicationCreateVolumeRequest(map[string]string{
+				parameters.ParameterKeyType:                           stdDiskType,
+				parameters.ParameterKeyAsyncReplicationSecondaryZones: secondaryZone,
+			}, zone),
+			expSecondaryKey:   meta.ZonalKey(name, secondaryZone),
+			expSecondaryZones: secondaryZone,
+		},
+		{
+			name: "regional disk",
+			req: asyncReplicationCreateVolumeRequest(map[string]string{
+				parameters.ParameterKeyType:                           stdDiskType,
+				parameters.ParameterKeyReplicationType:                replicationTypeRegionalPD,
+				parameters.ParameterKeyAsyncReplicationSecondaryZones: secondaryZone + "," + secondarySecondZone,
+			}, zone, secondZone),
+			expSecondaryKey:   meta.RegionalKey(name, "country-other"),
+			expSecondaryZones: secondaryZone + "," + secondarySecondZone,
+		},
+		{
+			name: "secondary zone in the region of the disk",
+			req: asyncReplicationCreateVolumeRequest(map[string]string{
+				parameters.ParameterKeyType:                           stdDiskType,
+				parameters.ParameterKeyAsyncReplicationSecondaryZones: secondZone,
+			}, zone),
+			expErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+			cloudProvider := gceDriver.cs.CloudProvider
+
+			// A retried request completes the setup and returns the same volume.
+			for i := 0; i < 2; i++ {
+				resp, err := gceDriver.cs.CreateVolume(context.Background(), tc.req)
+				if tc.expErrCode != codes.OK {
+					if status.Code(err) != tc.expErrCode {
+						t.Fatalf("Expected error code %v, got: %v", tc.expErrCode, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("CreateVolume returned error: %v", err)
+				}
+				secondaryVolumeID, _ := common.KeyToVolumeID(tc.expSecondaryKey, project)
+				want := map[string]string{
+					constants.ContextAsyncReplicationSecondaryVolume: secondaryVolumeID,
+					constants.ContextAsyncReplicationSecondaryZones:  tc.expSecondaryZones,
+				}
+				if diff := cmp.Diff(want, resp.GetVolume().GetVolumeContext()); diff != "" {
+					t.Errorf("Unexpected volume context (-want +got):\n%s", diff)
+				}
+			}
+
+			secondary, err := cloudProvider.GetDisk(context.Background(), project, tc.expSecondaryKey)
+			if err != nil {
+				t.Fatalf("Failed to get secondary disk: %v", err)
+			}
+			if state := secondary.GetAsyncReplicationState(); state != "ACTIVE" {
+				t.Errorf("Expected replication state ACTIVE, got %q", state)
+			}
+		})
+	}
+}
+
+func TestDeleteVolumeAsyncReplication(t *testing.T) {
+	gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+	cloudProvider := gceDriver.cs.CloudProvider
+	req := asyncReplicationCreateVolumeRequest(map[string]string{
+		parameters.ParameterKeyType:                           stdDiskType,
+		parameters.ParameterKeyAsyncReplicationSecondaryZones: secondaryZone,
+	}, zone)
+	resp, err := gceDriver.cs.CreateVolume(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateVolume returned error: %v", err)
+	}
+
+	_, err = gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: resp.GetVolume().GetVolumeId()})
+	if err != nil {
+		t.Fatalf("DeleteVolume returned error: %v", err)
+	}
+	for _, key := range []*meta.Key{meta.ZonalKey(name, zone), meta.ZonalKey(name, secondaryZone)} {
+		if _, err := cloudProvider.GetDisk(context.Background(), project, key); !gce.IsGCENotFoundError(err) {
+			t.Errorf("Expected disk %v to be deleted, got: %v", key, err)
+		}
+	}
+}
+
+// stopAsyncReplicationCloudProvider counts the calls to
+// StopAsyncReplication, and returns the error of stop.
+type stopAsyncReplicationCloudProvider struct {
+	*gce.FakeCloudProvider
+	stop  func(ctx context.Context, project string, volKey *meta.Key) error
+	stops int
+}
+
+func (c *stopAsyncReplicationCloudProvider) StopAsyncReplication(ctx context.Context, project string, volKey *meta.Key) error {
+	c.stops++
+	return c.stop(ctx, project, volKey)
+}
+
+func TestDeleteVolumeStopAsyncReplication(t *testing.T) {
+	primaryKey := meta.ZonalKey(name, zone)
+	secondaryKey := meta.ZonalKey(name, secondaryZone)
+	primaryLink := fmt.Sprintf("%sprojects/%s/zones/%s/disks/%s", gce.BasePath, project, zone, name)
+	secondaryLink := fmt.Sprintf("%sprojects/%s/zones/%s/disks/%s", gce.BasePath, project, secondaryZone, name)
+	invalidErr := &googleapi.Error{Code: 400, Errors: []googleapi.ErrorItem{{Reason: "invalid"}}}
+
+	testCases := []struct {
+		name                string
+		state               string
+		labels              map[string]string
+		stopErr             error
+		stop                bool
+		expStops            int
+		expSecondaryDeleted bool
+		expErr              bool
+	}{
+		{
+			name:                "active replication",
+			state:               asyncReplicationStateActive,
+			stop:                true,
+			expStops:            1,
+			expSecondaryDeleted: true,
+		},
+		{
+			name:  "replication stopped by a failover",
+			state: asyncReplicationStateStopped,
+		},
+		{
+			name:                "replication stopped by a previous attempt",
+			state:               asyncReplicationStateStopped,
+			labels:              map[string]string{constants.AsyncReplicationDeleteLabel: "true"},
+			expSecondaryDeleted: true,
+		},
+		{
+			name:                "replication never started",
+			state:               asyncReplicationStateCreated,
+			expSecondaryDeleted: true,
+		},
+		{
+			name:                "primary disk not found",
+			state:               asyncReplicationStateActive,
+			stopErr:             &googleapi.Error{Code: 404, Errors: []googleapi.ErrorItem{{Reason: "notFound"}}},
+			expStops:            1,
+			expSecondaryDeleted: true,
+		},
+		{
+			name:                "replication stopped meanwhile",
+			state:               asyncReplicationStateActive,
+			stop:                true,
+			stopErr:             invalidErr,
+			expStops:            1,
+			expSecondaryDeleted: true,
+		},
+		{
+			name:     "stop fails",
+			state:    asyncReplicationStateActive,
+			stopErr:  invalidErr,
+			expStops: 1,
+			expErr:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			primary := gce.CloudDiskFromBeta(&computebeta.Disk{
+				Name:     name,
+				Zone:     zone,
+				SelfLink: primaryLink,
+				Labels:   tc.labels,
+				AsyncSecondaryDisks: map[string]computebeta.DiskAsyncReplicationList{
+					secondaryLink: {AsyncReplicationDisk: &computebeta.DiskAsyncReplication{Disk: secondaryLink}},
+				},
+			})
+			secondary := gce.CloudDiskFromBeta(&computebeta.Disk{
+				Name:             name,
+				Zone:             secondaryZone,
+				SelfLink:         secondaryLink,
+				AsyncPrimaryDisk: &computebeta.DiskAsyncReplication{Disk: primaryLink},
+				ResourceStatus: &computebeta.DiskResourceStatus{
+					AsyncPrimaryDisk: &computebeta.DiskResourceStatusAsyncReplicationStatus{State: tc.state},
+				},
+			})
+			fcp, err := gce.CreateFakeCloudProvider(project, zone, []*gce.CloudDisk{primary, secondary})
+			if err != nil {
+				t.Fatalf("Failed to create fake cloud provider: %v", err)
+			}
+			cloudProvider := &stopAsyncReplicationCloudProvider{
+				FakeCloudProvider: fcp,
+				stop: func(ctx context.Context, project string, volKey *meta.Key) error {
+					if tc.stop {
+						if err := fcp.StopAsyncReplication(ctx, project, volKey); err != nil {
+							return err
+						}
+					}
+					return tc.stopErr
+				},
+			}
+			gceDriver := initGCEDriverWithCloudProvider(t, cloudProvider, &GCEControllerServerArgs{})
+
+			_, err = gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, name)})
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if cloudProvider.stops != tc.expStops {
+				t.Errorf("Expected %d calls to StopAsyncReplication, got %d", tc.expStops, cloudProvider.stops)
+			}
+			_, err = fcp.GetDisk(context.Background(), project, primaryKey)
+			if deleted := gce.IsGCENotFoundError(err); deleted == tc.expErr {
+				t.Errorf("Disk %v: expected deleted %v, got: %v", primaryKey, !tc.expErr, err)
+			}
+			_, err = fcp.GetDisk(context.Background(), project, secondaryKey)
+			if deleted := gce.IsGCENotFoundError(err); deleted != tc.expSecondaryDeleted {
+				t.Errorf("Disk %v: expected deleted %v, got: %v", secondaryKey, tc.expSecondaryDeleted, err)
+			}
+		})
+	}
+}
+
+func TestDeleteVolumeAfterFailover(t *testing.T) {
+	gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+	cloudProvider := gceDriver.cs.CloudProvider
+	req := asyncReplicationCreateVolumeRequest(map[string]string{
+		parameters.ParameterKeyType:                           stdDiskType,
+		parameters.ParameterKeyAsyncReplicationSecondaryZones: secondaryZone,
+	}, zone)
+	resp, err := gceDriver.cs.CreateVolume(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateVolume returned error: %v", err)
+	}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:           driver,
+					VolumeHandle:     resp.GetVolume().GetVolumeId(),
+					VolumeAttributes: resp.GetVolume().GetVolumeContext(),
+				},
+			},
+		},
+	}
+	if _, err := FailoverPersistentVolume(context.Background(), cloudProvider, pv, "pv-failover"); err != nil {
+		t.Fatalf("FailoverPersistentVolume returned error: %v", err)
+	}
+
+	_, err = gceDriver.cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: resp.GetVolume().GetVolumeId()})
+	if err != nil {
+		t.Fatalf("DeleteVolume returned error: %v", err)
+	}
+	if _, err := cloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, zone)); !gce.IsGCENotFoundError(err) {
+		t.Errorf("Expected primary disk to be deleted, got: %v", err)
+	}
+	if _, err := cloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, secondaryZone)); err != nil {
+		t.Errorf("Expected failed over secondary disk to be kept, got: %v", err)
+	}
+}
+
+func TestFailoverPersistentVolume(t *testing.T) {
+	gceDriver := initGCEDriver(t, nil, &GCEControllerServerArgs{})
+	cloudProvider := gceDriver.cs.CloudProvider
+	req := asyncReplicationCreateVolumeRequest(map[string]string{
+		parameters.ParameterKeyType:                           stdDiskType,
+		parameters.ParameterKeyAsyncReplicationSecondaryZones: secondaryZone,
+	}, zone)
+	resp, err := gceDriver.cs.CreateVolume(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateVolume returned error: %v", err)
+	}
+
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv", UID: "pv-uid", ResourceVersion: "1"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:           driver,
+					VolumeHandle:     resp.GetVolume().GetVolumeId(),
+					VolumeAttributes: resp.GetVolume().GetVolumeContext(),
+				},
+			},
+			ClaimRef: &v1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "ns", Name: "pvc", UID: "pvc-uid"},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+	}
+	for i := 0; i < 2; i++ {
+		got, err := FailoverPersistentVolume(context.Background(), cloudProvider, pv, "pv-failover")
+		if err != nil {
+			t.Fatalf("FailoverPersistentVolume returned error: %v", err)
+		}
+		want := &v1.PersistentVolume{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-failover"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{
+						Driver:           driver,
+						VolumeHandle:     fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, secondaryZone, name),
+						VolumeAttributes: map[string]string{},
+					},
+				},
+				ClaimRef: &v1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "ns", Name: "pvc"},
+				NodeAffinity: &v1.VolumeNodeAffinity{
+					Required: &v1.NodeSelector{
+						NodeSelectorTerms: []v1.NodeSelectorTerm{{
+							MatchExpressions: []v1.NodeSelectorRequirement{{
+								Key:      constants.TopologyKeyZone,
+								Operator: v1.NodeSelectorOpIn,
+								Values:   []string{secondaryZone},
+							}},
+						}},
+					},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unexpected persistent volume (-want +got):\n%s", diff)
+		}
+	}
+
+	secondary, err := cloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, secondaryZone))
+	if err != nil {
+		t.Fatalf("Failed to get secondary disk: %v", err)
+	}
+	if state := secondary.GetAsyncReplicationState(); state != "STOPPED" {
+		t.Errorf("Expected replication state STOPPED, got %q", state)
+	}
+	primary, err := cloudProvider.GetDisk(context.Background(), project, meta.ZonalKey(name, zone))
+	if err != nil {
+		t.Fatalf("Failed to get primary disk: %v", err)
+	}
+	if secondaryDisks := primary.GetAsyncSecondaryDisks(); len(secondaryDisks) != 1 {
+		t.Errorf("Expected the primary disk to list its secondary disk after failover, got %v", secondaryDisks)
+	}
+
+	delete(pv.Spec.CSI.VolumeAttributes, constants.ContextAsyncReplicationSecondaryVolume)
+	if _, err := FailoverPersistentVolume(context.Background(), cloudProvider, pv, "pv-failover"); err == nil {
+		t.Errorf("Expected error for a volume without secondary disk")
+	}
+}
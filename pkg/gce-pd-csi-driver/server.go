@@ -15,6 +15,7 @@ limitations under the License.
 package gceGCEDriver
 
 import (
+	"errors"
 	"net"
 	"net/url"
 	"os"
@@ -54,10 +55,13 @@ type nonBlockingGRPCServer struct {
 }
 
 func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+	// The server is created before returning so that Stop and ForceStop can
+	// be called right away.
+	s.server = s.newServer(ids, cs, ns)
 
 	s.wg.Add(1)
 
-	go s.serve(endpoint, ids, cs, ns)
+	go s.serve(endpoint)
 
 	return
 }
@@ -74,7 +78,7 @@ func (s *nonBlockingGRPCServer) ForceStop() {
 	s.server.Stop()
 }
 
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) newServer(ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) *grpc.Server {
 	interceptors := []grpc.UnaryServerInterceptor{logGRPC}
 	if s.metricsManager != nil {
 		metricsInterceptor := metrics.MetricInterceptor{
@@ -91,6 +95,23 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 		grpcInterceptor,
 	}
 
+	server := grpc.NewServer(opts...)
+
+	if ids != nil {
+		csi.RegisterIdentityServer(server, ids)
+	}
+	if cs != nil {
+		csi.RegisterControllerServer(server, cs)
+	}
+	if ns != nil {
+		csi.RegisterNodeServer(server, ns)
+	}
+	return server
+}
+
+func (s *nonBlockingGRPCServer) serve(endpoint string) {
+	defer s.wg.Done()
+
 	u, err := url.Parse(endpoint)
 
 	if err != nil {
@@ -125,22 +146,11 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 		klog.Fatalf("Failed to listen: %v", err.Error())
 	}
 
-	server := grpc.NewServer(opts...)
-	s.server = server
-
-	if ids != nil {
-		csi.RegisterIdentityServer(server, ids)
-	}
-	if cs != nil {
-		csi.RegisterControllerServer(server, cs)
-	}
-	if ns != nil {
-		csi.RegisterNodeServer(server, ns)
-	}
-
 	klog.V(4).Infof("Listening for connections on address: %#v", listener.Addr())
 
-	if err := server.Serve(listener); err != nil {
+	// Serve returns ErrServerStopped if the server was stopped before it
+	// started serving, which is not a failure during shutdown.
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
 		klog.Fatalf("Failed to serve: %v", err.Error())
 	}
 
@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc"
@@ -28,6 +30,7 @@ import (
 
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/constants"
+	gce "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/gce-cloud-provider/compute"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/parameters"
 )
 
@@ -185,7 +188,7 @@ func validateStoragePools(req *csi.CreateVolumeRequest, params parameters.DiskPa
 		return fmt.Errorf("failed to validate storage pools zones: %v", err)
 	}
 
-	// Check that Storage Pools are in same project as the GCEClient that creates the volume.
+	// Check that Storage Pools are in the same project as the disk.
 	if err := validateStoragePoolProjects(project, params.StoragePools); err != nil {
 		return fmt.Errorf("failed to validate storage pools projects: %v", err)
 	}
@@ -219,6 +222,33 @@ func validateStoragePoolProjects(project string, storagePools []parameters.Stora
 	return nil
 }
 
+// diskProject returns the project to provision the disk of params in, the
+// project parameter if set or else the default project of cloudProvider.
+func diskProject(cloudProvider gce.GCECompute, params parameters.DiskParameters) string {
+	if params.Project != "" {
+		return params.Project
+	}
+	return cloudProvider.GetDefaultProject()
+}
+
+// validateResourceTagsProject checks that the resource tags of a disk
+// provisioned in another project than the default one are not scoped to
+// another project, as project-scoped tags can only be bound to resources of
+// their project. Tags scoped to a numeric ID, an organization or a project
+// number, are left to GCE to validate.
+func validateResourceTagsProject(project string, resourceTags map[string]string) error {
+	for tagParentIDKey := range resourceTags {
+		parentID, _, _ := strings.Cut(tagParentIDKey, "/")
+		if _, err := strconv.ParseUint(parentID, 10, 64); err == nil {
+			continue
+		}
+		if parentID != project {
+			return fmt.Errorf("resource tag key %q is scoped to project %q and can't be bound to disks in project %q", tagParentIDKey, parentID, project)
+		}
+	}
+	return nil
+}
+
 func getMultiWriterFromCapability(vc *csi.VolumeCapability) (bool, error) {
 	if vc.GetAccessMode() == nil {
 		return false, errors.New("access mode is nil")
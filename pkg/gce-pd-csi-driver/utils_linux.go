@@ -24,6 +24,7 @@ import (
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
 	"k8s.io/mount-utils"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 )
@@ -37,6 +38,18 @@ func getDevicePath(ns *GCENodeServer, volumeID, partition string) (string, error
 	if err != nil {
 		return "", fmt.Errorf("error getting device name: %w", err)
 	}
+	// The cache only tracks whole disks, and only hands out paths that haven't
+	// changed since the volume was added. The device is still checked to be
+	// the disk, as the cache trusts the devices it found at startup.
+	if ns.DeviceCache != nil && partition == "" {
+		if devicePath, ok := ns.DeviceCache.GetDevicePath(volumeID); ok {
+			matches, err := ns.DeviceUtils.DevicePathMatches(devicePath, deviceName)
+			if err == nil && matches {
+				return devicePath, nil
+			}
+			klog.Warningf("Cached device path %s is not disk %s, finding it again: %v", devicePath, deviceName, err)
+		}
+	}
 	devicePaths := ns.DeviceUtils.GetDiskByIdPaths(deviceName, partition)
 	devicePath, err := ns.DeviceUtils.VerifyDevicePath(devicePaths, deviceName)
 	if err != nil {
@@ -48,7 +61,7 @@ func getDevicePath(ns *GCENodeServer, volumeID, partition string) (string, error
 	return devicePath, nil
 }
 
-func (ns *GCENodeServer) formatAndMount(source, target, fstype string, options []string, m *mount.SafeFormatAndMount) error {
+func (ns *GCENodeServer) formatAndMount(source, target, fstype string, options, formatOptions []string, m *mount.SafeFormatAndMount) error {
 	if ns.formatAndMountSemaphore != nil {
 		done := make(chan any)
 		defer close(done)
@@ -76,7 +89,7 @@ func (ns *GCENodeServer) formatAndMount(source, target, fstype string, options [
 		}()
 	}
 
-	err := m.FormatAndMount(source, target, fstype, options)
+	err := m.FormatAndMountSensitiveWithFormatOptions(source, target, fstype, options, nil /* sensitiveOptions */, formatOptions)
 	if ns.metricsManager != nil {
 		ns.metricsManager.RecordMountErrorMetric(fstype, err)
 	}
@@ -87,6 +100,24 @@ func preparePublishPath(path string, m *mount.SafeFormatAndMount) error {
 	return os.MkdirAll(path, 0750)
 }
 
+// prepareBlockPublishPath exposes the block volume as a file at path and
+// returns the source to bind mount onto it.
+func prepareBlockPublishPath(devicePath, path string, m *mount.SafeFormatAndMount) (string, error) {
+	if err := makeFile(path); err != nil {
+		if removeErr := os.Remove(path); removeErr != nil {
+			return "", fmt.Errorf("Error removing block file at target path %v: %v, mounti error: %v", path, removeErr, err.Error())
+		}
+		return "", fmt.Errorf("Failed to create block file at target path %v: %v", path, err.Error())
+	}
+	return devicePath, nil
+}
+
+// checkReadOnlyPublish is a no-op on linux, where a read-only bind mount
+// enforces ReadOnly.
+func checkReadOnlyPublish(ns *GCENodeServer, volumeID string) error {
+	return nil
+}
+
 func prepareStagePath(path string, m *mount.SafeFormatAndMount) error {
 	return os.MkdirAll(path, 0750)
 }
@@ -132,3 +163,7 @@ func setReadAheadKB(devicePath string, readAheadKB int64, m *mount.SafeFormatAnd
 	}
 	return nil
 }
+
+func getDiskFormat(devicePath string, m *mount.SafeFormatAndMount) (string, error) {
+	return m.GetDiskFormat(devicePath)
+}
@@ -19,12 +19,14 @@ import (
 	"fmt"
 	"strings"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/mount-utils"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	mounter "sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/mount-manager"
 )
 
-func (ns *GCENodeServer) formatAndMount(source, target, fstype string, options []string, m *mount.SafeFormatAndMount) error {
+func (ns *GCENodeServer) formatAndMount(source, target, fstype string, options, formatOptions []string, m *mount.SafeFormatAndMount) error {
 	if !strings.EqualFold(fstype, defaultWindowsFsType) {
 		return fmt.Errorf("GCE PD CSI driver can only supports %s file system, it does not support %s", defaultWindowsFsType, fstype)
 	}
@@ -53,6 +55,44 @@ func preparePublishPath(path string, m *mount.SafeFormatAndMount) error {
 	return nil
 }
 
+// Block volumes are published as a symlink to the raw disk, which is then
+// brought online. Like other symlinks, the target path must not exist.
+func prepareBlockPublishPath(devicePath, path string, m *mount.SafeFormatAndMount) (string, error) {
+	if err := preparePublishPath(path, m); err != nil {
+		return "", err
+	}
+	proxy, ok := m.Interface.(mounter.CSIProxyMounter)
+	if !ok {
+		return "", fmt.Errorf("could not cast to csi proxy class")
+	}
+	return proxy.BlockDevicePath(devicePath)
+}
+
+// checkReadOnlyPublish fails unless the disk is attached read-only, as CSI
+// Proxy can only publish volumes with the access mode of their disk.
+func checkReadOnlyPublish(ns *GCENodeServer, volumeID string) error {
+	_, volumeKey, err := common.VolumeIDToKey(volumeID)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("volume ID is invalid: %v", err.Error()))
+	}
+	deviceName, err := common.GetDeviceName(volumeKey)
+	if err != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("error getting device name: %v", err.Error()))
+	}
+	proxy, ok := ns.Mounter.Interface.(mounter.CSIProxyMounter)
+	if !ok {
+		return status.Error(codes.Internal, "could not cast to csi proxy class")
+	}
+	readOnly, err := proxy.IsDiskReadOnly(deviceName)
+	if err != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("failed to check access mode of disk %s: %v", deviceName, err.Error()))
+	}
+	if !readOnly {
+		return status.Error(codes.FailedPrecondition, fmt.Sprintf("volume %s cannot be published read-only on Windows because its disk is attached read-write", volumeID))
+	}
+	return nil
+}
+
 // Before staging (which means creating symlink) in Windows, the targetPath should
 // not exist.
 func prepareStagePath(path string, m *mount.SafeFormatAndMount) error {
@@ -110,3 +150,7 @@ func setReadAheadKB(devicePath string, readAheadKB int64, m *mount.SafeFormatAnd
 	// This is a no-op on windows.
 	return nil
 }
+
+func getDiskFormat(devicePath string, m *mount.SafeFormatAndMount) (string, error) {
+	return "", fmt.Errorf("getting the disk format is not supported on windows")
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health runs the health checks behind the CSI Probe of the driver.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// CheckFunc returns an error if a dependency of the driver is unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of the last run of a check.
+type Result struct {
+	Name string `json:"name"`
+	// Healthy is false once the check failed FailureThreshold times in a
+	// row.
+	Healthy             bool      `json:"healthy"`
+	Error               string    `json:"error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures,omitempty"`
+	CheckedAt           time.Time `json:"checkedAt"`
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	failures int
+	result   *Result
+}
+
+// Checker runs registered checks and caches their results for a TTL, so that
+// frequent probes don't put load on the dependencies being checked.
+type Checker struct {
+	ttl              time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	now              func() time.Time
+
+	// mutex serializes runs of the checks, a probe waits for a run in
+	// progress rather than starting its own.
+	mutex  sync.Mutex
+	checks []*check
+}
+
+// NewChecker returns a Checker that re-runs a check once its result is older
+// than ttl, and fails a check that takes longer than timeout. A check is
+// reported unhealthy once it failed failureThreshold times in a row, so that a
+// transient error doesn't get the driver restarted by its liveness probe.
+func NewChecker(ttl, timeout time.Duration, failureThreshold int) *Checker {
+	return &Checker{
+		ttl:              ttl,
+		timeout:          timeout,
+		failureThreshold: failureThreshold,
+		now:              time.Now,
+	}
+}
+
+// Register adds a check. Checks run in the order they were registered.
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks = append(c.checks, &check{name: name, fn: fn})
+}
+
+// Results returns the result of every check, running the ones whose cached
+// result has expired.
+func (c *Checker) Results(ctx context.Context) []Result {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	results := make([]Result, 0, len(c.checks))
+	for _, chk := range c.checks {
+		if chk.result == nil || c.now().Sub(chk.result.CheckedAt) >= c.ttl {
+			chk.result = c.run(ctx, chk)
+		}
+		results = append(results, *chk.result)
+	}
+	return results
+}
+
+func (c *Checker) run(ctx context.Context, chk *check) *Result {
+	// The result is cached and shared by the following probes, so the check
+	// doesn't fail when the probe that happens to run it is canceled.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	result := &Result{Name: chk.name}
+	if err := chk.fn(ctx); err != nil {
+		chk.failures++
+		klog.Warningf("Health check %s failed (%d in a row): %v", chk.name, chk.failures, err)
+		result.Error = err.Error()
+	} else {
+		chk.failures = 0
+	}
+	result.Healthy = chk.failures < c.failureThreshold
+	result.ConsecutiveFailures = chk.failures
+	result.CheckedAt = c.now()
+	return result
+}
+
+// Ready reports whether all checks are healthy.
+func (c *Checker) Ready(ctx context.Context) bool {
+	for _, result := range c.Results(ctx) {
+		if !result.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP writes the results of the checks as JSON, with status 503 if any
+// of them is unhealthy.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	results := c.Results(r.Context())
+	status := http.StatusOK
+	for _, result := range results {
+		if !result.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(struct {
+		Checks []Result `json:"checks"`
+	}{results}); err != nil {
+		klog.Errorf("Failed to write health check results: %v", err)
+	}
+}
+
+// BinariesCheck returns a check that the binaries are found in PATH, or at
+// their path if it is absolute.
+func BinariesCheck(binaries ...string) CheckFunc {
+	return func(ctx context.Context) error {
+		var missing []string
+		for _, binary := range binaries {
+			if _, err := exec.LookPath(binary); err != nil {
+				missing = append(missing, binary)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("binaries not found: %v", missing)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheckerCachesResults(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChecker(time.Minute, time.Second, 1)
+	c.now = func() time.Time { return now }
+
+	calls := 0
+	var checkErr error
+	c.Register("metadata", func(ctx context.Context) error {
+		calls++
+		return checkErr
+	})
+
+	if !c.Ready(context.Background()) {
+		t.Errorf("Expected ready")
+	}
+	// The failure is not seen until the cached result expires.
+	checkErr = errors.New("metadata server unreachable")
+	now = now.Add(30 * time.Second)
+	if !c.Ready(context.Background()) {
+		t.Errorf("Expected cached ready result")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	now = now.Add(30 * time.Second)
+	if c.Ready(context.Background()) {
+		t.Errorf("Expected not ready once the cached result expired")
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	c := NewChecker(time.Minute, 10*time.Millisecond, 1)
+	c.Register("gce-api", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	results := c.Results(context.Background())
+	if len(results) != 1 || results[0].Healthy {
+		t.Errorf("Expected timed out check to be unhealthy, got %+v", results)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	c := NewChecker(time.Minute, time.Second, 1)
+	c.Register("binaries", BinariesCheck(os.Args[0], "not-a-real-binary-for-tests"))
+	c.Register("metadata", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	var body struct {
+		Checks []Result `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response %q: %v", rec.Body.String(), err)
+	}
+	if len(body.Checks) != 2 {
+		t.Fatalf("Expected 2 checks, got %+v", body.Checks)
+	}
+	if body.Checks[0].Healthy || body.Checks[0].Error != "binaries not found: [not-a-real-binary-for-tests]" {
+		t.Errorf("Unexpected binaries check result %+v", body.Checks[0])
+	}
+	if !body.Checks[1].Healthy {
+		t.Errorf("Unexpected metadata check result %+v", body.Checks[1])
+	}
+}
+
+func TestCheckerFailureThreshold(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChecker(time.Minute, time.Second, 2)
+	c.now = func() time.Time { return now }
+
+	checkErr := errors.New("listing zones: 503")
+	c.Register("gce-api", func(ctx context.Context) error { return checkErr })
+
+	// A single failure is reported but leaves the driver ready.
+	results := c.Results(context.Background())
+	if !results[0].Healthy || results[0].Error == "" || results[0].ConsecutiveFailures != 1 {
+		t.Errorf("Unexpected result after a failure: %+v", results[0])
+	}
+	now = now.Add(time.Minute)
+	if c.Ready(context.Background()) {
+		t.Errorf("Expected not ready after 2 failures in a row")
+	}
+	checkErr = nil
+	now = now.Add(time.Minute)
+	results = c.Results(context.Background())
+	if !results[0].Healthy || results[0].ConsecutiveFailures != 0 {
+		t.Errorf("Unexpected result after a success: %+v", results[0])
+	}
+}
+
+func TestCheckerCanceledProbe(t *testing.T) {
+	c := NewChecker(time.Minute, time.Second, 1)
+	c.Register("metadata", func(ctx context.Context) error { return ctx.Err() })
+
+	// The probe gave up, the check still runs and its result is cached.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if !c.Ready(ctx) {
+		t.Errorf("Expected ready for a canceled probe")
+	}
+	if !c.Ready(context.Background()) {
+		t.Errorf("Expected cached ready result")
+	}
+}
@@ -0,0 +1,109 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const eventTimeout = 10 * time.Second
+
+// EventRecorder records events about the node the driver runs on.
+type EventRecorder interface {
+	Eventf(eventType, reason, messageFmt string, args ...any)
+}
+
+type nodeEventRecorder struct {
+	kubeClient kubernetes.Interface
+	nodeName   string
+	component  string
+}
+
+// NewNodeEventRecorder returns an EventRecorder that creates events on
+// nodeName, reported by component.
+func NewNodeEventRecorder(nodeName string, component string) (EventRecorder, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeEventRecorder{kubeClient: kubeClient, nodeName: nodeName, component: component}, nil
+}
+
+// Eventf creates the event in the background, failures are only logged.
+func (r *nodeEventRecorder) Eventf(eventType, reason, messageFmt string, args ...any) {
+	createNodeEvent(r.kubeClient, r.nodeName, r.component, r.nodeName, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// NodesEventRecorder records events about any node of the cluster.
+type NodesEventRecorder interface {
+	NodeEventf(nodeName, eventType, reason, messageFmt string, args ...any)
+}
+
+type nodesEventRecorder struct {
+	kubeClient kubernetes.Interface
+	component  string
+}
+
+// NewNodesEventRecorder returns a NodesEventRecorder that creates events
+// reported by component.
+func NewNodesEventRecorder(component string) (NodesEventRecorder, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &nodesEventRecorder{kubeClient: kubeClient, component: component}, nil
+}
+
+func (r *nodesEventRecorder) NodeEventf(nodeName, eventType, reason, messageFmt string, args ...any) {
+	createNodeEvent(r.kubeClient, nodeName, r.component, "", eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// createNodeEvent creates an event on nodeName reported by component on host
+// in the background, failures are only logged.
+func createNodeEvent(kubeClient kubernetes.Interface, nodeName, component, host, eventType, reason, message string) {
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: nodeName + ".",
+			Namespace:    metav1.NamespaceDefault,
+		},
+		// Like the kubelet, the node name is used as the UID of the node.
+		InvolvedObject: v1.ObjectReference{
+			Kind: "Node",
+			Name: nodeName,
+			UID:  types.UID(nodeName),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Source: v1.EventSource{
+			Component: component,
+			Host:      host,
+		},
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+		defer cancel()
+		if _, err := kubeClient.CoreV1().Events(event.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+			klog.Warningf("Failed to record event %s on node %s: %v", reason, nodeName, err)
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+package k8sclient
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FakeEventRecorder keeps the events it records as "<type> <reason> <message>",
+// and the events about nodes as "<type> <reason> <node>: <message>".
+type FakeEventRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func NewFakeEventRecorder() *FakeEventRecorder {
+	return &FakeEventRecorder{}
+}
+
+func (r *FakeEventRecorder) Eventf(eventType, reason, messageFmt string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("%s %s %s", eventType, reason, fmt.Sprintf(messageFmt, args...)))
+}
+
+func (r *FakeEventRecorder) NodeEventf(nodeName, eventType, reason, messageFmt string, args ...any) {
+	r.Eventf(eventType, reason, "%s: %s", nodeName, fmt.Sprintf(messageFmt, args...))
+}
+
+// Events returns the events recorded so far.
+func (r *FakeEventRecorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
@@ -2,12 +2,15 @@ package k8sclient
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
@@ -47,3 +50,25 @@ func getNodeWithRetry(ctx context.Context, kubeClient *kubernetes.Clientset, nod
 	}
 	return nodeObj, err
 }
+
+// NewNodeLister returns a lister of the nodes of the cluster, backed by an
+// informer that runs until ctx is done.
+func NewNodeLister(ctx context.Context, resync time.Duration) (corelisters.NodeLister, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	factory := informers.NewSharedInformerFactory(kubeClient, resync)
+	lister := factory.Core().V1().Nodes().Lister()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync %v informer", informerType)
+		}
+	}
+	return lister, nil
+}
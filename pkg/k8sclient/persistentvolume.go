@@ -0,0 +1,73 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PVVolumeHandleIndex is the index of the PersistentVolumes of the indexer of
+// NewPersistentVolumeIndexer by the key of their CSI volume, see
+// PVVolumeHandleKey.
+const PVVolumeHandleIndex = "csiVolumeHandle"
+
+// PVVolumeHandleKey returns the key of the PVVolumeHandleIndex of the volume
+// volumeHandle of the CSI driver driverName.
+func PVVolumeHandleKey(driverName, volumeHandle string) string {
+	return driverName + "/" + volumeHandle
+}
+
+// PVVolumeHandleIndexFunc is the index function of PVVolumeHandleIndex.
+func PVVolumeHandleIndexFunc(obj any) ([]string, error) {
+	pv, ok := obj.(*v1.PersistentVolume)
+	if !ok || pv.Spec.CSI == nil {
+		return nil, nil
+	}
+	return []string{PVVolumeHandleKey(pv.Spec.CSI.Driver, pv.Spec.CSI.VolumeHandle)}, nil
+}
+
+// NewPersistentVolumeIndexer returns an indexer of the PersistentVolumes of
+// the cluster with the PVVolumeHandleIndex, backed by an informer that runs
+// until ctx is done.
+func NewPersistentVolumeIndexer(ctx context.Context, resync time.Duration) (cache.Indexer, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	factory := informers.NewSharedInformerFactory(kubeClient, resync)
+	informer := factory.Core().V1().PersistentVolumes().Informer()
+	if err := informer.AddIndexers(cache.Indexers{PVVolumeHandleIndex: PVVolumeHandleIndexFunc}); err != nil {
+		return nil, err
+	}
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync %v informer", informerType)
+		}
+	}
+	return informer.GetIndexer(), nil
+}
@@ -3,7 +3,9 @@ package linkcache
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,17 +14,25 @@ import (
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/k8sclient"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
 )
 
-const byIdDir = "/dev/disk/by-id"
+const (
+	byIdDir = "/dev/disk/by-id"
+	// udev usually updates the by-id symlinks within milliseconds of the
+	// kernel uevent, but may lag behind under load.
+	defaultSettleDelay = 2 * time.Second
+)
 
-func NewDeviceCacheForNode(ctx context.Context, period time.Duration, nodeName string, driverName string, deviceUtils deviceutils.DeviceUtils) (*DeviceCache, error) {
+func NewDeviceCacheForNode(ctx context.Context, period time.Duration, nodeName string, driverName string, deviceUtils deviceutils.DeviceUtils, metricsManager *metrics.MetricsManager) (*DeviceCache, error) {
 	node, err := k8sclient.GetNodeWithRetry(ctx, nodeName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get node %s: %w", nodeName, err)
 	}
 
-	return newDeviceCacheForNode(period, node, driverName, deviceUtils), nil
+	deviceCache := newDeviceCacheForNode(period, node, driverName, deviceUtils)
+	deviceCache.metricsManager = metricsManager
+	return deviceCache, nil
 }
 
 func NewTestDeviceCache(period time.Duration, node *v1.Node) *DeviceCache {
@@ -44,10 +54,12 @@ func NewTestNodeWithVolumes(volumes []string) *v1.Node {
 
 func newDeviceCacheForNode(period time.Duration, node *v1.Node, driverName string, deviceUtils deviceutils.DeviceUtils) *DeviceCache {
 	deviceCache := &DeviceCache{
-		symlinks:    make(map[string]deviceMapping),
-		period:      period,
-		deviceUtils: deviceUtils,
-		dir:         byIdDir,
+		symlinks:      make(map[string]deviceMapping),
+		period:        period,
+		deviceUtils:   deviceUtils,
+		dir:           byIdDir,
+		listenUevents: listenBlockUevents,
+		settleDelay:   defaultSettleDelay,
 	}
 
 	// Look at the status.volumesInUse field.  For each, take the last section
@@ -73,14 +85,24 @@ func newDeviceCacheForNode(period time.Duration, node *v1.Node, driverName strin
 	return deviceCache
 }
 
-// Run since it needs an infinite loop to keep itself up to date
+// Run keeps the cache up to date until ctx is done. The symlinks are
+// re-evaluated on every block device uevent, and every period in case events
+// were missed.
 func (d *DeviceCache) Run(ctx context.Context) {
 	klog.Infof("Starting device cache watcher for directory %s with period %s", d.dir, d.period)
 
-	// Start the loop that runs every minute
+	events, err := d.listenUevents(ctx)
+	if err != nil {
+		klog.Warningf("Failed to subscribe to block device uevents, only checking symlinks every %s: %v", d.period, err)
+	}
+
 	ticker := time.NewTicker(d.period)
 	defer ticker.Stop()
 
+	// settle fires once after a burst of uevents, as udev only updates the
+	// symlinks after the kernel has sent the event. It is nil, and so never
+	// fires, when no re-evaluation is pending.
+	var settle <-chan time.Time
 	for {
 		select {
 		case <-ctx.Done():
@@ -88,8 +110,18 @@ func (d *DeviceCache) Run(ctx context.Context) {
 			return
 		case <-ticker.C:
 			d.listAndUpdate()
-
-			klog.Infof("Cache contents: %+v", d.symlinks)
+		case ev, ok := <-events:
+			if !ok {
+				klog.Warningf("Block device uevent listener stopped, only checking symlinks every %s", d.period)
+				events = nil
+				continue
+			}
+			klog.V(5).Infof("Received %s uevent for %s device %s", ev.action, ev.devType, ev.devName)
+			d.listAndUpdate()
+			settle = time.After(d.settleDelay)
+		case <-settle:
+			settle = nil
+			d.listAndUpdate()
 		}
 	}
 }
@@ -132,6 +164,7 @@ func (d *DeviceCache) AddVolume(volumeID string) error {
 		d.symlinks[symlink] = deviceMapping{
 			volumeID: volumeID,
 			realPath: realPath,
+			verified: realPath != "",
 		}
 		klog.V(4).Infof("Added volume %s to cache with symlink %s", volumeID, symlink)
 	}
@@ -151,22 +184,71 @@ func (d *DeviceCache) RemoveVolume(volumeID string) {
 	}
 }
 
+// GetDevicePath returns the /dev/disk/by-id symlink of the volume if the
+// cache knows it and it still points at the device it pointed at when the
+// volume was added. Otherwise callers need to find and verify the device
+// themselves.
+func (d *DeviceCache) GetDevicePath(volumeID string) (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// Symlinks are ordered so that the result is stable, which prefers the
+	// google-* links over the scsi-* ones.
+	var candidates []string
+	for symlink, device := range d.symlinks {
+		if device.volumeID == volumeID && device.verified {
+			candidates = append(candidates, symlink)
+		}
+	}
+	sort.Strings(candidates)
+	for _, symlink := range candidates {
+		realPath, err := filepath.EvalSymlinks(symlink)
+		if err == nil && realPath == d.symlinks[symlink].realPath {
+			return symlink, true
+		}
+	}
+	return "", false
+}
+
 func (d *DeviceCache) listAndUpdate() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	for symlink, device := range d.symlinks {
 		// Evaluate the symlink
 		realPath, err := filepath.EvalSymlinks(symlink)
 		if err != nil {
-			klog.Warningf("Error evaluating symlink for volume %s: %v", device.volumeID, err)
+			if os.IsNotExist(err) && device.verified {
+				klog.Warningf("Device path for volume %s (symlink: %s) disappeared, previous path: %s", device.volumeID, symlink, device.realPath)
+				device.verified = false
+				d.symlinks[symlink] = device
+				continue
+			}
+			klog.V(5).Infof("Error evaluating symlink for volume %s: %v", device.volumeID, err)
+			continue
+		}
+
+		if device.realPath == "" {
+			// The symlink was created after the volume was added.
+			klog.Infof("Found real path %s for volume %s (symlink: %s)", realPath, device.volumeID, symlink)
+			device.realPath = realPath
+			device.verified = true
+			d.symlinks[symlink] = device
 			continue
 		}
 
 		// Check if the realPath has changed
 		if realPath != device.realPath {
 			klog.Warningf("Change in device path for volume %s (symlink: %s), previous path: %s, new path: %s", device.volumeID, symlink, device.realPath, realPath)
+			if d.metricsManager != nil {
+				d.metricsManager.RecordDevicePathChangeMetric()
+			}
 
 			// Update the cache with the new realPath
 			device.realPath = realPath
+			device.verified = false
 			d.symlinks[symlink] = device
 		}
 	}
+	klog.V(5).Infof("Cache contents: %+v", d.symlinks)
 }
@@ -0,0 +1,224 @@
+package linkcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+)
+
+const testVolumeID = "projects/test-project/zones/us-central1-c/disks/test-disk"
+
+// testDeviceUtils puts the by-id symlinks of all disks into dir.
+type testDeviceUtils struct {
+	deviceutils.DeviceUtils
+	dir string
+}
+
+func (du *testDeviceUtils) GetDiskByIdPaths(deviceName string, partition string) []string {
+	return []string{
+		filepath.Join(du.dir, "google-"+deviceName),
+		filepath.Join(du.dir, "scsi-0Google_PersistentDisk_"+deviceName),
+	}
+}
+
+type testDevices struct {
+	t     *testing.T
+	devFs string
+	byId  string
+}
+
+func newTestDevices(t *testing.T, devices ...string) *testDevices {
+	root := t.TempDir()
+	td := &testDevices{t: t, devFs: root, byId: filepath.Join(root, "disk", "by-id")}
+	if err := os.MkdirAll(td.byId, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, device := range devices {
+		if err := os.WriteFile(filepath.Join(root, device), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return td
+}
+
+func (td *testDevices) link(name, device string) {
+	path := filepath.Join(td.byId, name)
+	os.Remove(path)
+	if err := os.Symlink(filepath.Join("..", "..", device), path); err != nil {
+		td.t.Fatalf("Failed to link %s to %s: %v", name, device, err)
+	}
+}
+
+func (td *testDevices) newCache() *DeviceCache {
+	d := newDeviceCacheForNode(time.Hour, NewTestNodeWithVolumes(nil), "pd.csi.storage.gke.io", &testDeviceUtils{dir: td.byId})
+	d.listenUevents = func(ctx context.Context) (<-chan uevent, error) {
+		return nil, nil
+	}
+	return d
+}
+
+func TestGetDevicePath(t *testing.T) {
+	td := newTestDevices(t, "sdb", "sdc")
+	td.link("google-test-disk", "sdb")
+	td.link("scsi-0Google_PersistentDisk_test-disk", "sdb")
+	d := td.newCache()
+
+	if _, ok := d.GetDevicePath(testVolumeID); ok {
+		t.Fatalf("Expected no device path before the volume is added")
+	}
+	if err := d.AddVolume(testVolumeID); err != nil {
+		t.Fatalf("AddVolume failed: %v", err)
+	}
+	got, ok := d.GetDevicePath(testVolumeID)
+	if want := filepath.Join(td.byId, "google-test-disk"); !ok || got != want {
+		t.Fatalf("Expected device path %s, got %q (found: %v)", want, got, ok)
+	}
+
+	// A path that changed behind the cache's back is not handed out, even
+	// before the cache has noticed.
+	td.link("google-test-disk", "sdc")
+	got, ok = d.GetDevicePath(testVolumeID)
+	if want := filepath.Join(td.byId, "scsi-0Google_PersistentDisk_test-disk"); !ok || got != want {
+		t.Fatalf("Expected fallback device path %s, got %q (found: %v)", want, got, ok)
+	}
+	td.link("scsi-0Google_PersistentDisk_test-disk", "sdc")
+	d.listAndUpdate()
+	if got, ok := d.GetDevicePath(testVolumeID); ok {
+		t.Fatalf("Expected no device path after the device changed, got %s", got)
+	}
+
+	// Re-adding the volume, eg when it is staged again, trusts the new path.
+	if err := d.AddVolume(testVolumeID); err != nil {
+		t.Fatalf("AddVolume failed: %v", err)
+	}
+	if _, ok := d.GetDevicePath(testVolumeID); !ok {
+		t.Fatalf("Expected device path after re-adding the volume")
+	}
+
+	d.RemoveVolume(testVolumeID)
+	if got, ok := d.GetDevicePath(testVolumeID); ok {
+		t.Fatalf("Expected no device path after removing the volume, got %s", got)
+	}
+}
+
+func TestListAndUpdate(t *testing.T) {
+	td := newTestDevices(t, "sdb", "sdc")
+	d := td.newCache()
+	symlink := filepath.Join(td.byId, "google-test-disk")
+
+	// The volume is added before udev created its symlinks.
+	if err := d.AddVolume(testVolumeID); err != nil {
+		t.Fatalf("AddVolume failed: %v", err)
+	}
+	if got := d.symlinks[symlink]; got.realPath != "" || got.verified {
+		t.Fatalf("Expected unresolved mapping, got %+v", got)
+	}
+
+	td.link("google-test-disk", "sdb")
+	d.listAndUpdate()
+	if got := d.symlinks[symlink]; got.realPath != filepath.Join(td.devFs, "sdb") || !got.verified {
+		t.Fatalf("Expected mapping to sdb, got %+v", got)
+	}
+
+	if err := os.Remove(symlink); err != nil {
+		t.Fatal(err)
+	}
+	d.listAndUpdate()
+	if got := d.symlinks[symlink]; got.verified {
+		t.Fatalf("Expected removed symlink to be unverified, got %+v", got)
+	}
+
+	td.link("google-test-disk", "sdc")
+	d.listAndUpdate()
+	if got := d.symlinks[symlink]; got.realPath != filepath.Join(td.devFs, "sdc") || got.verified {
+		t.Fatalf("Expected unverified mapping to sdc, got %+v", got)
+	}
+}
+
+func TestRunUpdatesOnUevent(t *testing.T) {
+	td := newTestDevices(t, "sdb", "sdc")
+	td.link("google-test-disk", "sdb")
+	d := td.newCache()
+	d.settleDelay = time.Millisecond
+	events := make(chan uevent)
+	d.listenUevents = func(ctx context.Context) (<-chan uevent, error) {
+		return events, nil
+	}
+	if err := d.AddVolume(testVolumeID); err != nil {
+		t.Fatalf("AddVolume failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	td.link("google-test-disk", "sdc")
+	events <- uevent{action: "change", subsystem: subsystemBlock, devName: "sdc", devType: "disk"}
+
+	symlink := filepath.Join(td.byId, "google-test-disk")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d.mutex.Lock()
+		got := d.symlinks[symlink]
+		d.mutex.Unlock()
+		if got.realPath == filepath.Join(td.devFs, "sdc") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Cache was not updated after uevent, got %+v", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestParseUevent(t *testing.T) {
+	testCases := []struct {
+		name  string
+		msg   string
+		want  uevent
+		expOk bool
+	}{
+		{
+			name:  "block disk add",
+			msg:   "add@/devices/pci0000:00/0000:00:03.0/virtio0/host0/target0:0:2/0:0:2:0/block/sdb\x00ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:03.0/virtio0/host0/target0:0:2/0:0:2:0/block/sdb\x00SUBSYSTEM=block\x00MAJOR=8\x00MINOR=16\x00DEVNAME=sdb\x00DEVTYPE=disk\x00SEQNUM=3012\x00",
+			want:  uevent{action: "add", subsystem: "block", devName: "sdb", devType: "disk"},
+			expOk: true,
+		},
+		{
+			name:  "nvme partition remove",
+			msg:   "remove@/devices/virtual/block/nvme0n2p1\x00ACTION=remove\x00SUBSYSTEM=block\x00DEVNAME=nvme0n2p1\x00DEVTYPE=partition\x00",
+			want:  uevent{action: "remove", subsystem: "block", devName: "nvme0n2p1", devType: "partition"},
+			expOk: true,
+		},
+		{
+			name: "udev message",
+			msg:  "libudev\x00\xfe\xed\xca\xfe",
+		},
+		{
+			name: "no action",
+			msg:  "add@/devices/foo\x00SUBSYSTEM=block\x00",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseUevent([]byte(tc.msg))
+			if ok != tc.expOk {
+				t.Fatalf("Expected ok: %v, got: %v", tc.expOk, ok)
+			}
+			if ok && got != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -8,9 +8,10 @@ import (
 
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
 )
 
-func NewDeviceCacheForNode(ctx context.Context, period time.Duration, nodeName string, driverName string, deviceUtils deviceutils.DeviceUtils) (*DeviceCache, error) {
+func NewDeviceCacheForNode(ctx context.Context, period time.Duration, nodeName string, driverName string, deviceUtils deviceutils.DeviceUtils, metricsManager *metrics.MetricsManager) (*DeviceCache, error) {
 	klog.Infof("NewDeviceCacheForNode is not implemented for Windows")
 	return nil, nil
 }
@@ -27,3 +28,8 @@ func (d *DeviceCache) AddVolume(volumeID string) error {
 func (d *DeviceCache) RemoveVolume(volumeID string) {
 	// Not implemented for Windows
 }
+
+func (d *DeviceCache) GetDevicePath(volumeID string) (string, bool) {
+	// Not implemented for Windows
+	return "", false
+}
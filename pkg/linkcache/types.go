@@ -1,15 +1,28 @@
 package linkcache
 
 import (
+	"context"
 	"sync"
 	"time"
 
 	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/deviceutils"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/metrics"
 )
 
 type deviceMapping struct {
 	volumeID string
 	realPath string
+	// verified is false once realPath changed after the volume was added, in
+	// which case the mapping is not handed out until the volume is re-added.
+	verified bool
+}
+
+// uevent is a kernel uevent as received over netlink.
+type uevent struct {
+	action    string
+	subsystem string
+	devName   string
+	devType   string
 }
 
 type DeviceCache struct {
@@ -19,4 +32,11 @@ type DeviceCache struct {
 	// dir is the directory to look for device symlinks
 	dir         string
 	deviceUtils deviceutils.DeviceUtils
+
+	metricsManager *metrics.MetricsManager
+	// listenUevents subscribes to block device uevents. The symlinks are
+	// re-evaluated on every event, and once more after settleDelay to pick up
+	// the links udev creates in response to the event.
+	listenUevents func(ctx context.Context) (<-chan uevent, error)
+	settleDelay   time.Duration
 }
@@ -0,0 +1,100 @@
+package linkcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Multicast group of the uevents sent by the kernel, as opposed to the
+	// ones re-broadcast by udev.
+	ueventKernelGroup = 1
+	ueventBufferSize  = 64 * 1024
+	// How often the listener checks whether its context is done.
+	ueventReadTimeout = time.Second
+	subsystemBlock    = "block"
+)
+
+// listenBlockUevents subscribes to kernel uevents over netlink and sends the
+// ones for block devices on the returned channel, which is closed when ctx is
+// done or the socket fails.
+func listenBlockUevents(ctx context.Context) (<-chan uevent, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create uevent socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventKernelGroup}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind uevent socket: %w", err)
+	}
+	tv := unix.NsecToTimeval(ueventReadTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to set uevent socket timeout: %w", err)
+	}
+
+	events := make(chan uevent)
+	go func() {
+		defer close(events)
+		defer unix.Close(fd)
+		buf := make([]byte, ueventBufferSize)
+		for ctx.Err() == nil {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+					continue
+				}
+				if errors.Is(err, unix.ENOBUFS) {
+					// Events were dropped, the periodic resync will catch up.
+					klog.Warningf("Uevent socket overrun, some block device events were lost")
+					continue
+				}
+				klog.Errorf("Failed to read uevent socket, stopping uevent listener: %v", err)
+				return
+			}
+			ev, ok := parseUevent(buf[:n])
+			if !ok || ev.subsystem != subsystemBlock {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// parseUevent parses a kernel uevent of the form
+// "action@devpath\0KEY=value\0KEY=value...".
+func parseUevent(msg []byte) (uevent, bool) {
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) < 2 || !bytes.Contains(fields[0], []byte("@")) {
+		return uevent{}, false
+	}
+	var ev uevent
+	for _, field := range fields[1:] {
+		key, value, found := bytes.Cut(field, []byte("="))
+		if !found {
+			continue
+		}
+		switch string(key) {
+		case "ACTION":
+			ev.action = string(value)
+		case "SUBSYSTEM":
+			ev.subsystem = string(value)
+		case "DEVNAME":
+			ev.devName = string(value)
+		case "DEVTYPE":
+			ev.devType = string(value)
+		}
+	}
+	return ev, ev.action != ""
+}
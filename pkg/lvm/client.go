@@ -0,0 +1,431 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+)
+
+var (
+	pvFields = []string{"pv_name", "vg_name", "pv_size"}
+	vgFields = []string{"vg_name"}
+	lvFields = []string{
+		"lv_name",
+		"vg_name",
+		"lv_tags",
+		"lv_size",
+		"lv_active",
+		"segtype",
+		"pool_lv",
+		"devices",
+		"cache_mode",
+		"kernel_cache_policy",
+		"cache_total_blocks",
+		"cache_used_blocks",
+		"cache_dirty_blocks",
+		"cache_read_hits",
+		"cache_read_misses",
+		"cache_write_hits",
+		"cache_write_misses",
+	}
+)
+
+type lvmClient struct {
+	runner common.CommandRunner
+}
+
+var _ LVM = &lvmClient{}
+
+// NewLVM returns an LVM that runs the LVM and mdadm tools with runner.
+func NewLVM(runner common.CommandRunner) LVM {
+	return &lvmClient{runner: runner}
+}
+
+func (c *lvmClient) run(cmd string, args ...string) ([]byte, error) {
+	return c.runner.RunCommand("" /* pipedCmd */, nil /* pipedCmdArg */, cmd, args...)
+}
+
+// report runs an LVM reporting command, pvs, vgs or lvs, and returns the rows
+// of the report.
+func (c *lvmClient) report(cmd string, fields []string, args ...string) ([]map[string]string, error) {
+	args = append([]string{"--reportformat", "json", "--units", "b", "--nosuffix", "-o", strings.Join(fields, ",")}, args...)
+	output, err := c.run(cmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseReport(output)
+}
+
+// parseReport parses a JSON report. The output may start with warnings, as
+// the command runner combines stderr and stdout.
+func parseReport(output []byte) ([]map[string]string, error) {
+	start := bytes.IndexByte(output, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("no report in output %q", output)
+	}
+	var r struct {
+		Report []map[string][]map[string]string `json:"report"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(output[start:])).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %w", err)
+	}
+	rows := []map[string]string{}
+	for _, report := range r.Report {
+		for _, section := range report {
+			rows = append(rows, section...)
+		}
+	}
+	return rows, nil
+}
+
+func parseInt(row map[string]string, field string) (int64, error) {
+	if row[field] == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(row[field], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", field, row[field], err)
+	}
+	return v, nil
+}
+
+func parseUint(row map[string]string, field string) (uint64, error) {
+	if row[field] == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(row[field], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", field, row[field], err)
+	}
+	return v, nil
+}
+
+// unhide strips the brackets around the names of hidden logical volumes.
+func unhide(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+func (c *lvmClient) ListPhysicalVolumes() ([]PhysicalVolume, error) {
+	rows, err := c.report("pvs", pvFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list physical volumes: %w", err)
+	}
+	pvs := []PhysicalVolume{}
+	for _, row := range rows {
+		size, err := parseInt(row, "pv_size")
+		if err != nil {
+			return nil, err
+		}
+		pvs = append(pvs, PhysicalVolume{Name: row["pv_name"], VGName: row["vg_name"], SizeBytes: size})
+	}
+	return pvs, nil
+}
+
+func (c *lvmClient) ResizePhysicalVolume(pvName string) error {
+	if _, err := c.run("pvresize", pvName); err != nil {
+		return fmt.Errorf("failed to resize physical volume %s: %w", pvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) ListVolumeGroups() ([]VolumeGroup, error) {
+	rows, err := c.report("vgs", vgFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume groups: %w", err)
+	}
+	vgs := []VolumeGroup{}
+	for _, row := range rows {
+		vgs = append(vgs, VolumeGroup{Name: row["vg_name"]})
+	}
+	return vgs, nil
+}
+
+func (c *lvmClient) CreateVolumeGroup(vgName string, pvNames ...string) error {
+	args := append([]string{"--zero", "y", vgName}, pvNames...)
+	if _, err := c.run("vgcreate", args...); err != nil {
+		return fmt.Errorf("failed to create volume group %s: %w", vgName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) ExtendVolumeGroup(vgName string, pvName string) error {
+	if _, err := c.run("vgextend", vgName, pvName); err != nil {
+		return fmt.Errorf("failed to extend volume group %s with %s: %w", vgName, pvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) MergeVolumeGroups(vgName string, mergedVgName string) error {
+	if _, err := c.run("vgmerge", vgName, mergedVgName); err != nil {
+		return fmt.Errorf("failed to merge volume group %s into %s: %w", mergedVgName, vgName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) RemoveMissingPhysicalVolumes(vgName string, force bool) error {
+	args := []string{"--removemissing", vgName}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, err := c.run("vgreduce", args...); err != nil {
+		return fmt.Errorf("failed to remove missing physical volumes from %s: %w", vgName, err)
+	}
+	return nil
+}
+
+func activationFlag(active bool) string {
+	if active {
+		return "-ay"
+	}
+	return "-an"
+}
+
+func (c *lvmClient) SetVolumeGroupActive(vgName string, active bool) error {
+	if _, err := c.run("vgchange", activationFlag(active), vgName); err != nil {
+		return fmt.Errorf("failed to change activation of volume group %s: %w", vgName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) UpdateVolumeGroupMetadata(vgName string) error {
+	if _, err := c.run("vgck", "--updatemetadata", vgName); err != nil {
+		return fmt.Errorf("failed to update metadata of volume group %s: %w", vgName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) ListLogicalVolumes(vgName string) ([]LogicalVolume, error) {
+	args := []string{"-a"}
+	if vgName != "" {
+		args = append(args, vgName)
+	}
+	rows, err := c.report("lvs", lvFields, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logical volumes: %w", err)
+	}
+	lvs := []LogicalVolume{}
+	for _, row := range rows {
+		lv, err := parseLogicalVolume(row)
+		if err != nil {
+			return nil, err
+		}
+		lvs = append(lvs, *lv)
+	}
+	return lvs, nil
+}
+
+func parseLogicalVolume(row map[string]string) (*LogicalVolume, error) {
+	lv := &LogicalVolume{
+		Name:    unhide(row["lv_name"]),
+		VGName:  row["vg_name"],
+		Tags:    splitList(row["lv_tags"]),
+		Active:  row["lv_active"] == "active",
+		SegType: row["segtype"],
+		PoolLV:  unhide(row["pool_lv"]),
+	}
+	var err error
+	if lv.SizeBytes, err = parseInt(row, "lv_size"); err != nil {
+		return nil, err
+	}
+	// Devices are listed with their first extent, e.g. /dev/sdb(0).
+	for _, device := range splitList(row["devices"]) {
+		if i := strings.Index(device, "("); i >= 0 {
+			device = device[:i]
+		}
+		lv.Devices = append(lv.Devices, device)
+	}
+	if lv.SegType != SegTypeCache || row["cache_mode"] == "" {
+		return lv, nil
+	}
+	lv.Cache = &CacheStatus{
+		Mode:   row["cache_mode"],
+		Policy: row["kernel_cache_policy"],
+	}
+	for field, value := range map[string]*uint64{
+		"cache_total_blocks": &lv.Cache.TotalBlocks,
+		"cache_used_blocks":  &lv.Cache.UsedBlocks,
+		"cache_dirty_blocks": &lv.Cache.DirtyBlocks,
+		"cache_read_hits":    &lv.Cache.ReadHits,
+		"cache_read_misses":  &lv.Cache.ReadMisses,
+		"cache_write_hits":   &lv.Cache.WriteHits,
+		"cache_write_misses": &lv.Cache.WriteMisses,
+	} {
+		if *value, err = parseUint(row, field); err != nil {
+			return nil, fmt.Errorf("logical volume %s: %w", lv.Name, err)
+		}
+	}
+	return lv, nil
+}
+
+func (c *lvmClient) CreateLogicalVolume(opts CreateLogicalVolumeOptions) error {
+	args := []string{"--yes", "-n", opts.Name}
+	for _, tag := range opts.Tags {
+		args = append(args, "--addtag", tag)
+	}
+	switch {
+	case opts.Extents != "" && opts.SizeGiB == 0:
+		args = append(args, "-l", opts.Extents)
+	case opts.Extents == "" && opts.SizeGiB > 0:
+		// LVM g|G is GiB.
+		args = append(args, "-L", strconv.FormatInt(opts.SizeGiB, 10)+"g")
+	default:
+		return fmt.Errorf("exactly one of extents and size must be set for logical volume %s", opts.Name)
+	}
+	args = append(args, opts.VGName)
+	args = append(args, opts.PVs...)
+	if _, err := c.run("lvcreate", args...); err != nil {
+		return fmt.Errorf("failed to create logical volume %s: %w", opts.Name, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) ExtendLogicalVolume(vgName, lvName string, pvName string) error {
+	_, err := c.run("lvextend", "-l", "100%PVS", vgName+"/"+lvName, pvName)
+	if err != nil && !strings.Contains(err.Error(), "matches existing size") {
+		return fmt.Errorf("failed to extend logical volume %s: %w", lvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) RemoveLogicalVolume(vgName, lvName string) error {
+	if _, err := c.run("lvremove", "-y", vgName+"/"+lvName); err != nil {
+		return fmt.Errorf("failed to remove logical volume %s: %w", lvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) SetLogicalVolumeActive(vgName, lvName string, active bool) error {
+	if _, err := c.run("lvchange", activationFlag(active), vgName+"/"+lvName); err != nil {
+		return fmt.Errorf("failed to change activation of logical volume %s: %w", lvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) AttachCache(vgName, lvName string, opts AttachCacheOptions) error {
+	args := []string{
+		"--type",
+		"cache",
+		"--cachevol",
+		opts.CacheLVName,
+		"--zero",
+		"y",
+		"--cachemode",
+		opts.Mode,
+		vgName + "/" + lvName,
+		"--chunksize",
+		opts.ChunkSize,
+		"--force",
+		"-y",
+	}
+	if _, err := c.run("lvconvert", args...); err != nil {
+		return fmt.Errorf("failed to attach cache %s to logical volume %s: %w", opts.CacheLVName, lvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) SplitCache(vgName, lvName string) error {
+	if _, err := c.run("lvconvert", "--splitcache", vgName+"/"+lvName, "-y"); err != nil {
+		return fmt.Errorf("failed to split cache of logical volume %s: %w", lvName, err)
+	}
+	return nil
+}
+
+func (c *lvmClient) Uncache(vgName, lvName string, force bool) error {
+	args := []string{"--uncache", vgName + "/" + lvName}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, "-y")
+	if _, err := c.run("lvconvert", args...); err != nil {
+		return fmt.Errorf("failed to uncache logical volume %s: %w", lvName, err)
+	}
+	return nil
+}
+
+// ListRaidArrays lists the arrays of mdadm --detail --scan, which have lines
+// like "ARRAY /dev/md/name metadata=1.2 name=host:name UUID=...", then their
+// devices from mdadm --detail --export.
+func (c *lvmClient) ListRaidArrays() ([]RaidArray, error) {
+	output, err := c.run("mdadm", "--detail", "--scan")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan RAID arrays: %w", err)
+	}
+	arrays := []RaidArray{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "ARRAY" {
+			continue
+		}
+		array := RaidArray{Path: fields[1]}
+		for _, field := range fields[2:] {
+			if name, ok := strings.CutPrefix(field, "name="); ok {
+				// The name is prefixed by the host that created the array.
+				array.Name = name[strings.LastIndex(name, ":")+1:]
+			}
+		}
+		detail, err := c.run("mdadm", "--detail", "--export", array.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get details of RAID array %s: %w", array.Path, err)
+		}
+		array.Devices = parseRaidDevices(string(detail))
+		arrays = append(arrays, array)
+	}
+	return arrays, nil
+}
+
+// parseRaidDevices returns the devices of lines like
+// MD_DEVICE_dev_nvme0n1_DEV=/dev/nvme0n1.
+func parseRaidDevices(detail string) []string {
+	devices := []string{}
+	for _, line := range strings.Split(detail, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && strings.HasPrefix(key, "MD_DEVICE_") && strings.HasSuffix(key, "_DEV") {
+			devices = append(devices, value)
+		}
+	}
+	return devices
+}
+
+func (c *lvmClient) CreateRaidArray(name string, level string, devices []string) error {
+	args := []string{
+		"--create",
+		name,
+		"-l" + level,
+		// Force RAIDing as sometime it might fail for caution if there is just 1 LSSD present as 1 LSSD need not be RAIDed
+		"--force",
+		"-n",
+		strconv.Itoa(len(devices)),
+	}
+	args = append(args, devices...)
+	if _, err := c.run("mdadm", args...); err != nil {
+		return fmt.Errorf("failed to create RAID array %s: %w", name, err)
+	}
+	return nil
+}
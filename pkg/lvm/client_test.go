@@ -0,0 +1,249 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/gcp-compute-persistent-disk-csi-driver/pkg/common"
+)
+
+const lvsCommand = "lvs --reportformat json --units b --nosuffix -o lv_name,vg_name,lv_tags,lv_size,lv_active,segtype,pool_lv,devices,cache_mode,kernel_cache_policy,cache_total_blocks,cache_used_blocks,cache_dirty_blocks,cache_read_hits,cache_read_misses,cache_write_hits,cache_write_misses -a"
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return string(data)
+}
+
+func TestListLogicalVolumes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fixture string
+		expLvs  []LogicalVolume
+		expErr  bool
+	}{
+		{
+			name:    "cached and plain logical volumes",
+			fixture: "lvs.json",
+			expLvs: []LogicalVolume{
+				{
+					Name:      "csi-main-pvc-1",
+					VGName:    "csi-vg-test",
+					Tags:      []string{"pd-csi-volume-id=projects/test-project/zones/us-central1-c/disks/pvc-1"},
+					SizeBytes: 107374182400,
+					Active:    true,
+					SegType:   SegTypeCache,
+					PoolLV:    "csi-fast-pvc-1_cvol",
+					Devices:   []string{"csi-main-pvc-1_corig"},
+					Cache: &CacheStatus{
+						Mode:        "writeback",
+						Policy:      "smq",
+						TotalBlocks: 81920,
+						UsedBlocks:  10240,
+						DirtyBlocks: 12,
+						ReadHits:    4000,
+						ReadMisses:  500,
+						WriteHits:   3000,
+						WriteMisses: 20,
+					},
+				},
+				{
+					Name:      "csi-fast-pvc-1_cvol",
+					VGName:    "csi-vg-test",
+					SizeBytes: 53687091200,
+					Active:    true,
+					SegType:   "linear",
+					Devices:   []string{"/dev/md127"},
+				},
+				{
+					// The cache of an inactive logical volume has no status.
+					Name:      "csi-main-pvc-2",
+					VGName:    "csi-vg-test",
+					Tags:      []string{"team=a", "pd-csi-volume-id=projects/test-project/zones/us-central1-c/disks/pvc-2"},
+					SizeBytes: 10737418240,
+					SegType:   SegTypeCache,
+					PoolLV:    "csi-fast-pvc-2_cvol",
+					Devices:   []string{"csi-main-pvc-2_corig"},
+				},
+				{
+					Name:      "csi-main-pvc-3",
+					VGName:    "csi-vg-test",
+					SizeBytes: 10737418240,
+					Active:    true,
+					SegType:   "linear",
+					Devices:   []string{"/dev/sdb", "/dev/sdc"},
+				},
+			},
+		},
+		{
+			name:    "invalid cache statistics",
+			fixture: "lvs-invalid.json",
+			expErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := common.NewFakeCommandRunner(common.FakeCommand{Command: lvsCommand, Output: readFixture(t, tc.fixture)})
+			lvs, err := NewLVM(runner).ListLogicalVolumes("")
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if diff := cmp.Diff(tc.expLvs, lvs); diff != "" {
+				t.Errorf("Unexpected logical volumes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListPhysicalVolumes(t *testing.T) {
+	runner := common.NewFakeCommandRunner(common.FakeCommand{
+		Command: "pvs --reportformat json --units b --nosuffix -o pv_name,vg_name,pv_size",
+		Output:  readFixture(t, "pvs.json"),
+	})
+	pvs, err := NewLVM(runner).ListPhysicalVolumes()
+	if err != nil {
+		t.Fatalf("ListPhysicalVolumes failed: %v", err)
+	}
+	want := []PhysicalVolume{
+		{Name: "/dev/md127", VGName: "csi-vg-test", SizeBytes: 805306368000},
+		{Name: "/dev/sdb", VGName: "csi-vg-test", SizeBytes: 107374182400},
+		{Name: "/dev/sdc", SizeBytes: 10737418240},
+	}
+	if diff := cmp.Diff(want, pvs); diff != "" {
+		t.Errorf("Unexpected physical volumes (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseReportErrors(t *testing.T) {
+	for _, output := range []string{"", "  No volume groups found\n", `{"report": [{"lv": [{"lv_name": 1}]}]}`} {
+		if _, err := parseReport([]byte(output)); err == nil {
+			t.Errorf("Expected error for output %q", output)
+		}
+	}
+}
+
+func TestListRaidArrays(t *testing.T) {
+	runner := common.NewFakeCommandRunner(
+		common.FakeCommand{Command: "mdadm --detail --scan", Output: readFixture(t, "mdadm-detail-scan.txt")},
+		common.FakeCommand{Command: "mdadm --detail --export /dev/md/csi-driver-data-cache", Output: readFixture(t, "mdadm-detail-export.txt")},
+		common.FakeCommand{Command: "mdadm --detail --export /dev/md0", Output: "MD_LEVEL=raid1\n"},
+	)
+	arrays, err := NewLVM(runner).ListRaidArrays()
+	if err != nil {
+		t.Fatalf("ListRaidArrays failed: %v", err)
+	}
+	want := []RaidArray{
+		{Name: "csi-driver-data-cache", Path: "/dev/md/csi-driver-data-cache", Devices: []string{"/dev/nvme0n1", "/dev/nvme1n1"}},
+		{Name: "other", Path: "/dev/md0", Devices: []string{}},
+	}
+	if diff := cmp.Diff(want, arrays); diff != "" {
+		t.Errorf("Unexpected RAID arrays (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	testCases := []struct {
+		name     string
+		run      func(l LVM) error
+		commands []common.FakeCommand
+		expErr   bool
+	}{
+		{
+			name: "create main logical volume",
+			run: func(l LVM) error {
+				return l.CreateLogicalVolume(CreateLogicalVolumeOptions{Name: "main", VGName: "vg", Extents: "100%PVS", Tags: []string{"a=b"}, PVs: []string{"/dev/sdb"}})
+			},
+			commands: []common.FakeCommand{{Command: "lvcreate --yes -n main --addtag a=b -l 100%PVS vg /dev/sdb"}},
+		},
+		{
+			name: "create cache logical volume",
+			run: func(l LVM) error {
+				return l.CreateLogicalVolume(CreateLogicalVolumeOptions{Name: "fast", VGName: "vg", SizeGiB: 100, PVs: []string{"/dev/md127"}})
+			},
+			commands: []common.FakeCommand{{Command: "lvcreate --yes -n fast -L 100g vg /dev/md127"}},
+		},
+		{
+			name: "logical volume without size",
+			run: func(l LVM) error {
+				return l.CreateLogicalVolume(CreateLogicalVolumeOptions{Name: "fast", VGName: "vg"})
+			},
+			expErr: true,
+		},
+		{
+			name: "attach cache",
+			run: func(l LVM) error {
+				return l.AttachCache("vg", "main", AttachCacheOptions{CacheLVName: "fast", Mode: "writeback", ChunkSize: "512KiB"})
+			},
+			commands: []common.FakeCommand{{Command: "lvconvert --type cache --cachevol fast --zero y --cachemode writeback vg/main --chunksize 512KiB --force -y"}},
+		},
+		{
+			name:     "forced uncache",
+			run:      func(l LVM) error { return l.Uncache("vg", "main", true) },
+			commands: []common.FakeCommand{{Command: "lvconvert --uncache vg/main --force -y"}},
+		},
+		{
+			name: "extend logical volume that already has the size",
+			run:  func(l LVM) error { return l.ExtendLogicalVolume("vg", "main", "/dev/sdb") },
+			commands: []common.FakeCommand{{
+				Command: "lvextend -l 100%PVS vg/main /dev/sdb",
+				Err:     errors.New("exit status 5; output: New size (2559 extents) matches existing size (2559 extents)."),
+			}},
+		},
+		{
+			name: "extend logical volume without space",
+			run:  func(l LVM) error { return l.ExtendLogicalVolume("vg", "main", "/dev/sdb") },
+			commands: []common.FakeCommand{{
+				Command: "lvextend -l 100%PVS vg/main /dev/sdb",
+				Err:     errors.New("exit status 5; output: Insufficient free space"),
+			}},
+			expErr: true,
+		},
+		{
+			name:     "remove missing physical volumes",
+			run:      func(l LVM) error { return l.RemoveMissingPhysicalVolumes("vg", true) },
+			commands: []common.FakeCommand{{Command: "vgreduce --removemissing vg --force"}},
+		},
+		{
+			name: "create RAID array",
+			run: func(l LVM) error {
+				return l.CreateRaidArray("csi-driver-data-cache", "0", []string{"/dev/nvme0n1", "/dev/nvme1n1"})
+			},
+			commands: []common.FakeCommand{{Command: "mdadm --create csi-driver-data-cache -l0 --force -n 2 /dev/nvme0n1 /dev/nvme1n1"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := common.NewFakeCommandRunner(tc.commands...)
+			err := tc.run(NewLVM(runner))
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if pending := runner.Pending(); len(pending) != 0 {
+				t.Errorf("Expected commands were not run: %s", strings.Join(pending, "; "))
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvm
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const fakeGiB = 1024 * 1024 * 1024
+
+// FakeLVM keeps LVM and RAID state in memory. Devices added with AddDevice
+// can be used as physical volumes right away, as vgcreate and vgextend
+// initialize them on first use.
+type FakeLVM struct {
+	mutex sync.Mutex
+
+	pvs    map[string]*PhysicalVolume
+	vgs    map[string]*VolumeGroup
+	lvs    map[string]*LogicalVolume // keyed by vg/lv
+	arrays map[string]*RaidArray
+
+	// Errors fails the methods with the given names.
+	Errors map[string]error
+	// Calls holds the names of the methods that were called, in order.
+	Calls []string
+}
+
+var _ LVM = &FakeLVM{}
+
+func NewFakeLVM() *FakeLVM {
+	return &FakeLVM{
+		pvs:    map[string]*PhysicalVolume{},
+		vgs:    map[string]*VolumeGroup{},
+		lvs:    map[string]*LogicalVolume{},
+		arrays: map[string]*RaidArray{},
+		Errors: map[string]error{},
+	}
+}
+
+// AddDevice adds a block device that can be used as a physical volume, or
+// changes its size.
+func (f *FakeLVM) AddDevice(name string, sizeBytes int64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if pv, ok := f.pvs[name]; ok {
+		pv.SizeBytes = sizeBytes
+		return
+	}
+	f.pvs[name] = &PhysicalVolume{Name: name, SizeBytes: sizeBytes}
+}
+
+// RemoveDevice removes a device the way detaching a disk does, which leaves
+// the physical volume missing in its volume group.
+func (f *FakeLVM) RemoveDevice(name string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.pvs, name)
+}
+
+// SetCacheStatus sets the cache status reported for a cached logical volume.
+func (f *FakeLVM) SetCacheStatus(vgName, lvName string, status CacheStatus) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	lv, ok := f.lvs[vgName+"/"+lvName]
+	if !ok || lv.Cache == nil {
+		return fmt.Errorf("logical volume %s/%s has no cache", vgName, lvName)
+	}
+	*lv.Cache = status
+	return nil
+}
+
+// begin records a call and returns its injected error, if any. The caller
+// must hold the mutex.
+func (f *FakeLVM) begin(method string) error {
+	f.Calls = append(f.Calls, method)
+	return f.Errors[method]
+}
+
+func (f *FakeLVM) ListPhysicalVolumes() ([]PhysicalVolume, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ListPhysicalVolumes"); err != nil {
+		return nil, err
+	}
+	pvs := []PhysicalVolume{}
+	for _, pv := range f.pvs {
+		pvs = append(pvs, *pv)
+	}
+	sort.Slice(pvs, func(i, j int) bool { return pvs[i].Name < pvs[j].Name })
+	return pvs, nil
+}
+
+func (f *FakeLVM) ResizePhysicalVolume(pvName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ResizePhysicalVolume"); err != nil {
+		return err
+	}
+	if _, ok := f.pvs[pvName]; !ok {
+		return fmt.Errorf("physical volume %s not found", pvName)
+	}
+	return nil
+}
+
+func (f *FakeLVM) ListVolumeGroups() ([]VolumeGroup, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ListVolumeGroups"); err != nil {
+		return nil, err
+	}
+	vgs := []VolumeGroup{}
+	for _, vg := range f.vgs {
+		vgs = append(vgs, *vg)
+	}
+	sort.Slice(vgs, func(i, j int) bool { return vgs[i].Name < vgs[j].Name })
+	return vgs, nil
+}
+
+// addToVolumeGroup must be called with the mutex held.
+func (f *FakeLVM) addToVolumeGroup(vgName string, pvName string) error {
+	pv, ok := f.pvs[pvName]
+	if !ok {
+		return fmt.Errorf("device %s not found", pvName)
+	}
+	if pv.VGName != "" {
+		return fmt.Errorf("physical volume %s is already in volume group %s", pvName, pv.VGName)
+	}
+	pv.VGName = vgName
+	return nil
+}
+
+func (f *FakeLVM) CreateVolumeGroup(vgName string, pvNames ...string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateVolumeGroup"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[vgName]; ok {
+		return fmt.Errorf("volume group %s already exists", vgName)
+	}
+	for _, pvName := range pvNames {
+		if err := f.addToVolumeGroup(vgName, pvName); err != nil {
+			return err
+		}
+	}
+	f.vgs[vgName] = &VolumeGroup{Name: vgName}
+	return nil
+}
+
+func (f *FakeLVM) ExtendVolumeGroup(vgName string, pvName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ExtendVolumeGroup"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[vgName]; !ok {
+		return fmt.Errorf("volume group %s not found", vgName)
+	}
+	return f.addToVolumeGroup(vgName, pvName)
+}
+
+func (f *FakeLVM) MergeVolumeGroups(vgName string, mergedVgName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("MergeVolumeGroups"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[vgName]; !ok {
+		return fmt.Errorf("volume group %s not found", vgName)
+	}
+	if _, ok := f.vgs[mergedVgName]; !ok {
+		return fmt.Errorf("volume group %s not found", mergedVgName)
+	}
+	for _, lv := range f.lvs {
+		if lv.VGName == mergedVgName && lv.Active {
+			return fmt.Errorf("logical volumes in %s must be inactive", mergedVgName)
+		}
+	}
+	for _, pv := range f.pvs {
+		if pv.VGName == mergedVgName {
+			pv.VGName = vgName
+		}
+	}
+	for key, lv := range f.lvs {
+		if lv.VGName == mergedVgName {
+			delete(f.lvs, key)
+			lv.VGName = vgName
+			f.lvs[vgName+"/"+lv.Name] = lv
+		}
+	}
+	delete(f.vgs, mergedVgName)
+	return nil
+}
+
+func (f *FakeLVM) RemoveMissingPhysicalVolumes(vgName string, force bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("RemoveMissingPhysicalVolumes"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[vgName]; !ok {
+		return fmt.Errorf("volume group %s not found", vgName)
+	}
+	for key, lv := range f.lvs {
+		if lv.VGName != vgName {
+			continue
+		}
+		for _, device := range lv.Devices {
+			if _, ok := f.pvs[device]; ok {
+				continue
+			}
+			if !force {
+				return fmt.Errorf("logical volume %s is on missing physical volume %s", lv.Name, device)
+			}
+			delete(f.lvs, key)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *FakeLVM) SetVolumeGroupActive(vgName string, active bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("SetVolumeGroupActive"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[vgName]; !ok {
+		return fmt.Errorf("volume group %s not found", vgName)
+	}
+	for _, lv := range f.lvs {
+		if lv.VGName == vgName {
+			lv.Active = active
+		}
+	}
+	return nil
+}
+
+func (f *FakeLVM) UpdateVolumeGroupMetadata(vgName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("UpdateVolumeGroupMetadata"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[vgName]; !ok {
+		return fmt.Errorf("volume group %s not found", vgName)
+	}
+	return nil
+}
+
+func (f *FakeLVM) ListLogicalVolumes(vgName string) ([]LogicalVolume, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ListLogicalVolumes"); err != nil {
+		return nil, err
+	}
+	lvs := []LogicalVolume{}
+	for _, lv := range f.lvs {
+		if vgName != "" && lv.VGName != vgName {
+			continue
+		}
+		c := *lv
+		c.Tags = slices.Clone(lv.Tags)
+		c.Devices = slices.Clone(lv.Devices)
+		if lv.Cache != nil {
+			cache := *lv.Cache
+			c.Cache = &cache
+		}
+		lvs = append(lvs, c)
+	}
+	sort.Slice(lvs, func(i, j int) bool {
+		return lvs[i].VGName+"/"+lvs[i].Name < lvs[j].VGName+"/"+lvs[j].Name
+	})
+	return lvs, nil
+}
+
+// freeBytes returns the space of a physical volume that isn't allocated to
+// logical volumes. The mutex must be held.
+func (f *FakeLVM) freeBytes(pv *PhysicalVolume) int64 {
+	free := pv.SizeBytes
+	for _, lv := range f.lvs {
+		if slices.Contains(lv.Devices, pv.Name) {
+			free -= lv.SizeBytes / int64(len(lv.Devices))
+		}
+	}
+	return free
+}
+
+func (f *FakeLVM) CreateLogicalVolume(opts CreateLogicalVolumeOptions) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateLogicalVolume"); err != nil {
+		return err
+	}
+	if _, ok := f.vgs[opts.VGName]; !ok {
+		return fmt.Errorf("volume group %s not found", opts.VGName)
+	}
+	key := opts.VGName + "/" + opts.Name
+	if _, ok := f.lvs[key]; ok {
+		return fmt.Errorf("logical volume %s already exists", key)
+	}
+	pvNames := opts.PVs
+	if len(pvNames) == 0 {
+		for _, pv := range f.pvs {
+			if pv.VGName == opts.VGName {
+				pvNames = append(pvNames, pv.Name)
+			}
+		}
+		sort.Strings(pvNames)
+	}
+	var pvsBytes, freeBytes int64
+	for _, pvName := range pvNames {
+		pv, ok := f.pvs[pvName]
+		if !ok || pv.VGName != opts.VGName {
+			return fmt.Errorf("physical volume %s not found in volume group %s", pvName, opts.VGName)
+		}
+		pvsBytes += pv.SizeBytes
+		freeBytes += f.freeBytes(pv)
+	}
+	var size int64
+	switch {
+	case opts.Extents == "100%PVS" && opts.SizeGiB == 0:
+		size = pvsBytes
+	case opts.Extents == "" && opts.SizeGiB > 0:
+		size = opts.SizeGiB * fakeGiB
+	default:
+		return fmt.Errorf("unsupported size of logical volume %s", key)
+	}
+	if size > freeBytes {
+		return fmt.Errorf("Volume group %q has insufficient free space", opts.VGName)
+	}
+	f.lvs[key] = &LogicalVolume{
+		Name:      opts.Name,
+		VGName:    opts.VGName,
+		Tags:      slices.Clone(opts.Tags),
+		SizeBytes: size,
+		Active:    true,
+		SegType:   "linear",
+		Devices:   pvNames,
+	}
+	return nil
+}
+
+func (f *FakeLVM) ExtendLogicalVolume(vgName, lvName string, pvName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ExtendLogicalVolume"); err != nil {
+		return err
+	}
+	lv, ok := f.lvs[vgName+"/"+lvName]
+	if !ok {
+		return fmt.Errorf("logical volume %s/%s not found", vgName, lvName)
+	}
+	pv, ok := f.pvs[pvName]
+	if !ok || pv.VGName != vgName {
+		return fmt.Errorf("physical volume %s not found in volume group %s", pvName, vgName)
+	}
+	if pv.SizeBytes > lv.SizeBytes {
+		lv.SizeBytes = pv.SizeBytes
+	}
+	return nil
+}
+
+func (f *FakeLVM) RemoveLogicalVolume(vgName, lvName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("RemoveLogicalVolume"); err != nil {
+		return err
+	}
+	key := vgName + "/" + lvName
+	lv, ok := f.lvs[key]
+	if !ok {
+		return fmt.Errorf("logical volume %s not found", key)
+	}
+	if lv.SegType == SegTypeCache {
+		return fmt.Errorf("logical volume %s has a cache attached", key)
+	}
+	delete(f.lvs, key)
+	return nil
+}
+
+func (f *FakeLVM) SetLogicalVolumeActive(vgName, lvName string, active bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("SetLogicalVolumeActive"); err != nil {
+		return err
+	}
+	lv, ok := f.lvs[vgName+"/"+lvName]
+	if !ok {
+		return fmt.Errorf("logical volume %s/%s not found", vgName, lvName)
+	}
+	lv.Active = active
+	return nil
+}
+
+func (f *FakeLVM) AttachCache(vgName, lvName string, opts AttachCacheOptions) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("AttachCache"); err != nil {
+		return err
+	}
+	lv, ok := f.lvs[vgName+"/"+lvName]
+	if !ok {
+		return fmt.Errorf("logical volume %s/%s not found", vgName, lvName)
+	}
+	if lv.SegType == SegTypeCache {
+		return fmt.Errorf("logical volume %s/%s is already cached", vgName, lvName)
+	}
+	cacheLv, ok := f.lvs[vgName+"/"+opts.CacheLVName]
+	if !ok {
+		return fmt.Errorf("logical volume %s/%s not found", vgName, opts.CacheLVName)
+	}
+	if opts.Mode == "" || opts.ChunkSize == "" {
+		return fmt.Errorf("cache mode and chunk size must be set")
+	}
+	delete(f.lvs, vgName+"/"+opts.CacheLVName)
+	cacheLv.Name = opts.CacheLVName + CacheVolSuffix
+	f.lvs[vgName+"/"+cacheLv.Name] = cacheLv
+	lv.SegType = SegTypeCache
+	lv.PoolLV = cacheLv.Name
+	lv.Cache = &CacheStatus{Mode: opts.Mode, Policy: "smq"}
+	return nil
+}
+
+// detachCache must be called with the mutex held.
+func (f *FakeLVM) detachCache(vgName, lvName string) (*LogicalVolume, error) {
+	lv, ok := f.lvs[vgName+"/"+lvName]
+	if !ok {
+		return nil, fmt.Errorf("logical volume %s/%s not found", vgName, lvName)
+	}
+	if lv.SegType != SegTypeCache {
+		return nil, fmt.Errorf("logical volume %s/%s is not cached", vgName, lvName)
+	}
+	cacheLv := f.lvs[vgName+"/"+lv.PoolLV]
+	delete(f.lvs, vgName+"/"+lv.PoolLV)
+	lv.SegType = "linear"
+	lv.PoolLV = ""
+	lv.Cache = nil
+	return cacheLv, nil
+}
+
+func (f *FakeLVM) SplitCache(vgName, lvName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("SplitCache"); err != nil {
+		return err
+	}
+	cacheLv, err := f.detachCache(vgName, lvName)
+	if err != nil {
+		return err
+	}
+	cacheLv.Name = strings.TrimSuffix(cacheLv.Name, CacheVolSuffix)
+	f.lvs[vgName+"/"+cacheLv.Name] = cacheLv
+	return nil
+}
+
+func (f *FakeLVM) Uncache(vgName, lvName string, force bool) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("Uncache"); err != nil {
+		return err
+	}
+	_, err := f.detachCache(vgName, lvName)
+	return err
+}
+
+func (f *FakeLVM) ListRaidArrays() ([]RaidArray, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ListRaidArrays"); err != nil {
+		return nil, err
+	}
+	arrays := []RaidArray{}
+	for _, array := range f.arrays {
+		c := *array
+		c.Devices = slices.Clone(array.Devices)
+		arrays = append(arrays, c)
+	}
+	sort.Slice(arrays, func(i, j int) bool { return arrays[i].Name < arrays[j].Name })
+	return arrays, nil
+}
+
+// CreateRaidArray creates the array at /dev/md/<name>, which can then be
+// used as a physical volume with the total size of its devices.
+func (f *FakeLVM) CreateRaidArray(name string, level string, devices []string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateRaidArray"); err != nil {
+		return err
+	}
+	if _, ok := f.arrays[name]; ok {
+		return fmt.Errorf("RAID array %s already exists", name)
+	}
+	var size int64
+	for _, device := range devices {
+		pv, ok := f.pvs[device]
+		if !ok {
+			return fmt.Errorf("device %s not found", device)
+		}
+		size += pv.SizeBytes
+	}
+	path := "/dev/md/" + name
+	f.arrays[name] = &RaidArray{Name: name, Path: path, Devices: slices.Clone(devices)}
+	f.pvs[path] = &PhysicalVolume{Name: path, SizeBytes: size}
+	return nil
+}
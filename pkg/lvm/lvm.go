@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lvm manages the LVM volumes and the md RAID arrays that back Data
+// Cache.
+package lvm
+
+// SegTypeCache is the segment type of a logical volume with a cache attached.
+const SegTypeCache = "cache"
+
+// CacheVolSuffix is appended by LVM to the name of a logical volume when it's
+// attached as a cache with --cachevol, which also hides it.
+const CacheVolSuffix = "_cvol"
+
+type PhysicalVolume struct {
+	Name string
+	// VGName is empty if the physical volume isn't part of a volume group.
+	VGName    string
+	SizeBytes int64
+}
+
+type VolumeGroup struct {
+	Name string
+}
+
+type LogicalVolume struct {
+	Name      string
+	VGName    string
+	Tags      []string
+	SizeBytes int64
+	Active    bool
+	// SegType is SegTypeCache when a cache is attached, and PoolLV is the
+	// name of the cache then.
+	SegType string
+	PoolLV  string
+	// Devices are the physical volumes the logical volume is allocated on.
+	Devices []string
+	// Cache is set for logical volumes with an active cache.
+	Cache *CacheStatus
+}
+
+// CacheStatus is the status of the cache of a logical volume, as reported by
+// dm-cache.
+type CacheStatus struct {
+	Mode   string
+	Policy string
+
+	TotalBlocks uint64
+	UsedBlocks  uint64
+	DirtyBlocks uint64
+	ReadHits    uint64
+	ReadMisses  uint64
+	WriteHits   uint64
+	WriteMisses uint64
+}
+
+type RaidArray struct {
+	Name    string
+	Path    string
+	Devices []string
+}
+
+type CreateLogicalVolumeOptions struct {
+	Name   string
+	VGName string
+	// Exactly one of Extents, e.g. 100%PVS, and SizeGiB must be set.
+	Extents string
+	SizeGiB int64
+	Tags    []string
+	// PVs restricts the allocation of the logical volume to these physical
+	// volumes.
+	PVs []string
+}
+
+type AttachCacheOptions struct {
+	// CacheLVName is the logical volume to use as cache, in the same volume
+	// group.
+	CacheLVName string
+	Mode        string
+	ChunkSize   string
+}
+
+// LVM runs LVM and mdadm operations. Logical volumes are addressed by their
+// volume group and name.
+type LVM interface {
+	ListPhysicalVolumes() ([]PhysicalVolume, error)
+	// ResizePhysicalVolume grows a physical volume to the size of its device.
+	ResizePhysicalVolume(pvName string) error
+
+	ListVolumeGroups() ([]VolumeGroup, error)
+	CreateVolumeGroup(vgName string, pvNames ...string) error
+	ExtendVolumeGroup(vgName string, pvName string) error
+	// MergeVolumeGroups moves the physical and logical volumes of mergedVgName
+	// into vgName. mergedVgName must be inactive.
+	MergeVolumeGroups(vgName string, mergedVgName string) error
+	// RemoveMissingPhysicalVolumes drops the physical volumes whose device is
+	// gone from a volume group, and with force the logical volumes on them.
+	RemoveMissingPhysicalVolumes(vgName string, force bool) error
+	SetVolumeGroupActive(vgName string, active bool) error
+	UpdateVolumeGroupMetadata(vgName string) error
+
+	// ListLogicalVolumes lists the logical volumes of a volume group,
+	// including hidden ones, or of all volume groups if vgName is empty.
+	ListLogicalVolumes(vgName string) ([]LogicalVolume, error)
+	CreateLogicalVolume(opts CreateLogicalVolumeOptions) error
+	// ExtendLogicalVolume grows a logical volume to all of pvName. It is not
+	// an error if the logical volume already has that size.
+	ExtendLogicalVolume(vgName, lvName string, pvName string) error
+	RemoveLogicalVolume(vgName, lvName string) error
+	SetLogicalVolumeActive(vgName, lvName string, active bool) error
+
+	AttachCache(vgName, lvName string, opts AttachCacheOptions) error
+	// SplitCache flushes and detaches the cache of a logical volume, and
+	// keeps the cache as a separate logical volume.
+	SplitCache(vgName, lvName string) error
+	// Uncache detaches and removes the cache of a logical volume. With force,
+	// dirty blocks are dropped rather than flushed.
+	Uncache(vgName, lvName string, force bool) error
+
+	ListRaidArrays() ([]RaidArray, error)
+	CreateRaidArray(name string, level string, devices []string) error
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+)
+
+// DataCacheStats are the statistics of the LVM cache of a volume.
+type DataCacheStats struct {
+	VolumeID string
+	// Policy is the kernel cache policy, e.g. smq, and Mode is writeback or
+	// writethrough.
+	Policy string
+	Mode   string
+
+	ReadHits    uint64
+	ReadMisses  uint64
+	WriteHits   uint64
+	WriteMisses uint64
+
+	TotalBlocks uint64
+	UsedBlocks  uint64
+	DirtyBlocks uint64
+}
+
+func newDataCacheDesc(name, help string, labels ...string) *metrics.Desc {
+	return metrics.NewDesc(
+		metrics.BuildFQName("", "node", name),
+		help,
+		append([]string{"driver_name", "volume_id"}, labels...),
+		nil,
+		metrics.ALPHA,
+		"",
+	)
+}
+
+var (
+	dataCacheReadHitsDesc    = newDataCacheDesc("data_cache_read_hits", "Number of reads of a Data Cache volume served by the cache")
+	dataCacheReadMissesDesc  = newDataCacheDesc("data_cache_read_misses", "Number of reads of a Data Cache volume served by the persistent disk")
+	dataCacheWriteHitsDesc   = newDataCacheDesc("data_cache_write_hits", "Number of writes of a Data Cache volume to blocks in the cache")
+	dataCacheWriteMissesDesc = newDataCacheDesc("data_cache_write_misses", "Number of writes of a Data Cache volume to blocks not in the cache")
+	dataCacheTotalBlocksDesc = newDataCacheDesc("data_cache_total_blocks", "Number of blocks of the cache of a Data Cache volume")
+	dataCacheUsedBlocksDesc  = newDataCacheDesc("data_cache_used_blocks", "Number of blocks of the cache of a Data Cache volume in use")
+	dataCacheDirtyBlocksDesc = newDataCacheDesc("data_cache_dirty_blocks", "Number of blocks of the cache of a Data Cache volume not written back to the persistent disk yet")
+	dataCacheInfoDesc        = newDataCacheDesc("data_cache_info", "Policy and mode of the cache of a Data Cache volume", "policy", "mode")
+)
+
+// dataCacheCollector reports the statistics of the last RecordDataCacheStats
+// call when metrics are scraped.
+type dataCacheCollector struct {
+	metrics.BaseStableCollector
+
+	mutex sync.Mutex
+	stats []DataCacheStats
+}
+
+var dataCacheStatsCollector = &dataCacheCollector{}
+
+func (c *dataCacheCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- dataCacheReadHitsDesc
+	ch <- dataCacheReadMissesDesc
+	ch <- dataCacheWriteHitsDesc
+	ch <- dataCacheWriteMissesDesc
+	ch <- dataCacheTotalBlocksDesc
+	ch <- dataCacheUsedBlocksDesc
+	ch <- dataCacheDirtyBlocksDesc
+	ch <- dataCacheInfoDesc
+}
+
+func (c *dataCacheCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	c.mutex.Lock()
+	stats := c.stats
+	c.mutex.Unlock()
+
+	for _, s := range stats {
+		ch <- metrics.NewLazyConstMetric(dataCacheReadHitsDesc, metrics.CounterValue, float64(s.ReadHits), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheReadMissesDesc, metrics.CounterValue, float64(s.ReadMisses), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheWriteHitsDesc, metrics.CounterValue, float64(s.WriteHits), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheWriteMissesDesc, metrics.CounterValue, float64(s.WriteMisses), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheTotalBlocksDesc, metrics.GaugeValue, float64(s.TotalBlocks), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheUsedBlocksDesc, metrics.GaugeValue, float64(s.UsedBlocks), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheDirtyBlocksDesc, metrics.GaugeValue, float64(s.DirtyBlocks), pdcsiDriverName, s.VolumeID)
+		ch <- metrics.NewLazyConstMetric(dataCacheInfoDesc, metrics.GaugeValue, 1, pdcsiDriverName, s.VolumeID, s.Policy, s.Mode)
+	}
+}
+
+// RecordDataCacheStats replaces the reported statistics, so volumes that are
+// no longer cached stop being reported.
+func (mm *MetricsManager) RecordDataCacheStats(stats []DataCacheStats) {
+	dataCacheStatsCollector.mutex.Lock()
+	defer dataCacheStatsCollector.mutex.Unlock()
+	dataCacheStatsCollector.stats = stats
+}
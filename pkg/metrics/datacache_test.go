@@ -0,0 +1,82 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+)
+
+func TestRecordDataCacheStats(t *testing.T) {
+	mm := NewMetricsManager()
+	mm.RegisterDataCacheMetric()
+	defer mm.RecordDataCacheStats(nil)
+
+	mm.RecordDataCacheStats([]DataCacheStats{
+		{VolumeID: "vol-1", Policy: "smq", Mode: "writeback", ReadHits: 10, DirtyBlocks: 3},
+		{VolumeID: "vol-2", Policy: "smq", Mode: "writethrough", ReadHits: 20},
+	})
+	values := gatherDataCacheMetrics(t, mm)
+	for key, want := range map[string]float64{
+		"node_data_cache_read_hits/vol-1":      10,
+		"node_data_cache_read_hits/vol-2":      20,
+		"node_data_cache_dirty_blocks/vol-1":   3,
+		"node_data_cache_info/vol-1/writeback": 1,
+	} {
+		if got, ok := values[key]; !ok || got != want {
+			t.Errorf("Expected %s = %v, got %v (found: %v)", key, want, got, ok)
+		}
+	}
+
+	// Volumes missing from the next collection are no longer reported.
+	mm.RecordDataCacheStats([]DataCacheStats{{VolumeID: "vol-2", Policy: "smq", Mode: "writethrough"}})
+	values = gatherDataCacheMetrics(t, mm)
+	if _, ok := values["node_data_cache_read_hits/vol-1"]; ok {
+		t.Errorf("Expected vol-1 to no longer be reported")
+	}
+	if got := values["node_data_cache_read_hits/vol-2"]; got != 0 {
+		t.Errorf("Expected read hits of vol-2 to be 0, got %v", got)
+	}
+}
+
+// gatherDataCacheMetrics returns the Data Cache metrics keyed by name and
+// volume ID, and mode for node_data_cache_info.
+func gatherDataCacheMetrics(t *testing.T, mm MetricsManager) map[string]float64 {
+	families, err := mm.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			key := family.GetName() + "/" + labels["volume_id"]
+			if mode, ok := labels["mode"]; ok {
+				key += "/" + mode
+			}
+			switch {
+			case m.GetCounter() != nil:
+				values[key] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[key] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return values
+}
@@ -63,10 +63,48 @@ var (
 	},
 		[]string{"driver_name", "file_system_format", "error_type"},
 	)
+
+	devicePathChangeMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "node",
+		Name:           "device_path_changes",
+		Help:           "Number of times the /dev/disk/by-id symlink of a staged volume started pointing at a different device",
+		StabilityLevel: metrics.ALPHA,
+	},
+		[]string{"driver_name"},
+	)
+
+	dataCacheReconfigureMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "node",
+		Name:           "data_cache_reconfigurations",
+		Help:           "Number of attempts to change the size or mode of the Data Cache of a staged volume, by outcome",
+		StabilityLevel: metrics.ALPHA,
+	},
+		[]string{"driver_name", "outcome"},
+	)
+
+	deleteVolumeRefusalMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "csidriver",
+		Name:           "delete_volume_refusals",
+		Help:           "Number of DeleteVolume calls refused by deletion protection, by reason",
+		StabilityLevel: metrics.ALPHA,
+	},
+		[]string{"driver_name", "reason"},
+	)
+
+	startupReconcileActionMetric = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "node",
+		Name:           "startup_reconcile_actions",
+		Help:           "Number of stale mounts, device symlinks and Data Cache volumes found when the node plugin started, by action and outcome",
+		StabilityLevel: metrics.ALPHA,
+	},
+		[]string{"driver_name", "action", "outcome"},
+	)
 )
 
 type MetricsManager struct {
 	registry metrics.KubeRegistry
+	// mux is the handler of the metrics server once it's initialized.
+	mux *http.ServeMux
 }
 
 func NewMetricsManager() MetricsManager {
@@ -92,6 +130,23 @@ func (mm *MetricsManager) RegisterMountMetric() {
 	mm.registry.MustRegister(mountErrorMetric)
 }
 
+func (mm *MetricsManager) RegisterDevicePathChangeMetric() {
+	mm.registry.MustRegister(devicePathChangeMetric)
+}
+
+func (mm *MetricsManager) RegisterDataCacheMetric() {
+	mm.registry.MustRegister(dataCacheReconfigureMetric)
+	mm.registry.CustomMustRegister(dataCacheStatsCollector)
+}
+
+func (mm *MetricsManager) RegisterDeleteVolumeRefusalMetric() {
+	mm.registry.MustRegister(deleteVolumeRefusalMetric)
+}
+
+func (mm *MetricsManager) RegisterStartupReconcileMetric() {
+	mm.registry.MustRegister(startupReconcileActionMetric)
+}
+
 func (mm *MetricsManager) recordComponentVersionMetric() error {
 	v := getEnvVar(envGKEPDCSIVersion)
 	if v == "" {
@@ -121,6 +176,22 @@ func (mm *MetricsManager) RecordMountErrorMetric(fs_format string, err error) {
 	klog.Infof("Recorded mount error type: %q", errType)
 }
 
+func (mm *MetricsManager) RecordDevicePathChangeMetric() {
+	devicePathChangeMetric.WithLabelValues(pdcsiDriverName).Inc()
+}
+
+func (mm *MetricsManager) RecordDataCacheReconfigureMetric(outcome string) {
+	dataCacheReconfigureMetric.WithLabelValues(pdcsiDriverName, outcome).Inc()
+}
+
+func (mm *MetricsManager) RecordStartupReconcileActionMetric(action, outcome string) {
+	startupReconcileActionMetric.WithLabelValues(pdcsiDriverName, action, outcome).Inc()
+}
+
+func (mm *MetricsManager) RecordDeleteVolumeRefusalMetric(reason string) {
+	deleteVolumeRefusalMetric.WithLabelValues(pdcsiDriverName, reason).Inc()
+}
+
 func (mm *MetricsManager) EmmitProcessStartTime() error {
 	return metrics.RegisterProcessStartTime(mm.registry.Register)
 }
@@ -153,6 +224,7 @@ func (mm *MetricsManager) registerToServer(s Server, metricsPath string) {
 func (mm *MetricsManager) InitializeHttpHandler(address, path string) {
 	mux := http.NewServeMux()
 	mm.registerToServer(mux, path)
+	mm.mux = mux
 	go func() {
 		klog.Infof("Metric server listening at %q", address)
 		if err := http.ListenAndServe(address, mux); err != nil {
@@ -161,6 +233,16 @@ func (mm *MetricsManager) InitializeHttpHandler(address, path string) {
 	}()
 }
 
+// Handle serves handler on path of the metrics server, which must have been
+// initialized with InitializeHttpHandler.
+func (mm *MetricsManager) Handle(path string, handler http.Handler) {
+	if mm.mux == nil {
+		klog.Errorf("Metric server not initialized, not serving %q", path)
+		return
+	}
+	mm.mux.Handle(path, handler)
+}
+
 func getEnvVar(envVarName string) string {
 	v, ok := os.LookupEnv(envVarName)
 	if !ok {
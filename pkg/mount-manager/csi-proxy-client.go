@@ -0,0 +1,32 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mountmanager
+
+import (
+	diskapi "github.com/kubernetes-csi/csi-proxy/client/api/disk/v1"
+	fsapi "github.com/kubernetes-csi/csi-proxy/client/api/filesystem/v1"
+	volumeapi "github.com/kubernetes-csi/csi-proxy/client/api/volume/v1"
+)
+
+// ProxyClient holds the CSI Proxy v1 API groups used by the Windows mounter.
+// On Windows they are backed by the named pipe clients of a running CSI Proxy,
+// in tests by a FakeCSIProxy.
+type ProxyClient struct {
+	Disk       diskapi.DiskClient
+	Filesystem fsapi.FilesystemClient
+	Volume     volumeapi.VolumeClient
+	// Version describes the API versions of the clients for logging.
+	Version string
+}
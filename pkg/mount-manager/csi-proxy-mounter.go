@@ -1,5 +1,3 @@
-//go:build windows
-
 /*
 Copyright 2021 The Kubernetes Authors.
 
@@ -30,18 +28,77 @@ import (
 	"time"
 
 	diskapi "github.com/kubernetes-csi/csi-proxy/client/api/disk/v1"
-	diskclient "github.com/kubernetes-csi/csi-proxy/client/groups/disk/v1"
-
 	fsapi "github.com/kubernetes-csi/csi-proxy/client/api/filesystem/v1"
-	fsclient "github.com/kubernetes-csi/csi-proxy/client/groups/filesystem/v1"
-
 	volumeapi "github.com/kubernetes-csi/csi-proxy/client/api/volume/v1"
-	volumeclient "github.com/kubernetes-csi/csi-proxy/client/groups/volume/v1"
 
 	"k8s.io/klog/v2"
 	mount "k8s.io/mount-utils"
 )
 
+const (
+	// physicalDrivePrefix is the prefix of the Win32 device path of a disk,
+	// followed by its disk number.
+	physicalDrivePrefix = `\\.\PHYSICALDRIVE`
+
+	googleCloudDiskReadOnly = "READ_ONLY"
+)
+
+// CSIProxyMounter is the mounter interface exposed as a utility to
+// internal methods
+type CSIProxyMounter interface {
+	mount.Interface
+
+	// GetAPIVersions returns the versions of the client APIs this mounter is using.
+	GetAPIVersions() string
+
+	// Delete the given directory with Pod context. CSI proxy does a check for path prefix
+	// based on context
+	RemovePodDir(target string) error
+
+	// UnmountDevice uses target path to find the volume id first, and then
+	// call DismountVolume through csi-proxy. If succeeded, it will delete the given path
+	// at last step. CSI proxy does a check for path prefix
+	// based on context
+	UnmountDevice(target string) error
+
+	// GetDiskNumber finds the disk number of the given device name
+	GetDiskNumber(deviceName string, partition string, volumeKey string) (string, error)
+
+	// FormatAndMount accepts the source disk number, target path to mount, the fstype to format with and options to be used.
+	// After formatting, it will mount the disk to target path on the host
+	FormatAndMount(source string, target string, fstype string, options []string) error
+
+	// IsMountPointMatch checks if the mount point matches the directory `dir`
+	IsMountPointMatch(mp mount.MountPoint, dir string) bool
+
+	// ExistsPath checks if a path exists.
+	// Unlike util ExistsPath, this call does not perform follow link.
+	ExistsPath(path string) (bool, error)
+
+	// GetDiskTotalBytes gets the total size of a disk, given either its disk
+	// number or a path where it was published as a block device.
+	GetDiskTotalBytes(devicePath string) (int64, error)
+
+	// BlockDevicePath returns the device path of the disk with the given
+	// number. Mounting it links the target path to the raw disk.
+	BlockDevicePath(diskNumber string) (string, error)
+
+	// IsBlockDevice returns true if path is where a disk was published as a
+	// block device.
+	IsBlockDevice(path string) (bool, error)
+
+	// IsDiskReadOnly returns true if the disk with the given device name is
+	// attached to the instance in read-only mode.
+	IsDiskReadOnly(deviceName string) (bool, error)
+
+	// GetVolumeStats returns the capacity and used bytes of the volume
+	// mounted at target.
+	GetVolumeStats(target string) (capacity int64, used int64, err error)
+
+	// ResizeVolume grows the volume mounted at target to fill its disk.
+	ResizeVolume(target string) error
+}
+
 // GoogleCloudDisk represents a disk from Google Cloud metadata
 type GoogleCloudDisk struct {
 	DeviceName              string `json:"deviceName"`
@@ -52,48 +109,34 @@ type GoogleCloudDisk struct {
 	Type                    string `json:"type"`
 }
 
-// CSIProxyMounterV1 is the mounter implementation that uses the v1 API
-type CSIProxyMounterV1 struct {
-	FsClient     *fsclient.Client
-	DiskClient   *diskclient.Client
-	VolumeClient *volumeclient.Client
+// csiProxyMounter is the mounter implementation on top of the CSI Proxy v1 API.
+type csiProxyMounter struct {
+	client *ProxyClient
+	// getGoogleCloudDisks and readlink are replaced in tests.
+	getGoogleCloudDisks func() ([]GoogleCloudDisk, error)
+	readlink            func(path string) (string, error)
 }
 
-// check that CSIProxyMounterV1 implements CSIProxyMounter
-var _ CSIProxyMounter = &CSIProxyMounterV1{}
+// check that csiProxyMounter implements CSIProxyMounter
+var _ CSIProxyMounter = &csiProxyMounter{}
 
-func NewCSIProxyMounterV1() (*CSIProxyMounterV1, error) {
-	fsClient, err := fsclient.NewClient()
-	if err != nil {
-		return nil, err
-	}
-	diskClient, err := diskclient.NewClient()
-	if err != nil {
-		return nil, err
-	}
-	volumeClient, err := volumeclient.NewClient()
-	if err != nil {
-		return nil, err
+// NewCSIProxyMounter returns a CSIProxyMounter that calls CSI Proxy through
+// client.
+func NewCSIProxyMounter(client *ProxyClient) *csiProxyMounter {
+	return &csiProxyMounter{
+		client:              client,
+		getGoogleCloudDisks: getGoogleCloudDisks,
+		readlink:            os.Readlink,
 	}
-	return &CSIProxyMounterV1{
-		FsClient:     fsClient,
-		DiskClient:   diskClient,
-		VolumeClient: volumeClient,
-	}, nil
 }
 
 // GetAPIVersions returns the versions of the client APIs this mounter is using.
-func (mounter *CSIProxyMounterV1) GetAPIVersions() string {
-	return fmt.Sprintf(
-		"API Versions Disk: %s, Filesystem: %s, Volume: %s",
-		diskclient.Version,
-		fsclient.Version,
-		volumeclient.Version,
-	)
+func (mounter *csiProxyMounter) GetAPIVersions() string {
+	return mounter.client.Version
 }
 
 // Mount just creates a soft link at target pointing to source.
-func (mounter *CSIProxyMounterV1) Mount(source string, target string, fstype string, options []string) error {
+func (mounter *csiProxyMounter) Mount(source string, target string, fstype string, options []string) error {
 	return mounter.MountSensitive(source, target, fstype, options, nil /* sensitiveOptions */)
 }
 
@@ -102,7 +145,7 @@ func (mounter *CSIProxyMounterV1) Mount(source string, target string, fstype str
 // mount options and ensures the sensitiveOptions are never logged.
 // Since Mount here is just create a synlink, so options and sensitiveOptions
 // are not used here
-func (mounter *CSIProxyMounterV1) MountSensitive(source string, target string, fstype string, options []string, sensitiveOptions []string) error {
+func (mounter *csiProxyMounter) MountSensitive(source string, target string, fstype string, options []string, sensitiveOptions []string) error {
 	// Mount is called after the format is done.
 	// TODO: Confirm that fstype is empty.
 	// Call the LinkPath CSI proxy from the source path to the target path
@@ -110,11 +153,24 @@ func (mounter *CSIProxyMounterV1) MountSensitive(source string, target string, f
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
 		return err
 	}
+	if diskNumber, ok := parseBlockDevicePath(source); ok {
+		// A raw disk has no volume that could bring it online when mounted, as
+		// FormatAndMount does for filesystem volumes.
+		setDiskStateRequest := &diskapi.SetDiskStateRequest{
+			DiskNumber: diskNumber,
+			IsOnline:   true,
+		}
+		if _, err := mounter.client.Disk.SetDiskState(context.Background(), setDiskStateRequest); err != nil {
+			return err
+		}
+	} else {
+		source = normalizeWindowsPath(source)
+	}
 	createSymlinkRequest := &fsapi.CreateSymlinkRequest{
-		SourcePath: mount.NormalizeWindowsPath(source),
-		TargetPath: mount.NormalizeWindowsPath(target),
+		SourcePath: source,
+		TargetPath: normalizeWindowsPath(target),
 	}
-	_, err := mounter.FsClient.CreateSymlink(context.Background(), createSymlinkRequest)
+	_, err := mounter.client.Filesystem.CreateSymlink(context.Background(), createSymlinkRequest)
 	if err != nil {
 		return err
 	}
@@ -123,12 +179,12 @@ func (mounter *CSIProxyMounterV1) MountSensitive(source string, target string, f
 
 // Delete the given directory with Pod context. CSI proxy does a check for path prefix
 // based on context
-func (mounter *CSIProxyMounterV1) RemovePodDir(target string) error {
+func (mounter *csiProxyMounter) RemovePodDir(target string) error {
 	rmdirRequest := &fsapi.RmdirRequest{
-		Path:  mount.NormalizeWindowsPath(target),
+		Path:  normalizeWindowsPath(target),
 		Force: true,
 	}
-	_, err := mounter.FsClient.Rmdir(context.Background(), rmdirRequest)
+	_, err := mounter.client.Filesystem.Rmdir(context.Background(), rmdirRequest)
 	if err != nil {
 		return err
 	}
@@ -139,25 +195,21 @@ func (mounter *CSIProxyMounterV1) RemovePodDir(target string) error {
 // call DismountVolume through csi-proxy. If succeeded, it will delete the given path
 // at last step. CSI proxy does a check for path prefix
 // based on context
-func (mounter *CSIProxyMounterV1) UnmountDevice(target string) error {
-	target = mount.NormalizeWindowsPath(target)
+func (mounter *csiProxyMounter) UnmountDevice(target string) error {
+	target = normalizeWindowsPath(target)
 	if exists, err := mounter.ExistsPath(target); !exists {
 		return err
 	}
-	idRequest := &volumeapi.GetVolumeIDFromTargetPathRequest{
-		TargetPath: target,
-	}
-	idResponse, err := mounter.VolumeClient.GetVolumeIDFromTargetPath(context.Background(), idRequest)
+	volumeId, err := mounter.getVolumeID(target)
 	if err != nil {
 		return err
 	}
-	volumeId := idResponse.GetVolumeId()
 
 	unmountRequest := &volumeapi.UnmountVolumeRequest{
 		TargetPath: target,
 		VolumeId:   volumeId,
 	}
-	_, err = mounter.VolumeClient.UnmountVolume(context.Background(), unmountRequest)
+	_, err = mounter.client.Volume.UnmountVolume(context.Background(), unmountRequest)
 	if err != nil {
 		return err
 	}
@@ -165,7 +217,7 @@ func (mounter *CSIProxyMounterV1) UnmountDevice(target string) error {
 		Path:  target,
 		Force: true,
 	}
-	_, err = mounter.FsClient.Rmdir(context.Background(), rmdirRequest)
+	_, err = mounter.client.Filesystem.Rmdir(context.Background(), rmdirRequest)
 	if err != nil {
 		return err
 	}
@@ -174,7 +226,7 @@ func (mounter *CSIProxyMounterV1) UnmountDevice(target string) error {
 	getDiskNumberRequest := &volumeapi.GetDiskNumberFromVolumeIDRequest{
 		VolumeId: volumeId,
 	}
-	getDiskNumberResponse, err := mounter.VolumeClient.GetDiskNumberFromVolumeID(context.Background(), getDiskNumberRequest)
+	getDiskNumberResponse, err := mounter.client.Volume.GetDiskNumberFromVolumeID(context.Background(), getDiskNumberRequest)
 	if err != nil {
 		return err
 	}
@@ -184,18 +236,18 @@ func (mounter *CSIProxyMounterV1) UnmountDevice(target string) error {
 		DiskNumber: diskNumber,
 		IsOnline:   false,
 	}
-	if _, err = mounter.DiskClient.SetDiskState(context.Background(), setDiskStateRequest); err != nil {
+	if _, err = mounter.client.Disk.SetDiskState(context.Background(), setDiskStateRequest); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (mounter *CSIProxyMounterV1) Unmount(target string) error {
+func (mounter *csiProxyMounter) Unmount(target string) error {
 	return mounter.RemovePodDir(target)
 }
 
-func (mounter *CSIProxyMounterV1) GetDiskNumber(deviceName string, partition string, volumeKey string) (string, error) {
+func (mounter *csiProxyMounter) GetDiskNumber(deviceName string, partition string, volumeKey string) (string, error) {
 	// First, get Google Cloud metadata to find the nvmeNamespaceIdentifier for this device
 	googleDisks, err := mounter.getGoogleCloudDisks()
 	if err != nil {
@@ -230,7 +282,7 @@ func (mounter *CSIProxyMounterV1) GetDiskNumber(deviceName string, partition str
 
 	// Get Windows disk information
 	listRequest := &diskapi.ListDiskIDsRequest{}
-	diskIDsResponse, err := mounter.DiskClient.ListDiskIDs(context.Background(), listRequest)
+	diskIDsResponse, err := mounter.client.Disk.ListDiskIDs(context.Background(), listRequest)
 	if err != nil {
 		return "", err
 	}
@@ -241,13 +293,13 @@ func (mounter *CSIProxyMounterV1) GetDiskNumber(deviceName string, partition str
 		klog.V(4).Infof("found disk number %d, disk info %v", diskNum, diskInfo)
 
 		// Check if this disk has an EUI identifier
-		euiValue := mounter.extractEUIFromDiskInfo(diskInfo)
+		euiValue := extractEUIFromDiskInfo(diskInfo)
 		if euiValue == "" {
 			continue
 		}
 
 		// Convert EUI hex to decimal
-		decimalValue, err := mounter.convertEUIToDecimal(euiValue)
+		decimalValue, err := convertEUIToDecimal(euiValue)
 		if err != nil {
 			klog.V(4).Infof("Failed to convert EUI %s to decimal: %v", euiValue, err)
 			continue
@@ -268,7 +320,7 @@ func (mounter *CSIProxyMounterV1) GetDiskNumber(deviceName string, partition str
 }
 
 // Helper function to extract EUI from disk info (v1 API format)
-func (mounter *CSIProxyMounterV1) extractEUIFromDiskInfo(diskInfo *diskapi.DiskIDs) string {
+func extractEUIFromDiskInfo(diskInfo *diskapi.DiskIDs) string {
 	klog.V(4).Infof("extractEUIFromDiskInfo called for disk with Page83=%s, SerialNumber=%s", diskInfo.Page83, diskInfo.SerialNumber)
 
 	// Check if Page83 contains an EUI format
@@ -281,7 +333,7 @@ func (mounter *CSIProxyMounterV1) extractEUIFromDiskInfo(diskInfo *diskapi.DiskI
 	if diskInfo.SerialNumber != "" {
 		klog.V(4).Infof("Attempting to convert serial number %s to EUI", diskInfo.SerialNumber)
 		// Convert serial number format like "10CC_9636_B6E3_CE3B_0000_0000_0000_0000." to EUI format
-		eui := mounter.convertSerialToEUI(diskInfo.SerialNumber)
+		eui := convertSerialToEUI(diskInfo.SerialNumber)
 		if eui != "" {
 			klog.V(4).Infof("Successfully converted serial number %s to EUI %s", diskInfo.SerialNumber, eui)
 			return eui
@@ -297,7 +349,7 @@ func (mounter *CSIProxyMounterV1) extractEUIFromDiskInfo(diskInfo *diskapi.DiskI
 }
 
 // Helper function to convert serial number to EUI format
-func (mounter *CSIProxyMounterV1) convertSerialToEUI(serialNumber string) string {
+func convertSerialToEUI(serialNumber string) string {
 	klog.V(4).Infof("convertSerialToEUI: input=%s", serialNumber)
 
 	// Remove trailing period and underscores from serial number
@@ -325,7 +377,7 @@ func (mounter *CSIProxyMounterV1) convertSerialToEUI(serialNumber string) string
 }
 
 // Helper function to convert EUI hex to decimal
-func (mounter *CSIProxyMounterV1) convertEUIToDecimal(euiValue string) (uint64, error) {
+func convertEUIToDecimal(euiValue string) (uint64, error) {
 	// Extract hex part from EUI (first 16 characters after "eui.")
 	if !strings.HasPrefix(euiValue, "eui.") {
 		return 0, fmt.Errorf("invalid EUI format: %s", euiValue)
@@ -349,7 +401,7 @@ func (mounter *CSIProxyMounterV1) convertEUIToDecimal(euiValue string) (uint64,
 }
 
 // Helper function to get Google Cloud metadata
-func (mounter *CSIProxyMounterV1) getGoogleCloudDisks() ([]GoogleCloudDisk, error) {
+func getGoogleCloudDisks() ([]GoogleCloudDisk, error) {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
@@ -386,9 +438,9 @@ func (mounter *CSIProxyMounterV1) getGoogleCloudDisks() ([]GoogleCloudDisk, erro
 }
 
 // Legacy method for backward compatibility
-func (mounter *CSIProxyMounterV1) getDiskNumberLegacy(deviceName string) (string, error) {
+func (mounter *csiProxyMounter) getDiskNumberLegacy(deviceName string) (string, error) {
 	listRequest := &diskapi.ListDiskIDsRequest{}
-	diskIDsResponse, err := mounter.DiskClient.ListDiskIDs(context.Background(), listRequest)
+	diskIDsResponse, err := mounter.client.Disk.ListDiskIDs(context.Background(), listRequest)
 	if err != nil {
 		return "", err
 	}
@@ -412,19 +464,18 @@ func (mounter *CSIProxyMounterV1) getDiskNumberLegacy(deviceName string) (string
 
 // FormatAndMount accepts the source disk number, target path to mount, the fstype to format with and options to be used.
 // After formatting, it will mount the disk to target path on the host
-func (mounter *CSIProxyMounterV1) FormatAndMount(source string, target string, fstype string, options []string) error {
-	diskNumberUint64, err := strconv.ParseUint(source, 10, 64)
+func (mounter *csiProxyMounter) FormatAndMount(source string, target string, fstype string, options []string) error {
+	diskNumber, err := parseDiskNumber(source)
 	if err != nil {
 		return err
 	}
-	diskNumber := uint32(diskNumberUint64)
 
 	// Call PartitionDisk CSI proxy call to partition the disk and return the volume id
 	partionDiskRequest := &diskapi.PartitionDiskRequest{
 		DiskNumber: diskNumber,
 	}
 
-	_, err = mounter.DiskClient.PartitionDisk(context.Background(), partionDiskRequest)
+	_, err = mounter.client.Disk.PartitionDisk(context.Background(), partionDiskRequest)
 	if err != nil {
 		return err
 	}
@@ -434,7 +485,7 @@ func (mounter *CSIProxyMounterV1) FormatAndMount(source string, target string, f
 		DiskNumber: diskNumber,
 		IsOnline:   true,
 	}
-	_, err = mounter.DiskClient.SetDiskState(context.Background(), setDiskStateRequest)
+	_, err = mounter.client.Disk.SetDiskState(context.Background(), setDiskStateRequest)
 	if err != nil {
 		return err
 	}
@@ -442,7 +493,7 @@ func (mounter *CSIProxyMounterV1) FormatAndMount(source string, target string, f
 	volumeIDsRequest := &volumeapi.ListVolumesOnDiskRequest{
 		DiskNumber: diskNumber,
 	}
-	volumeIdResponse, err := mounter.VolumeClient.ListVolumesOnDisk(context.Background(), volumeIDsRequest)
+	volumeIdResponse, err := mounter.client.Volume.ListVolumesOnDisk(context.Background(), volumeIDsRequest)
 	if err != nil {
 		return err
 	}
@@ -454,7 +505,7 @@ func (mounter *CSIProxyMounterV1) FormatAndMount(source string, target string, f
 	isVolumeFormattedRequest := &volumeapi.IsVolumeFormattedRequest{
 		VolumeId: volumeID,
 	}
-	isVolumeFormattedResponse, err := mounter.VolumeClient.IsVolumeFormatted(context.Background(), isVolumeFormattedRequest)
+	isVolumeFormattedResponse, err := mounter.client.Volume.IsVolumeFormatted(context.Background(), isVolumeFormattedRequest)
 	if err != nil {
 		return err
 	}
@@ -463,7 +514,7 @@ func (mounter *CSIProxyMounterV1) FormatAndMount(source string, target string, f
 			VolumeId: volumeID,
 			// TODO (jingxu97): Accept the filesystem and other options
 		}
-		_, err = mounter.VolumeClient.FormatVolume(context.Background(), formatVolumeRequest)
+		_, err = mounter.client.Volume.FormatVolume(context.Background(), formatVolumeRequest)
 		if err != nil {
 			return err
 		}
@@ -473,23 +524,23 @@ func (mounter *CSIProxyMounterV1) FormatAndMount(source string, target string, f
 		VolumeId:   volumeID,
 		TargetPath: target,
 	}
-	_, err = mounter.VolumeClient.MountVolume(context.Background(), mountVolumeRequest)
+	_, err = mounter.client.Volume.MountVolume(context.Background(), mountVolumeRequest)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (mounter *CSIProxyMounterV1) GetMountRefs(pathname string) ([]string, error) {
+func (mounter *csiProxyMounter) GetMountRefs(pathname string) ([]string, error) {
 	return []string{}, fmt.Errorf("GetMountRefs not implemented for ProxyMounter")
 }
 
-func (mounter *CSIProxyMounterV1) IsLikelyNotMountPoint(file string) (bool, error) {
+func (mounter *csiProxyMounter) IsLikelyNotMountPoint(file string) (bool, error) {
 	isSymlinkRequest := &fsapi.IsSymlinkRequest{
 		Path: file,
 	}
 
-	isSymlinkResponse, err := mounter.FsClient.IsSymlink(context.Background(), isSymlinkRequest)
+	isSymlinkResponse, err := mounter.client.Filesystem.IsSymlink(context.Background(), isSymlinkRequest)
 	if err != nil {
 		return true, err
 	}
@@ -497,19 +548,19 @@ func (mounter *CSIProxyMounterV1) IsLikelyNotMountPoint(file string) (bool, erro
 	return !isSymlinkResponse.IsSymlink, nil
 }
 
-func (mounter *CSIProxyMounterV1) List() ([]mount.MountPoint, error) {
+func (mounter *csiProxyMounter) List() ([]mount.MountPoint, error) {
 	return []mount.MountPoint{}, nil
 }
 
-func (mounter *CSIProxyMounterV1) IsMountPointMatch(mp mount.MountPoint, dir string) bool {
+func (mounter *csiProxyMounter) IsMountPointMatch(mp mount.MountPoint, dir string) bool {
 	return mp.Path == dir
 }
 
 // ExistsPath - Checks if a path exists. Unlike util ExistsPath, this call does not perform follow link.
-func (mounter *CSIProxyMounterV1) ExistsPath(path string) (bool, error) {
-	isExistsResponse, err := mounter.FsClient.PathExists(context.Background(),
+func (mounter *csiProxyMounter) ExistsPath(path string) (bool, error) {
+	isExistsResponse, err := mounter.client.Filesystem.PathExists(context.Background(),
 		&fsapi.PathExistsRequest{
-			Path: mount.NormalizeWindowsPath(path),
+			Path: normalizeWindowsPath(path),
 		})
 	if err != nil {
 		return false, err
@@ -517,39 +568,146 @@ func (mounter *CSIProxyMounterV1) ExistsPath(path string) (bool, error) {
 	return isExistsResponse.Exists, err
 }
 
-func (mounter *CSIProxyMounterV1) GetDiskTotalBytes(devicePath string) (int64, error) {
-	diskNumberUint64, err := strconv.ParseUint(devicePath, 10, 64)
+func (mounter *csiProxyMounter) GetDiskTotalBytes(devicePath string) (int64, error) {
+	diskNumber, err := parseDiskNumber(devicePath)
 	if err != nil {
-		return 0, err
+		// devicePath may also be where the disk is published as a block device.
+		var ok bool
+		if diskNumber, ok = mounter.getBlockDeviceDiskNumber(devicePath); !ok {
+			return 0, err
+		}
 	}
-	diskNumber := uint32(diskNumberUint64)
 
-	DiskStatsResponse, err := mounter.DiskClient.GetDiskStats(context.Background(),
+	diskStatsResponse, err := mounter.client.Disk.GetDiskStats(context.Background(),
 		&diskapi.GetDiskStatsRequest{
 			DiskNumber: diskNumber,
 		})
-	return DiskStatsResponse.TotalBytes, err
+	if err != nil {
+		return 0, err
+	}
+	return diskStatsResponse.TotalBytes, nil
+}
+
+func (mounter *csiProxyMounter) BlockDevicePath(diskNumber string) (string, error) {
+	n, err := parseDiskNumber(diskNumber)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d", physicalDrivePrefix, n), nil
+}
+
+func (mounter *csiProxyMounter) IsBlockDevice(path string) (bool, error) {
+	_, ok := mounter.getBlockDeviceDiskNumber(path)
+	return ok, nil
+}
+
+func (mounter *csiProxyMounter) IsDiskReadOnly(deviceName string) (bool, error) {
+	googleDisks, err := mounter.getGoogleCloudDisks()
+	if err != nil {
+		return false, err
+	}
+	for _, disk := range googleDisks {
+		if disk.DeviceName == deviceName {
+			return disk.Mode == googleCloudDiskReadOnly, nil
+		}
+	}
+	return false, fmt.Errorf("device %s not found in Google Cloud metadata", deviceName)
+}
+
+func (mounter *csiProxyMounter) GetVolumeStats(target string) (int64, int64, error) {
+	volumeID, err := mounter.getVolumeID(target)
+	if err != nil {
+		return 0, 0, err
+	}
+	response, err := mounter.client.Volume.GetVolumeStats(context.Background(), &volumeapi.GetVolumeStatsRequest{
+		VolumeId: volumeID,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return response.GetTotalBytes(), response.GetUsedBytes(), nil
+}
+
+func (mounter *csiProxyMounter) ResizeVolume(target string) error {
+	volumeID, err := mounter.getVolumeID(target)
+	if err != nil {
+		return err
+	}
+	// A zero size grows the volume to the maximum supported by its disk.
+	_, err = mounter.client.Volume.ResizeVolume(context.Background(), &volumeapi.ResizeVolumeRequest{
+		VolumeId: volumeID,
+	})
+	return err
+}
+
+func (mounter *csiProxyMounter) getVolumeID(target string) (string, error) {
+	idResponse, err := mounter.client.Volume.GetVolumeIDFromTargetPath(context.Background(), &volumeapi.GetVolumeIDFromTargetPathRequest{
+		TargetPath: normalizeWindowsPath(target),
+	})
+	if err != nil {
+		return "", err
+	}
+	return idResponse.GetVolumeId(), nil
+}
+
+// getBlockDeviceDiskNumber returns the number of the disk linked at path by
+// Mount, if any.
+func (mounter *csiProxyMounter) getBlockDeviceDiskNumber(path string) (uint32, bool) {
+	source, err := mounter.readlink(path)
+	if err != nil {
+		return 0, false
+	}
+	return parseBlockDevicePath(source)
+}
+
+func parseBlockDevicePath(devicePath string) (uint32, bool) {
+	if len(devicePath) <= len(physicalDrivePrefix) || !strings.EqualFold(devicePath[:len(physicalDrivePrefix)], physicalDrivePrefix) {
+		return 0, false
+	}
+	diskNumber, err := parseDiskNumber(devicePath[len(physicalDrivePrefix):])
+	if err != nil {
+		return 0, false
+	}
+	return diskNumber, true
+}
+
+// normalizeWindowsPath is mount.NormalizeWindowsPath, which is only built on
+// Windows.
+func normalizeWindowsPath(path string) string {
+	normalizedPath := strings.ReplaceAll(path, "/", "\\")
+	if strings.HasPrefix(normalizedPath, "\\") {
+		normalizedPath = "c:" + normalizedPath
+	}
+	return normalizedPath
+}
+
+func parseDiskNumber(diskNumber string) (uint32, error) {
+	n, err := strconv.ParseUint(diskNumber, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(n), nil
 }
 
 // MountSensitiveWithoutSystemd is the same as MountSensitive() but this method disable using systemd mount.
 // It's unimplemented in PD CSI Driver
-func (mounter *CSIProxyMounterV1) MountSensitiveWithoutSystemd(source string, target string, fstype string, options []string, sensitiveOptions []string) error {
+func (mounter *csiProxyMounter) MountSensitiveWithoutSystemd(source string, target string, fstype string, options []string, sensitiveOptions []string) error {
 	return errors.New("MountSensitiveWithoutSystemd is not implemented")
 }
 
 // MountSensitiveWithoutSystemdWithMountFlags is the same as MountSensitiveWithoutSystemd with additional mount flags.
 // It's unimplemented in PD CSI Driver
-func (mounter *CSIProxyMounterV1) MountSensitiveWithoutSystemdWithMountFlags(source string, target string, fstype string, options []string, sensitiveOptions []string, mountFlags []string) error {
+func (mounter *csiProxyMounter) MountSensitiveWithoutSystemdWithMountFlags(source string, target string, fstype string, options []string, sensitiveOptions []string, mountFlags []string) error {
 	return errors.New("MountSensitiveWithoutSystemd is not implemented")
 }
 
 // CanSafelySkipMountPointCheck always returns false on Windows.
-func (mounter *CSIProxyMounterV1) CanSafelySkipMountPointCheck() bool {
+func (mounter *csiProxyMounter) CanSafelySkipMountPointCheck() bool {
 	return false
 }
 
 // IsMountPoint returns true if a directory is a mountpoint.
-func (mounter *CSIProxyMounterV1) IsMountPoint(file string) (bool, error) {
+func (mounter *csiProxyMounter) IsMountPoint(file string) (bool, error) {
 	isNotMnt, err := mounter.IsLikelyNotMountPoint(file)
 	if err != nil {
 		return false, err
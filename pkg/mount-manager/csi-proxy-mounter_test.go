@@ -0,0 +1,206 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mountmanager
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const testDiskBytes = 10 * 1024 * 1024 * 1024
+
+func newTestCSIProxy() *FakeCSIProxy {
+	p := NewFakeCSIProxy()
+	p.Disks[0] = &FakeDisk{Page83: "Google persistent-disk-0", TotalBytes: testDiskBytes, Online: true}
+	p.Disks[1] = &FakeDisk{
+		Page83:     "Google pvc-scsi",
+		TotalBytes: testDiskBytes,
+		Metadata:   &GoogleCloudDisk{DeviceName: "pvc-scsi", Interface: "SCSI", Mode: "READ_WRITE"},
+	}
+	p.Disks[2] = &FakeDisk{
+		SerialNumber: "10CC_9636_B6E3_CE3B_0000_0000_0000_0000.",
+		TotalBytes:   testDiskBytes,
+		Metadata:     &GoogleCloudDisk{DeviceName: "pvc-nvme", Interface: "NVME", Mode: "READ_ONLY", NvmeNamespaceIdentifier: 0x10CC9636B6E3CE3B},
+	}
+	return p
+}
+
+func TestCSIProxyGetDiskNumber(t *testing.T) {
+	mounter := NewFakeCSIProxyMounter(newTestCSIProxy())
+	for deviceName, want := range map[string]string{
+		"persistent-disk-0": "0",
+		"pvc-scsi":          "1",
+		"pvc-nvme":          "2",
+	} {
+		got, err := mounter.GetDiskNumber(deviceName, "", deviceName)
+		if err != nil {
+			t.Errorf("GetDiskNumber(%s) failed: %v", deviceName, err)
+		} else if got != want {
+			t.Errorf("GetDiskNumber(%s) = %s, want %s", deviceName, got, want)
+		}
+	}
+	if got, err := mounter.GetDiskNumber("pvc-missing", "", "pvc-missing"); err == nil {
+		t.Errorf("Expected error for a missing disk, got %s", got)
+	}
+}
+
+func TestCSIProxyFormatAndMount(t *testing.T) {
+	p := newTestCSIProxy()
+	mounter := NewFakeCSIProxyMounter(p)
+	stagingPath := filepath.Join(t.TempDir(), "staging")
+	targetPath := filepath.Join(t.TempDir(), "mount")
+
+	if err := mounter.FormatAndMount("1", stagingPath, "NTFS", nil); err != nil {
+		t.Fatalf("FormatAndMount failed: %v", err)
+	}
+	if disk := p.Disks[1]; !disk.Online || !disk.Formatted {
+		t.Fatalf("Expected disk to be online and formatted, got %+v", disk)
+	}
+	if notMnt, err := mounter.IsLikelyNotMountPoint(stagingPath); err != nil || notMnt {
+		t.Fatalf("Expected %s to be a mount point, got notMnt: %v, err: %v", stagingPath, notMnt, err)
+	}
+	if err := mounter.Mount(stagingPath, targetPath, "", []string{"bind"}); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	if isBlock, _ := mounter.IsBlockDevice(targetPath); isBlock {
+		t.Errorf("Expected filesystem volume not to be a block device")
+	}
+
+	p.Disks[1].UsedBytes = 1024
+	capacity, used, err := mounter.GetVolumeStats(targetPath)
+	if err == nil {
+		t.Errorf("Expected GetVolumeStats on a symlink to fail, got %d/%d", used, capacity)
+	}
+	capacity, used, err = mounter.GetVolumeStats(stagingPath)
+	if err != nil {
+		t.Fatalf("GetVolumeStats failed: %v", err)
+	}
+	if capacity != testDiskBytes || used != 1024 {
+		t.Errorf("Expected %d bytes with 1024 used, got %d with %d used", testDiskBytes, capacity, used)
+	}
+
+	p.Disks[1].TotalBytes = 2 * testDiskBytes
+	if err := mounter.ResizeVolume(stagingPath); err != nil {
+		t.Fatalf("ResizeVolume failed: %v", err)
+	}
+	if capacity, _, _ := mounter.GetVolumeStats(stagingPath); capacity != 2*testDiskBytes {
+		t.Errorf("Expected volume of %d bytes after resize, got %d", 2*testDiskBytes, capacity)
+	}
+
+	if err := mounter.Unmount(targetPath); err != nil {
+		t.Fatalf("Unmount failed: %v", err)
+	}
+	if exists, _ := mounter.ExistsPath(targetPath); exists {
+		t.Errorf("Expected %s to be removed", targetPath)
+	}
+	if err := mounter.UnmountDevice(stagingPath); err != nil {
+		t.Fatalf("UnmountDevice failed: %v", err)
+	}
+	if exists, _ := mounter.ExistsPath(stagingPath); exists {
+		t.Errorf("Expected %s to be removed", stagingPath)
+	}
+	if p.Disks[1].Online {
+		t.Errorf("Expected disk to be offline after UnmountDevice")
+	}
+	// Unmounting again is a no-op.
+	if err := mounter.UnmountDevice(stagingPath); err != nil {
+		t.Errorf("Second UnmountDevice failed: %v", err)
+	}
+}
+
+func TestCSIProxyBlockDevice(t *testing.T) {
+	p := newTestCSIProxy()
+	mounter := NewFakeCSIProxyMounter(p)
+	targetPath := filepath.Join(t.TempDir(), "block")
+
+	if _, err := mounter.BlockDevicePath("disk1"); err == nil {
+		t.Errorf("Expected error for an invalid disk number")
+	}
+	devicePath, err := mounter.BlockDevicePath("1")
+	if err != nil {
+		t.Fatalf("BlockDevicePath failed: %v", err)
+	}
+	if want := `\\.\PHYSICALDRIVE1`; devicePath != want {
+		t.Errorf("Expected device path %s, got %s", want, devicePath)
+	}
+	if err := mounter.Mount(devicePath, targetPath, "", []string{"bind"}); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	if source := p.Symlinks[normalizeWindowsPath(targetPath)]; source != devicePath {
+		t.Errorf("Expected %s to link to %s, got %q", targetPath, devicePath, source)
+	}
+	if !p.Disks[1].Online {
+		t.Errorf("Expected raw disk to be brought online")
+	}
+	if isBlock, err := mounter.IsBlockDevice(targetPath); err != nil || !isBlock {
+		t.Errorf("Expected %s to be a block device, got %v, err: %v", targetPath, isBlock, err)
+	}
+	for _, path := range []string{"1", targetPath} {
+		size, err := mounter.GetDiskTotalBytes(path)
+		if err != nil {
+			t.Errorf("GetDiskTotalBytes(%s) failed: %v", path, err)
+		} else if size != testDiskBytes {
+			t.Errorf("GetDiskTotalBytes(%s) = %d, want %d", path, size, testDiskBytes)
+		}
+	}
+	if _, err := mounter.GetDiskTotalBytes(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("Expected GetDiskTotalBytes to fail on a path that is not a block device")
+	}
+}
+
+func TestCSIProxyIsDiskReadOnly(t *testing.T) {
+	mounter := NewFakeCSIProxyMounter(newTestCSIProxy())
+	testCases := []struct {
+		deviceName string
+		expRO      bool
+		expErr     bool
+	}{
+		{deviceName: "pvc-scsi"},
+		{deviceName: "pvc-nvme", expRO: true},
+		{deviceName: "pvc-missing", expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.deviceName, func(t *testing.T) {
+			ro, err := mounter.IsDiskReadOnly(tc.deviceName)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expErr, err)
+			}
+			if ro != tc.expRO {
+				t.Errorf("Expected read-only: %v, got: %v", tc.expRO, ro)
+			}
+		})
+	}
+}
+
+func TestParseBlockDevicePath(t *testing.T) {
+	testCases := []struct {
+		path   string
+		number uint32
+		ok     bool
+	}{
+		{path: `\\.\PHYSICALDRIVE3`, number: 3, ok: true},
+		{path: `\\.\PhysicalDrive12`, number: 12, ok: true},
+		{path: `\\.\PHYSICALDRIVE`},
+		{path: `\\.\PHYSICALDRIVEx`},
+		{path: `c:\var\lib\kubelet\plugins\staging`},
+		{path: "3"},
+	}
+	for _, tc := range testCases {
+		number, ok := parseBlockDevicePath(tc.path)
+		if ok != tc.ok || number != tc.number {
+			t.Errorf("parseBlockDevicePath(%s) = %d, %v, want %d, %v", tc.path, number, ok, tc.number, tc.ok)
+		}
+	}
+}
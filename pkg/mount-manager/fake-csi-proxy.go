@@ -0,0 +1,363 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mountmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	diskapi "github.com/kubernetes-csi/csi-proxy/client/api/disk/v1"
+	fsapi "github.com/kubernetes-csi/csi-proxy/client/api/filesystem/v1"
+	volumeapi "github.com/kubernetes-csi/csi-proxy/client/api/volume/v1"
+	"google.golang.org/grpc"
+)
+
+// FakeDisk is a disk attached to the node of a FakeCSIProxy.
+type FakeDisk struct {
+	// Page83 and SerialNumber are reported by ListDiskIDs.
+	Page83       string
+	SerialNumber string
+	TotalBytes   int64
+	Online       bool
+	// Metadata is reported by the instance metadata of the node, if set.
+	Metadata *GoogleCloudDisk
+
+	// The single volume created by PartitionDisk.
+	Partitioned bool
+	Formatted   bool
+	VolumeBytes int64
+	UsedBytes   int64
+}
+
+// FakeCSIProxy keeps the state of the disks, volumes and paths of a Windows
+// node in memory, so that the mounter can be tested without CSI Proxy.
+type FakeCSIProxy struct {
+	mutex sync.Mutex
+	Disks map[uint32]*FakeDisk
+	// Paths holds the paths that exist on the node.
+	Paths map[string]bool
+	// Symlinks maps symlinks to their source.
+	Symlinks map[string]string
+	// Mounts maps the target paths of mounted volumes to their volume ID.
+	Mounts map[string]string
+}
+
+func NewFakeCSIProxy() *FakeCSIProxy {
+	return &FakeCSIProxy{
+		Disks:    map[uint32]*FakeDisk{},
+		Paths:    map[string]bool{},
+		Symlinks: map[string]string{},
+		Mounts:   map[string]string{},
+	}
+}
+
+// Client returns a ProxyClient whose API groups are served by the fake.
+func (p *FakeCSIProxy) Client() *ProxyClient {
+	return &ProxyClient{
+		Disk:       &fakeDiskClient{p},
+		Filesystem: &fakeFilesystemClient{p},
+		Volume:     &fakeVolumeClient{p},
+		Version:    "API Versions Disk: fake, Filesystem: fake, Volume: fake",
+	}
+}
+
+// NewFakeCSIProxyMounter returns a CSIProxyMounter backed by p, which also
+// stands in for the instance metadata and the filesystem of the node.
+func NewFakeCSIProxyMounter(p *FakeCSIProxy) *csiProxyMounter {
+	mounter := NewCSIProxyMounter(p.Client())
+	mounter.getGoogleCloudDisks = p.googleCloudDisks
+	mounter.readlink = p.readlink
+	return mounter
+}
+
+func (p *FakeCSIProxy) googleCloudDisks() ([]GoogleCloudDisk, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	disks := []GoogleCloudDisk{}
+	for _, disk := range p.Disks {
+		if disk.Metadata != nil {
+			disks = append(disks, *disk.Metadata)
+		}
+	}
+	return disks, nil
+}
+
+func (p *FakeCSIProxy) readlink(path string) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	source, ok := p.Symlinks[normalizeWindowsPath(path)]
+	if !ok {
+		return "", fmt.Errorf("%s is not a symlink", path)
+	}
+	return source, nil
+}
+
+func fakeVolumeID(diskNumber uint32) string {
+	return fmt.Sprintf(`\\?\Volume{%08d-0000-0000-0000-000000000000}\`, diskNumber)
+}
+
+func (p *FakeCSIProxy) getDisk(diskNumber uint32) (*FakeDisk, error) {
+	disk, ok := p.Disks[diskNumber]
+	if !ok {
+		return nil, fmt.Errorf("disk %d not found", diskNumber)
+	}
+	return disk, nil
+}
+
+func (p *FakeCSIProxy) getVolume(volumeID string) (*FakeDisk, error) {
+	for diskNumber, disk := range p.Disks {
+		if disk.Partitioned && fakeVolumeID(diskNumber) == volumeID {
+			return disk, nil
+		}
+	}
+	return nil, fmt.Errorf("volume %s not found", volumeID)
+}
+
+type fakeDiskClient struct {
+	p *FakeCSIProxy
+}
+
+var _ diskapi.DiskClient = &fakeDiskClient{}
+
+func (c *fakeDiskClient) ListDiskLocations(ctx context.Context, in *diskapi.ListDiskLocationsRequest, opts ...grpc.CallOption) (*diskapi.ListDiskLocationsResponse, error) {
+	return nil, fmt.Errorf("ListDiskLocations is not implemented")
+}
+
+func (c *fakeDiskClient) PartitionDisk(ctx context.Context, in *diskapi.PartitionDiskRequest, opts ...grpc.CallOption) (*diskapi.PartitionDiskResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getDisk(in.DiskNumber)
+	if err != nil {
+		return nil, err
+	}
+	disk.Partitioned = true
+	return &diskapi.PartitionDiskResponse{}, nil
+}
+
+func (c *fakeDiskClient) Rescan(ctx context.Context, in *diskapi.RescanRequest, opts ...grpc.CallOption) (*diskapi.RescanResponse, error) {
+	return &diskapi.RescanResponse{}, nil
+}
+
+func (c *fakeDiskClient) ListDiskIDs(ctx context.Context, in *diskapi.ListDiskIDsRequest, opts ...grpc.CallOption) (*diskapi.ListDiskIDsResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	ids := map[uint32]*diskapi.DiskIDs{}
+	for diskNumber, disk := range c.p.Disks {
+		ids[diskNumber] = &diskapi.DiskIDs{Page83: disk.Page83, SerialNumber: disk.SerialNumber}
+	}
+	return &diskapi.ListDiskIDsResponse{DiskIDs: ids}, nil
+}
+
+func (c *fakeDiskClient) GetDiskStats(ctx context.Context, in *diskapi.GetDiskStatsRequest, opts ...grpc.CallOption) (*diskapi.GetDiskStatsResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getDisk(in.DiskNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &diskapi.GetDiskStatsResponse{TotalBytes: disk.TotalBytes}, nil
+}
+
+func (c *fakeDiskClient) SetDiskState(ctx context.Context, in *diskapi.SetDiskStateRequest, opts ...grpc.CallOption) (*diskapi.SetDiskStateResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getDisk(in.DiskNumber)
+	if err != nil {
+		return nil, err
+	}
+	disk.Online = in.IsOnline
+	return &diskapi.SetDiskStateResponse{}, nil
+}
+
+func (c *fakeDiskClient) GetDiskState(ctx context.Context, in *diskapi.GetDiskStateRequest, opts ...grpc.CallOption) (*diskapi.GetDiskStateResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getDisk(in.DiskNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &diskapi.GetDiskStateResponse{IsOnline: disk.Online}, nil
+}
+
+type fakeFilesystemClient struct {
+	p *FakeCSIProxy
+}
+
+var _ fsapi.FilesystemClient = &fakeFilesystemClient{}
+
+func (c *fakeFilesystemClient) PathExists(ctx context.Context, in *fsapi.PathExistsRequest, opts ...grpc.CallOption) (*fsapi.PathExistsResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	return &fsapi.PathExistsResponse{Exists: c.p.Paths[normalizeWindowsPath(in.Path)]}, nil
+}
+
+func (c *fakeFilesystemClient) Mkdir(ctx context.Context, in *fsapi.MkdirRequest, opts ...grpc.CallOption) (*fsapi.MkdirResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	c.p.Paths[normalizeWindowsPath(in.Path)] = true
+	return &fsapi.MkdirResponse{}, nil
+}
+
+func (c *fakeFilesystemClient) Rmdir(ctx context.Context, in *fsapi.RmdirRequest, opts ...grpc.CallOption) (*fsapi.RmdirResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	path := normalizeWindowsPath(in.Path)
+	delete(c.p.Paths, path)
+	delete(c.p.Symlinks, path)
+	delete(c.p.Mounts, path)
+	return &fsapi.RmdirResponse{}, nil
+}
+
+func (c *fakeFilesystemClient) CreateSymlink(ctx context.Context, in *fsapi.CreateSymlinkRequest, opts ...grpc.CallOption) (*fsapi.CreateSymlinkResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	target := normalizeWindowsPath(in.TargetPath)
+	if c.p.Paths[target] {
+		return nil, fmt.Errorf("symlink target %s already exists", in.TargetPath)
+	}
+	c.p.Paths[target] = true
+	c.p.Symlinks[target] = in.SourcePath
+	return &fsapi.CreateSymlinkResponse{}, nil
+}
+
+func (c *fakeFilesystemClient) IsSymlink(ctx context.Context, in *fsapi.IsSymlinkRequest, opts ...grpc.CallOption) (*fsapi.IsSymlinkResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	path := normalizeWindowsPath(in.Path)
+	// Volume mount points are reparse points, just like symlinks.
+	_, isSymlink := c.p.Symlinks[path]
+	_, isMount := c.p.Mounts[path]
+	return &fsapi.IsSymlinkResponse{IsSymlink: isSymlink || isMount}, nil
+}
+
+type fakeVolumeClient struct {
+	p *FakeCSIProxy
+}
+
+var _ volumeapi.VolumeClient = &fakeVolumeClient{}
+
+func (c *fakeVolumeClient) ListVolumesOnDisk(ctx context.Context, in *volumeapi.ListVolumesOnDiskRequest, opts ...grpc.CallOption) (*volumeapi.ListVolumesOnDiskResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getDisk(in.DiskNumber)
+	if err != nil {
+		return nil, err
+	}
+	resp := &volumeapi.ListVolumesOnDiskResponse{}
+	if disk.Partitioned {
+		resp.VolumeIds = []string{fakeVolumeID(in.DiskNumber)}
+	}
+	return resp, nil
+}
+
+func (c *fakeVolumeClient) MountVolume(ctx context.Context, in *volumeapi.MountVolumeRequest, opts ...grpc.CallOption) (*volumeapi.MountVolumeResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	if _, err := c.p.getVolume(in.VolumeId); err != nil {
+		return nil, err
+	}
+	target := normalizeWindowsPath(in.TargetPath)
+	c.p.Paths[target] = true
+	c.p.Mounts[target] = in.VolumeId
+	return &volumeapi.MountVolumeResponse{}, nil
+}
+
+func (c *fakeVolumeClient) UnmountVolume(ctx context.Context, in *volumeapi.UnmountVolumeRequest, opts ...grpc.CallOption) (*volumeapi.UnmountVolumeResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	target := normalizeWindowsPath(in.TargetPath)
+	if c.p.Mounts[target] != in.VolumeId {
+		return nil, fmt.Errorf("volume %s is not mounted at %s", in.VolumeId, in.TargetPath)
+	}
+	delete(c.p.Mounts, target)
+	return &volumeapi.UnmountVolumeResponse{}, nil
+}
+
+func (c *fakeVolumeClient) IsVolumeFormatted(ctx context.Context, in *volumeapi.IsVolumeFormattedRequest, opts ...grpc.CallOption) (*volumeapi.IsVolumeFormattedResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getVolume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	return &volumeapi.IsVolumeFormattedResponse{Formatted: disk.Formatted}, nil
+}
+
+func (c *fakeVolumeClient) FormatVolume(ctx context.Context, in *volumeapi.FormatVolumeRequest, opts ...grpc.CallOption) (*volumeapi.FormatVolumeResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getVolume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	disk.Formatted = true
+	disk.VolumeBytes = disk.TotalBytes
+	disk.UsedBytes = 0
+	return &volumeapi.FormatVolumeResponse{}, nil
+}
+
+func (c *fakeVolumeClient) ResizeVolume(ctx context.Context, in *volumeapi.ResizeVolumeRequest, opts ...grpc.CallOption) (*volumeapi.ResizeVolumeResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getVolume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	size := in.SizeBytes
+	if size == 0 {
+		size = disk.TotalBytes
+	}
+	if size > disk.TotalBytes {
+		return nil, fmt.Errorf("volume %s cannot grow to %d bytes on a disk of %d bytes", in.VolumeId, size, disk.TotalBytes)
+	}
+	disk.VolumeBytes = size
+	return &volumeapi.ResizeVolumeResponse{}, nil
+}
+
+func (c *fakeVolumeClient) GetVolumeStats(ctx context.Context, in *volumeapi.GetVolumeStatsRequest, opts ...grpc.CallOption) (*volumeapi.GetVolumeStatsResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	disk, err := c.p.getVolume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	return &volumeapi.GetVolumeStatsResponse{TotalBytes: disk.VolumeBytes, UsedBytes: disk.UsedBytes}, nil
+}
+
+func (c *fakeVolumeClient) GetDiskNumberFromVolumeID(ctx context.Context, in *volumeapi.GetDiskNumberFromVolumeIDRequest, opts ...grpc.CallOption) (*volumeapi.GetDiskNumberFromVolumeIDResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	for diskNumber, disk := range c.p.Disks {
+		if disk.Partitioned && fakeVolumeID(diskNumber) == in.VolumeId {
+			return &volumeapi.GetDiskNumberFromVolumeIDResponse{DiskNumber: diskNumber}, nil
+		}
+	}
+	return nil, fmt.Errorf("volume %s not found", in.VolumeId)
+}
+
+func (c *fakeVolumeClient) GetVolumeIDFromTargetPath(ctx context.Context, in *volumeapi.GetVolumeIDFromTargetPathRequest, opts ...grpc.CallOption) (*volumeapi.GetVolumeIDFromTargetPathResponse, error) {
+	c.p.mutex.Lock()
+	defer c.p.mutex.Unlock()
+	volumeID, ok := c.p.Mounts[normalizeWindowsPath(in.TargetPath)]
+	if !ok {
+		return nil, fmt.Errorf("no volume is mounted at %s", in.TargetPath)
+	}
+	return &volumeapi.GetVolumeIDFromTargetPathResponse{VolumeId: volumeID}, nil
+}
+
+func (c *fakeVolumeClient) WriteVolumeCache(ctx context.Context, in *volumeapi.WriteVolumeCacheRequest, opts ...grpc.CallOption) (*volumeapi.WriteVolumeCacheResponse, error) {
+	return &volumeapi.WriteVolumeCacheResponse{}, nil
+}
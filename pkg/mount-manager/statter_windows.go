@@ -17,11 +17,8 @@ limitations under the License.
 package mountmanager
 
 import (
-	"context"
 	"fmt"
 
-	volumeapiv1 "github.com/kubernetes-csi/csi-proxy/client/api/volume/v1"
-	volumeapiv1beta1 "github.com/kubernetes-csi/csi-proxy/client/api/volume/v1beta1"
 	"k8s.io/mount-utils"
 )
 
@@ -37,72 +34,36 @@ func NewStatter(mounter *mount.SafeFormatAndMount) *realStatter {
 
 // IsBlock checks if the given path is a block device
 func (r *realStatter) IsBlockDevice(fullPath string) (bool, error) {
-	return false, nil
+	proxy, err := r.proxy()
+	if err != nil {
+		return false, err
+	}
+	return proxy.IsBlockDevice(fullPath)
 }
 
 // StatFS returns volume usage information
 func (r *realStatter) StatFS(path string) (available, capacity, used, inodesFree, inodes, inodesUsed int64, err error) {
-	switch r.mounter.Interface.(type) {
-	case *CSIProxyMounterV1:
-		return r.StatFSV1(path)
-	case *CSIProxyMounterV1Beta:
-		return r.StatFSV1Beta(path)
-	}
-
-	return 0, 0, 0, 0, 0, 0, fmt.Errorf("Invalid interface type=%v", r.mounter.Interface)
-}
-
-func (r *realStatter) StatFSV1(path string) (available, capacity, used, inodesFree, inodes, inodesUsed int64, err error) {
 	zero := int64(0)
 
-	proxy := r.mounter.Interface.(*CSIProxyMounterV1)
-	idRequest := &volumeapiv1.GetVolumeIDFromTargetPathRequest{
-		TargetPath: path,
-	}
-	idResponse, err := proxy.VolumeClient.GetVolumeIDFromTargetPath(context.Background(), idRequest)
+	proxy, err := r.proxy()
 	if err != nil {
 		return zero, zero, zero, zero, zero, zero, err
 	}
-	volumeID := idResponse.GetVolumeId()
-
-	request := &volumeapiv1.GetVolumeStatsRequest{
-		VolumeId: volumeID,
-	}
-	response, err := proxy.VolumeClient.GetVolumeStats(context.Background(), request)
+	capacity, used, err = proxy.GetVolumeStats(path)
 	if err != nil {
 		return zero, zero, zero, zero, zero, zero, err
 	}
-	capacity = response.GetTotalBytes()
-	used = response.GetUsedBytes()
+	// NTFS has no fixed number of inodes to report.
 	available = capacity - used
 	return available, capacity, used, zero, zero, zero, nil
 }
 
-func (r *realStatter) StatFSV1Beta(path string) (available, capacity, used, inodesFree, inodes, inodesUsed int64, err error) {
-	zero := int64(0)
-
-	proxy := r.mounter.Interface.(*CSIProxyMounterV1Beta)
-
-	idRequest := &volumeapiv1beta1.VolumeIDFromMountRequest{
-		Mount: path,
-	}
-	idResponse, err := proxy.VolumeClient.GetVolumeIDFromMount(context.Background(), idRequest)
-	if err != nil {
-		return zero, zero, zero, zero, zero, zero, err
-	}
-	volumeID := idResponse.GetVolumeId()
-
-	request := &volumeapiv1beta1.VolumeStatsRequest{
-		VolumeId: volumeID,
+func (r *realStatter) proxy() (CSIProxyMounter, error) {
+	proxy, ok := r.mounter.Interface.(CSIProxyMounter)
+	if !ok {
+		return nil, fmt.Errorf("Invalid interface type=%v", r.mounter.Interface)
 	}
-	response, err := proxy.VolumeClient.VolumeStats(context.Background(), request)
-	if err != nil {
-		return zero, zero, zero, zero, zero, zero, err
-	}
-	capacity = response.GetVolumeSize()
-	used = response.GetVolumeUsedSize()
-	available = capacity - used
-	return available, capacity, used, zero, zero, zero, nil
+	return proxy, nil
 }
 
 type fakeStatter struct{}
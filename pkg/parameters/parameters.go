@@ -18,8 +18,10 @@ package parameters
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
@@ -42,6 +44,13 @@ const (
 	ParameterKeyEnableConfidentialCompute     = "enable-confidential-storage"
 	ParameterKeyStoragePools                  = "storage-pools"
 	ParameterKeyUseAllowedDiskTopology        = "use-allowed-disk-topology"
+	ParameterKeyProject                       = "project"
+
+	// Parameters for node-side encryption
+	ParameterKeyNodeEncryption       = "node-encryption"
+	ParameterKeyNodeEncryptionKmsKey = "node-encryption-kms-key"
+	NodeEncryptionLuks               = "luks"
+	nodeEncryptionNone               = "none"
 
 	// Parameters for Data Cache
 	ParameterKeyDataCacheSize               = "data-cache-size"
@@ -49,6 +58,14 @@ const (
 	ParameterKeyResourceTags                = "resource-tags"
 	ParameterKeyEnableMultiZoneProvisioning = "enable-multi-zone-provisioning"
 
+	// Parameters for asynchronous replication
+	ParameterKeyAsyncReplicationSecondaryZones = "async-replication-secondary-zones"
+
+	// Parameters for the final snapshot taken before deleting the disk
+	ParameterKeySnapshotBeforeDelete                = "snapshot-before-delete"
+	ParameterKeySnapshotBeforeDeleteStorageLocation = "snapshot-before-delete-storage-location"
+	ParameterKeySnapshotBeforeDeleteRetentionDays   = "snapshot-before-delete-retention-days"
+
 	// Parameters for VolumeSnapshotClass
 	ParameterKeyStorageLocations = "storage-locations"
 	ParameterKeySnapshotType     = "snapshot-type"
@@ -65,6 +82,7 @@ const (
 	tagKeyCreatedForClaimName      = "kubernetes.io/created-for/pvc/name"
 	tagKeyCreatedForVolumeName     = "kubernetes.io/created-for/pv/name"
 	tagKeyCreatedBy                = "storage.gke.io/created-by"
+	tagKeyCreatedForClusterName    = "storage.gke.io/created-for/cluster/name"
 
 	// Keys for Snapshot and SnapshotContent parameters as reported by external-snapshotter
 	ParameterKeyVolumeSnapshotName        = "csi.storage.k8s.io/volumesnapshot/name"
@@ -150,6 +168,33 @@ type DiskParameters struct {
 	// Values {}
 	// Default: false
 	UseAllowedDiskTopology bool
+	// Values: "", luks
+	// Default: ""
+	NodeEncryption string
+	// Values: {string}
+	// Default: ""
+	NodeEncryptionKMSKey string
+	// Values: {[]string}, 1 zone for zonal and 2 for regional disks, in a
+	// region other than the one of the disk
+	// Default: ""
+	AsyncReplicationSecondaryZones []string
+	// Values: {string}, the volume ID of the primary disk. Set by the driver
+	// when it creates the secondary disk of asynchronous replication, not a
+	// StorageClass parameter.
+	// Default: ""
+	AsyncPrimaryDisk string
+	// Values: {string}, one of the allowed projects
+	// Default: "", the default project of the driver
+	Project string
+	// Values: {bool}
+	// Default: false
+	SnapshotBeforeDelete bool
+	// Values: {string}, a region or multi-region
+	// Default: "", the default storage location of snapshots
+	SnapshotBeforeDeleteStorageLocation string
+	// Values: {int}, days
+	// Default: 0, the final snapshot is kept until deleted manually
+	SnapshotBeforeDeleteRetentionDays int
 }
 
 func (dp *DiskParameters) IsRegional() bool {
@@ -172,11 +217,26 @@ type ParameterProcessor struct {
 	EnableMultiZone    bool
 	EnableHdHA         bool
 	EnableDiskTopology bool
+	// AllowedProjects are the projects the project parameter may be set to.
+	AllowedProjects []string
+	// ClusterName is recorded in the description tags of the disks, if set,
+	// and available to the templates of the labels parameter.
+	ClusterName string
 }
 
 type ModifyVolumeParameters struct {
 	IOPS       *int64
 	Throughput *int64
+	// Labels are merged into the labels of the disk.
+	Labels map[string]string
+	// ResourceTags are bound to the disk, replacing the values of the same
+	// tag keys.
+	ResourceTags map[string]string
+	// AccessMode is the GCE access mode of the disk, empty if unchanged.
+	AccessMode string
+	// DiskType is the disk type the disk is migrated to, empty if
+	// unchanged.
+	DiskType string
 }
 
 // ExtractAndDefaultParameters will take the relevant parameters from a map and
@@ -208,9 +268,11 @@ func (pp *ParameterProcessor) ExtractAndDefaultParameters(parameters map[string]
 		p.ResourceTags[k] = v
 	}
 
+	var labelsParam string
 	for k, v := range parameters {
 		if k == "csiProvisionerSecretName" || k == "csiProvisionerSecretNamespace" {
-			// These are hardcoded secrets keys required to function but not needed by GCE PD
+			// These are hardcoded secrets keys of the external-provisioner. The
+			// secret they reference is passed in the CreateVolume secrets.
 			continue
 		}
 		switch strings.ToLower(k) {
@@ -235,14 +297,8 @@ func (pp *ParameterProcessor) ExtractAndDefaultParameters(parameters map[string]
 		case ParameterKeyPVName:
 			p.Tags[tagKeyCreatedForVolumeName] = v
 		case ParameterKeyLabels:
-			paramLabels, err := convert.ConvertLabelsStringToMap(v)
-			if err != nil {
-				return p, d, fmt.Errorf("parameters contain invalid labels parameter: %w", err)
-			}
-			// Override any existing labels with those from this parameter.
-			for labelKey, labelValue := range paramLabels {
-				p.Labels[labelKey] = labelValue
-			}
+			// Templates are executed once the PVC and PV parameters are read.
+			labelsParam = v
 		case ParameterKeyProvisionedIOPSOnCreate:
 			paramProvisionedIOPSOnCreate, err := convert.ConvertStringToInt64(v)
 			if err != nil {
@@ -344,17 +400,110 @@ func (pp *ParameterProcessor) ExtractAndDefaultParameters(parameters map[string]
 			}
 
 			p.UseAllowedDiskTopology = paramUseAllowedDiskTopology
+		case ParameterKeyNodeEncryption:
+			switch strings.ToLower(v) {
+			case "", nodeEncryptionNone:
+				p.NodeEncryption = ""
+			case NodeEncryptionLuks:
+				p.NodeEncryption = NodeEncryptionLuks
+			default:
+				return p, d, fmt.Errorf("parameters contain invalid value %q for %s parameter", v, ParameterKeyNodeEncryption)
+			}
+		case ParameterKeyNodeEncryptionKmsKey:
+			if !isValidDiskEncryptionKmsKey(v) {
+				return p, d, fmt.Errorf("parameters contain invalid value %q for %s parameter", v, ParameterKeyNodeEncryptionKmsKey)
+			}
+			p.NodeEncryptionKMSKey = v
+		case ParameterKeyAsyncReplicationSecondaryZones:
+			zones, err := ParseAsyncReplicationSecondaryZones(v)
+			if err != nil {
+				return p, d, fmt.Errorf("parameters contain invalid value %q for %s parameter: %w", v, ParameterKeyAsyncReplicationSecondaryZones, err)
+			}
+			p.AsyncReplicationSecondaryZones = zones
+		case ParameterKeyProject:
+			if v != "" && !slices.Contains(pp.AllowedProjects, v) {
+				return p, d, fmt.Errorf("parameters contain project %q which is not in the allowed projects %v", v, pp.AllowedProjects)
+			}
+			p.Project = v
+		case ParameterKeySnapshotBeforeDelete:
+			paramSnapshotBeforeDelete, err := convert.ConvertStringToBool(v)
+			if err != nil {
+				return p, d, fmt.Errorf("parameters contain invalid value for %s parameter: %w", ParameterKeySnapshotBeforeDelete, err)
+			}
+			p.SnapshotBeforeDelete = paramSnapshotBeforeDelete
+		case ParameterKeySnapshotBeforeDeleteStorageLocation:
+			locations, err := ProcessStorageLocations(v)
+			if err != nil {
+				return p, d, fmt.Errorf("parameters contain invalid value for %s parameter: %w", ParameterKeySnapshotBeforeDeleteStorageLocation, err)
+			}
+			p.SnapshotBeforeDeleteStorageLocation = locations[0]
+		case ParameterKeySnapshotBeforeDeleteRetentionDays:
+			days, err := strconv.Atoi(v)
+			if err != nil || days <= 0 {
+				return p, d, fmt.Errorf("parameters contain invalid value %q for %s parameter, must be a positive number of days", v, ParameterKeySnapshotBeforeDeleteRetentionDays)
+			}
+			p.SnapshotBeforeDeleteRetentionDays = days
 		default:
 			return p, d, fmt.Errorf("parameters contains invalid option %q", k)
 		}
 	}
+	if labelsParam != "" {
+		templateData := volumeLabelTemplateData{
+			PVCName:      p.Tags[tagKeyCreatedForClaimName],
+			PVCNamespace: p.Tags[tagKeyCreatedForClaimNamespace],
+			PVName:       p.Tags[tagKeyCreatedForVolumeName],
+			ClusterName:  pp.ClusterName,
+		}
+		if err := extractLabelsParameter(labelsParam, templateData, p.Labels); err != nil {
+			return p, d, err
+		}
+	}
+	if p.SnapshotBeforeDelete {
+		p.Labels[constants.FinalSnapshotLabel] = "true"
+		if p.SnapshotBeforeDeleteStorageLocation != "" {
+			p.Labels[constants.FinalSnapshotStorageLocationLabel] = p.SnapshotBeforeDeleteStorageLocation
+		}
+		if p.SnapshotBeforeDeleteRetentionDays > 0 {
+			p.Labels[constants.FinalSnapshotRetentionDaysLabel] = strconv.Itoa(p.SnapshotBeforeDeleteRetentionDays)
+		}
+	} else if p.SnapshotBeforeDeleteStorageLocation != "" || p.SnapshotBeforeDeleteRetentionDays > 0 {
+		return p, d, fmt.Errorf("%s and %s require %s to be true", ParameterKeySnapshotBeforeDeleteStorageLocation, ParameterKeySnapshotBeforeDeleteRetentionDays, ParameterKeySnapshotBeforeDelete)
+	}
+	if p.NodeEncryptionKMSKey != "" && p.NodeEncryption != NodeEncryptionLuks {
+		return p, d, fmt.Errorf("%s requires %s to be %q", ParameterKeyNodeEncryptionKmsKey, ParameterKeyNodeEncryption, NodeEncryptionLuks)
+	}
+	if len(p.AsyncReplicationSecondaryZones) > 0 {
+		if want := numZones(p); len(p.AsyncReplicationSecondaryZones) != want {
+			return p, d, fmt.Errorf("%s needs %d zones for disk type %s, got %v", ParameterKeyAsyncReplicationSecondaryZones, want, p.DiskType, p.AsyncReplicationSecondaryZones)
+		}
+		if p.MultiZoneProvisioning {
+			return p, d, fmt.Errorf("%s is not supported with %s", ParameterKeyAsyncReplicationSecondaryZones, ParameterKeyEnableMultiZoneProvisioning)
+		}
+	}
+	if pp.ClusterName != "" {
+		p.Tags[tagKeyCreatedForClusterName] = pp.ClusterName
+	}
 	if len(p.Tags) > 0 {
 		p.Tags[tagKeyCreatedBy] = pp.DriverName
 	}
 	return p, d, nil
 }
 
-func ExtractAndDefaultSnapshotParameters(parameters map[string]string, driverName string, extraTags map[string]string) (SnapshotParameters, error) {
+// CreatedForVolumeName returns the name of the PV in the description tags of
+// a disk, or "" if it has none.
+func CreatedForVolumeName(diskTags map[string]string) string {
+	return diskTags[tagKeyCreatedForVolumeName]
+}
+
+// CreatedForClusterName returns the name of the cluster in the description
+// tags of a disk, or "" if it has none.
+func CreatedForClusterName(diskTags map[string]string) string {
+	return diskTags[tagKeyCreatedForClusterName]
+}
+
+// ExtractAndDefaultSnapshotParameters extracts the parameters of snapshots and
+// images. clusterName is available to the templates of the labels parameter.
+func ExtractAndDefaultSnapshotParameters(parameters map[string]string, driverName, clusterName string, extraTags map[string]string) (SnapshotParameters, error) {
 	p := SnapshotParameters{
 		StorageLocations: []string{},
 		SnapshotType:     DiskSnapshotType,
@@ -367,6 +516,7 @@ func ExtractAndDefaultSnapshotParameters(parameters map[string]string, driverNam
 		p.ResourceTags[k] = v
 	}
 
+	var labelsParam string
 	for k, v := range parameters {
 		switch strings.ToLower(k) {
 		case ParameterKeyStorageLocations:
@@ -390,14 +540,9 @@ func ExtractAndDefaultSnapshotParameters(parameters map[string]string, driverNam
 		case ParameterKeyVolumeSnapshotContentName:
 			p.Tags[tagKeyCreatedForSnapshotContentName] = v
 		case ParameterKeyLabels:
-			paramLabels, err := convert.ConvertLabelsStringToMap(v)
-			if err != nil {
-				return p, fmt.Errorf("parameters contain invalid labels parameter: %w", err)
-			}
-			// Override any existing labels with those from this parameter.
-			for labelKey, labelValue := range paramLabels {
-				p.Labels[labelKey] = labelValue
-			}
+			// Templates are executed once the VolumeSnapshot parameters are
+			// read.
+			labelsParam = v
 		case ParameterKeyResourceTags:
 			if err := extractResourceTagsParameter(v, p.ResourceTags); err != nil {
 				return p, err
@@ -406,12 +551,120 @@ func ExtractAndDefaultSnapshotParameters(parameters map[string]string, driverNam
 			return p, fmt.Errorf("parameters contains invalid option %q", k)
 		}
 	}
+	if labelsParam != "" {
+		templateData := snapshotLabelTemplateData{
+			VolumeSnapshotName:        p.Tags[tagKeyCreatedForSnapshotName],
+			VolumeSnapshotNamespace:   p.Tags[tagKeyCreatedForSnapshotNamespace],
+			VolumeSnapshotContentName: p.Tags[tagKeyCreatedForSnapshotContentName],
+			ClusterName:               clusterName,
+		}
+		if err := extractLabelsParameter(labelsParam, templateData, p.Labels); err != nil {
+			return p, err
+		}
+	}
 	if len(p.Tags) > 0 {
 		p.Tags[tagKeyCreatedBy] = driverName
 	}
 	return p, nil
 }
 
+// FinalSnapshotParameters returns the parameters of the snapshot taken before
+// deleting a disk with the labels diskLabels. The PV and PVC tags of the
+// description of the disk, diskTags, are copied to the snapshot so that it
+// can be traced back to the deleted volume.
+func FinalSnapshotParameters(diskLabels, diskTags map[string]string, driverName string) SnapshotParameters {
+	p := SnapshotParameters{
+		StorageLocations: []string{},
+		SnapshotType:     DiskSnapshotType,
+		Tags:             make(map[string]string),
+		Labels:           map[string]string{constants.FinalSnapshotLabel: "true"},
+		ResourceTags:     make(map[string]string),
+	}
+	if location := diskLabels[constants.FinalSnapshotStorageLocationLabel]; location != "" {
+		p.StorageLocations = []string{location}
+	}
+	if days := diskLabels[constants.FinalSnapshotRetentionDaysLabel]; days != "" {
+		p.Labels[constants.FinalSnapshotRetentionDaysLabel] = days
+	}
+	for _, key := range []string{tagKeyCreatedForClaimName, tagKeyCreatedForClaimNamespace, tagKeyCreatedForVolumeName} {
+		if v, ok := diskTags[key]; ok {
+			p.Tags[key] = v
+		}
+	}
+	p.Tags[tagKeyCreatedBy] = driverName
+	return p
+}
+
+// volumeLabelTemplateData is the data of the templates of label values in the
+// labels parameter of volumes, such as "team={{.PVCNamespace}}".
+type volumeLabelTemplateData struct {
+	PVCName      string
+	PVCNamespace string
+	PVName       string
+	ClusterName  string
+}
+
+// snapshotLabelTemplateData is the data of the templates of label values in
+// the labels parameter of snapshots and images.
+type snapshotLabelTemplateData struct {
+	VolumeSnapshotName        string
+	VolumeSnapshotNamespace   string
+	VolumeSnapshotContentName string
+	ClusterName               string
+}
+
+// extractLabelsParameter adds the labels of labelsString to labels, overriding
+// existing labels. Label values containing "{{" are text/template templates
+// executed with templateData, and sanitized to valid GCE label values.
+func extractLabelsParameter(labelsString string, templateData any, labels map[string]string) error {
+	var rendered []string
+	for _, keyValue := range splitOutsideActions(labelsString, ',') {
+		key, value, found := strings.Cut(keyValue, "=")
+		if found && strings.Contains(value, "{{") {
+			tmpl, err := template.New(strings.TrimSpace(key)).Option("missingkey=error").Parse(value)
+			if err != nil {
+				return fmt.Errorf("parameters contain invalid template in labels parameter: %w", err)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, templateData); err != nil {
+				return fmt.Errorf("parameters contain invalid template in labels parameter: %w", err)
+			}
+			keyValue = key + "=" + convert.SanitizeLabelValue(b.String())
+		}
+		rendered = append(rendered, keyValue)
+	}
+	paramLabels, err := convert.ConvertLabelsStringToMap(strings.Join(rendered, ","))
+	if err != nil {
+		return fmt.Errorf("parameters contain invalid labels parameter: %w", err)
+	}
+	// Override any existing labels with those from this parameter.
+	for labelKey, labelValue := range paramLabels {
+		labels[labelKey] = labelValue
+	}
+	return nil
+}
+
+// splitOutsideActions splits s around sep, except inside the {{ }} actions of
+// templates, whose arguments may contain sep.
+func splitOutsideActions(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			depth++
+			i++
+		case strings.HasPrefix(s[i:], "}}") && depth > 0:
+			depth--
+			i++
+		case s[i] == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
 func extractResourceTagsParameter(tagsString string, resourceTags map[string]string) error {
 	paramResourceTags, err := convert.ConvertTagsStringToMap(tagsString)
 	if err != nil {
@@ -442,6 +695,30 @@ func ExtractModifyVolumeParameters(parameters map[string]string) (ModifyVolumePa
 				return ModifyVolumeParameters{}, fmt.Errorf("parameters contain invalid throughput parameter: %w", err)
 			}
 			modifyVolumeParams.Throughput = &throughput
+		case ParameterKeyLabels:
+			labels, err := convert.ConvertLabelsStringToMap(value)
+			if err != nil {
+				return ModifyVolumeParameters{}, fmt.Errorf("parameters contain invalid labels parameter: %w", err)
+			}
+			modifyVolumeParams.Labels = labels
+		case ParameterKeyResourceTags:
+			resourceTags := make(map[string]string)
+			if err := extractResourceTagsParameter(value, resourceTags); err != nil {
+				return ModifyVolumeParameters{}, err
+			}
+			modifyVolumeParams.ResourceTags = resourceTags
+		case ParameterKeyType:
+			if value == "" {
+				return ModifyVolumeParameters{}, fmt.Errorf("parameters contain empty %s parameter", ParameterKeyType)
+			}
+			modifyVolumeParams.DiskType = strings.ToLower(value)
+		case ParameterAccessMode:
+			switch value {
+			case constants.GCEReadWriteOnceAccessMode, constants.GCEReadOnlyManyAccessMode:
+				modifyVolumeParams.AccessMode = value
+			default:
+				return ModifyVolumeParameters{}, fmt.Errorf("parameters contain invalid %s parameter %q, must be %s or %s", ParameterAccessMode, value, constants.GCEReadWriteOnceAccessMode, constants.GCEReadOnlyManyAccessMode)
+			}
 		default:
 			return ModifyVolumeParameters{}, fmt.Errorf("parameters contain unknown parameter: %s", key)
 		}
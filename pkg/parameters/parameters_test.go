@@ -18,6 +18,7 @@ package parameters
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -34,6 +35,8 @@ func TestExtractAndDefaultParameters(t *testing.T) {
 		enableMultiZone       bool
 		enableHdHA            bool
 		enableDiskTopology    bool
+		allowedProjects       []string
+		clusterName           string
 		extraTags             map[string]string
 		expectParams          DiskParameters
 		expectDataCacheParams DataCacheParameters
@@ -170,6 +173,19 @@ func TestExtractAndDefaultParameters(t *testing.T) {
 				ResourceTags:         map[string]string{},
 			},
 		},
+		{
+			name:        "cluster name",
+			parameters:  map[string]string{ParameterKeyPVName: "testPVName"},
+			labels:      map[string]string{},
+			clusterName: "test-cluster",
+			expectParams: DiskParameters{
+				DiskType:        "pd-standard",
+				ReplicationType: "none",
+				Tags:            map[string]string{tagKeyCreatedForVolumeName: "testPVName", tagKeyCreatedForClusterName: "test-cluster", tagKeyCreatedBy: "testDriver"},
+				Labels:          map[string]string{},
+				ResourceTags:    map[string]string{},
+			},
+		},
 		{
 			name:       "extra labels",
 			parameters: map[string]string{},
@@ -450,6 +466,101 @@ func TestExtractAndDefaultParameters(t *testing.T) {
 			parameters: map[string]string{ParameterKeyType: "hyperdisk-ml", ParameterKeyEnableMultiZoneProvisioning: "true"},
 			expectErr:  true,
 		},
+		{
+			name:       "async replication secondary zones, zonal disk",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyAsyncReplicationSecondaryZones: " US-East1-B "},
+			labels:     map[string]string{},
+			expectParams: DiskParameters{
+				DiskType:                       "pd-ssd",
+				ReplicationType:                "none",
+				Tags:                           map[string]string{},
+				ResourceTags:                   map[string]string{},
+				Labels:                         map[string]string{},
+				AsyncReplicationSecondaryZones: []string{"us-east1-b"},
+			},
+		},
+		{
+			name:       "async replication secondary zones, regional disk",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyReplicationType: "regional-pd", ParameterKeyAsyncReplicationSecondaryZones: "us-east1-b,us-east1-c"},
+			labels:     map[string]string{},
+			expectParams: DiskParameters{
+				DiskType:                       "pd-ssd",
+				ReplicationType:                "regional-pd",
+				Tags:                           map[string]string{},
+				ResourceTags:                   map[string]string{},
+				Labels:                         map[string]string{},
+				AsyncReplicationSecondaryZones: []string{"us-east1-b", "us-east1-c"},
+			},
+		},
+		{
+			name:       "async replication secondary zones, wrong number of zones",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyAsyncReplicationSecondaryZones: "us-east1-b,us-east1-c"},
+			expectErr:  true,
+		},
+		{
+			name:       "async replication secondary zones, zones in different regions",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyReplicationType: "regional-pd", ParameterKeyAsyncReplicationSecondaryZones: "us-east1-b,us-west1-a"},
+			expectErr:  true,
+		},
+		{
+			name:       "async replication secondary zones, invalid zone",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyAsyncReplicationSecondaryZones: "useast1"},
+			expectErr:  true,
+		},
+		{
+			name:            "allowed project",
+			parameters:      map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyProject: "storage-project"},
+			allowedProjects: []string{"other-project", "storage-project"},
+			labels:          map[string]string{},
+			expectParams: DiskParameters{
+				DiskType:        "pd-ssd",
+				ReplicationType: "none",
+				Tags:            map[string]string{},
+				ResourceTags:    map[string]string{},
+				Labels:          map[string]string{},
+				Project:         "storage-project",
+			},
+		},
+		{
+			name:            "project not allowed",
+			parameters:      map[string]string{ParameterKeyType: "pd-ssd", ParameterKeyProject: "storage-project"},
+			allowedProjects: []string{"other-project"},
+			expectErr:       true,
+		},
+		{
+			name:       "snapshot before delete",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeySnapshotBeforeDelete: "true", ParameterKeySnapshotBeforeDeleteStorageLocation: "US-Central1", ParameterKeySnapshotBeforeDeleteRetentionDays: "30"},
+			labels:     map[string]string{},
+			expectParams: DiskParameters{
+				DiskType:        "pd-ssd",
+				ReplicationType: "none",
+				Tags:            map[string]string{},
+				ResourceTags:    map[string]string{},
+				Labels: map[string]string{
+					constants.FinalSnapshotLabel:                "true",
+					constants.FinalSnapshotStorageLocationLabel: "us-central1",
+					constants.FinalSnapshotRetentionDaysLabel:   "30",
+				},
+				SnapshotBeforeDelete:                true,
+				SnapshotBeforeDeleteStorageLocation: "us-central1",
+				SnapshotBeforeDeleteRetentionDays:   30,
+			},
+		},
+		{
+			name:       "snapshot before delete, invalid retention days",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeySnapshotBeforeDelete: "true", ParameterKeySnapshotBeforeDeleteRetentionDays: "0"},
+			expectErr:  true,
+		},
+		{
+			name:       "snapshot before delete, invalid storage location",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeySnapshotBeforeDelete: "true", ParameterKeySnapshotBeforeDeleteStorageLocation: "not a location"},
+			expectErr:  true,
+		},
+		{
+			name:       "snapshot before delete disabled with retention days",
+			parameters: map[string]string{ParameterKeyType: "pd-ssd", ParameterKeySnapshotBeforeDeleteRetentionDays: "30"},
+			expectErr:  true,
+		},
 		{
 			name:       "disk parameters, hdha disabled",
 			parameters: map[string]string{ParameterKeyType: "hyperdisk-balanced-high-availability"},
@@ -514,6 +625,118 @@ func TestExtractAndDefaultParameters(t *testing.T) {
 				UseAllowedDiskTopology: true,
 			},
 		},
+		{
+			name:       "node encryption luks with kms key",
+			parameters: map[string]string{ParameterKeyNodeEncryption: "LUKS", ParameterKeyNodeEncryptionKmsKey: "projects/my-project/locations/us-central1/keyRings/TestKeyRing/cryptoKeys/test-key"},
+			expectParams: DiskParameters{
+				DiskType:             "pd-standard",
+				ReplicationType:      "none",
+				Tags:                 map[string]string{},
+				Labels:               map[string]string{},
+				ResourceTags:         map[string]string{},
+				NodeEncryption:       "luks",
+				NodeEncryptionKMSKey: "projects/my-project/locations/us-central1/keyRings/TestKeyRing/cryptoKeys/test-key",
+			},
+		},
+		{
+			name:       "node encryption none",
+			parameters: map[string]string{ParameterKeyNodeEncryption: "none"},
+			expectParams: DiskParameters{
+				DiskType:        "pd-standard",
+				ReplicationType: "none",
+				Tags:            map[string]string{},
+				Labels:          map[string]string{},
+				ResourceTags:    map[string]string{},
+			},
+		},
+		{
+			name:       "node encryption invalid value",
+			parameters: map[string]string{ParameterKeyNodeEncryption: "bitlocker"},
+			expectErr:  true,
+		},
+		{
+			name:       "node encryption kms key without luks",
+			parameters: map[string]string{ParameterKeyNodeEncryptionKmsKey: "projects/my-project/locations/us-central1/keyRings/TestKeyRing/cryptoKeys/test-key"},
+			expectErr:  true,
+		},
+		{
+			name:       "node encryption invalid kms key",
+			parameters: map[string]string{ParameterKeyNodeEncryption: "luks", ParameterKeyNodeEncryptionKmsKey: "test-key"},
+			expectErr:  true,
+		},
+		{
+			name: "labels templates",
+			parameters: map[string]string{
+				ParameterKeyLabels:       "team={{.PVCNamespace}},pvc={{.PVCName}},pv={{.PVName}},cluster={{.ClusterName}},env=prod",
+				ParameterKeyPVCName:      "Data.Postgres-0",
+				ParameterKeyPVCNamespace: "team-a",
+				ParameterKeyPVName:       "pvc-" + strings.Repeat("0123456789", 7),
+			},
+			clusterName: "Prod/Cluster",
+			expectParams: DiskParameters{
+				DiskType:        "pd-standard",
+				ReplicationType: "none",
+				Tags: map[string]string{
+					tagKeyCreatedForClaimName:      "Data.Postgres-0",
+					tagKeyCreatedForClaimNamespace: "team-a",
+					tagKeyCreatedForVolumeName:     "pvc-" + strings.Repeat("0123456789", 7),
+					tagKeyCreatedForClusterName:    "Prod/Cluster",
+					tagKeyCreatedBy:                "testDriver",
+				},
+				Labels: map[string]string{
+					"team":    "team-a",
+					"pvc":     "data-postgres-0",
+					"pv":      "pvc-" + strings.Repeat("0123456789", 5) + "012345678",
+					"cluster": "prod-cluster",
+					"env":     "prod",
+				},
+				ResourceTags: map[string]string{},
+			},
+		},
+		{
+			name:       "labels template without PVC metadata",
+			parameters: map[string]string{ParameterKeyLabels: "pvc={{.PVCName}}"},
+			expectParams: DiskParameters{
+				DiskType:        "pd-standard",
+				ReplicationType: "none",
+				Tags:            map[string]string{},
+				Labels:          map[string]string{"pvc": ""},
+				ResourceTags:    map[string]string{},
+			},
+		},
+		{
+			name: "labels templates with commas",
+			parameters: map[string]string{
+				ParameterKeyLabels:       `owner={{printf "%s,%s" .PVCNamespace .PVCName}},pvc={{.PVCName}},env=prod`,
+				ParameterKeyPVCName:      "data-0",
+				ParameterKeyPVCNamespace: "team-a",
+			},
+			expectParams: DiskParameters{
+				DiskType:        "pd-standard",
+				ReplicationType: "none",
+				Tags: map[string]string{
+					tagKeyCreatedForClaimName:      "data-0",
+					tagKeyCreatedForClaimNamespace: "team-a",
+					tagKeyCreatedBy:                "testDriver",
+				},
+				Labels: map[string]string{
+					"owner": "team-a-data-0",
+					"pvc":   "data-0",
+					"env":   "prod",
+				},
+				ResourceTags: map[string]string{},
+			},
+		},
+		{
+			name:       "labels template with unknown field",
+			parameters: map[string]string{ParameterKeyLabels: "snapshot={{.VolumeSnapshotName}}"},
+			expectErr:  true,
+		},
+		{
+			name:       "labels template with invalid syntax",
+			parameters: map[string]string{ParameterKeyLabels: "pvc={{.PVCName"},
+			expectErr:  true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -524,6 +747,8 @@ func TestExtractAndDefaultParameters(t *testing.T) {
 				EnableMultiZone:    tc.enableMultiZone,
 				EnableHdHA:         tc.enableHdHA,
 				EnableDiskTopology: tc.enableDiskTopology,
+				AllowedProjects:    tc.allowedProjects,
+				ClusterName:        tc.clusterName,
 			}
 			p, d, err := pp.ExtractAndDefaultParameters(tc.parameters, tc.labels, tc.enableDataCache, tc.extraTags)
 			if gotErr := err != nil; gotErr != tc.expectErr {
@@ -598,10 +823,36 @@ func TestSnapshotParameters(t *testing.T) {
 			parameters:  map[string]string{ParameterKeySnapshotType: "invalid-type"},
 			expectError: true,
 		},
+		{
+			desc: "labels templates",
+			parameters: map[string]string{
+				ParameterKeyVolumeSnapshotName:        "Nightly.Backup",
+				ParameterKeyVolumeSnapshotContentName: "snapcontent-1",
+				ParameterKeyVolumeSnapshotNamespace:   "team-a",
+				ParameterKeyLabels:                    "team={{.VolumeSnapshotNamespace}},snapshot={{.VolumeSnapshotName}},content={{.VolumeSnapshotContentName}},cluster={{.ClusterName}}",
+			},
+			expectedSnapshotParames: SnapshotParameters{
+				StorageLocations: []string{},
+				SnapshotType:     DiskSnapshotType,
+				Tags: map[string]string{
+					tagKeyCreatedForSnapshotName:        "Nightly.Backup",
+					tagKeyCreatedForSnapshotContentName: "snapcontent-1",
+					tagKeyCreatedForSnapshotNamespace:   "team-a",
+					tagKeyCreatedBy:                     "test-driver",
+				},
+				Labels:       map[string]string{"team": "team-a", "snapshot": "nightly-backup", "content": "snapcontent-1", "cluster": "test-cluster"},
+				ResourceTags: map[string]string{},
+			},
+		},
+		{
+			desc:        "labels template with unknown field",
+			parameters:  map[string]string{ParameterKeyLabels: "pvc={{.PVCName}}"},
+			expectError: true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.desc, func(t *testing.T) {
-			p, err := ExtractAndDefaultSnapshotParameters(tc.parameters, "test-driver", tc.extraTags)
+			p, err := ExtractAndDefaultSnapshotParameters(tc.parameters, "test-driver", "test-cluster", tc.extraTags)
 			if err != nil && !tc.expectError {
 				t.Errorf("Got error %v; expect no error", err)
 			}
@@ -614,6 +865,39 @@ func TestSnapshotParameters(t *testing.T) {
 		})
 	}
 }
+
+func TestFinalSnapshotParameters(t *testing.T) {
+	diskLabels := map[string]string{
+		constants.FinalSnapshotLabel:                "true",
+		constants.FinalSnapshotStorageLocationLabel: "us-central1",
+		constants.FinalSnapshotRetentionDaysLabel:   "7",
+		"team": "storage",
+	}
+	diskTags := map[string]string{
+		tagKeyCreatedForClaimName:      "claim",
+		tagKeyCreatedForClaimNamespace: "default",
+		tagKeyCreatedForVolumeName:     "pvc-1234",
+		tagKeyCreatedBy:                "other-driver",
+	}
+	want := SnapshotParameters{
+		StorageLocations: []string{"us-central1"},
+		SnapshotType:     DiskSnapshotType,
+		Tags: map[string]string{
+			tagKeyCreatedForClaimName:      "claim",
+			tagKeyCreatedForClaimNamespace: "default",
+			tagKeyCreatedForVolumeName:     "pvc-1234",
+			tagKeyCreatedBy:                "test-driver",
+		},
+		Labels: map[string]string{
+			constants.FinalSnapshotLabel:              "true",
+			constants.FinalSnapshotRetentionDaysLabel: "7",
+		},
+		ResourceTags: map[string]string{},
+	}
+	if got := FinalSnapshotParameters(diskLabels, diskTags, "test-driver"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got FinalSnapshotParameters() = %+v; expect %+v", got, want)
+	}
+}
 func TestExtractModifyVolumeParameters(t *testing.T) {
 	parameters := map[string]string{
 		"iops":       "1000",
@@ -636,3 +920,67 @@ func TestExtractModifyVolumeParameters(t *testing.T) {
 		t.Errorf("Got ExtractModifyVolumeParameters(%+v) = %+v; want: %v", parameters, result, expected)
 	}
 }
+
+func TestExtractModifyVolumeMetadataParameters(t *testing.T) {
+	testCases := []struct {
+		name       string
+		parameters map[string]string
+		expected   ModifyVolumeParameters
+		expectErr  bool
+	}{
+		{
+			name:       "labels",
+			parameters: map[string]string{ParameterKeyLabels: "team=storage,cost-center=42"},
+			expected:   ModifyVolumeParameters{Labels: map[string]string{"team": "storage", "cost-center": "42"}},
+		},
+		{
+			name:       "resource tags",
+			parameters: map[string]string{ParameterKeyResourceTags: "parent1/key1/value1,parent2/key2/value2"},
+			expected:   ModifyVolumeParameters{ResourceTags: map[string]string{"parent1/key1": "value1", "parent2/key2": "value2"}},
+		},
+		{
+			name:       "access mode",
+			parameters: map[string]string{ParameterAccessMode: "READ_ONLY_MANY"},
+			expected:   ModifyVolumeParameters{AccessMode: "READ_ONLY_MANY"},
+		},
+		{
+			name:       "disk type",
+			parameters: map[string]string{ParameterKeyType: "Hyperdisk-Balanced"},
+			expected:   ModifyVolumeParameters{DiskType: "hyperdisk-balanced"},
+		},
+		{
+			name:       "empty disk type",
+			parameters: map[string]string{ParameterKeyType: ""},
+			expectErr:  true,
+		},
+		{
+			name:       "invalid labels",
+			parameters: map[string]string{ParameterKeyLabels: "Team=storage"},
+			expectErr:  true,
+		},
+		{
+			name:       "invalid resource tags",
+			parameters: map[string]string{ParameterKeyResourceTags: "parent1/key1"},
+			expectErr:  true,
+		},
+		{
+			name:       "unsupported access mode",
+			parameters: map[string]string{ParameterAccessMode: "READ_WRITE_MANY"},
+			expectErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ExtractModifyVolumeParameters(tc.parameters)
+			if gotErr := err != nil; gotErr != tc.expectErr {
+				t.Fatalf("ExtractModifyVolumeParameters(%+v) = %v; expectedErr: %v", tc.parameters, err, tc.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Got ExtractModifyVolumeParameters(%+v) = %+v; want: %+v", tc.parameters, result, tc.expected)
+			}
+		})
+	}
+}
@@ -124,3 +124,37 @@ func isValidDiskEncryptionKmsKey(DiskEncryptionKmsKey string) bool {
 	kmsKeyPattern := regexp.MustCompile("projects/[^/]+/locations/([^/]+)/keyRings/[^/]+/cryptoKeys/[^/]+")
 	return kmsKeyPattern.MatchString(DiskEncryptionKmsKey)
 }
+
+// ParseAsyncReplicationSecondaryZones parses the comma separated zones of the
+// secondary disk of asynchronous replication, which must be in one region.
+func ParseAsyncReplicationSecondaryZones(value string) ([]string, error) {
+	zones := []string{}
+	region := ""
+	for _, zone := range strings.Split(value, ",") {
+		zone = strings.TrimSpace(strings.ToLower(zone))
+		if zone == "" || StringInSlice(zone, zones) {
+			continue
+		}
+		i := strings.LastIndex(zone, "-")
+		if i <= 0 || strings.Count(zone, "-") < 2 {
+			return nil, fmt.Errorf("zone in unexpected format, expected {locale}-{region}-{zone}, got %q", zone)
+		}
+		if region != "" && zone[:i] != region {
+			return nil, fmt.Errorf("zones must be in one region, got %s and %s", region, zone[:i])
+		}
+		region = zone[:i]
+		zones = append(zones, zone)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no zones specified")
+	}
+	return zones, nil
+}
+
+// numZones returns the number of zones of a disk with parameters p.
+func numZones(p DiskParameters) int {
+	if p.IsRegional() {
+		return 2
+	}
+	return 1
+}
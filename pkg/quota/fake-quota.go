@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// FakeProjectQuota keeps project assignments and usage in memory so that
+// callers can be unit tested without quota tools or a quota enabled filesystem.
+type FakeProjectQuota struct {
+	mutex sync.Mutex
+	// Projects maps a directory to the project ID assigned to it.
+	Projects map[string]uint32
+	// Usage maps a project ID to its reported usage. Limits are filled in by
+	// SetProjectQuota.
+	Usage map[uint32]*ProjectUsage
+	// FeatureEnabledDevices records the devices EnableProjectFeature was
+	// called on. HasProjectFeature is true for these devices.
+	FeatureEnabledDevices []string
+}
+
+var _ ProjectQuota = &FakeProjectQuota{}
+
+func NewFakeProjectQuota() *FakeProjectQuota {
+	return &FakeProjectQuota{
+		Projects: map[string]uint32{},
+		Usage:    map[uint32]*ProjectUsage{},
+	}
+}
+
+func (f *FakeProjectQuota) EnableProjectFeature(devicePath, fsType string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if fsType == FsTypeExt4 {
+		f.FeatureEnabledDevices = append(f.FeatureEnabledDevices, devicePath)
+	}
+	return nil
+}
+
+func (f *FakeProjectQuota) HasProjectFeature(devicePath, fsType string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return fsType != FsTypeExt4 || slices.Contains(f.FeatureEnabledDevices, devicePath), nil
+}
+
+func (f *FakeProjectQuota) SetProjectQuota(mountPath, dir string, projectID uint32, limitBytes int64) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Projects[dir] = projectID
+	usage, ok := f.Usage[projectID]
+	if !ok {
+		usage = &ProjectUsage{}
+		f.Usage[projectID] = usage
+	}
+	usage.LimitBytes = limitBytes
+	return nil
+}
+
+func (f *FakeProjectQuota) GetProjectID(path string) (uint32, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.Projects[path], nil
+}
+
+func (f *FakeProjectQuota) GetProjectUsage(path string, projectID uint32) (*ProjectUsage, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	usage, ok := f.Usage[projectID]
+	if !ok {
+		return nil, fmt.Errorf("project %d: %w", projectID, ErrProjectNotFound)
+	}
+	u := *usage
+	return &u, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	FsTypeXFS  = "xfs"
+	FsTypeExt4 = "ext4"
+
+	// MountOptionPrjQuota is the mount option that enables project quota
+	// accounting and enforcement on both xfs and ext4.
+	MountOptionPrjQuota = "prjquota"
+
+	// Project IDs below minProjectID are left for administrators, and 0 is the
+	// default project every inode belongs to.
+	minProjectID uint32 = 1000
+	maxProjectID uint32 = 1<<31 - 1
+
+	// xfs_quota reports block usage in 1KiB units.
+	reportBlockSize = 1024
+)
+
+var (
+	// A line of `xfs_quota -c "report -p -b -i -N -n"` looks like:
+	// #1000        1024          0     102400     00 [--------]          2          0          0     00 [--------]
+	// The grace columns may contain spaces ("[7 days]") so they are matched explicitly.
+	reportLineRegex = regexp.MustCompile(`^#(\d+)\s+(\d+)\s+\d+\s+(\d+)\s+\d+\s+\[[^\]]*\]\s+(\d+)\s+\d+\s+(\d+)`)
+	// `lsattr -pd <path>` prints the project ID followed by the flags and the path.
+	lsattrLineRegex = regexp.MustCompile(`^\s*(\d+)\s+\S+\s+`)
+
+	// ErrProjectNotFound is returned by GetProjectUsage for projects that
+	// have neither usage nor limits.
+	ErrProjectNotFound = errors.New("project not found in quota report")
+)
+
+// ProjectQuota wraps the tools needed to confine a directory to an xfs or
+// ext4 project quota.
+type ProjectQuota interface {
+	// EnableProjectFeature turns on the ext4 project and quota features on an
+	// unmounted device that already holds a filesystem. It is a no-op for xfs.
+	EnableProjectFeature(devicePath, fsType string) error
+	// HasProjectFeature returns true if the filesystem on devicePath can be
+	// mounted with project quotas. It is always true for xfs.
+	HasProjectFeature(devicePath, fsType string) (bool, error)
+	// SetProjectQuota assigns projectID to dir, which must live on the
+	// filesystem mounted at mountPath, and limits the project to limitBytes.
+	SetProjectQuota(mountPath, dir string, projectID uint32, limitBytes int64) error
+	// GetProjectID returns the project ID the inode at path belongs to.
+	GetProjectID(path string) (uint32, error)
+	// GetProjectUsage reports the usage and limits of projectID on the
+	// filesystem containing path.
+	GetProjectUsage(path string, projectID uint32) (*ProjectUsage, error)
+}
+
+// ProjectUsage is the accounting xfs_quota keeps for a single project. Limits
+// of zero mean the project is not limited.
+type ProjectUsage struct {
+	UsedBytes   int64
+	LimitBytes  int64
+	UsedInodes  int64
+	LimitInodes int64
+}
+
+// SupportsFsType returns true if project quotas can be enforced on fsType.
+func SupportsFsType(fsType string) bool {
+	return fsType == FsTypeXFS || fsType == FsTypeExt4
+}
+
+// ProjectIDForSubPath deterministically maps a subdirectory onto a project ID
+// so the same subdirectory is always assigned the same project, even across
+// node plugin restarts. Different subdirectories may be mapped onto the same
+// project ID, callers must check that it isn't used by another one.
+func ProjectIDForSubPath(subPath string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(subPath))
+	return minProjectID + h.Sum32()%(maxProjectID-minProjectID+1)
+}
+
+// ParseProjectID parses a user provided project ID and makes sure it is in the
+// range reserved for the driver.
+func ParseProjectID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid project ID %q: %w", s, err)
+	}
+	if uint32(id) < minProjectID || uint32(id) > maxProjectID {
+		return 0, fmt.Errorf("project ID %d must be between %d and %d", id, minProjectID, maxProjectID)
+	}
+	return uint32(id), nil
+}
+
+func parseReport(output string, projectID uint32) (*ProjectUsage, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		match := reportLineRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if len(match) != 6 {
+			continue
+		}
+		id, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || uint32(id) != projectID {
+			continue
+		}
+		var values [4]int64
+		for i := range values {
+			values[i], err = strconv.ParseInt(match[i+2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse quota report line %q: %w", scanner.Text(), err)
+			}
+		}
+		return &ProjectUsage{
+			UsedBytes:   values[0] * reportBlockSize,
+			LimitBytes:  values[1] * reportBlockSize,
+			UsedInodes:  values[2],
+			LimitInodes: values[3],
+		}, nil
+	}
+	return nil, fmt.Errorf("project %d: %w", projectID, ErrProjectNotFound)
+}
+
+// parseFeatures returns true if the "Filesystem features" line of the output
+// of `tune2fs -l` lists both the project and quota features.
+func parseFeatures(output string) (bool, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		features, ok := strings.CutPrefix(scanner.Text(), "Filesystem features:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(features)
+		return slices.Contains(fields, "project") && slices.Contains(fields, "quota"), nil
+	}
+	return false, fmt.Errorf("filesystem features not found in tune2fs output %q", output)
+}
+
+func parseLsattr(output string) (uint32, error) {
+	match := lsattrLineRegex.FindStringSubmatch(output)
+	if len(match) != 2 {
+		return 0, fmt.Errorf("unexpected lsattr output %q", output)
+	}
+	id, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse project ID from lsattr output %q: %w", output, err)
+	}
+	return uint32(id), nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testReport = `#0                 0          0          0     00 [--------]          3          0          0     00 [--------]
+#1000           1024          0     102400     00 [--------]          2          0          0     00 [--------]
+#2000         204800     102400     204800     00  [7 days]         12          0        100     00 [--------]
+`
+
+func TestParseReport(t *testing.T) {
+	testCases := []struct {
+		name      string
+		output    string
+		projectID uint32
+		expUsage  *ProjectUsage
+		expErr    bool
+	}{
+		{
+			name:      "limited project",
+			output:    testReport,
+			projectID: 1000,
+			expUsage: &ProjectUsage{
+				UsedBytes:  1024 * 1024,
+				LimitBytes: 102400 * 1024,
+				UsedInodes: 2,
+			},
+		},
+		{
+			name:      "project in grace period",
+			output:    testReport,
+			projectID: 2000,
+			expUsage: &ProjectUsage{
+				UsedBytes:   204800 * 1024,
+				LimitBytes:  204800 * 1024,
+				UsedInodes:  12,
+				LimitInodes: 100,
+			},
+		},
+		{
+			name:      "missing project",
+			output:    testReport,
+			projectID: 3000,
+			expErr:    true,
+		},
+		{
+			name:      "empty report",
+			projectID: 1000,
+			expErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := parseReport(tc.output, tc.projectID)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("parseReport() got error %v, expected error %v", err, tc.expErr)
+			}
+			if diff := cmp.Diff(tc.expUsage, usage); diff != "" {
+				t.Errorf("parseReport() returned unexpected usage (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseLsattr(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+		expID  uint32
+		expErr bool
+	}{
+		{
+			name:   "ext4 project directory",
+			output: " 1234 --------------e-------P-- /var/lib/kubelet/staging/tenant-a\n",
+			expID:  1234,
+		},
+		{
+			name:   "xfs default project",
+			output: "    0 --------------------- /mnt/test\n",
+			expID:  0,
+		},
+		{
+			name:   "garbage",
+			output: "lsattr: Operation not supported While reading flags on /mnt/test\n",
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := parseLsattr(tc.output)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("parseLsattr() got error %v, expected error %v", err, tc.expErr)
+			}
+			if id != tc.expID {
+				t.Errorf("parseLsattr() got %d, expected %d", id, tc.expID)
+			}
+		})
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	testCases := []struct {
+		name       string
+		output     string
+		expEnabled bool
+		expErr     bool
+	}{
+		{
+			name:       "project and quota features",
+			output:     "Filesystem volume name:   <none>\nFilesystem features:      has_journal ext_attr resize_inode dir_index filetype extent 64bit flex_bg sparse_super large_file huge_file dir_nlink extra_isize quota metadata_csum project\nFilesystem flags:         signed_directory_hash\n",
+			expEnabled: true,
+		},
+		{
+			name:   "quota feature only",
+			output: "Filesystem features:      has_journal ext_attr extent quota\n",
+		},
+		{
+			name:   "no features",
+			output: "tune2fs: Bad magic number in super-block while trying to open /dev/sdb\n",
+			expErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enabled, err := parseFeatures(tc.output)
+			if gotErr := err != nil; gotErr != tc.expErr {
+				t.Fatalf("parseFeatures() got error %v, expected error %t", err, tc.expErr)
+			}
+			if enabled != tc.expEnabled {
+				t.Errorf("parseFeatures() returned %t, expected %t", enabled, tc.expEnabled)
+			}
+		})
+	}
+}
+
+func TestProjectIDForSubPath(t *testing.T) {
+	for _, subPath := range []string{"a", "tenant-a", "tenants/b/data"} {
+		id := ProjectIDForSubPath(subPath)
+		if id < minProjectID || id > maxProjectID {
+			t.Errorf("ProjectIDForSubPath(%q) = %d, out of range", subPath, id)
+		}
+		if again := ProjectIDForSubPath(subPath); again != id {
+			t.Errorf("ProjectIDForSubPath(%q) is not deterministic: %d != %d", subPath, id, again)
+		}
+	}
+	if ProjectIDForSubPath("tenant-a") == ProjectIDForSubPath("tenant-b") {
+		t.Errorf("expected different subpaths to map to different projects")
+	}
+}
+
+func TestParseProjectID(t *testing.T) {
+	testCases := []struct {
+		in     string
+		expID  uint32
+		expErr bool
+	}{
+		{in: "1000", expID: 1000},
+		{in: "2147483647", expID: 2147483647},
+		{in: "999", expErr: true},
+		{in: "0", expErr: true},
+		{in: "-5", expErr: true},
+		{in: "abc", expErr: true},
+	}
+	for _, tc := range testCases {
+		id, err := ParseProjectID(tc.in)
+		if gotErr := err != nil; gotErr != tc.expErr {
+			t.Errorf("ParseProjectID(%q) got error %v, expected error %v", tc.in, err, tc.expErr)
+		}
+		if id != tc.expID {
+			t.Errorf("ParseProjectID(%q) got %d, expected %d", tc.in, id, tc.expID)
+		}
+	}
+}
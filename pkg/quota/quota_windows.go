@@ -0,0 +1,53 @@
+//go:build windows
+
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"errors"
+
+	"k8s.io/utils/exec"
+)
+
+var errUnsupported = errors.New("project quota is not supported on Windows")
+
+var _ ProjectQuota = &projectQuota{}
+
+type projectQuota struct{}
+
+func NewProjectQuota(exec exec.Interface) *projectQuota {
+	return &projectQuota{}
+}
+
+func (pq *projectQuota) EnableProjectFeature(devicePath, fsType string) error {
+	return errUnsupported
+}
+
+func (pq *projectQuota) HasProjectFeature(devicePath, fsType string) (bool, error) {
+	return false, errUnsupported
+}
+
+func (pq *projectQuota) SetProjectQuota(mountPath, dir string, projectID uint32, limitBytes int64) error {
+	return errUnsupported
+}
+
+func (pq *projectQuota) GetProjectID(path string) (uint32, error) {
+	return 0, errUnsupported
+}
+
+func (pq *projectQuota) GetProjectUsage(path string, projectID uint32) (*ProjectUsage, error) {
+	return nil, errUnsupported
+}
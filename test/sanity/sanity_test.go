@@ -15,6 +15,7 @@ limitations under the License.
 package sanitytest
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -114,7 +115,7 @@ func TestSanity(t *testing.T) {
 	}()
 
 	go func() {
-		gceDriver.Run(endpoint, 10000, false /* enableOtelTracing */, nil /* metricsManager */)
+		gceDriver.Run(context.Background(), endpoint, 10000, false /* enableOtelTracing */, nil /* metricsManager */, 0 /* drainTimeout */)
 	}()
 
 	// TODO(#818): Fix failing tests and remove test skip flag.